@@ -0,0 +1,63 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BootProfileSpec defines the desired state of BootProfile
+// +kubebuilder:validation:XValidation:rule="has(self.template) || has(self.kernel)",message="kernel is required without template"
+type BootProfileSpec struct {
+	// Template is the Go template of the iPXE script. It is rendered with the facts of the host,
+	// see the BootScriptData of the PXE controller, and has to start with #!ipxe. Without a
+	// template, the kernel is booted with the initrds and kernel arguments.
+	// +optional
+	Template string `json:"template,omitempty"`
+	// Kernel is the URL of the kernel.
+	// +optional
+	Kernel string `json:"kernel,omitempty"`
+	// Initrds are the URLs of the initrds, e.g. the initramfs and root filesystem of a live image.
+	// +optional
+	Initrds []string `json:"initrds,omitempty"`
+	// KernelArgs are the kernel arguments of all claims using the profile, e.g. console=ttyS1.
+	// +optional
+	KernelArgs []string `json:"kernelArgs,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BootProfile is the Schema for the bootprofiles API
+type BootProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec BootProfileSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BootProfileList contains a list of BootProfile
+type BootProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BootProfile `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BootProfile{}, &BootProfileList{})
+}
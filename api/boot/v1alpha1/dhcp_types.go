@@ -21,9 +21,71 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// DHCPMode is the way the DHCP server serves a host.
+// +kubebuilder:validation:Enum=Server;Proxy
+type DHCPMode string
+
+const (
+	// DHCPModeServer assigns the addresses of the host and serves its boot information.
+	DHCPModeServer DHCPMode = "Server"
+	// DHCPModeProxy only serves the boot information as ProxyDHCP server, i.e. to PXEClient
+	// requests (vendor class option 60) on port 67 and 4011, and leaves the address assignment
+	// to the existing DHCP server of the network.
+	DHCPModeProxy DHCPMode = "Proxy"
+)
+
 // DHCPSpec defines the desired state of DHCP
 type DHCPSpec struct {
 	BareMetalHostRef v1.LocalObjectReference `json:"bareMetalHostRef"`
+	// Mode is the way the host is served. It defaults to the DHCP mode of the manager.
+	// +optional
+	Mode DHCPMode `json:"mode,omitempty"`
+	// MACAddress is the boot MAC address of the host.
+	// +optional
+	MACAddress string `json:"macAddress,omitempty"`
+	// Addresses are the addresses served to the interfaces of the host.
+	// +optional
+	Addresses []StaticAddress `json:"addresses,omitempty"`
+	// BootFileURL is the URL of the boot file served to the host, in DHCPv6 by the boot file URL
+	// option (59) and in DHCPv4 by the boot file name option (67). IPv6 addresses are enclosed in
+	// brackets, e.g. http://[2001:db8::1]:8082/ipxe/<uuid>.
+	// +optional
+	BootFileURL string `json:"bootFileURL,omitempty"`
+	// HTTPBoot is true if the host boots via UEFI HTTP boot. The boot file URL then points to the
+	// EFI binary or ISO image and is served to HTTPClient requests (vendor class option 60).
+	// +optional
+	HTTPBoot bool `json:"httpBoot,omitempty"`
+	// Reservations are the DHCPv6 reservations of the network interfaces of the host.
+	// +optional
+	Reservations []DHCPv6Reservation `json:"reservations,omitempty"`
+}
+
+// DHCPv6Reservation identifies the DHCPv6 client of a network interface of a host.
+type DHCPv6Reservation struct {
+	// Interface is the ID of the network interface.
+	Interface  string `json:"interface"`
+	MACAddress string `json:"macAddress"`
+	// DUID is the DHCP unique identifier of the host in colon separated hex notation. It is the
+	// DUID-UUID (RFC 6355) of the system UUID with the first three fields encoded little-endian.
+	DUID string `json:"duid"`
+	// IAID is the identity association identifier of the interface, derived from the last four
+	// bytes of its MAC address.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=4294967295
+	IAID int64 `json:"iaid"`
+}
+
+// StaticAddress is an address allocated to a network interface of a host.
+type StaticAddress struct {
+	// Name is the name of the network.
+	Name       string `json:"name"`
+	MACAddress string `json:"macAddress"`
+	// Address is the address in CIDR notation, e.g. 10.0.0.5/24.
+	Address string `json:"address"`
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+	// +optional
+	DNSServers []string `json:"dnsServers,omitempty"`
 }
 
 type DHCPState string
@@ -38,6 +100,8 @@ const (
 // DHCPStatus defines the observed state of DHCP
 type DHCPStatus struct {
 	State DHCPState `json:"state,omitempty"`
+	// Mode is the way the host is served, taking the DHCP mode of the manager into account.
+	Mode DHCPMode `json:"mode,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -45,6 +109,8 @@ type DHCPStatus struct {
 
 // DHCP is the Schema for the dhcps API
 // +kubebuilder:printcolumn:name="BareMetalHost",type="string",JSONPath=".spec.bareMetalHostRef.name"
+// +kubebuilder:printcolumn:name="MAC",type="string",JSONPath=".spec.macAddress"
+// +kubebuilder:printcolumn:name="Mode",type="string",JSONPath=".status.mode"
 // +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 type DHCP struct {
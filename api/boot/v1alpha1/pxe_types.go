@@ -32,6 +32,19 @@ type PXESpec struct {
 	BareMetalHostClaimRef v1.LocalObjectReference  `json:"bareMetalHostRef"`
 	IgnitionRef           *v1.LocalObjectReference `json:"ignitionRef,omitempty"`
 	Image                 string                   `json:"image,omitempty"`
+	// ProvisioningGeneration is the provisioning generation of the claim the configuration
+	// is served for. The ignition is copied again whenever it changes.
+	ProvisioningGeneration int64 `json:"provisioningGeneration,omitempty"`
+	// Addresses are configured statically by adding a network configuration per address to the
+	// ignition.
+	// +optional
+	Addresses []StaticAddress `json:"addresses,omitempty"`
+	// BootProfileRef references the boot profile the iPXE script of the host is rendered from.
+	// +optional
+	BootProfileRef *v1.LocalObjectReference `json:"bootProfileRef,omitempty"`
+	// KernelArgs are appended to the kernel arguments of the boot profile.
+	// +optional
+	KernelArgs []string `json:"kernelArgs,omitempty"`
 }
 
 type PXEState string
@@ -46,8 +59,26 @@ const (
 // PXEStatus defines the observed state of PXE
 type PXEStatus struct {
 	State PXEState `json:"state,omitempty"`
+	// ObservedGeneration is the generation of the configuration which is served.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions describe the configuration served to the host.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+const (
+	// PXEConditionBootScriptValid reports whether the iPXE script was rendered from the boot profile.
+	PXEConditionBootScriptValid = "BootScriptValid"
+
+	// PXEReasonBootScriptRendered is the reason of a rendered iPXE script.
+	PXEReasonBootScriptRendered = "BootScriptRendered"
+	// PXEReasonBootProfileNotFound is the reason if the boot profile does not exist.
+	PXEReasonBootProfileNotFound = "BootProfileNotFound"
+	// PXEReasonTemplateInvalid is the reason if the template of the boot profile cannot be
+	// rendered or does not result in an iPXE script.
+	PXEReasonTemplateInvalid = "TemplateInvalid"
+)
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 
@@ -28,20 +28,6 @@ var (
 	BareMetalHostClaimFinalizer = "metal.afritzler.github.io/baremetalhostclaim"
 )
 
-type BMCType string
-
-const (
-	BMCTypeRedfish      BMCType = "Redfish"
-	BMCTypeRedfishLocal BMCType = "RedfishLocal"
-)
-
-type BMCConfiguration struct {
-	Type      BMCType            `json:"type"`
-	Address   string             `json:"address"`
-	BasicAuth bool               `json:"basicAuth"`
-	SecretRef v1.SecretReference `json:"secretRef"`
-}
-
 type PowerState string
 
 const (
@@ -55,9 +41,76 @@ type BareMetalHostSpec struct {
 	SystemID string              `json:"systemId"`
 	Power    PowerState          `json:"power"`
 	ClaimRef *v1.ObjectReference `json:"claimRef,omitempty"`
-	BMC      BMCConfiguration    `json:"bmc"`
+	// BMCRef references the BMC which manages this host.
+	BMCRef v1.LocalObjectReference `json:"bmcRef"`
+	// BootMACAddress is the MAC address of the network interface the host boots from via PXE.
+	// It is validated against the network interfaces of the system and detected if unset.
 	// +kubebuilder:validation:Pattern=`[0-9a-fA-F]{2}(:[0-9a-fA-F]{2}){5}`
 	BootMACAddress string `json:"bootMACAddress,omitempty"`
+	// Maintenance takes the host out of service. The host is initialized again once
+	// maintenance is disabled.
+	Maintenance bool `json:"maintenance,omitempty"`
+	// ProvisioningGeneration is set by the claim of the host to provision the host again. The
+	// host boots via PXE once and is reset if it is powered on whenever the generation changes.
+	// +optional
+	ProvisioningGeneration int64 `json:"provisioningGeneration,omitempty"`
+	// NetworkBoot configures how the host boots from the network. The host boots via PXE if unset.
+	// It is overridden by the network boot of the claim of the host.
+	// +optional
+	NetworkBoot *NetworkBoot `json:"networkBoot,omitempty"`
+	// Taints take the host out of service until they are removed, e.g. after a hardware fault.
+	// A tainted host is not selected by claims and is initialized again once all taints have
+	// been removed.
+	// +listType=map
+	// +listMapKey=key
+	// +optional
+	Taints []HostTaint `json:"taints,omitempty"`
+}
+
+// HostTaint takes a host out of service.
+type HostTaint struct {
+	// Key identifies the cause of the taint, e.g. HardwareFault.
+	Key string `json:"key"`
+	// Message describes why the host has been tainted.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// TimeAdded is the time the taint has been added.
+	// +optional
+	TimeAdded *metav1.Time `json:"timeAdded,omitempty"`
+}
+
+const (
+	// HostTaintHardwareFault is added by the Taint action of a fault policy.
+	HostTaintHardwareFault = "HardwareFault"
+	// HostTaintDiskCleaning is added on the release of a claim with the Required disk cleaning
+	// policy and is removed once the disks of the host have been wiped.
+	HostTaintDiskCleaning = "DiskCleaning"
+	// HostTaintDeprovisioningFailed is added if a host is released before all deprovisioning
+	// steps of its claim completed, e.g. as it did not power off.
+	HostTaintDeprovisioningFailed = "DeprovisioningFailed"
+)
+
+// NetworkBootMode is the protocol a host boots from the network with.
+// +kubebuilder:validation:Enum=PXE;HTTP
+type NetworkBootMode string
+
+const (
+	// NetworkBootModePXE boots via PXE and TFTP.
+	NetworkBootModePXE NetworkBootMode = "PXE"
+	// NetworkBootModeHTTP boots via UEFI HTTP boot.
+	NetworkBootModeHTTP NetworkBootMode = "HTTP"
+)
+
+// NetworkBoot configures how a host boots from the network.
+// +kubebuilder:validation:XValidation:rule="self.mode != 'HTTP' || has(self.url)",message="url is required for HTTP boot"
+type NetworkBoot struct {
+	// +kubebuilder:default=PXE
+	// +optional
+	Mode NetworkBootMode `json:"mode,omitempty"`
+	// URL is the URL of the EFI binary or ISO image the firmware loads via UEFI HTTP boot. It is
+	// set as HTTP boot URI of the boot override and served by DHCP.
+	// +optional
+	URL string `json:"url,omitempty"`
 }
 
 type Phase string
@@ -65,6 +118,10 @@ type Phase string
 const (
 	PhaseBound   Phase = "Bound"
 	PhaseUnbound Phase = "Unbound"
+	// PhaseDeprovisioning is the phase of a deleted claim until its host has been released.
+	PhaseDeprovisioning Phase = "Deprovisioning"
+	// PhaseConflict is the phase of a claim referencing a host which is claimed by another claim.
+	PhaseConflict Phase = "Conflict"
 )
 
 type HostState string
@@ -77,10 +134,31 @@ const (
 	StateMaintenance HostState = "Maintenance"
 )
 
+const (
+	// BareMetalHostConditionHealthy is false if a health policy found the host unhealthy.
+	BareMetalHostConditionHealthy = "Healthy"
+
+	BareMetalHostReasonHealthy   = "Healthy"
+	BareMetalHostReasonUnhealthy = "Unhealthy"
+
+	// BareMetalHostConditionBootMACAddressValid is true if the boot MAC address belongs to a
+	// network interface of the system, and unknown if the system reports no interfaces.
+	BareMetalHostConditionBootMACAddressValid = "BootMACAddressValid"
+
+	BareMetalHostReasonBootMACAddressFound       = "BootMACAddressFound"
+	BareMetalHostReasonBootMACAddressDetected    = "BootMACAddressDetected"
+	BareMetalHostReasonBootMACAddressNotFound    = "BootMACAddressNotFound"
+	BareMetalHostReasonBootMACAddressNotDetected = "BootMACAddressNotDetected"
+	BareMetalHostReasonNoNetworkInterfaces       = "NoNetworkInterfaces"
+)
+
 type NetworkInterface struct {
 	ID                  string `json:"id"`
+	Name                string `json:"name,omitempty"`
 	MACAddress          string `json:"macAddress,omitempty"`
 	PermanentMACAddress string `json:"permanentMacAddress,omitempty"`
+	// PXEBoot is true if a network boot option of the system boots from the interface.
+	PXEBoot bool `json:"pxeBoot,omitempty"`
 }
 
 type Processor struct {
@@ -95,6 +173,55 @@ type Processor struct {
 	Threads               int32  `json:"threads,omitempty"`
 }
 
+// PowerAction is a power action which was issued to the BMC of a host.
+type PowerAction struct {
+	Action PowerState  `json:"action"`
+	Time   metav1.Time `json:"time"`
+}
+
+// BootOverride is a boot source override which was issued to the BMC of a host.
+type BootOverride struct {
+	Enabled redfish.BootSourceOverrideEnabled `json:"enabled"`
+	Target  redfish.BootSourceOverrideTarget  `json:"target"`
+	// URI is the HTTP boot URI of a UEFI HTTP boot override.
+	// +optional
+	URI  string      `json:"uri,omitempty"`
+	Time metav1.Time `json:"time"`
+}
+
+// LogCursor is the position in a log service of the BMC up to which the entries have been
+// processed.
+type LogCursor struct {
+	Service string      `json:"service"`
+	Created metav1.Time `json:"created"`
+	ID      string      `json:"id,omitempty"`
+}
+
+// LogEntry is an entry of a log service of the BMC.
+type LogEntry struct {
+	Service   string                `json:"service"`
+	ID        string                `json:"id"`
+	Created   metav1.Time           `json:"created"`
+	Severity  redfish.EventSeverity `json:"severity,omitempty"`
+	MessageID string                `json:"messageId,omitempty"`
+	Message   string                `json:"message,omitempty"`
+}
+
+// LogSummary summarizes the entries collected from the log services of the BMC.
+type LogSummary struct {
+	// CriticalCount is the number of critical entries collected so far.
+	CriticalCount int32 `json:"criticalCount"`
+	// WarningCount is the number of warning entries collected so far.
+	WarningCount int32 `json:"warningCount"`
+	// LastCollectionTime is the time the log services were read last.
+	LastCollectionTime *metav1.Time `json:"lastCollectionTime,omitempty"`
+	// FirstCollectionTime is the time the log services were read first. The entries present at
+	// that time only set the cursors and are neither counted, reported nor acted on.
+	FirstCollectionTime *metav1.Time `json:"firstCollectionTime,omitempty"`
+	// LatestCritical are the latest critical entries, oldest first.
+	LatestCritical []LogEntry `json:"latestCritical,omitempty"`
+}
+
 // BareMetalHostStatus defines the observed state of BareMetalHost
 type BareMetalHostStatus struct {
 	SystemUUID        string             `json:"systemUUID,omitempty"`
@@ -108,7 +235,21 @@ type BareMetalHostStatus struct {
 	Phase             Phase              `json:"phase,omitempty"`
 	State             HostState          `json:"state,omitempty"`
 	NetworkInterfaces []NetworkInterface `json:"networkInterfaces,omitempty"`
-	Processors        []Processor        `json:"processors"`
+	Processors        []Processor        `json:"processors,omitempty"`
+	// PowerActions are the latest power actions issued to the BMC, oldest first.
+	PowerActions []PowerAction `json:"powerActions,omitempty"`
+	// BootOverrides are the latest boot source overrides issued to the BMC, oldest first.
+	BootOverrides []BootOverride `json:"bootOverrides,omitempty"`
+	// LogCursors are the positions up to which the log services of the BMC have been processed.
+	LogCursors []LogCursor `json:"logCursors,omitempty"`
+	// LogSummary summarizes the entries of the log services of the BMC.
+	LogSummary *LogSummary `json:"logSummary,omitempty"`
+	// ObservedProvisioningGeneration is the provisioning generation the host has been booted
+	// for.
+	ObservedProvisioningGeneration int64 `json:"observedProvisioningGeneration,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -116,12 +257,17 @@ type BareMetalHostStatus struct {
 //+kubebuilder:resource:scope=Cluster,shortName=host
 
 // BareMetalHost is the Schema for the baremetalhosts API
+// +kubebuilder:printcolumn:name="BMC",type="string",JSONPath=".spec.bmcRef.name"
+// +kubebuilder:printcolumn:name="SystemID",type="string",JSONPath=".spec.systemId"
 // +kubebuilder:printcolumn:name="SystemUUID",type="string",JSONPath=".status.systemUUID"
 // +kubebuilder:printcolumn:name="Manufacturer",type="string",JSONPath=".status.manufacturer"
 // +kubebuilder:printcolumn:name="Model",type="string",JSONPath=".status.model"
 // +kubebuilder:printcolumn:name="PowerState",type="string",JSONPath=".status.powerState"
 // +kubebuilder:printcolumn:name="Health",type="string",JSONPath=".status.health"
 // +kubebuilder:printcolumn:name="SystemState",type="string",JSONPath=".status.systemState"
+// +kubebuilder:printcolumn:name="Healthy",type="string",JSONPath=".status.conditions[?(@.type==\"Healthy\")].status"
+// +kubebuilder:printcolumn:name="Claimed By",type="string",JSONPath=".spec.claimRef.name"
+// +kubebuilder:printcolumn:name="Claim Namespace",type="string",JSONPath=".spec.claimRef.namespace",priority=1
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
 // +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
@@ -22,16 +22,236 @@ import (
 )
 
 // BareMetalHostClaimSpec defines the desired state of BareMetalHostClaim
+// +kubebuilder:validation:XValidation:rule="(has(self.bareMetalHostRef) && has(self.bareMetalHostRef.name)) || has(self.bareMetalHostSelector)",message="either bareMetalHostRef or bareMetalHostSelector is required"
 type BareMetalHostClaimSpec struct {
-	Power            PowerState               `json:"power"`
-	BareMetalHostRef v1.LocalObjectReference  `json:"bareMetalHostRef"`
-	IgnitionRef      *v1.LocalObjectReference `json:"ignitionRef,omitempty"`
-	Image            string                   `json:"image"`
+	Power PowerState `json:"power"`
+	// BareMetalHostRef references the claimed host. It is set to the selected host if the
+	// claim selects its host by labels.
+	// +optional
+	BareMetalHostRef v1.LocalObjectReference `json:"bareMetalHostRef"`
+	// BareMetalHostSelector selects the claimed host by labels if no host is referenced.
+	// Only available hosts which are not unhealthy are selected.
+	BareMetalHostSelector *metav1.LabelSelector `json:"bareMetalHostSelector,omitempty"`
+	// IgnitionRef references the secret containing the ignition in the key ignition. The
+	// secret is labeled with the IgnitionSecretLabel, so that changes are watched.
+	IgnitionRef *v1.LocalObjectReference `json:"ignitionRef,omitempty"`
+	Image       string                   `json:"image"`
+	// ReprovisionPolicy defines whether changes of the image or the ignition are applied to
+	// the host. OnChange reboots the host to provision it again, OnDelete only applies them
+	// once the host is provisioned by a new claim.
+	// +kubebuilder:validation:Enum=OnChange;OnDelete
+	// +kubebuilder:default=OnChange
+	// +optional
+	ReprovisionPolicy ReprovisionPolicy `json:"reprovisionPolicy,omitempty"`
+	// Priority orders the claims waiting for a host. Claims with a higher priority are bound
+	// first, claims with the same priority in the order of their creation.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+	// PreemptionPolicy defines whether the claim preempts a claim of its namespace with a lower
+	// priority if no host is available. The preempted claim is unbound after a grace period.
+	// +kubebuilder:validation:Enum=Never;PreemptLowerPriority
+	// +kubebuilder:default=Never
+	// +optional
+	PreemptionPolicy PreemptionPolicy `json:"preemptionPolicy,omitempty"`
+	// LeaseDuration is the duration after which the claim expires once it is bound. The lease
+	// is extended by increasing the duration.
+	// +optional
+	LeaseDuration *metav1.Duration `json:"leaseDuration,omitempty"`
+	// ExpiresAt is the time at which the claim expires. If both ExpiresAt and LeaseDuration are
+	// set, the claim expires at the earlier time.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// IPAddresses are the addresses allocated to the host for the claim. The address of the boot
+	// interface is served by DHCP, all addresses are configured statically by the ignition.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	IPAddresses []ClaimIPAddress `json:"ipAddresses,omitempty"`
+	// NetworkBoot configures how the host boots from the network for the claim, overriding the
+	// network boot of the host.
+	// +optional
+	NetworkBoot *NetworkBoot `json:"networkBoot,omitempty"`
+	// BootProfileRef references the boot profile the iPXE script of the host is rendered from.
+	// +optional
+	BootProfileRef *v1.LocalObjectReference `json:"bootProfileRef,omitempty"`
+	// KernelArgs are appended to the kernel arguments of the boot profile, e.g. ignition.platform.id=metal.
+	// +optional
+	KernelArgs []string `json:"kernelArgs,omitempty"`
+	// DiskCleaning defines whether the disks of the host have to be cleaned once the claim is
+	// deleted. Required releases the host with the DiskCleaning taint, which is removed once
+	// the disks have been wiped.
+	// +kubebuilder:validation:Enum=None;Required
+	// +kubebuilder:default=None
+	// +optional
+	DiskCleaning DiskCleaningPolicy `json:"diskCleaning,omitempty"`
 }
 
+// ClaimIPAddress requests an address of a pool for a network interface of the claimed host.
+type ClaimIPAddress struct {
+	// Name is the name of the network, which names the IPAddressClaim <claim>-<name> and the
+	// network configuration rendered into the ignition.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+	// IPPoolRef references the pool the address is allocated from.
+	IPPoolRef v1.LocalObjectReference `json:"ipPoolRef"`
+	// Address requests a specific address of the pool.
+	// +optional
+	Address string `json:"address,omitempty"`
+	// MACAddress is the MAC address of the interface the address is configured on. It defaults
+	// to the boot MAC address of the host.
+	// +kubebuilder:validation:Pattern=`[0-9a-fA-F]{2}(:[0-9a-fA-F]{2}){5}`
+	// +optional
+	MACAddress string `json:"macAddress,omitempty"`
+}
+
+// PreemptionPolicy defines whether a claim preempts claims with a lower priority.
+type PreemptionPolicy string
+
+const (
+	// PreemptionPolicyNever waits for a host to become available.
+	PreemptionPolicyNever PreemptionPolicy = "Never"
+	// PreemptionPolicyPreemptLowerPriority preempts the claim with the lowest priority bound
+	// to a host the claim could be bound to.
+	PreemptionPolicyPreemptLowerPriority PreemptionPolicy = "PreemptLowerPriority"
+)
+
+// IgnitionSecretLabel is set to true on the ignition secrets referenced by claims. Only the
+// secrets with the label are watched by the operator.
+const IgnitionSecretLabel = "metal.afritzler.github.io/ignition"
+
+// ReprovisionPolicy defines how changes of a claim are applied to the provisioned host.
+type ReprovisionPolicy string
+
+const (
+	// ReprovisionPolicyOnChange provisions the host again whenever the image or the content
+	// of the ignition changes.
+	ReprovisionPolicyOnChange ReprovisionPolicy = "OnChange"
+	// ReprovisionPolicyOnDelete leaves the provisioned host untouched on changes.
+	ReprovisionPolicyOnDelete ReprovisionPolicy = "OnDelete"
+)
+
+// DiskCleaningPolicy defines whether the disks of a released host have to be cleaned.
+type DiskCleaningPolicy string
+
+const (
+	// DiskCleaningPolicyNone releases the host without cleaning its disks.
+	DiskCleaningPolicyNone DiskCleaningPolicy = "None"
+	// DiskCleaningPolicyRequired releases the host with the DiskCleaning taint.
+	DiskCleaningPolicyRequired DiskCleaningPolicy = "Required"
+)
+
+// ForceReleaseAnnotation releases the host of a deleted claim without waiting for the
+// pending deprovisioning steps if set to true. The host is released with the
+// DeprovisioningFailed taint.
+const ForceReleaseAnnotation = "metal.afritzler.github.io/force-release"
+
 // BareMetalHostClaimStatus defines the observed state of BareMetalHostClaim
 type BareMetalHostClaimStatus struct {
 	Phase Phase `json:"phase,omitempty"`
+	// ProvisionedAt is the time the host was first powered on with the PXE configuration
+	// of the claim.
+	ProvisionedAt *metav1.Time `json:"provisionedAt,omitempty"`
+	// ProvisioningGeneration is increased whenever the host is provisioned for the claim,
+	// starting with 1 for the first provisioning.
+	ProvisioningGeneration int64 `json:"provisioningGeneration,omitempty"`
+	// ProvisioningHash is the hash of the image and the ignition content the host has been
+	// provisioned with.
+	ProvisioningHash string `json:"provisioningHash,omitempty"`
+	// ConsoleLog is the recording of the serial console output of the host during
+	// provisioning.
+	ConsoleLog *ConsoleLogStatus `json:"consoleLog,omitempty"`
+	// BoundAt is the time the claim was first bound to its host, at which its lease starts.
+	BoundAt *metav1.Time `json:"boundAt,omitempty"`
+	// ExpiresAt is the time the lease of the claim expires, limited by the maximum lease of the
+	// pools of its host. The claim is deleted once it expired.
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// PreemptedBy references the claim with a higher priority which preempts the claim.
+	PreemptedBy *v1.ObjectReference `json:"preemptedBy,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// BareMetalHostClaimConditionDeprovisioned is false while the host of a deleted claim is
+	// torn down and describes the pending step or the failure.
+	BareMetalHostClaimConditionDeprovisioned = "Deprovisioned"
+
+	BareMetalHostClaimReasonPoweringOff          = "PoweringOff"
+	BareMetalHostClaimReasonRemovingBootConfig   = "RemovingBootConfiguration"
+	BareMetalHostClaimReasonDeprovisioningFailed = "DeprovisioningFailed"
+
+	// BareMetalHostClaimConditionWithinQuota reports whether the quotas of the pools of the
+	// host admit the claim. It is only set if the host belongs to a pool.
+	BareMetalHostClaimConditionWithinQuota = "WithinQuota"
+
+	BareMetalHostClaimReasonQuotaAvailable = "QuotaAvailable"
+	BareMetalHostClaimReasonQuotaExceeded  = "QuotaExceeded"
+
+	// BareMetalHostClaimConditionPreempted is true while the claim is preempted by a claim with
+	// a higher priority. The claim is unbound once the grace period passed since the last
+	// transition of the condition and waits until the preempting claim is bound.
+	BareMetalHostClaimConditionPreempted = "Preempted"
+
+	BareMetalHostClaimReasonPreemptedByHigherPriority = "PreemptedByHigherPriority"
+
+	// BareMetalHostClaimConditionLeaseExpiring is true once the lease of the claim expires
+	// within the warning period. The reason is LeaseExpired once the lease of a member of a
+	// claim set expired, which is released by the set.
+	BareMetalHostClaimConditionLeaseExpiring = "LeaseExpiring"
+
+	BareMetalHostClaimReasonLeaseExpiring = "LeaseExpiring"
+	BareMetalHostClaimReasonLeaseExpired  = "LeaseExpired"
+
+	// BareMetalHostClaimConditionConflict is true while the host referenced by the claim is
+	// claimed by another claim, which is named in the message.
+	BareMetalHostClaimConditionConflict = "Conflict"
+
+	BareMetalHostClaimReasonHostClaimed = "HostClaimed"
+
+	// BareMetalHostClaimConditionIPAddressesAllocated reports whether the addresses of the claim
+	// are allocated. The host is only provisioned once all addresses are allocated.
+	BareMetalHostClaimConditionIPAddressesAllocated = "IPAddressesAllocated"
+
+	BareMetalHostClaimReasonIPAddressesAllocated = "IPAddressesAllocated"
+	BareMetalHostClaimReasonIPAddressesPending   = "IPAddressesPending"
+
+	// BareMetalHostClaimConditionProvisioned reports whether the console output of the host
+	// showed that the provisioning succeeded or failed. It is removed once the host is
+	// provisioned again.
+	BareMetalHostClaimConditionProvisioned = "Provisioned"
+
+	BareMetalHostClaimReasonProvisioningSucceeded = "ProvisioningSucceeded"
+	BareMetalHostClaimReasonProvisioningFailed    = "ProvisioningFailed"
+)
+
+// ConsoleLogState is the state of the recording of a serial console.
+type ConsoleLogState string
+
+const (
+	// ConsoleLogStateRecording is the state while the console output is recorded.
+	ConsoleLogStateRecording ConsoleLogState = "Recording"
+	// ConsoleLogStateRecorded is the state once the recording completed.
+	ConsoleLogStateRecorded ConsoleLogState = "Recorded"
+	// ConsoleLogStateFailed is the state if the console could not be recorded, e.g. as the
+	// BMC provides no serial console.
+	ConsoleLogStateFailed ConsoleLogState = "Failed"
+)
+
+// ConsoleLogSecretKey is the key of the recorded console output in the console log secret.
+const ConsoleLogSecretKey = "console.log"
+
+// ConsoleLogStatus describes the recording of the serial console output of a host.
+type ConsoleLogStatus struct {
+	State ConsoleLogState `json:"state"`
+	// SecretRef references the secret in the namespace of the claim containing the most
+	// recent console output in the key console.log.
+	SecretRef v1.LocalObjectReference `json:"secretRef"`
+	// StartedAt is the time the recording started.
+	StartedAt metav1.Time `json:"startedAt"`
+	// Message describes why the recording failed.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -43,6 +263,9 @@ type BareMetalHostClaimStatus struct {
 // +kubebuilder:printcolumn:name="Ignition",type="string",JSONPath=".spec.ignitionRef.name"
 // +kubebuilder:printcolumn:name="Image",type="string",JSONPath=".spec.image"
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Generation",type="integer",JSONPath=".status.provisioningGeneration",priority=1
+// +kubebuilder:printcolumn:name="Priority",type="integer",JSONPath=".spec.priority",priority=1
+// +kubebuilder:printcolumn:name="Expires",type="date",JSONPath=".status.expiresAt",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 type BareMetalHostClaim struct {
 	metav1.TypeMeta   `json:",inline"`
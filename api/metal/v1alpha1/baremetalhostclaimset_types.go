@@ -0,0 +1,132 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ClaimSetLabel is the label of the member claims of a BareMetalHostClaimSet naming the set.
+	ClaimSetLabel = "metal.afritzler.github.io/claim-set"
+	// ClaimSetIndexLabel is the label of the member claims of a BareMetalHostClaimSet containing
+	// the index of the member.
+	ClaimSetIndexLabel = "metal.afritzler.github.io/claim-set-index"
+)
+
+// BareMetalHostClaimTemplate describes the member claims of a BareMetalHostClaimSet.
+type BareMetalHostClaimTemplate struct {
+	// Labels are added to the member claims.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Spec is the spec of the member claims. The members reference the hosts selected for
+	// them by the set.
+	Spec BareMetalHostClaimSpec `json:"spec"`
+}
+
+// BareMetalHostClaimSetSpec defines the desired state of BareMetalHostClaimSet
+type BareMetalHostClaimSetSpec struct {
+	// Replicas is the number of hosts claimed by the set.
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 `json:"replicas"`
+	// TopologyKey is the label of the hosts whose value has to differ for every member, e.g.
+	// the rack of the hosts. Hosts without the label are not selected.
+	// +optional
+	TopologyKey string `json:"topologyKey,omitempty"`
+	// Template is the template of the member claims. Its host selector selects the hosts of
+	// the set.
+	Template BareMetalHostClaimTemplate `json:"template"`
+	// BindingTimeout is the duration within which all members have to be bound once they have
+	// been placed. Otherwise all members are released and placed again once the timeout passed
+	// again.
+	// +kubebuilder:default="10m"
+	// +optional
+	BindingTimeout *metav1.Duration `json:"bindingTimeout,omitempty"`
+}
+
+// BareMetalHostClaimSetStatus defines the observed state of BareMetalHostClaimSet
+type BareMetalHostClaimSetStatus struct {
+	// Phase is Bound once all members are bound to their hosts.
+	Phase Phase `json:"phase,omitempty"`
+	// Replicas is the number of member claims.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+	// BoundReplicas is the number of member claims bound to their hosts.
+	// +optional
+	BoundReplicas int32 `json:"boundReplicas,omitempty"`
+	// PlacedAt is the time members have been placed while not all members are bound. It is
+	// removed once all members are bound.
+	PlacedAt *metav1.Time `json:"placedAt,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// BareMetalHostClaimSetConditionPlaced reports whether hosts have been selected for all
+	// members of the set.
+	BareMetalHostClaimSetConditionPlaced = "Placed"
+
+	BareMetalHostClaimSetReasonPlaced            = "Placed"
+	BareMetalHostClaimSetReasonInsufficientHosts = "InsufficientHosts"
+
+	// BareMetalHostClaimSetConditionBindingTimedOut is true once the members have been released
+	// as not all of them were bound within the binding timeout. It is removed once all members
+	// are bound.
+	BareMetalHostClaimSetConditionBindingTimedOut = "BindingTimedOut"
+
+	BareMetalHostClaimSetReasonMembersNotBound = "MembersNotBound"
+
+	// BareMetalHostClaimSetConditionExpired is true once the lease of a member expired. All
+	// members are released and no members are placed until the spec of the set changes, e.g.
+	// the lease of the template is extended.
+	BareMetalHostClaimSetConditionExpired = "Expired"
+
+	BareMetalHostClaimSetReasonLeaseExpired = "LeaseExpired"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas
+//+kubebuilder:resource:scope=Namespaced,shortName=hostclaimset
+
+// BareMetalHostClaimSet is the Schema for the baremetalhostclaimsets API
+// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".spec.replicas"
+// +kubebuilder:printcolumn:name="Bound",type="integer",JSONPath=".status.boundReplicas"
+// +kubebuilder:printcolumn:name="TopologyKey",type="string",JSONPath=".spec.topologyKey"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type BareMetalHostClaimSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BareMetalHostClaimSetSpec   `json:"spec,omitempty"`
+	Status BareMetalHostClaimSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BareMetalHostClaimSetList contains a list of BareMetalHostClaimSet
+type BareMetalHostClaimSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BareMetalHostClaimSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BareMetalHostClaimSet{}, &BareMetalHostClaimSetList{})
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HardwareClassLabel is the label of a host naming its hardware class, which quotas of a
+// BareMetalHostPool can be granted for.
+const HardwareClassLabel = "metal.afritzler.github.io/hardware-class"
+
+// NamespaceQuota grants a namespace hosts of a pool.
+type NamespaceQuota struct {
+	Namespace string `json:"namespace"`
+	// MaxHosts is the number of hosts of the pool the namespace may claim. The number is only
+	// limited by the classes if omitted.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxHosts *int32 `json:"maxHosts,omitempty"`
+	// Classes limit the number of hosts per hardware class. Hosts of other classes are only
+	// limited by MaxHosts.
+	// +listType=map
+	// +listMapKey=class
+	// +optional
+	Classes []HardwareClassQuota `json:"classes,omitempty"`
+	// MaxLeaseDuration is the maximum lease of the claims of the namespace for hosts of the
+	// pool. Claims without a lease expire after the maximum lease.
+	// +optional
+	MaxLeaseDuration *metav1.Duration `json:"maxLeaseDuration,omitempty"`
+}
+
+// HardwareClassQuota is the number of hosts of a hardware class a namespace may claim.
+type HardwareClassQuota struct {
+	// Class is the value of the hardware class label of the hosts.
+	Class string `json:"class"`
+	// +kubebuilder:validation:Minimum=0
+	MaxHosts int32 `json:"maxHosts"`
+}
+
+// BareMetalHostPoolSpec defines the desired state of BareMetalHostPool
+type BareMetalHostPoolSpec struct {
+	// HostSelector selects the hosts of the pool.
+	HostSelector metav1.LabelSelector `json:"hostSelector"`
+	// Quotas grant namespaces hosts of the pool. Claims of namespaces without a quota cannot
+	// claim hosts of the pool.
+	// +listType=map
+	// +listMapKey=namespace
+	// +optional
+	Quotas []NamespaceQuota `json:"quotas,omitempty"`
+	// MaxLeaseDuration is the maximum lease of claims for hosts of the pool. Claims without a
+	// lease expire after the maximum lease.
+	// +optional
+	MaxLeaseDuration *metav1.Duration `json:"maxLeaseDuration,omitempty"`
+}
+
+// HardwareClassCapacity is the number of hosts of a hardware class in a pool.
+type HardwareClassCapacity struct {
+	Class string `json:"class"`
+	// Capacity is the number of hosts of the class.
+	Capacity int32 `json:"capacity"`
+	// Available is the number of hosts of the class which can be claimed.
+	Available int32 `json:"available"`
+}
+
+// HardwareClassUsage is the number of hosts of a hardware class claimed by a namespace.
+type HardwareClassUsage struct {
+	Class string `json:"class"`
+	Hosts int32  `json:"hosts"`
+}
+
+// NamespaceUsage is the number of hosts of a pool claimed by a namespace.
+type NamespaceUsage struct {
+	Namespace string `json:"namespace"`
+	Hosts     int32  `json:"hosts"`
+	// Classes are the claimed hosts per hardware class.
+	Classes []HardwareClassUsage `json:"classes,omitempty"`
+}
+
+// BareMetalHostPoolStatus defines the observed state of BareMetalHostPool
+type BareMetalHostPoolStatus struct {
+	// Capacity is the number of hosts of the pool.
+	Capacity int32 `json:"capacity"`
+	// Available is the number of hosts of the pool which can be claimed.
+	Available int32 `json:"available"`
+	// Classes are the hosts of the pool per hardware class.
+	Classes []HardwareClassCapacity `json:"classes,omitempty"`
+	// Usage lists the hosts claimed per namespace, including the namespaces with a quota
+	// which do not claim any hosts.
+	Usage []NamespaceUsage `json:"usage,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,shortName=hostpool
+
+// BareMetalHostPool is the Schema for the baremetalhostpools API
+// +kubebuilder:printcolumn:name="Capacity",type="integer",JSONPath=".status.capacity"
+// +kubebuilder:printcolumn:name="Available",type="integer",JSONPath=".status.available"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type BareMetalHostPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BareMetalHostPoolSpec   `json:"spec,omitempty"`
+	Status BareMetalHostPoolStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BareMetalHostPoolList contains a list of BareMetalHostPool
+type BareMetalHostPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BareMetalHostPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BareMetalHostPool{}, &BareMetalHostPoolList{})
+}
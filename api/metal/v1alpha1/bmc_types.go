@@ -0,0 +1,140 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type BMCType string
+
+const (
+	BMCTypeRedfish      BMCType = "Redfish"
+	BMCTypeRedfishLocal BMCType = "RedfishLocal"
+	// BMCTypeFake is an in-memory BMC for demos and tests which needs no hardware.
+	BMCTypeFake BMCType = "Fake"
+)
+
+const (
+	// FakeInventoryAnnotation contains the JSON encoded list of systems served by a BMC of
+	// type Fake.
+	FakeInventoryAnnotation = "metal.afritzler.github.io/fake-inventory"
+	// FakeInventoryConfigMapAnnotation references a ConfigMap as <namespace>/<name> whose
+	// FakeInventoryKey contains the JSON encoded list of systems served by a BMC of type Fake.
+	// It takes precedence over the FakeInventoryAnnotation.
+	FakeInventoryConfigMapAnnotation = "metal.afritzler.github.io/fake-inventory-configmap"
+	// FakeInventoryKey is the key of the inventory in the ConfigMap of a BMC of type Fake.
+	FakeInventoryKey = "inventory"
+	// FakePowerTransitionDelayAnnotation contains the duration a system of a BMC of type
+	// Fake takes to change its power state.
+	FakePowerTransitionDelayAnnotation = "metal.afritzler.github.io/fake-power-transition-delay"
+)
+
+// BMCTLS defines how the TLS connection to the BMC is verified.
+type BMCTLS struct {
+	// InsecureSkipVerify disables the verification of the BMC server certificate.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// CASecretRef references a secret containing the CA bundle under the key 'ca.crt'
+	// which is used to verify the BMC server certificate.
+	CASecretRef *v1.SecretReference `json:"caSecretRef,omitempty"`
+}
+
+// BMCSpec defines the desired state of BMC
+type BMCSpec struct {
+	// Type is the protocol used to talk to the BMC.
+	Type BMCType `json:"type"`
+	// Address is the URL of the BMC endpoint, e.g. https://10.0.0.1.
+	Address   string `json:"address"`
+	BasicAuth bool   `json:"basicAuth,omitempty"`
+	// SecretRef references a secret containing the 'username' and 'password' keys
+	// used to authenticate against the BMC.
+	SecretRef v1.SecretReference `json:"secretRef,omitempty"`
+	// TLS configures the verification of the BMC server certificate. If omitted, the
+	// certificate is not verified.
+	TLS *BMCTLS `json:"tls,omitempty"`
+}
+
+type BMCState string
+
+const (
+	BMCStateReady BMCState = "Ready"
+	BMCStateError BMCState = "Error"
+)
+
+const (
+	// BMCConditionHostConflict is true if the host of a discovered system cannot be created
+	// because its name is taken by a host of another BMC or system.
+	BMCConditionHostConflict = "HostConflict"
+
+	BMCReasonHostNameTaken = "HostNameTaken"
+	BMCReasonNoConflict    = "NoConflict"
+)
+
+// BMCSystem is a system discovered on a BMC.
+type BMCSystem struct {
+	ID               string                   `json:"id"`
+	UUID             string                   `json:"uuid,omitempty"`
+	BareMetalHostRef *v1.LocalObjectReference `json:"bareMetalHostRef,omitempty"`
+}
+
+// BMCStatus defines the observed state of BMC
+type BMCStatus struct {
+	Manufacturer    string      `json:"manufacturer,omitempty"`
+	Model           string      `json:"model,omitempty"`
+	FirmwareVersion string      `json:"firmwareVersion,omitempty"`
+	State           BMCState    `json:"state,omitempty"`
+	Message         string      `json:"message,omitempty"`
+	Systems         []BMCSystem `json:"systems,omitempty"`
+	// Conditions contains the HostConflict condition.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// SSHHostKey is the host key of the SSH service serving the serial consoles in
+	// authorized_keys format. It is recorded on first use unless the key is given in the
+	// sshHostKey key of the BMC secret. Remove it to trust the key presented next.
+	SSHHostKey string `json:"sshHostKey,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// BMC is the Schema for the bmcs API
+// +kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.type"
+// +kubebuilder:printcolumn:name="Address",type="string",JSONPath=".spec.address"
+// +kubebuilder:printcolumn:name="Manufacturer",type="string",JSONPath=".status.manufacturer"
+// +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type BMC struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BMCSpec   `json:"spec,omitempty"`
+	Status BMCStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BMCList contains a list of BMC
+type BMCList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BMC `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BMC{}, &BMCList{})
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BMCDiscoverySpec defines the desired state of BMCDiscovery
+type BMCDiscoverySpec struct {
+	// CIDRs are the network ranges which are scanned for BMCs.
+	// +kubebuilder:validation:MinItems=1
+	CIDRs []string `json:"cidrs"`
+	// Ports are the ports probed for a Redfish service root. Defaults to 443.
+	Ports []int32 `json:"ports,omitempty"`
+	// Scheme is the URL scheme used to probe for a Redfish service root.
+	// +kubebuilder:validation:Enum=http;https
+	// +kubebuilder:default=https
+	Scheme string `json:"scheme,omitempty"`
+	// CredentialsSecretRefs are the candidate secrets containing the 'username' and 'password'
+	// keys which are tried in order against every discovered endpoint.
+	CredentialsSecretRefs []v1.SecretReference `json:"credentialsSecretRefs,omitempty"`
+	// ProbeIPMI enables probing for IPMI endpoints on UDP port 623.
+	ProbeIPMI bool `json:"probeIPMI,omitempty"`
+	// Concurrency is the maximum number of endpoints probed in parallel.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=16
+	Concurrency int32 `json:"concurrency,omitempty"`
+	// Timeout is the timeout of a single probe.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// RescanInterval is the interval in which the network ranges are scanned again. If omitted,
+	// the ranges are only scanned once.
+	RescanInterval *metav1.Duration `json:"rescanInterval,omitempty"`
+	// TLS configures how the TLS connections to the registered BMCs are verified. If omitted,
+	// the server certificates of the registered BMCs are not verified, as BMCs usually serve
+	// self-signed certificates.
+	TLS *BMCTLS `json:"tls,omitempty"`
+}
+
+type DiscoveredEndpointProtocol string
+
+const (
+	DiscoveredEndpointProtocolRedfish DiscoveredEndpointProtocol = "Redfish"
+	DiscoveredEndpointProtocolIPMI    DiscoveredEndpointProtocol = "IPMI"
+)
+
+type DiscoveredEndpointState string
+
+const (
+	// DiscoveredEndpointStateRegistered is set if a BMC has been registered for the endpoint.
+	DiscoveredEndpointStateRegistered DiscoveredEndpointState = "Registered"
+	// DiscoveredEndpointStateUnauthenticated is set if none of the candidate credentials are accepted.
+	DiscoveredEndpointStateUnauthenticated DiscoveredEndpointState = "Unauthenticated"
+	// DiscoveredEndpointStateUnsupported is set if there is no driver for the endpoint protocol.
+	DiscoveredEndpointStateUnsupported DiscoveredEndpointState = "Unsupported"
+)
+
+// DiscoveredEndpoint is an endpoint found while scanning the network ranges.
+type DiscoveredEndpoint struct {
+	Address  string                     `json:"address"`
+	Protocol DiscoveredEndpointProtocol `json:"protocol"`
+	State    DiscoveredEndpointState    `json:"state"`
+	Vendor   string                     `json:"vendor,omitempty"`
+	Product  string                     `json:"product,omitempty"`
+	BMCRef   *v1.LocalObjectReference   `json:"bmcRef,omitempty"`
+	// AuthenticationAttempts is the number of failed attempts to authenticate against an
+	// Unauthenticated endpoint.
+	AuthenticationAttempts int32 `json:"authenticationAttempts,omitempty"`
+	// NextAuthenticationTime is the time the candidate credentials are tried again against an
+	// Unauthenticated endpoint.
+	NextAuthenticationTime *metav1.Time `json:"nextAuthenticationTime,omitempty"`
+}
+
+type BMCDiscoveryState string
+
+const (
+	// BMCDiscoveryStateScanning is set while a scan of the network ranges is in progress.
+	BMCDiscoveryStateScanning BMCDiscoveryState = "Scanning"
+	BMCDiscoveryStateScanned  BMCDiscoveryState = "Scanned"
+	BMCDiscoveryStateFailed   BMCDiscoveryState = "Failed"
+)
+
+// BMCDiscoveryStatus defines the observed state of BMCDiscovery
+type BMCDiscoveryStatus struct {
+	ObservedGeneration int64             `json:"observedGeneration,omitempty"`
+	State              BMCDiscoveryState `json:"state,omitempty"`
+	Message            string            `json:"message,omitempty"`
+	LastScanTime       *metav1.Time      `json:"lastScanTime,omitempty"`
+	// ScannedAddresses is the number of addresses scanned by the current scan.
+	ScannedAddresses int32 `json:"scannedAddresses,omitempty"`
+	// TotalAddresses is the number of addresses in the network ranges.
+	TotalAddresses int32                `json:"totalAddresses,omitempty"`
+	Endpoints      []DiscoveredEndpoint `json:"endpoints,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// BMCDiscovery is the Schema for the bmcdiscoveries API
+// +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="Scanned",type="integer",JSONPath=".status.scannedAddresses"
+// +kubebuilder:printcolumn:name="Total",type="integer",JSONPath=".status.totalAddresses"
+// +kubebuilder:printcolumn:name="LastScan",type="date",JSONPath=".status.lastScanTime"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type BMCDiscovery struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BMCDiscoverySpec   `json:"spec,omitempty"`
+	Status BMCDiscoveryStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BMCDiscoveryList contains a list of BMCDiscovery
+type BMCDiscoveryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BMCDiscovery `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BMCDiscovery{}, &BMCDiscoveryList{})
+}
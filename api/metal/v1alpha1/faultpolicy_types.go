@@ -0,0 +1,87 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/stmcginnis/gofish/redfish"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type FaultAction string
+
+const (
+	// FaultActionMaintenance enables the maintenance of the host.
+	FaultActionMaintenance FaultAction = "Maintenance"
+	// FaultActionTaint marks the host as tainted, so that it is sanitized before it becomes
+	// available again.
+	FaultActionTaint FaultAction = "Taint"
+)
+
+// FaultRule matches log entries of a hardware fault.
+type FaultRule struct {
+	// Name identifies the rule in events.
+	Name string `json:"name"`
+	// Pattern is a regular expression matched against the message ID and the message of
+	// a log entry.
+	Pattern string `json:"pattern"`
+	// Severity is the minimum severity of the matched log entries.
+	// +kubebuilder:validation:Enum=OK;Warning;Critical
+	// +kubebuilder:default=Critical
+	Severity redfish.EventSeverity `json:"severity,omitempty"`
+	// Action is applied to the host once a log entry matches.
+	// +kubebuilder:validation:Enum=Maintenance;Taint
+	Action FaultAction `json:"action"`
+}
+
+// FaultPolicySpec defines the desired state of FaultPolicy
+type FaultPolicySpec struct {
+	// HostSelector selects the hosts the policy applies to. All hosts are selected if omitted.
+	HostSelector *metav1.LabelSelector `json:"hostSelector,omitempty"`
+	// Rules are evaluated for every new log entry of a host.
+	Rules []FaultRule `json:"rules,omitempty"`
+}
+
+// FaultPolicyStatus defines the observed state of FaultPolicy
+type FaultPolicyStatus struct {
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// FaultPolicy is the Schema for the faultpolicies API
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type FaultPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FaultPolicySpec   `json:"spec,omitempty"`
+	Status FaultPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// FaultPolicyList contains a list of FaultPolicy
+type FaultPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FaultPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FaultPolicy{}, &FaultPolicyList{})
+}
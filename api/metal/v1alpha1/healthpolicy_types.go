@@ -0,0 +1,114 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/stmcginnis/gofish/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ComponentType string
+
+const (
+	ComponentTypeProcessor   ComponentType = "Processor"
+	ComponentTypeMemory      ComponentType = "Memory"
+	ComponentTypeDrive       ComponentType = "Drive"
+	ComponentTypePowerSupply ComponentType = "PowerSupply"
+)
+
+type RemediationAction string
+
+const (
+	// RemediationActionPowerCycle restarts the host.
+	RemediationActionPowerCycle RemediationAction = "PowerCycle"
+	// RemediationActionResetBMC restarts the BMC of the host.
+	RemediationActionResetBMC RemediationAction = "ResetBMC"
+)
+
+// ComponentThreshold is the number of unhealthy components of a type which is tolerated.
+type ComponentThreshold struct {
+	// +kubebuilder:validation:Enum=Processor;Memory;Drive;PowerSupply
+	Type ComponentType `json:"type"`
+	// +kubebuilder:validation:Minimum=0
+	MaxUnhealthy int32 `json:"maxUnhealthy"`
+}
+
+// LogThreshold is the number of recent critical log entries which is tolerated.
+type LogThreshold struct {
+	// MaxCritical is the number of critical entries created within the window which is
+	// tolerated. Only the latest five critical entries of the host status are considered,
+	// so at most four entries can be tolerated.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=4
+	MaxCritical int32 `json:"maxCritical"`
+	// Window is the duration before the evaluation in which critical entries are counted.
+	Window metav1.Duration `json:"window"`
+}
+
+// HealthPolicySpec defines the desired state of HealthPolicy
+type HealthPolicySpec struct {
+	// HostSelector selects the hosts the policy applies to. All hosts are selected if omitted.
+	HostSelector *metav1.LabelSelector `json:"hostSelector,omitempty"`
+	// Rollup is the health rollup of the system at which the host is unhealthy. The rollup
+	// is ignored if omitted.
+	// +kubebuilder:validation:Enum=Warning;Critical
+	Rollup common.Health `json:"rollup,omitempty"`
+	// ComponentHealth is the health at which a component counts as unhealthy.
+	// +kubebuilder:validation:Enum=Warning;Critical
+	// +kubebuilder:default=Critical
+	ComponentHealth common.Health `json:"componentHealth,omitempty"`
+	// Components are the thresholds of unhealthy components per type. Components of other
+	// types are ignored.
+	Components []ComponentThreshold `json:"components,omitempty"`
+	// Logs is the threshold of critical log entries. Log entries are ignored if omitted.
+	Logs *LogThreshold `json:"logs,omitempty"`
+	// Remediation is applied once a host becomes unhealthy.
+	// +kubebuilder:validation:Enum=PowerCycle;ResetBMC
+	Remediation RemediationAction `json:"remediation,omitempty"`
+}
+
+// HealthPolicyStatus defines the observed state of HealthPolicy
+type HealthPolicyStatus struct {
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// HealthPolicy is the Schema for the healthpolicies API
+// +kubebuilder:printcolumn:name="Remediation",type="string",JSONPath=".spec.remediation"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type HealthPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HealthPolicySpec   `json:"spec,omitempty"`
+	Status HealthPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HealthPolicyList contains a list of HealthPolicy
+type HealthPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HealthPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HealthPolicy{}, &HealthPolicyList{})
+}
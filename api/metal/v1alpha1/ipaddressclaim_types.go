@@ -0,0 +1,104 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IPAddressClaimFinalizer is the finalizer releasing the address of an IPAddressClaim.
+const IPAddressClaimFinalizer = "metal.afritzler.github.io/ipaddressclaim"
+
+// IPAddressClaimSpec defines the desired state of IPAddressClaim
+type IPAddressClaimSpec struct {
+	// IPPoolRef references the pool the address is allocated from.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="ipPoolRef is immutable"
+	IPPoolRef v1.LocalObjectReference `json:"ipPoolRef"`
+	// Address requests a specific address of the pool. Any free address is allocated if omitted.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="address is immutable"
+	// +optional
+	Address string `json:"address,omitempty"`
+	// BareMetalHostRef references the host the address is allocated for. A host is allocated
+	// the same address again whenever it is free, so that hosts keep their address across claims.
+	// +optional
+	BareMetalHostRef *v1.LocalObjectReference `json:"bareMetalHostRef,omitempty"`
+}
+
+// IPAddressClaimStatus defines the observed state of IPAddressClaim
+type IPAddressClaimStatus struct {
+	// Phase is Bound once the address is allocated, Conflict while the requested address is
+	// allocated to another claim and Unbound while no address can be allocated otherwise.
+	Phase Phase `json:"phase,omitempty"`
+	// Address is the allocated address.
+	Address string `json:"address,omitempty"`
+	// Prefix is the prefix length of the network of the pool.
+	Prefix int32 `json:"prefix,omitempty"`
+	// Gateway is the default gateway of the network of the pool.
+	Gateway string `json:"gateway,omitempty"`
+	// DNSServers are the name servers of the network of the pool.
+	DNSServers []string `json:"dnsServers,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// IPAddressClaimConditionAllocated reports whether an address is allocated to the claim and
+	// describes why no address could be allocated otherwise.
+	IPAddressClaimConditionAllocated = "Allocated"
+
+	IPAddressClaimReasonAllocated        = "Allocated"
+	IPAddressClaimReasonPoolNotFound     = "PoolNotFound"
+	IPAddressClaimReasonPoolInvalid      = "PoolInvalid"
+	IPAddressClaimReasonNamespaceDenied  = "NamespaceDenied"
+	IPAddressClaimReasonPoolExhausted    = "PoolExhausted"
+	IPAddressClaimReasonAddressNotInPool = "AddressNotInPool"
+	IPAddressClaimReasonAddressInUse     = "AddressInUse"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Namespaced,shortName=ipclaim
+
+// IPAddressClaim is the Schema for the ipaddressclaims API
+// +kubebuilder:printcolumn:name="IPPool",type="string",JSONPath=".spec.ipPoolRef.name"
+// +kubebuilder:printcolumn:name="Address",type="string",JSONPath=".status.address"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="BareMetalHost",type="string",JSONPath=".spec.bareMetalHostRef.name",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type IPAddressClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IPAddressClaimSpec   `json:"spec,omitempty"`
+	Status IPAddressClaimStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// IPAddressClaimList contains a list of IPAddressClaim
+type IPAddressClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IPAddressClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IPAddressClaim{}, &IPAddressClaimList{})
+}
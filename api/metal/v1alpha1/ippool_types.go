@@ -0,0 +1,114 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IPRange is a range of addresses from Start to End, both included.
+type IPRange struct {
+	Start string `json:"start"`
+	// End is the last address of the range. The range only contains Start if omitted.
+	// +optional
+	End string `json:"end,omitempty"`
+}
+
+// IPPoolSpec defines the desired state of IPPool
+type IPPoolSpec struct {
+	// CIDR is the network the addresses of the pool are allocated from, e.g. 10.0.0.0/24 or
+	// 2001:db8::/64.
+	CIDR string `json:"cidr"`
+	// Gateway is the default gateway of the network. It is never allocated.
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+	// DNSServers are the name servers of the network.
+	// +optional
+	DNSServers []string `json:"dnsServers,omitempty"`
+	// Reserved are the ranges of the network which are never allocated, e.g. addresses of
+	// routers or of a dynamic DHCP range.
+	// +optional
+	Reserved []IPRange `json:"reserved,omitempty"`
+	// Namespaces are the namespaces whose claims may allocate addresses of the pool. Claims of
+	// all namespaces may allocate addresses if omitted. Removing a namespace does not release the
+	// addresses already allocated to its claims.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// IPAllocation is an address of a pool allocated to an IPAddressClaim.
+type IPAllocation struct {
+	Address string `json:"address"`
+	// ClaimRef references the IPAddressClaim the address is allocated to.
+	ClaimRef v1.ObjectReference `json:"claimRef"`
+}
+
+// IPPoolStatus defines the observed state of IPPool
+type IPPoolStatus struct {
+	// Allocated is the number of allocated addresses.
+	// +optional
+	Allocated int32 `json:"allocated,omitempty"`
+	// Allocations are the addresses allocated to claims. An address is only allocated once it is
+	// listed here. Allocations missing for the addresses of bound claims, e.g. as the status has
+	// not been restored from a backup, are rebuilt from the status of the claims.
+	// +optional
+	Allocations []IPAllocation `json:"allocations,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// IPPoolConditionValid reports whether the CIDR, the gateway and the reserved ranges of the
+	// pool are valid.
+	IPPoolConditionValid = "Valid"
+
+	IPPoolReasonValid   = "Valid"
+	IPPoolReasonInvalid = "Invalid"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster,shortName=ippool
+
+// IPPool is the Schema for the ippools API
+// +kubebuilder:printcolumn:name="CIDR",type="string",JSONPath=".spec.cidr"
+// +kubebuilder:printcolumn:name="Gateway",type="string",JSONPath=".spec.gateway"
+// +kubebuilder:printcolumn:name="Allocated",type="integer",JSONPath=".status.allocated"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type IPPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IPPoolSpec   `json:"spec,omitempty"`
+	Status IPPoolStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// IPPoolList contains a list of IPPool
+type IPPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IPPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IPPool{}, &IPPoolList{})
+}
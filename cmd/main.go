@@ -17,10 +17,17 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
 	coreafritzlergithubiov1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/bmc"
 	"github.com/afritzler/baremetal-operator/internal/controller/metal"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
@@ -58,8 +65,64 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var PXEServiceNamespace string
+	var bmcResyncInterval time.Duration
+	var telemetryInterval time.Duration
+	var telemetryTimeout time.Duration
+	var telemetryMaxHostsPerBMC int
+	var telemetryConcurrency int
+	var logInterval time.Duration
+	var logTimeout time.Duration
+	var logServices string
+	var logConcurrency int
+	var healthInterval time.Duration
+	var healthTimeout time.Duration
+	var healthConcurrency int
+	var consoleAddr string
+	var consoleCertDir string
+	var consoleInsecure bool
+	var consoleBufferSize int
+	var consoleMaxBuffers int
+	var consoleLog bool
+	var consoleLogMaxDuration time.Duration
+	var consoleLogSucceededPattern string
+	var consoleLogFailedPattern string
+	var consoleLogFlushInterval time.Duration
+	var consoleLogSize int
+	var preemptionGracePeriod time.Duration
+	var leaseWarningPeriod time.Duration
+	var bootInterfacePattern string
+	var bootServer string
+	var dhcpMode string
 
 	flag.StringVar(&PXEServiceNamespace, "pxe-namespace", "oob", "The namespace of the PXE service.")
+	flag.DurationVar(&bmcResyncInterval, "bmc-resync-interval", 5*time.Minute, "The interval in which the systems of a BMC are rediscovered.")
+	flag.DurationVar(&telemetryInterval, "telemetry-interval", time.Minute, "The interval in which the sensors of the hosts are read. Zero disables the telemetry collection.")
+	flag.DurationVar(&telemetryTimeout, "telemetry-timeout", 30*time.Second, "The timeout for reading the sensors of a single host.")
+	flag.IntVar(&telemetryMaxHostsPerBMC, "telemetry-max-hosts-per-bmc", 8, "The maximum number of hosts of a BMC whose sensors are read per interval.")
+	flag.IntVar(&telemetryConcurrency, "telemetry-concurrency", 4, "The maximum number of BMCs whose sensors are read in parallel.")
+	flag.DurationVar(&logInterval, "log-interval", 5*time.Minute, "The interval in which the log services of the hosts are read. Zero disables the log collection.")
+	flag.DurationVar(&logTimeout, "log-timeout", time.Minute, "The timeout for reading the log services of a single host.")
+	flag.StringVar(&logServices, "log-services", strings.Join(bmc.DefaultLogServices, ","), "The comma separated IDs of the log services which are read.")
+	flag.IntVar(&logConcurrency, "log-concurrency", 4, "The maximum number of hosts whose log services are read in parallel.")
+	flag.DurationVar(&healthInterval, "health-interval", time.Minute, "The interval in which the health of the hosts is evaluated. Zero disables the health evaluation.")
+	flag.DurationVar(&healthTimeout, "health-timeout", 30*time.Second, "The timeout for evaluating the health of a single host.")
+	flag.IntVar(&healthConcurrency, "health-concurrency", 4, "The maximum number of hosts whose health is evaluated in parallel.")
+	flag.StringVar(&consoleAddr, "console-bind-address", "0", "The address the console endpoint binds to. Use 0 to disable the console endpoint.")
+	flag.StringVar(&consoleCertDir, "console-cert-dir", "", "The directory containing the tls.crt and tls.key used to serve the console endpoint. Required unless --console-insecure is set.")
+	flag.BoolVar(&consoleInsecure, "console-insecure", false, "If set, the console endpoint serves plain HTTP if no --console-cert-dir is given, which exposes the bearer tokens of the callers.")
+	flag.IntVar(&consoleBufferSize, "console-buffer-size", metal.DefaultConsoleBufferSize, "The number of bytes of recent console output kept per host.")
+	flag.IntVar(&consoleMaxBuffers, "console-max-buffers", metal.DefaultConsoleMaxBuffers, "The maximum number of hosts whose recent console output is kept.")
+	flag.BoolVar(&consoleLog, "console-log", false, "If set, the console of a host is recorded while it is provisioned for a claim.")
+	flag.DurationVar(&consoleLogMaxDuration, "console-log-max-duration", metal.DefaultConsoleLogMaxDuration, "The maximum duration the console of a host is recorded after it has been powered on for a claim.")
+	flag.StringVar(&consoleLogSucceededPattern, "console-log-succeeded-pattern", metal.DefaultConsoleLogSucceededPattern, "The regular expression matching a line of the console output once the provisioning succeeded, which ends the recording.")
+	flag.StringVar(&consoleLogFailedPattern, "console-log-failed-pattern", metal.DefaultConsoleLogFailedPattern, "The regular expression matching a line of the console output once the provisioning failed, which ends the recording.")
+	flag.DurationVar(&consoleLogFlushInterval, "console-log-flush-interval", metal.DefaultConsoleLogFlushInterval, "The interval in which the recorded console output is stored.")
+	flag.IntVar(&consoleLogSize, "console-log-size", metal.DefaultConsoleBufferSize, fmt.Sprintf("The number of bytes of the most recent console output stored for a claim, at most %d.", metal.MaxConsoleLogSize))
+	flag.DurationVar(&preemptionGracePeriod, "claim-preemption-grace-period", metal.DefaultPreemptionGracePeriod, "The duration after which a claim preempted by a claim with a higher priority is unbound.")
+	flag.DurationVar(&leaseWarningPeriod, "claim-lease-warning-period", metal.DefaultLeaseWarningPeriod, "The period before the expiry of the lease of a claim in which the claim is warned.")
+	flag.StringVar(&bootInterfacePattern, "boot-interface-pattern", "", "The regular expression the ID or name of a network interface has to match to be detected as boot interface of a host. If empty, the interface referenced by a network boot option or the only interface of a host is detected.")
+	flag.StringVar(&bootServer, "boot-server-url", "", "The URL of the boot server serving the iPXE scripts of the hosts, e.g. http://[2001:db8::1]:8082. If empty, no boot file URL is served by DHCP.")
+	flag.StringVar(&dhcpMode, "dhcp-mode", string(bootv1alpha1.DHCPModeServer), "The mode of the DHCP configurations which do not specify one. Server assigns the addresses of the hosts, Proxy only serves the boot information as ProxyDHCP server alongside an existing DHCP server.")
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -73,7 +136,7 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	mgrOptions := ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
 		HealthProbeBindAddress: probeAddr,
@@ -90,26 +153,196 @@ func main() {
 		// if you are doing or is intended to do any operation such as perform cleanups
 		// after the manager stops then its usage might be unsafe.
 		// LeaderElectionReleaseOnCancel: true,
-	})
+	}
+	metal.ConfigureSecretCache(&mgrOptions)
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOptions)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	if err = metal.SetupFieldIndexes(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(err, "unable to set up field indexes")
+		os.Exit(1)
+	}
+
+	// the state of BMCs of type Fake is shared by all controllers talking to BMCs
+	fakeBMCs := bmc.NewFakeBMCs()
+
+	var bootInterfaceRegexp *regexp.Regexp
+	if bootInterfacePattern != "" {
+		if bootInterfaceRegexp, err = regexp.Compile(bootInterfacePattern); err != nil {
+			setupLog.Error(err, "invalid boot interface pattern")
+			os.Exit(1)
+		}
+	}
 	if err = (&metal.BareMetalHostReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		FakeBMCs:             fakeBMCs,
+		BootInterfacePattern: bootInterfaceRegexp,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "BareMetalHost")
 		os.Exit(1)
 	}
+	var consoleServer *metal.ConsoleServer
+	if consoleAddr != "0" || consoleLog {
+		consoleServer = &metal.ConsoleServer{
+			Client:     mgr.GetClient(),
+			FakeBMCs:   fakeBMCs,
+			CertDir:    consoleCertDir,
+			Insecure:   consoleInsecure,
+			BufferSize: consoleBufferSize,
+			MaxBuffers: consoleMaxBuffers,
+		}
+		// without bind address, the server only opens the consoles for the recorder
+		if consoleAddr != "0" {
+			if consoleCertDir == "" && !consoleInsecure {
+				setupLog.Error(nil, "the console endpoint requires --console-cert-dir unless --console-insecure is set")
+				os.Exit(1)
+			}
+			consoleServer.BindAddress = consoleAddr
+		}
+		if err = mgr.Add(consoleServer); err != nil {
+			setupLog.Error(err, "unable to add console server")
+			os.Exit(1)
+		}
+	}
+	var consoleRecorder *metal.ConsoleRecorder
+	if consoleLog {
+		if consoleLogSize > metal.MaxConsoleLogSize {
+			setupLog.Error(nil, "the console log size exceeds the size of a secret", "Size", consoleLogSize, "MaxSize", metal.MaxConsoleLogSize)
+			os.Exit(1)
+		}
+		succeededPattern, err := regexp.Compile(consoleLogSucceededPattern)
+		if err != nil {
+			setupLog.Error(err, "invalid console log succeeded pattern")
+			os.Exit(1)
+		}
+		failedPattern, err := regexp.Compile(consoleLogFailedPattern)
+		if err != nil {
+			setupLog.Error(err, "invalid console log failed pattern")
+			os.Exit(1)
+		}
+		consoleRecorder = &metal.ConsoleRecorder{
+			Client:           mgr.GetClient(),
+			Consoles:         consoleServer,
+			MaxDuration:      consoleLogMaxDuration,
+			SucceededPattern: succeededPattern,
+			FailedPattern:    failedPattern,
+			FlushInterval:    consoleLogFlushInterval,
+			BufferSize:       consoleLogSize,
+		}
+		if err = mgr.Add(consoleRecorder); err != nil {
+			setupLog.Error(err, "unable to add console recorder")
+			os.Exit(1)
+		}
+	}
+	var bootServerURL *url.URL
+	if bootServer != "" {
+		if bootServerURL, err = metal.ParseBootServerURL(bootServer); err != nil {
+			setupLog.Error(err, "invalid boot server URL")
+			os.Exit(1)
+		}
+	}
 	if err = (&metal.BareMetalHostClaimReconciler{
+		Client:                mgr.GetClient(),
+		Scheme:                mgr.GetScheme(),
+		ConsoleRecorder:       consoleRecorder,
+		Recorder:              mgr.GetEventRecorderFor("hostclaim-controller"),
+		PreemptionGracePeriod: preemptionGracePeriod,
+		LeaseWarningPeriod:    leaseWarningPeriod,
+		BootServerURL:         bootServerURL,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BareMetalHostClaim")
+		os.Exit(1)
+	}
+	if err = (&metal.BareMetalHostPoolReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "BareMetalHostClaim")
+		setupLog.Error(err, "unable to create controller", "controller", "BareMetalHostPool")
+		os.Exit(1)
+	}
+	if err = (&metal.BareMetalHostClaimSetReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BareMetalHostClaimSet")
+		os.Exit(1)
+	}
+	if err = (&metal.IPPoolReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "IPPool")
+		os.Exit(1)
+	}
+	if err = (&metal.IPAddressClaimReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("ipaddressclaim-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "IPAddressClaim")
+		os.Exit(1)
+	}
+	if err = (&metal.BMCReconciler{
+		Client:         mgr.GetClient(),
+		FakeBMCs:       fakeBMCs,
+		APIReader:      mgr.GetAPIReader(),
+		Scheme:         mgr.GetScheme(),
+		ResyncInterval: bmcResyncInterval,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BMC")
+		os.Exit(1)
+	}
+	if err = (&metal.BMCDiscoveryReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BMCDiscovery")
 		os.Exit(1)
 	}
+	if telemetryInterval > 0 {
+		if err = mgr.Add(&metal.TelemetryCollector{
+			Client:         mgr.GetClient(),
+			FakeBMCs:       fakeBMCs,
+			Interval:       telemetryInterval,
+			Timeout:        telemetryTimeout,
+			MaxHostsPerBMC: telemetryMaxHostsPerBMC,
+			Concurrency:    telemetryConcurrency,
+		}); err != nil {
+			setupLog.Error(err, "unable to add telemetry collector")
+			os.Exit(1)
+		}
+	}
+	if logInterval > 0 {
+		if err = mgr.Add(&metal.LogCollector{
+			Client:      mgr.GetClient(),
+			FakeBMCs:    fakeBMCs,
+			Recorder:    mgr.GetEventRecorderFor("log-collector"),
+			Interval:    logInterval,
+			Timeout:     logTimeout,
+			Services:    strings.Split(logServices, ","),
+			Concurrency: logConcurrency,
+		}); err != nil {
+			setupLog.Error(err, "unable to add log collector")
+			os.Exit(1)
+		}
+	}
+	if healthInterval > 0 {
+		if err = mgr.Add(&metal.HealthMonitor{
+			Client:      mgr.GetClient(),
+			FakeBMCs:    fakeBMCs,
+			Recorder:    mgr.GetEventRecorderFor("health-monitor"),
+			Interval:    healthInterval,
+			Timeout:     healthTimeout,
+			Concurrency: healthConcurrency,
+		}); err != nil {
+			setupLog.Error(err, "unable to add health monitor")
+			os.Exit(1)
+		}
+	}
 	if err = (&bootcontroller.PXEReconciler{
 		Client:              mgr.GetClient(),
 		Scheme:              mgr.GetScheme(),
@@ -118,9 +351,16 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "PXE")
 		os.Exit(1)
 	}
+	switch bootv1alpha1.DHCPMode(dhcpMode) {
+	case bootv1alpha1.DHCPModeServer, bootv1alpha1.DHCPModeProxy:
+	default:
+		setupLog.Error(nil, "invalid DHCP mode", "Mode", dhcpMode)
+		os.Exit(1)
+	}
 	if err = (&bootcontroller.DHCPReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		DefaultMode: bootv1alpha1.DHCPMode(dhcpMode),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DHCP")
 		os.Exit(1)
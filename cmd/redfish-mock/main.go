@@ -0,0 +1,98 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/afritzler/baremetal-operator/internal/redfishmock"
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+func main() {
+	var listenAddr string
+	var systemsFile string
+	var systemCount int
+	var username string
+	var password string
+	var powerTransitionDelay time.Duration
+
+	flag.StringVar(&listenAddr, "listen-address", ":8000", "The address the Redfish service binds to.")
+	flag.StringVar(&systemsFile, "systems-file", "", "A JSON file containing the list of mocked systems.")
+	flag.IntVar(&systemCount, "systems", 1, "The number of generated systems if no systems file is given.")
+	flag.StringVar(&username, "username", "", "The username required for basic and session authentication.")
+	flag.StringVar(&password, "password", "", "The password required for basic and session authentication.")
+	flag.DurationVar(&powerTransitionDelay, "power-transition-delay", 0, "The time a system takes to change its power state.")
+	flag.Parse()
+
+	systems, err := loadSystems(systemsFile, systemCount)
+	if err != nil {
+		log.Fatalf("failed to load systems: %v", err)
+	}
+
+	var opts []redfishmock.Option
+	if username != "" {
+		opts = append(opts, redfishmock.WithCredentials(username, password))
+	}
+	if powerTransitionDelay > 0 {
+		opts = append(opts, redfishmock.WithPowerTransitionDelay(powerTransitionDelay))
+	}
+
+	log.Printf("serving %d systems on %s", len(systems), listenAddr)
+	if err := http.ListenAndServe(listenAddr, redfishmock.NewService(systems, opts...)); err != nil {
+		log.Fatalf("failed to serve Redfish service: %v", err)
+	}
+}
+
+func loadSystems(file string, count int) ([]redfishmock.System, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read systems file: %w", err)
+		}
+		var systems []redfishmock.System
+		if err := json.Unmarshal(data, &systems); err != nil {
+			return nil, fmt.Errorf("failed to parse systems file: %w", err)
+		}
+		return systems, nil
+	}
+
+	systems := make([]redfishmock.System, 0, count)
+	for i := 1; i <= count; i++ {
+		systems = append(systems, redfishmock.System{
+			ID:           fmt.Sprintf("System-%d", i),
+			UUID:         fmt.Sprintf("00000000-0000-4000-8000-%012d", i),
+			Manufacturer: "Redfish Mock",
+			Model:        "Virtual",
+			SerialNumber: fmt.Sprintf("MOCK%06d", i),
+			PowerState:   redfish.OffPowerState,
+			NetworkInterfaces: []redfishmock.NetworkInterface{
+				{ID: "1", MACAddress: fmt.Sprintf("02:00:00:00:%02x:%02x", i>>8&0xff, i&0xff)},
+			},
+			Processors: []redfishmock.Processor{
+				{ID: "CPU1", ProcessorArchitecture: "x86", TotalCores: 4, TotalThreads: 8},
+			},
+		})
+	}
+	return systems, nil
+}
@@ -1,6 +1,10 @@
 package bmc
 
 import (
+	"context"
+	"io"
+	"time"
+
 	"github.com/stmcginnis/gofish/common"
 	"github.com/stmcginnis/gofish/redfish"
 )
@@ -19,17 +23,64 @@ type BMC interface {
 	// SetPXEBootOnce sets the boot device for the next system boot.
 	SetPXEBootOnce(systemID string) error
 
+	// SetHTTPBootOnce boots the system once via UEFI HTTP boot from the given URI. The firmware
+	// requests the URI from DHCP if it is empty.
+	SetHTTPBootOnce(systemID, uri string) error
+
 	// GetSystemInfo retrieves information about the system.
 	GetSystemInfo() (SystemInfo, error)
 
+	// GetSystems retrieves all systems managed by the BMC.
+	GetSystems() ([]System, error)
+
+	// GetManagerInfo retrieves information about the BMC itself.
+	GetManagerInfo() (ManagerInfo, error)
+
+	// GetTelemetry retrieves the sensor readings of the chassis containing the system.
+	GetTelemetry() (Telemetry, error)
+
+	// GetLogEntries retrieves the entries of the given log services of the system and its
+	// managers which were created after the given cursors, oldest first.
+	GetLogEntries(services []string, cursors []LogCursor) ([]LogEntry, error)
+
+	// GetHealth retrieves the health rollup of the system and the health of its components.
+	GetHealth() (Health, error)
+
+	// ResetManager restarts the managers responsible for the system.
+	ResetManager() error
+
+	// OpenConsole attaches to the serial console of the system with the given options.
+	// ErrConsoleNotSupported is returned if the BMC provides no serial console. The console is
+	// detached on close.
+	OpenConsole(ctx context.Context, options ConsoleOptions) (io.ReadWriteCloser, error)
+
 	// Logout closes the BMC client connection by logging out
 	Logout()
 }
 
+// System represents a system managed by the BMC.
+type System struct {
+	ID           string
+	UUID         string
+	Manufacturer string
+	Model        string
+	SerialNumber string
+}
+
+// ManagerInfo represents basic information about the BMC.
+type ManagerInfo struct {
+	Manufacturer    string
+	Model           string
+	FirmwareVersion string
+}
+
 type NetworkInterface struct {
 	ID                  string
+	Name                string
 	MACAddress          string
 	PermanentMACAddress string
+	// PXEBoot is true if a network boot option of the system boots from the interface.
+	PXEBoot bool
 }
 
 type Processor struct {
@@ -54,3 +105,72 @@ type SystemInfo struct {
 	Processors        []Processor
 	SystemUUID        string
 }
+
+// Telemetry represents the sensor readings of the chassis containing a system.
+type Telemetry struct {
+	Temperatures  []Temperature
+	Fans          []Fan
+	PowerSupplies []PowerSupply
+	// PowerConsumedWatts is nil if the chassis does not report its power draw.
+	PowerConsumedWatts *float64
+}
+
+type Temperature struct {
+	Name           string
+	ReadingCelsius float64
+}
+
+type Fan struct {
+	Name       string
+	ReadingRPM float64
+}
+
+type PowerSupply struct {
+	Name   string
+	Health common.Health
+	State  common.State
+}
+
+// LogEntry represents an entry of a log service of the system or its manager.
+type LogEntry struct {
+	// Service is the ID of the log service containing the entry, e.g. SEL, Lclog or IML.
+	Service string
+	ID      string
+	// Created is the creation time of the entry with a precision of seconds, which is the
+	// precision of the cursors.
+	Created   time.Time
+	Severity  redfish.EventSeverity
+	MessageID string
+	Message   string
+}
+
+// LogCursor is the position in a log service up to which the entries have been read.
+type LogCursor struct {
+	Service string
+	Created time.Time
+	ID      string
+}
+
+// Health represents the health of a system and its components.
+type Health struct {
+	// Rollup is the health of the system including its dependent resources.
+	Rollup     common.Health
+	Components []ComponentHealth
+}
+
+// ComponentType is the type of a component of a system.
+type ComponentType string
+
+const (
+	ComponentTypeProcessor   ComponentType = "Processor"
+	ComponentTypeMemory      ComponentType = "Memory"
+	ComponentTypeDrive       ComponentType = "Drive"
+	ComponentTypePowerSupply ComponentType = "PowerSupply"
+)
+
+// ComponentHealth represents the health of a component of a system.
+type ComponentHealth struct {
+	Type   ComponentType
+	Name   string
+	Health common.Health
+}
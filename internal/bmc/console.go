@@ -0,0 +1,197 @@
+package bmc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stmcginnis/gofish"
+	"github.com/stmcginnis/gofish/redfish"
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrConsoleNotSupported is returned if the BMC provides no serial console for the system.
+var ErrConsoleNotSupported = errors.New("serial console is not supported")
+
+// ErrUnknownHostKey is returned if neither a host key nor a way to trust the presented host
+// key is given.
+var ErrUnknownHostKey = errors.New("host key of the SSH service is unknown")
+
+// ConsoleOptions are the credentials and the host key used to attach to a serial console.
+type ConsoleOptions struct {
+	Username string
+	Password string
+	// HostKey is the host key of the SSH service in authorized_keys format. Connections to
+	// services presenting another key are refused.
+	HostKey string
+	// TrustHostKey is called with the key presented by the SSH service in authorized_keys
+	// format if no HostKey is given. The key is trusted on first use unless an error is
+	// returned.
+	TrustHostKey func(key string) error
+}
+
+// hostKeyCallback returns the callback verifying the host key of the SSH service.
+func (o ConsoleOptions) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if o.HostKey != "" {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(o.HostKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse host key: %w", err)
+		}
+		return ssh.FixedHostKey(key), nil
+	}
+	if o.TrustHostKey == nil {
+		return nil, ErrUnknownHostKey
+	}
+	return func(_ string, _ net.Addr, key ssh.PublicKey) error {
+		return o.TrustHostKey(strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))))
+	}, nil
+}
+
+const (
+	defaultSSHPort = 22
+	sshDialTimeout = 30 * time.Second
+)
+
+// openSSHConsole connects to the SSH service of a manager responsible for the system with the
+// given ID and attaches the session to the serial console of the system. The host of the
+// SSH service is taken from the address of the Redfish endpoint.
+func openSSHConsole(ctx context.Context, client *gofish.APIClient, systemID string, oem func(*redfish.ComputerSystem) OEM, address string, options ConsoleOptions) (io.ReadWriteCloser, error) {
+	hostKeyCallback, err := options.hostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+	systems, err := client.GetService().Systems()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get systems: %w", err)
+	}
+	system := getSystemWithSytemID(systems, systemID)
+	if system == nil {
+		return nil, fmt.Errorf("no system found for system ID %s", systemID)
+	}
+
+	manager, err := getSSHConsoleManager(client, system)
+	if err != nil {
+		return nil, err
+	}
+	port := int64(defaultSSHPort)
+	if protocol, err := manager.NetworkProtocol(); err == nil && protocol.SSH.ProtocolEnabled && protocol.SSH.Port != 0 {
+		port = protocol.SSH.Port
+	}
+	endpoint, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse BMC address: %w", err)
+	}
+
+	config := &ssh.ClientConfig{
+		User: options.Username,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(options.Password),
+			// some BMCs only offer keyboard interactive authentication asking for the password
+			ssh.KeyboardInteractive(func(_, _ string, questions []string, _ []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = options.Password
+				}
+				return answers, nil
+			}),
+		},
+		// BMCs generate their host keys themselves and do not publish them via Redfish, so
+		// the key is either configured or trusted on first use.
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sshDialTimeout,
+	}
+	hostPort := net.JoinHostPort(endpoint.Hostname(), strconv.FormatInt(port, 10))
+	dialer := &net.Dialer{Timeout: sshDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", hostPort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH service %s: %w", hostPort, err)
+	}
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, hostPort, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to establish SSH connection to %s: %w", hostPort, err)
+	}
+	sshClient := ssh.NewClient(sshConn, channels, requests)
+
+	console, err := attachSSHConsole(sshClient, oem(system).SerialConsoleCommand())
+	if err != nil {
+		_ = sshClient.Close()
+		return nil, err
+	}
+	return console, nil
+}
+
+// getSSHConsoleManager returns the first manager of the system which serves the serial
+// console via SSH.
+func getSSHConsoleManager(client *gofish.APIClient, system *redfish.ComputerSystem) (*redfish.Manager, error) {
+	for _, uri := range system.ManagedBy {
+		manager, err := redfish.GetManager(client, uri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get manager: %w", err)
+		}
+		if !manager.SerialConsole.ServiceEnabled {
+			continue
+		}
+		for _, connectType := range manager.SerialConsole.ConnectTypesSupported {
+			if connectType == redfish.SSHSerialConnectTypesSupported {
+				return manager, nil
+			}
+		}
+	}
+	return nil, ErrConsoleNotSupported
+}
+
+// attachSSHConsole starts an interactive shell and runs the given command to attach the
+// shell to the serial console.
+func attachSSHConsole(client *ssh.Client, command string) (*sshConsole, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	if err := session.RequestPty("vt100", 40, 120, ssh.TerminalModes{ssh.ECHO: 0}); err != nil {
+		_ = session.Close()
+		return nil, fmt.Errorf("failed to request terminal: %w", err)
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		_ = session.Close()
+		return nil, fmt.Errorf("failed to get SSH session input: %w", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		_ = session.Close()
+		return nil, fmt.Errorf("failed to get SSH session output: %w", err)
+	}
+	if err := session.Shell(); err != nil {
+		_ = session.Close()
+		return nil, fmt.Errorf("failed to start shell: %w", err)
+	}
+	if command != "" {
+		if _, err := io.WriteString(stdin, command+"\r"); err != nil {
+			_ = session.Close()
+			return nil, fmt.Errorf("failed to attach to serial console: %w", err)
+		}
+	}
+	return &sshConsole{Reader: stdout, WriteCloser: stdin, session: session, client: client}, nil
+}
+
+// sshConsole is a serial console attached via an SSH session.
+type sshConsole struct {
+	io.Reader
+	io.WriteCloser
+	session *ssh.Session
+	client  *ssh.Client
+}
+
+// Close terminates the session and closes the SSH connection.
+func (c *sshConsole) Close() error {
+	_ = c.WriteCloser.Close()
+	_ = c.session.Close()
+	return c.client.Close()
+}
@@ -0,0 +1,210 @@
+package bmc
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+)
+
+// newHostKey returns a new host key for an SSH service.
+func newHostKey() ssh.Signer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	signer, err := ssh.NewSignerFromKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	return signer
+}
+
+// authorizedKey returns the public key of the signer in authorized_keys format.
+func authorizedKey(signer ssh.Signer) string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+}
+
+// serveSSHConsole serves an SSH shell on the listener which acts like the racadm shell of
+// the iDRAC: the serial console output is written once the session is attached with
+// 'console com2'.
+func serveSSHConsole(listener net.Listener, signer ssh.Signer, username, password string) {
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if meta.User() != username || string(pass) != password {
+				return nil, errors.New("invalid credentials")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer GinkgoRecover()
+			_, channels, requests, err := ssh.NewServerConn(conn, config)
+			if err != nil {
+				return
+			}
+			go ssh.DiscardRequests(requests)
+			for newChannel := range channels {
+				channel, requests, err := newChannel.Accept()
+				Expect(err).NotTo(HaveOccurred())
+				go func() {
+					for request := range requests {
+						// pty-req and shell are accepted
+						_ = request.Reply(true, nil)
+					}
+				}()
+				go func() {
+					defer channel.Close()
+					_, _ = io.WriteString(channel, "racadm>>")
+					scanner := bufio.NewScanner(channel)
+					scanner.Split(scanCarriageReturns)
+					for scanner.Scan() {
+						if scanner.Text() == "console com2" {
+							_, _ = io.WriteString(channel, "iPXE initialising devices...\r\n")
+						}
+					}
+				}()
+			}
+		}()
+	}
+}
+
+func scanCarriageReturns(data []byte, atEOF bool) (int, []byte, error) {
+	if i := strings.IndexByte(string(data), '\r'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+var _ = Describe("Console", func() {
+	var (
+		server         *httptest.Server
+		sshPort        int
+		serviceEnabled bool
+		hostKey        ssh.Signer
+	)
+
+	BeforeEach(func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(listener.Close)
+		sshPort = listener.Addr().(*net.TCPAddr).Port
+		hostKey = newHostKey()
+		go serveSSHConsole(listener, hostKey, "admin", "secret")
+		serviceEnabled = true
+
+		mux := http.NewServeMux()
+		serve := func(path string, body func() string) {
+			mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(body()))
+			})
+		}
+		static := func(body string) func() string {
+			return func() string { return body }
+		}
+		serve("/redfish/v1/", static(`{"@odata.id":"/redfish/v1/","RedfishVersion":"1.11.0","Vendor":"Dell","Systems":{"@odata.id":"/redfish/v1/Systems"}}`))
+		serve("/redfish/v1/Systems", static(`{"Members":[{"@odata.id":"/redfish/v1/Systems/1"}]}`))
+		serve("/redfish/v1/Systems/1", static(`{
+			"@odata.id": "/redfish/v1/Systems/1",
+			"Id": "1",
+			"Links": {"ManagedBy": [{"@odata.id": "/redfish/v1/Managers/1"}]}
+		}`))
+		serve("/redfish/v1/Managers/1", func() string {
+			return fmt.Sprintf(`{
+				"@odata.id": "/redfish/v1/Managers/1",
+				"Id": "1",
+				"SerialConsole": {"ServiceEnabled": %t, "ConnectTypesSupported": ["SSH", "IPMI"]},
+				"NetworkProtocol": {"@odata.id": "/redfish/v1/Managers/1/NetworkProtocol"}
+			}`, serviceEnabled)
+		})
+		serve("/redfish/v1/Managers/1/NetworkProtocol", func() string {
+			return fmt.Sprintf(`{"@odata.id":"/redfish/v1/Managers/1/NetworkProtocol","SSH":{"ProtocolEnabled":true,"Port":%d}}`, sshPort)
+		})
+		server = httptest.NewServer(mux)
+		DeferCleanup(server.Close)
+	})
+
+	newClient := func(ctx SpecContext) *RedfishBMC {
+		bmcClient, err := NewRedfishBMC(ctx, "1", v1alpha1.BMCSpec{
+			Type:      v1alpha1.BMCTypeRedfish,
+			Address:   server.URL,
+			BasicAuth: true,
+		}, "admin", "secret", nil)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(bmcClient.Logout)
+		return bmcClient
+	}
+
+	It("should attach to the serial console via the SSH service of the manager", func(ctx SpecContext) {
+		console, err := newClient(ctx).OpenConsole(ctx, ConsoleOptions{
+			Username: "admin",
+			Password: "secret",
+			HostKey:  authorizedKey(hostKey),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer console.Close()
+
+		reader := bufio.NewReader(console)
+		Eventually(func() (string, error) {
+			return reader.ReadString('\n')
+		}).Should(ContainSubstring("iPXE initialising devices..."))
+	})
+
+	It("should refuse SSH services presenting another host key", func(ctx SpecContext) {
+		_, err := newClient(ctx).OpenConsole(ctx, ConsoleOptions{
+			Username: "admin",
+			Password: "secret",
+			HostKey:  authorizedKey(newHostKey()),
+		})
+		Expect(err).To(MatchError(ContainSubstring("host key mismatch")))
+	})
+
+	It("should trust the host key on first use only if allowed", func(ctx SpecContext) {
+		_, err := newClient(ctx).OpenConsole(ctx, ConsoleOptions{Username: "admin", Password: "secret"})
+		Expect(err).To(MatchError(ErrUnknownHostKey))
+
+		var trusted string
+		console, err := newClient(ctx).OpenConsole(ctx, ConsoleOptions{
+			Username: "admin",
+			Password: "secret",
+			TrustHostKey: func(key string) error {
+				trusted = key
+				return nil
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(console.Close()).To(Succeed())
+		Expect(trusted).To(Equal(authorizedKey(hostKey)))
+
+		_, err = newClient(ctx).OpenConsole(ctx, ConsoleOptions{
+			Username:     "admin",
+			Password:     "secret",
+			TrustHostKey: func(string) error { return errors.New("untrusted") },
+		})
+		Expect(err).To(MatchError(ContainSubstring("untrusted")))
+	})
+
+	It("should report that the serial console is not supported if it is disabled", func(ctx SpecContext) {
+		serviceEnabled = false
+		_, err := newClient(ctx).OpenConsole(ctx, ConsoleOptions{HostKey: authorizedKey(hostKey)})
+		Expect(err).To(MatchError(ErrConsoleNotSupported))
+	})
+})
@@ -0,0 +1,539 @@
+package bmc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/stmcginnis/gofish/common"
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+var _ BMC = (*FakeBMC)(nil)
+
+// FakeSystem is a system served by the fake BMC.
+type FakeSystem struct {
+	ID                string             `json:"id"`
+	UUID              string             `json:"uuid"`
+	Manufacturer      string             `json:"manufacturer,omitempty"`
+	Model             string             `json:"model,omitempty"`
+	SerialNumber      string             `json:"serialNumber,omitempty"`
+	PowerState        redfish.PowerState `json:"powerState,omitempty"`
+	NetworkInterfaces []NetworkInterface `json:"networkInterfaces,omitempty"`
+	Processors        []Processor        `json:"processors,omitempty"`
+}
+
+type fakeSystemState struct {
+	FakeSystem
+	target        redfish.PowerState
+	deadline      time.Time
+	pendingBoot   *redfish.Boot
+	bootOverrides []redfish.Boot
+	logEntries    []LogEntry
+	components    []ComponentHealth
+	consoles      map[*fakeConsole]struct{}
+}
+
+type fakeBMCState struct {
+	mu            sync.Mutex
+	systems       []*fakeSystemState
+	managerResets int
+}
+
+// FakeBMCs keeps the state of the fake BMCs in memory, so that every client created for the
+// same key observes the same systems. The state of a fake BMC is kept until it is removed.
+type FakeBMCs struct {
+	mu   sync.Mutex
+	bmcs map[string]*fakeBMCState
+}
+
+// NewFakeBMCs creates a new FakeBMCs without any fake BMC.
+func NewFakeBMCs() *FakeBMCs {
+	return &FakeBMCs{bmcs: map[string]*fakeBMCState{}}
+}
+
+// FakeBMC is an in-memory implementation of the BMC interface.
+type FakeBMC struct {
+	systemId             string
+	powerTransitionDelay time.Duration
+	state                *fakeBMCState
+}
+
+// NewFakeBMC creates a new FakeBMC for the fake BMC with the given key. If an inventory is
+// given, the systems of the fake BMC are synchronized with it while the power state and
+// boot overrides of known systems are preserved. Otherwise, the known systems are kept.
+func (f *FakeBMCs) NewFakeBMC(key, systemId string, inventory []FakeSystem, powerTransitionDelay time.Duration) *FakeBMC {
+	state := f.ensure(key)
+	if inventory != nil {
+		state.sync(inventory)
+	}
+	return &FakeBMC{systemId: systemId, powerTransitionDelay: powerTransitionDelay, state: state}
+}
+
+// Remove drops the state of the fake BMC with the given key.
+func (f *FakeBMCs) Remove(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.bmcs, key)
+}
+
+// Reset drops the state of all fake BMCs.
+func (f *FakeBMCs) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bmcs = map[string]*fakeBMCState{}
+}
+
+func (f *FakeBMCs) ensure(key string) *fakeBMCState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	state, ok := f.bmcs[key]
+	if !ok {
+		state = &fakeBMCState{}
+		f.bmcs[key] = state
+	}
+	return state
+}
+
+func (f *FakeBMCs) get(key string) (*fakeBMCState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	state, ok := f.bmcs[key]
+	if !ok {
+		return nil, fmt.Errorf("no fake BMC found for key %s", key)
+	}
+	return state, nil
+}
+
+// sync synchronizes the systems with the given inventory.
+func (s *fakeBMCState) sync(inventory []FakeSystem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	systems := make([]*fakeSystemState, 0, len(inventory))
+	for _, system := range inventory {
+		if system.PowerState == "" {
+			system.PowerState = redfish.OffPowerState
+		}
+		if existing := s.system(system.ID); existing != nil {
+			system.PowerState = existing.PowerState
+			existing.FakeSystem = system
+			systems = append(systems, existing)
+			continue
+		}
+		systems = append(systems, &fakeSystemState{FakeSystem: system})
+	}
+	s.systems = systems
+}
+
+// AddLogEntry appends an entry to a log service of the system with the given ID of the fake
+// BMC with the given key. The entry is numbered if it has no ID.
+func (f *FakeBMCs) AddLogEntry(key, systemID string, entry LogEntry) error {
+	state, err := f.get(key)
+	if err != nil {
+		return err
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	system := state.system(systemID)
+	if system == nil {
+		return fmt.Errorf("no system found for system ID %s", systemID)
+	}
+	if entry.ID == "" {
+		entry.ID = strconv.Itoa(len(system.logEntries) + 1)
+	}
+	if entry.Created.IsZero() {
+		entry.Created = time.Now()
+	}
+	entry.Created = entry.Created.Truncate(time.Second)
+	system.logEntries = append(system.logEntries, entry)
+	return nil
+}
+
+// SetComponentHealth replaces the health of the components of the system with the given ID
+// of the fake BMC with the given key.
+func (f *FakeBMCs) SetComponentHealth(key, systemID string, components []ComponentHealth) error {
+	state, err := f.get(key)
+	if err != nil {
+		return err
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	system := state.system(systemID)
+	if system == nil {
+		return fmt.Errorf("no system found for system ID %s", systemID)
+	}
+	system.components = append([]ComponentHealth(nil), components...)
+	return nil
+}
+
+// AddConsoleOutput writes the output to the attached serial consoles of the system with the
+// given ID of the fake BMC with the given key.
+func (f *FakeBMCs) AddConsoleOutput(key, systemID, output string) error {
+	state, err := f.get(key)
+	if err != nil {
+		return err
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	system := state.system(systemID)
+	if system == nil {
+		return fmt.Errorf("no system found for system ID %s", systemID)
+	}
+	system.writeConsole(output)
+	return nil
+}
+
+// ManagerResets returns the number of restarts of the fake BMC with the given key.
+func (f *FakeBMCs) ManagerResets(key string) int {
+	state, err := f.get(key)
+	if err != nil {
+		return 0
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.managerResets
+}
+
+func (s *fakeBMCState) system(id string) *fakeSystemState {
+	for _, system := range s.systems {
+		if system.ID == id {
+			return system
+		}
+	}
+	return nil
+}
+
+// settle completes a pending power transition once its deadline has passed.
+func (s *fakeSystemState) settle() {
+	if s.target == "" || time.Now().Before(s.deadline) {
+		return
+	}
+	s.PowerState = s.target
+	s.target = ""
+	if s.PowerState == redfish.OnPowerState {
+		bootSource := "disk"
+		if s.pendingBoot != nil {
+			bootSource = string(s.pendingBoot.BootSourceOverrideTarget)
+		}
+		s.writeConsole(fmt.Sprintf("%s booting from %s\r\n", s.ID, bootSource))
+	}
+	if s.PowerState == redfish.OnPowerState && s.pendingBoot != nil {
+		// The system booted, so a one time boot override is consumed.
+		s.pendingBoot = nil
+	}
+}
+
+// writeConsole writes the output to all attached consoles of the system.
+func (s *fakeSystemState) writeConsole(output string) {
+	for console := range s.consoles {
+		console.output([]byte(output))
+	}
+}
+
+func (f *FakeBMC) getSystem() (*fakeSystemState, error) {
+	system := f.state.system(f.systemId)
+	if system == nil {
+		return nil, fmt.Errorf("no system found for system ID %s", f.systemId)
+	}
+	system.settle()
+	return system, nil
+}
+
+func (f *FakeBMC) transition(target, intermediate redfish.PowerState) error {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	system, err := f.getSystem()
+	if err != nil {
+		return err
+	}
+	if system.PowerState == target {
+		return nil
+	}
+	system.PowerState = intermediate
+	system.target = target
+	system.deadline = time.Now().Add(f.powerTransitionDelay)
+	system.settle()
+	return nil
+}
+
+// Logout is a no-op for the fake BMC.
+func (f *FakeBMC) Logout() {}
+
+// PowerOn powers on the system after the configured transition delay.
+func (f *FakeBMC) PowerOn() error {
+	return f.transition(redfish.OnPowerState, redfish.PoweringOnPowerState)
+}
+
+// PowerOff powers off the system after the configured transition delay.
+func (f *FakeBMC) PowerOff() error {
+	return f.transition(redfish.OffPowerState, redfish.PoweringOffPowerState)
+}
+
+// Reset restarts the system which consumes a pending one time boot override.
+func (f *FakeBMC) Reset() error {
+	f.state.mu.Lock()
+	system, err := f.getSystem()
+	if err != nil {
+		f.state.mu.Unlock()
+		return err
+	}
+	system.PowerState = redfish.OffPowerState
+	f.state.mu.Unlock()
+	return f.PowerOn()
+}
+
+// SetPXEBootOnce records a one time PXE boot override for the given system.
+func (f *FakeBMC) SetPXEBootOnce(systemID string) error {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	system := f.state.system(systemID)
+	if system == nil {
+		return fmt.Errorf("no system found for system ID %s", systemID)
+	}
+	boot := redfish.Boot{
+		BootSourceOverrideEnabled: redfish.OnceBootSourceOverrideEnabled,
+		BootSourceOverrideMode:    redfish.UEFIBootSourceOverrideMode,
+		BootSourceOverrideTarget:  redfish.PxeBootSourceOverrideTarget,
+	}
+	system.pendingBoot = &boot
+	system.bootOverrides = append(system.bootOverrides, boot)
+	return nil
+}
+
+// SetHTTPBootOnce records a one time UEFI HTTP boot override for the given system.
+func (f *FakeBMC) SetHTTPBootOnce(systemID, uri string) error {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	system := f.state.system(systemID)
+	if system == nil {
+		return fmt.Errorf("no system found for system ID %s", systemID)
+	}
+	boot := redfish.Boot{
+		BootSourceOverrideEnabled: redfish.OnceBootSourceOverrideEnabled,
+		BootSourceOverrideMode:    redfish.UEFIBootSourceOverrideMode,
+		BootSourceOverrideTarget:  redfish.UefiHTTPBootSourceOverrideTarget,
+		HTTPBootURI:               uri,
+	}
+	system.pendingBoot = &boot
+	system.bootOverrides = append(system.bootOverrides, boot)
+	return nil
+}
+
+// BootOverrides returns all boot overrides which were set for the system.
+func (f *FakeBMC) BootOverrides() []redfish.Boot {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	system := f.state.system(f.systemId)
+	if system == nil {
+		return nil
+	}
+	return append([]redfish.Boot(nil), system.bootOverrides...)
+}
+
+// GetSystems retrieves all systems of the fake BMC.
+func (f *FakeBMC) GetSystems() ([]System, error) {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	result := make([]System, 0, len(f.state.systems))
+	for _, system := range f.state.systems {
+		result = append(result, System{
+			ID:           system.ID,
+			UUID:         system.UUID,
+			Manufacturer: system.Manufacturer,
+			Model:        system.Model,
+			SerialNumber: system.SerialNumber,
+		})
+	}
+	return result, nil
+}
+
+// GetManagerInfo retrieves information about the fake BMC.
+func (f *FakeBMC) GetManagerInfo() (ManagerInfo, error) {
+	return ManagerInfo{
+		Manufacturer:    "Fake",
+		Model:           "FakeBMC",
+		FirmwareVersion: "1.0.0",
+	}, nil
+}
+
+// GetSystemInfo retrieves information about the system.
+func (f *FakeBMC) GetSystemInfo() (SystemInfo, error) {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	system, err := f.getSystem()
+	if err != nil {
+		return SystemInfo{}, err
+	}
+	return SystemInfo{
+		Manufacturer: system.Manufacturer,
+		Model:        system.Model,
+		Status: common.Status{
+			Health: common.OKHealth,
+			State:  common.EnabledState,
+		},
+		PowerState:        system.PowerState,
+		NetworkInterfaces: append([]NetworkInterface(nil), system.NetworkInterfaces...),
+		Processors:        append([]Processor(nil), system.Processors...),
+		SystemUUID:        system.UUID,
+	}, nil
+}
+
+// GetTelemetry returns synthetic sensor readings which depend on the power state of the system.
+func (f *FakeBMC) GetTelemetry() (Telemetry, error) {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	system, err := f.getSystem()
+	if err != nil {
+		return Telemetry{}, err
+	}
+	temperature, fanSpeed, power := 25.0, 0.0, 10.0
+	if system.PowerState == redfish.OnPowerState {
+		temperature, fanSpeed, power = 45.0, 6000.0, 250.0
+	}
+	return Telemetry{
+		Temperatures: []Temperature{{Name: "CPU1 Temp", ReadingCelsius: temperature}},
+		Fans:         []Fan{{Name: "Fan1", ReadingRPM: fanSpeed}},
+		PowerSupplies: []PowerSupply{{
+			Name:   "PSU1",
+			Health: common.OKHealth,
+			State:  common.EnabledState,
+		}},
+		PowerConsumedWatts: &power,
+	}, nil
+}
+
+// GetLogEntries retrieves the new entries which were added to the logs of the system.
+func (f *FakeBMC) GetLogEntries(services []string, cursors []LogCursor) ([]LogEntry, error) {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	system, err := f.getSystem()
+	if err != nil {
+		return nil, err
+	}
+	return filterLogEntries(system.logEntries, services, cursors), nil
+}
+
+// GetHealth returns the health of the components set for the system. The rollup is the worst
+// health of the components.
+func (f *FakeBMC) GetHealth() (Health, error) {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	system, err := f.getSystem()
+	if err != nil {
+		return Health{}, err
+	}
+	health := Health{Rollup: common.OKHealth, Components: append([]ComponentHealth(nil), system.components...)}
+	for _, component := range system.components {
+		switch {
+		case component.Health == common.CriticalHealth:
+			health.Rollup = common.CriticalHealth
+		case component.Health == common.WarningHealth && health.Rollup == common.OKHealth:
+			health.Rollup = common.WarningHealth
+		}
+	}
+	return health, nil
+}
+
+// ResetManager counts the restarts of the fake BMC.
+func (f *FakeBMC) ResetManager() error {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	f.state.managerResets++
+	return nil
+}
+
+// OpenConsole attaches to the serial console of the system. The console receives the output
+// added with AddConsoleOutput and a boot message whenever the system is powered on.
+func (f *FakeBMC) OpenConsole(_ context.Context, _ ConsoleOptions) (io.ReadWriteCloser, error) {
+	f.state.mu.Lock()
+	defer f.state.mu.Unlock()
+
+	system, err := f.getSystem()
+	if err != nil {
+		return nil, err
+	}
+	console := &fakeConsole{}
+	console.cond = sync.NewCond(&console.mu)
+	console.detach = func() {
+		f.state.mu.Lock()
+		defer f.state.mu.Unlock()
+		delete(system.consoles, console)
+	}
+	if system.consoles == nil {
+		system.consoles = map[*fakeConsole]struct{}{}
+	}
+	system.consoles[console] = struct{}{}
+	return console, nil
+}
+
+// fakeConsole is an attached serial console of a fake system. The input is echoed like a
+// terminal does.
+type fakeConsole struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    bytes.Buffer
+	closed bool
+	detach func()
+}
+
+func (c *fakeConsole) output(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buf.Write(p)
+	c.cond.Broadcast()
+}
+
+// Read blocks until output is available or the console is closed.
+func (c *fakeConsole) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.buf.Len() == 0 && !c.closed {
+		c.cond.Wait()
+	}
+	if c.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.buf.Read(p)
+}
+
+func (c *fakeConsole) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return 0, io.ErrClosedPipe
+	}
+	c.output(p)
+	return len(p), nil
+}
+
+func (c *fakeConsole) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.cond.Broadcast()
+	c.mu.Unlock()
+	c.detach()
+	return nil
+}
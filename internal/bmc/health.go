@@ -0,0 +1,109 @@
+package bmc
+
+import (
+	"fmt"
+
+	"github.com/stmcginnis/gofish"
+	"github.com/stmcginnis/gofish/common"
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// getHealth reads the health rollup of the system with the given ID and the health of its
+// processors, memory modules, drives and power supplies. Absent components are skipped.
+func getHealth(client *gofish.APIClient, systemID string) (Health, error) {
+	systems, err := client.GetService().Systems()
+	if err != nil {
+		return Health{}, fmt.Errorf("failed to get systems: %w", err)
+	}
+	system := getSystemWithSytemID(systems, systemID)
+	if system == nil {
+		return Health{}, fmt.Errorf("no system found for system ID %s", systemID)
+	}
+
+	health := Health{Rollup: system.Status.HealthRollup}
+	if health.Rollup == "" {
+		health.Rollup = system.Status.Health
+	}
+
+	processors, err := system.Processors()
+	if err != nil {
+		return Health{}, fmt.Errorf("failed to get processors: %w", err)
+	}
+	for _, processor := range processors {
+		addComponentHealth(&health, ComponentTypeProcessor, processor.ID, processor.Status)
+	}
+
+	memory, err := system.Memory()
+	if err != nil {
+		return Health{}, fmt.Errorf("failed to get memory: %w", err)
+	}
+	for _, module := range memory {
+		addComponentHealth(&health, ComponentTypeMemory, module.ID, module.Status)
+	}
+
+	storages, err := system.Storage()
+	if err != nil {
+		return Health{}, fmt.Errorf("failed to get storage: %w", err)
+	}
+	for _, storage := range storages {
+		drives, err := storage.Drives()
+		if err != nil {
+			return Health{}, fmt.Errorf("failed to get drives of storage %s: %w", storage.ID, err)
+		}
+		for _, drive := range drives {
+			addComponentHealth(&health, ComponentTypeDrive, drive.ID, drive.Status)
+		}
+	}
+
+	chassis, err := getSystemChassis(client, system)
+	if err != nil {
+		return Health{}, err
+	}
+	telemetry := Telemetry{}
+	for _, links := range chassis {
+		if err := getPower(client, links, &telemetry); err != nil {
+			return Health{}, err
+		}
+	}
+	for _, supply := range telemetry.PowerSupplies {
+		addComponentHealth(&health, ComponentTypePowerSupply, supply.Name, common.Status{Health: supply.Health, State: supply.State})
+	}
+	return health, nil
+}
+
+func addComponentHealth(health *Health, componentType ComponentType, name string, status common.Status) {
+	if status.State == common.AbsentState {
+		return
+	}
+	health.Components = append(health.Components, ComponentHealth{Type: componentType, Name: name, Health: status.Health})
+}
+
+// resetManagers restarts the managers responsible for the system with the given ID. A
+// graceful restart is preferred if the manager supports it.
+func resetManagers(client *gofish.APIClient, systemID string) error {
+	systems, err := client.GetService().Systems()
+	if err != nil {
+		return fmt.Errorf("failed to get systems: %w", err)
+	}
+	system := getSystemWithSytemID(systems, systemID)
+	if system == nil {
+		return fmt.Errorf("no system found for system ID %s", systemID)
+	}
+
+	for _, uri := range system.ManagedBy {
+		manager, err := redfish.GetManager(client, uri)
+		if err != nil {
+			return fmt.Errorf("failed to get manager: %w", err)
+		}
+		resetType := redfish.ForceRestartResetType
+		for _, supported := range manager.SupportedResetTypes {
+			if supported == redfish.GracefulRestartResetType {
+				resetType = supported
+			}
+		}
+		if err := manager.Reset(resetType); err != nil {
+			return fmt.Errorf("failed to reset manager %s with reset type %s: %w", manager.ID, resetType, err)
+		}
+	}
+	return nil
+}
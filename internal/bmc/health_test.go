@@ -0,0 +1,108 @@
+package bmc
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stmcginnis/gofish/common"
+)
+
+var _ = Describe("Health", func() {
+	var (
+		server    *httptest.Server
+		mu        sync.Mutex
+		resetBody string
+	)
+
+	BeforeEach(func() {
+		mux := http.NewServeMux()
+		serve := func(path, body string) {
+			mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(body))
+			})
+		}
+		serve("/redfish/v1/", `{"@odata.id":"/redfish/v1/","RedfishVersion":"1.11.0","Systems":{"@odata.id":"/redfish/v1/Systems"}}`)
+		serve("/redfish/v1/Systems", `{"Members":[{"@odata.id":"/redfish/v1/Systems/1"}]}`)
+		serve("/redfish/v1/Systems/1", `{
+			"@odata.id": "/redfish/v1/Systems/1",
+			"Id": "1",
+			"Status": {"State": "Enabled", "Health": "OK", "HealthRollup": "Critical"},
+			"Processors": {"@odata.id": "/redfish/v1/Systems/1/Processors"},
+			"Memory": {"@odata.id": "/redfish/v1/Systems/1/Memory"},
+			"Storage": {"@odata.id": "/redfish/v1/Systems/1/Storage"},
+			"Links": {
+				"Chassis": [{"@odata.id": "/redfish/v1/Chassis/1"}],
+				"ManagedBy": [{"@odata.id": "/redfish/v1/Managers/1"}]
+			}
+		}`)
+		serve("/redfish/v1/Systems/1/Processors", `{"Members":[{"@odata.id":"/redfish/v1/Systems/1/Processors/CPU1"}]}`)
+		serve("/redfish/v1/Systems/1/Processors/CPU1", `{"@odata.id":"/redfish/v1/Systems/1/Processors/CPU1","Id":"CPU1","Status":{"State":"Enabled","Health":"OK"}}`)
+		serve("/redfish/v1/Systems/1/Memory", `{"Members":[
+			{"@odata.id":"/redfish/v1/Systems/1/Memory/DIMMA1"},
+			{"@odata.id":"/redfish/v1/Systems/1/Memory/DIMMA2"}
+		]}`)
+		serve("/redfish/v1/Systems/1/Memory/DIMMA1", `{"@odata.id":"/redfish/v1/Systems/1/Memory/DIMMA1","Id":"DIMMA1","Status":{"State":"Enabled","Health":"Critical"}}`)
+		serve("/redfish/v1/Systems/1/Memory/DIMMA2", `{"@odata.id":"/redfish/v1/Systems/1/Memory/DIMMA2","Id":"DIMMA2","Status":{"State":"Absent"}}`)
+		serve("/redfish/v1/Systems/1/Storage", `{"Members":[{"@odata.id":"/redfish/v1/Systems/1/Storage/RAID1"}]}`)
+		serve("/redfish/v1/Systems/1/Storage/RAID1", `{"@odata.id":"/redfish/v1/Systems/1/Storage/RAID1","Id":"RAID1","Drives":[{"@odata.id":"/redfish/v1/Systems/1/Storage/RAID1/Drives/Disk0"}]}`)
+		serve("/redfish/v1/Systems/1/Storage/RAID1/Drives/Disk0", `{"@odata.id":"/redfish/v1/Systems/1/Storage/RAID1/Drives/Disk0","Id":"Disk0","Status":{"State":"Enabled","Health":"Warning"}}`)
+		serve("/redfish/v1/Chassis/1", `{"@odata.id":"/redfish/v1/Chassis/1","Power":{"@odata.id":"/redfish/v1/Chassis/1/Power"}}`)
+		serve("/redfish/v1/Chassis/1/Power", `{
+			"@odata.id": "/redfish/v1/Chassis/1/Power",
+			"PowerSupplies": [{"MemberId": "0", "Name": "PSU1", "Status": {"State": "Enabled", "Health": "OK"}}]
+		}`)
+		serve("/redfish/v1/Managers/1", `{
+			"@odata.id": "/redfish/v1/Managers/1",
+			"Id": "1",
+			"Actions": {"#Manager.Reset": {
+				"target": "/redfish/v1/Managers/1/Actions/Manager.Reset",
+				"ResetType@Redfish.AllowableValues": ["ForceRestart", "GracefulRestart"]
+			}}
+		}`)
+		mux.HandleFunc("/redfish/v1/Managers/1/Actions/Manager.Reset", func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			resetBody = string(body)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		})
+		server = httptest.NewServer(mux)
+		DeferCleanup(server.Close)
+	})
+
+	newClient := func(ctx SpecContext) *RedfishBMC {
+		bmcClient, err := NewRedfishBMC(ctx, "1", v1alpha1.BMCSpec{
+			Type:      v1alpha1.BMCTypeRedfish,
+			Address:   server.URL,
+			BasicAuth: true,
+		}, "admin", "secret", nil)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(bmcClient.Logout)
+		return bmcClient
+	}
+
+	It("should read the health rollup and the health of the present components", func(ctx SpecContext) {
+		health, err := newClient(ctx).GetHealth()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(health.Rollup).To(Equal(common.CriticalHealth))
+		Expect(health.Components).To(Equal([]ComponentHealth{
+			{Type: ComponentTypeProcessor, Name: "CPU1", Health: common.OKHealth},
+			{Type: ComponentTypeMemory, Name: "DIMMA1", Health: common.CriticalHealth},
+			{Type: ComponentTypeDrive, Name: "Disk0", Health: common.WarningHealth},
+			{Type: ComponentTypePowerSupply, Name: "PSU1", Health: common.OKHealth},
+		}))
+	})
+
+	It("should restart the managers of the system gracefully", func(ctx SpecContext) {
+		Expect(newClient(ctx).ResetManager()).To(Succeed())
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(resetBody).To(MatchJSON(`{"ResetType":"GracefulRestart"}`))
+	})
+})
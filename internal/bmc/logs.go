@@ -0,0 +1,136 @@
+package bmc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stmcginnis/gofish"
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// DefaultLogServices are the log services read if none are given: the System Event Log,
+// the Lifecycle Controller log of the Dell iDRAC and the Integrated Management Log of the
+// HPE iLO.
+var DefaultLogServices = []string{"SEL", "Lclog", "IML"}
+
+// After reports whether the entry was created after the position of the cursor. Entries
+// created at the same time are ordered by their ID.
+func (e LogEntry) After(cursor LogCursor) bool {
+	if !e.Created.Equal(cursor.Created) {
+		return e.Created.After(cursor.Created)
+	}
+	return compareLogEntryIDs(e.ID, cursor.ID) > 0
+}
+
+// compareLogEntryIDs compares numeric IDs by value, as most services number their entries,
+// and all other IDs lexically.
+func compareLogEntryIDs(a, b string) int {
+	x, errA := strconv.ParseInt(a, 10, 64)
+	y, errB := strconv.ParseInt(b, 10, 64)
+	switch {
+	case errA != nil || errB != nil:
+		return strings.Compare(a, b)
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func matchesLogService(services []string, service string) bool {
+	if len(services) == 0 {
+		services = DefaultLogServices
+	}
+	for _, s := range services {
+		if strings.EqualFold(s, service) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterLogEntries returns the entries of the given services which were created after the
+// cursor of their service, oldest first.
+func filterLogEntries(entries []LogEntry, services []string, cursors []LogCursor) []LogEntry {
+	var result []LogEntry
+	for _, entry := range entries {
+		if !matchesLogService(services, entry.Service) {
+			continue
+		}
+		after := true
+		for _, cursor := range cursors {
+			if cursor.Service == entry.Service {
+				after = entry.After(cursor)
+				break
+			}
+		}
+		if after {
+			result = append(result, entry)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].Created.Equal(result[j].Created) {
+			return result[i].Created.Before(result[j].Created)
+		}
+		return compareLogEntryIDs(result[i].ID, result[j].ID) < 0
+	})
+	return result
+}
+
+// getLogEntries reads the log services of the system with the given ID and of the managers
+// responsible for it. Entries without a valid creation time are treated as created at the
+// zero time, so they are ordered before all other entries of their service.
+func getLogEntries(client *gofish.APIClient, systemID string, services []string, cursors []LogCursor) ([]LogEntry, error) {
+	systems, err := client.GetService().Systems()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get systems: %w", err)
+	}
+	system := getSystemWithSytemID(systems, systemID)
+	if system == nil {
+		return nil, fmt.Errorf("no system found for system ID %s", systemID)
+	}
+
+	logServices, err := system.LogServices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log services of system: %w", err)
+	}
+	for _, uri := range system.ManagedBy {
+		manager, err := redfish.GetManager(client, uri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get manager: %w", err)
+		}
+		managerLogServices, err := manager.LogServices()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get log services of manager: %w", err)
+		}
+		logServices = append(logServices, managerLogServices...)
+	}
+
+	var entries []LogEntry
+	for _, logService := range logServices {
+		if !matchesLogService(services, logService.ID) {
+			continue
+		}
+		logEntries, err := logService.Entries()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get entries of log service %s: %w", logService.ID, err)
+		}
+		for _, logEntry := range logEntries {
+			created, _ := time.Parse(time.RFC3339, logEntry.Created)
+			entries = append(entries, LogEntry{
+				Service:   logService.ID,
+				ID:        logEntry.ID,
+				Created:   created.Truncate(time.Second),
+				Severity:  logEntry.Severity,
+				MessageID: logEntry.MessageID,
+				Message:   logEntry.Message,
+			})
+		}
+	}
+	return filterLogEntries(entries, services, cursors), nil
+}
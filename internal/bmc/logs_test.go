@@ -0,0 +1,116 @@
+package bmc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// newLogServer serves a system with a SEL and a manager with a Lclog and an audit log.
+func newLogServer() *httptest.Server {
+	mux := http.NewServeMux()
+	serve := func(path, body string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(body))
+		})
+	}
+	// serveEntries serves the collection and each of the given entries, as members of
+	// collections are read one by one.
+	serveEntries := func(path string, entries ...string) {
+		var members []string
+		for _, entry := range entries {
+			var link odataLink
+			Expect(json.Unmarshal([]byte(entry), &link)).To(Succeed())
+			serve(link.ODataID, entry)
+			members = append(members, fmt.Sprintf(`{"@odata.id":%q}`, link.ODataID))
+		}
+		serve(path, `{"Members":[`+strings.Join(members, ",")+`]}`)
+	}
+	serve("/redfish/v1/", `{"@odata.id":"/redfish/v1/","RedfishVersion":"1.11.0","Systems":{"@odata.id":"/redfish/v1/Systems"}}`)
+	serve("/redfish/v1/Systems", `{"Members":[{"@odata.id":"/redfish/v1/Systems/1"}]}`)
+	serve("/redfish/v1/Systems/1", `{
+		"@odata.id": "/redfish/v1/Systems/1",
+		"Id": "1",
+		"LogServices": {"@odata.id": "/redfish/v1/Systems/1/LogServices"},
+		"Links": {"ManagedBy": [{"@odata.id": "/redfish/v1/Managers/1"}]}
+	}`)
+	serve("/redfish/v1/Systems/1/LogServices", `{"Members":[{"@odata.id":"/redfish/v1/Systems/1/LogServices/SEL"}]}`)
+	serve("/redfish/v1/Systems/1/LogServices/SEL", `{"@odata.id":"/redfish/v1/Systems/1/LogServices/SEL","Id":"SEL","Entries":{"@odata.id":"/redfish/v1/Systems/1/LogServices/SEL/Entries"}}`)
+	serveEntries("/redfish/v1/Systems/1/LogServices/SEL/Entries",
+		`{"@odata.id":"/redfish/v1/Systems/1/LogServices/SEL/Entries/10","Id":"10","Created":"2024-03-01T10:00:00Z","Severity":"Critical","MessageId":"MEM0001","Message":"Uncorrectable ECC error on DIMM A1."}`,
+		`{"@odata.id":"/redfish/v1/Systems/1/LogServices/SEL/Entries/9","Id":"9","Created":"2024-03-01T10:00:00Z","Severity":"Warning","Message":"Correctable ECC error on DIMM A1."}`,
+		`{"@odata.id":"/redfish/v1/Systems/1/LogServices/SEL/Entries/2","Id":"2","Created":"2024-03-01T09:00:00Z","Severity":"OK","Message":"Log cleared."}`,
+	)
+	serve("/redfish/v1/Managers/1", `{"@odata.id":"/redfish/v1/Managers/1","Id":"1","LogServices":{"@odata.id":"/redfish/v1/Managers/1/LogServices"}}`)
+	serve("/redfish/v1/Managers/1/LogServices", `{"Members":[
+		{"@odata.id":"/redfish/v1/Managers/1/LogServices/Lclog"},
+		{"@odata.id":"/redfish/v1/Managers/1/LogServices/Audit"}
+	]}`)
+	serve("/redfish/v1/Managers/1/LogServices/Lclog", `{"@odata.id":"/redfish/v1/Managers/1/LogServices/Lclog","Id":"Lclog","Entries":{"@odata.id":"/redfish/v1/Managers/1/LogServices/Lclog/Entries"}}`)
+	serveEntries("/redfish/v1/Managers/1/LogServices/Lclog/Entries",
+		`{"@odata.id":"/redfish/v1/Managers/1/LogServices/Lclog/Entries/1","Id":"1","Created":"2024-03-01T09:30:00+00:00","Severity":"Critical","MessageId":"PSU0003","Message":"The power supply PSU2 is not receiving input power."}`,
+	)
+	serve("/redfish/v1/Managers/1/LogServices/Audit", `{"@odata.id":"/redfish/v1/Managers/1/LogServices/Audit","Id":"Audit","Entries":{"@odata.id":"/redfish/v1/Managers/1/LogServices/Audit/Entries"}}`)
+	serveEntries("/redfish/v1/Managers/1/LogServices/Audit/Entries",
+		`{"@odata.id":"/redfish/v1/Managers/1/LogServices/Audit/Entries/1","Id":"1","Created":"2024-03-01T09:45:00Z","Severity":"OK","Message":"User root logged in."}`,
+	)
+	server := httptest.NewServer(mux)
+	DeferCleanup(server.Close)
+	return server
+}
+
+var _ = Describe("Logs", func() {
+	ids := func(entries []LogEntry) []string {
+		var result []string
+		for _, entry := range entries {
+			result = append(result, entry.Service+"/"+entry.ID)
+		}
+		return result
+	}
+
+	It("should read the entries of the system and manager log services after the cursors", func(ctx SpecContext) {
+		server := newLogServer()
+		bmcClient, err := NewRedfishBMC(ctx, "1", v1alpha1.BMCSpec{
+			Type:      v1alpha1.BMCTypeRedfish,
+			Address:   server.URL,
+			BasicAuth: true,
+		}, "admin", "secret", nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer bmcClient.Logout()
+
+		By("Reading all entries of the default log services")
+		entries, err := bmcClient.GetLogEntries(nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(entries)).To(Equal([]string{"SEL/2", "Lclog/1", "SEL/9", "SEL/10"}))
+		Expect(entries[3]).To(Equal(LogEntry{
+			Service:   "SEL",
+			ID:        "10",
+			Created:   time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+			Severity:  redfish.CriticalEventSeverity,
+			MessageID: "MEM0001",
+			Message:   "Uncorrectable ECC error on DIMM A1.",
+		}))
+
+		By("Reading the entries after the cursors")
+		entries, err = bmcClient.GetLogEntries(nil, []LogCursor{
+			{Service: "SEL", Created: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), ID: "9"},
+			{Service: "Lclog", Created: time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC), ID: "1"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(entries)).To(Equal([]string{"SEL/10"}))
+
+		By("Reading only the configured log services")
+		entries, err = bmcClient.GetLogEntries([]string{"audit"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(entries)).To(Equal([]string{"Audit/1"}))
+	})
+})
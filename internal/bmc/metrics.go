@@ -0,0 +1,149 @@
+package bmc
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "baremetal_bmc_request_duration_seconds",
+		Help:    "Duration of BMC operations by method, BMC type and host.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "type", "host"})
+	requestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "baremetal_bmc_request_errors_total",
+		Help: "Number of failed BMC operations by method, BMC type and host.",
+	}, []string{"method", "type", "host"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(requestDuration, requestErrors)
+}
+
+// ObserveRequest records the duration and the result of a BMC operation which started at
+// the given time. The host is empty for operations which concern the BMC as a whole.
+func ObserveRequest(method, bmcType, host string, start time.Time, err error) {
+	requestDuration.WithLabelValues(method, bmcType, host).Observe(time.Since(start).Seconds())
+	if err != nil {
+		requestErrors.WithLabelValues(method, bmcType, host).Inc()
+	}
+}
+
+var _ BMC = (*instrumentedBMC)(nil)
+
+// instrumentedBMC records metrics for all operations of the wrapped BMC.
+type instrumentedBMC struct {
+	bmc     BMC
+	bmcType string
+	host    string
+}
+
+// Instrument wraps the BMC so that the duration and the errors of all operations are
+// recorded with the given BMC type and host.
+func Instrument(bmc BMC, bmcType, host string) BMC {
+	return &instrumentedBMC{bmc: bmc, bmcType: bmcType, host: host}
+}
+
+func (i *instrumentedBMC) observe(method string, start time.Time, err error) {
+	ObserveRequest(method, i.bmcType, i.host, start, err)
+}
+
+func (i *instrumentedBMC) PowerOn() error {
+	start := time.Now()
+	err := i.bmc.PowerOn()
+	i.observe("PowerOn", start, err)
+	return err
+}
+
+func (i *instrumentedBMC) PowerOff() error {
+	start := time.Now()
+	err := i.bmc.PowerOff()
+	i.observe("PowerOff", start, err)
+	return err
+}
+
+func (i *instrumentedBMC) Reset() error {
+	start := time.Now()
+	err := i.bmc.Reset()
+	i.observe("Reset", start, err)
+	return err
+}
+
+func (i *instrumentedBMC) SetPXEBootOnce(systemID string) error {
+	start := time.Now()
+	err := i.bmc.SetPXEBootOnce(systemID)
+	i.observe("SetPXEBootOnce", start, err)
+	return err
+}
+
+func (i *instrumentedBMC) SetHTTPBootOnce(systemID, uri string) error {
+	start := time.Now()
+	err := i.bmc.SetHTTPBootOnce(systemID, uri)
+	i.observe("SetHTTPBootOnce", start, err)
+	return err
+}
+
+func (i *instrumentedBMC) GetSystemInfo() (SystemInfo, error) {
+	start := time.Now()
+	result, err := i.bmc.GetSystemInfo()
+	i.observe("GetSystemInfo", start, err)
+	return result, err
+}
+
+func (i *instrumentedBMC) GetSystems() ([]System, error) {
+	start := time.Now()
+	result, err := i.bmc.GetSystems()
+	i.observe("GetSystems", start, err)
+	return result, err
+}
+
+func (i *instrumentedBMC) GetManagerInfo() (ManagerInfo, error) {
+	start := time.Now()
+	result, err := i.bmc.GetManagerInfo()
+	i.observe("GetManagerInfo", start, err)
+	return result, err
+}
+
+func (i *instrumentedBMC) GetTelemetry() (Telemetry, error) {
+	start := time.Now()
+	result, err := i.bmc.GetTelemetry()
+	i.observe("GetTelemetry", start, err)
+	return result, err
+}
+
+func (i *instrumentedBMC) GetLogEntries(services []string, cursors []LogCursor) ([]LogEntry, error) {
+	start := time.Now()
+	result, err := i.bmc.GetLogEntries(services, cursors)
+	i.observe("GetLogEntries", start, err)
+	return result, err
+}
+
+func (i *instrumentedBMC) GetHealth() (Health, error) {
+	start := time.Now()
+	result, err := i.bmc.GetHealth()
+	i.observe("GetHealth", start, err)
+	return result, err
+}
+
+func (i *instrumentedBMC) ResetManager() error {
+	start := time.Now()
+	err := i.bmc.ResetManager()
+	i.observe("ResetManager", start, err)
+	return err
+}
+
+func (i *instrumentedBMC) OpenConsole(ctx context.Context, options ConsoleOptions) (io.ReadWriteCloser, error) {
+	start := time.Now()
+	console, err := i.bmc.OpenConsole(ctx, options)
+	i.observe("OpenConsole", start, err)
+	return console, err
+}
+
+func (i *instrumentedBMC) Logout() {
+	i.bmc.Logout()
+}
@@ -0,0 +1,107 @@
+package bmc
+
+import (
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+var (
+	// uefiMACPattern matches the MAC address in the UEFI device path of a network boot option,
+	// e.g. MAC(0A1B2C3D4E5F,0x1).
+	uefiMACPattern = regexp.MustCompile(`(?i)MAC\(([0-9a-f]{12})`)
+	// macPattern matches a MAC address in the display name of a boot option, e.g.
+	// "UEFI PXEv4 (MAC:0A:1B:2C:3D:4E:5F)".
+	macPattern = regexp.MustCompile(`(?i)\b[0-9a-f]{2}([:-][0-9a-f]{2}){5}\b|MAC:([0-9a-f]{12})\b`)
+)
+
+// getNetworkInterfaces returns the network interfaces of the system. The interfaces referenced
+// by a network boot option of the system are reported as PXE capable.
+func getNetworkInterfaces(system *redfish.ComputerSystem) ([]NetworkInterface, error) {
+	nics, err := system.EthernetInterfaces()
+	if err != nil {
+		return nil, err
+	}
+	// the boot options are optional, so the interfaces are not marked if they cannot be read
+	pxeMACs := map[string]bool{}
+	if options, err := system.BootOptions(); err == nil {
+		for _, option := range options {
+			if isNetworkBootOption(option) {
+				for _, mac := range bootOptionMACs(option) {
+					pxeMACs[mac] = true
+				}
+			}
+		}
+	}
+
+	var networkInterfaces []NetworkInterface
+	for _, nic := range nics {
+		networkInterface := NetworkInterface{
+			ID:                  nic.ID,
+			Name:                nic.Name,
+			MACAddress:          nic.MACAddress,
+			PermanentMACAddress: nic.PermanentMACAddress,
+			PXEBoot:             pxeMACs[NormalizeMAC(nic.MACAddress)] || pxeMACs[NormalizeMAC(nic.PermanentMACAddress)],
+		}
+		updated := false
+		for i, existing := range networkInterfaces {
+			if existing.ID == nic.ID {
+				networkInterfaces[i] = networkInterface
+				updated = true
+				break
+			}
+		}
+		if !updated {
+			networkInterfaces = append(networkInterfaces, networkInterface)
+		}
+	}
+	// the interfaces are collected concurrently, so they are sorted to report them in a stable order
+	sort.Slice(networkInterfaces, func(i, j int) bool {
+		return networkInterfaces[i].ID < networkInterfaces[j].ID
+	})
+	return networkInterfaces, nil
+}
+
+// isNetworkBootOption reports whether the boot option boots via the network.
+func isNetworkBootOption(option *redfish.BootOption) bool {
+	if option.Alias == redfish.PxeBootSourceOverrideTarget || strings.Contains(strings.ToUpper(option.DisplayName), "PXE") {
+		return true
+	}
+	return uefiMACPattern.MatchString(option.UefiDevicePath)
+}
+
+// bootOptionMACs returns the normalized MAC addresses of the interfaces a boot option boots from.
+func bootOptionMACs(option *redfish.BootOption) []string {
+	var macs []string
+	for _, match := range uefiMACPattern.FindAllStringSubmatch(option.UefiDevicePath, -1) {
+		macs = append(macs, NormalizeMAC(match[1]))
+	}
+	for _, match := range macPattern.FindAllStringSubmatch(option.DisplayName, -1) {
+		if match[2] != "" {
+			macs = append(macs, NormalizeMAC(match[2]))
+		} else {
+			macs = append(macs, NormalizeMAC(match[0]))
+		}
+	}
+	return macs
+}
+
+// NormalizeMAC returns the MAC address in lower case separated by colons, so that MAC addresses
+// reported in different notations can be compared. Invalid addresses are returned in lower case.
+func NormalizeMAC(mac string) string {
+	if len(mac) == 12 && !strings.ContainsAny(mac, ":-.") {
+		parts := make([]string, 0, 6)
+		for i := 0; i < 12; i += 2 {
+			parts = append(parts, mac[i:i+2])
+		}
+		mac = strings.Join(parts, ":")
+	}
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return strings.ToLower(mac)
+	}
+	return hw.String()
+}
@@ -0,0 +1,80 @@
+package bmc
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NetworkInterfaces", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		mux := http.NewServeMux()
+		serve := func(path, body string) {
+			mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(body))
+			})
+		}
+		serve("/redfish/v1/", `{"@odata.id":"/redfish/v1/","RedfishVersion":"1.11.0","Systems":{"@odata.id":"/redfish/v1/Systems"}}`)
+		serve("/redfish/v1/Systems", `{"Members":[{"@odata.id":"/redfish/v1/Systems/1"}]}`)
+		serve("/redfish/v1/Systems/1", `{
+			"@odata.id": "/redfish/v1/Systems/1",
+			"Id": "1",
+			"Boot": {"BootOptions": {"@odata.id": "/redfish/v1/Systems/1/BootOptions"}},
+			"EthernetInterfaces": {"@odata.id": "/redfish/v1/Systems/1/EthernetInterfaces"},
+			"Processors": {"@odata.id": "/redfish/v1/Systems/1/Processors"}
+		}`)
+		serve("/redfish/v1/Systems/1/Processors", `{"Members":[]}`)
+		serve("/redfish/v1/Systems/1/EthernetInterfaces", `{"Members":[
+			{"@odata.id":"/redfish/v1/Systems/1/EthernetInterfaces/NIC.Embedded.1"},
+			{"@odata.id":"/redfish/v1/Systems/1/EthernetInterfaces/NIC.Slot.1"},
+			{"@odata.id":"/redfish/v1/Systems/1/EthernetInterfaces/NIC.Slot.2"}
+		]}`)
+		serve("/redfish/v1/Systems/1/EthernetInterfaces/NIC.Embedded.1", `{"@odata.id":"/redfish/v1/Systems/1/EthernetInterfaces/NIC.Embedded.1","Id":"NIC.Embedded.1","Name":"eno1","MACAddress":"0A:1B:2C:3D:4E:01"}`)
+		serve("/redfish/v1/Systems/1/EthernetInterfaces/NIC.Slot.1", `{"@odata.id":"/redfish/v1/Systems/1/EthernetInterfaces/NIC.Slot.1","Id":"NIC.Slot.1","Name":"ens1f0","MACAddress":"0A:1B:2C:3D:4E:02","PermanentMACAddress":"0A:1B:2C:3D:4E:12"}`)
+		serve("/redfish/v1/Systems/1/EthernetInterfaces/NIC.Slot.2", `{"@odata.id":"/redfish/v1/Systems/1/EthernetInterfaces/NIC.Slot.2","Id":"NIC.Slot.2","Name":"ens2f0","MACAddress":"0A:1B:2C:3D:4E:03"}`)
+		serve("/redfish/v1/Systems/1/BootOptions", `{"Members":[
+			{"@odata.id":"/redfish/v1/Systems/1/BootOptions/0001"},
+			{"@odata.id":"/redfish/v1/Systems/1/BootOptions/0002"},
+			{"@odata.id":"/redfish/v1/Systems/1/BootOptions/0003"}
+		]}`)
+		serve("/redfish/v1/Systems/1/BootOptions/0001", `{"@odata.id":"/redfish/v1/Systems/1/BootOptions/0001","Id":"0001","DisplayName":"Hard Disk","UefiDevicePath":"PciRoot(0x0)/Pci(0x17,0x0)/Sata(0x0,0xFFFF,0x0)"}`)
+		serve("/redfish/v1/Systems/1/BootOptions/0002", `{"@odata.id":"/redfish/v1/Systems/1/BootOptions/0002","Id":"0002","DisplayName":"UEFI PXEv4 (MAC:0A1B2C3D4E12)"}`)
+		serve("/redfish/v1/Systems/1/BootOptions/0003", `{"@odata.id":"/redfish/v1/Systems/1/BootOptions/0003","Id":"0003","DisplayName":"Network","UefiDevicePath":"PciRoot(0x0)/Pci(0x1C,0x0)/MAC(0a1b2c3d4e03,0x1)/IPv4(0.0.0.0)"}`)
+		server = httptest.NewServer(mux)
+		DeferCleanup(server.Close)
+	})
+
+	It("should report the interfaces referenced by network boot options as PXE capable", func(ctx SpecContext) {
+		bmcClient, err := NewRedfishBMC(ctx, "1", v1alpha1.BMCSpec{
+			Type:      v1alpha1.BMCTypeRedfish,
+			Address:   server.URL,
+			BasicAuth: true,
+		}, "admin", "secret", nil)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(bmcClient.Logout)
+
+		info, err := bmcClient.GetSystemInfo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.NetworkInterfaces).To(Equal([]NetworkInterface{
+			{ID: "NIC.Embedded.1", Name: "eno1", MACAddress: "0A:1B:2C:3D:4E:01"},
+			{ID: "NIC.Slot.1", Name: "ens1f0", MACAddress: "0A:1B:2C:3D:4E:02", PermanentMACAddress: "0A:1B:2C:3D:4E:12", PXEBoot: true},
+			{ID: "NIC.Slot.2", Name: "ens2f0", MACAddress: "0A:1B:2C:3D:4E:03", PXEBoot: true},
+		}))
+	})
+
+	DescribeTable("should normalize MAC addresses",
+		func(mac, expected string) {
+			Expect(NormalizeMAC(mac)).To(Equal(expected))
+		},
+		Entry("colon separated", "0A:1B:2C:3D:4E:5F", "0a:1b:2c:3d:4e:5f"),
+		Entry("dash separated", "0A-1B-2C-3D-4E-5F", "0a:1b:2c:3d:4e:5f"),
+		Entry("not separated", "0A1B2C3D4E5F", "0a:1b:2c:3d:4e:5f"),
+		Entry("invalid", "Not-A-MAC", "not-a-mac"),
+	)
+})
@@ -0,0 +1,212 @@
+package bmc
+
+import (
+	"strings"
+
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// Vendor identifies a Redfish implementation.
+type Vendor string
+
+const (
+	VendorGeneric    Vendor = "Generic"
+	VendorDell       Vendor = "Dell"
+	VendorHPE        Vendor = "HPE"
+	VendorSupermicro Vendor = "Supermicro"
+)
+
+// OEM adapts the generic Redfish implementation to the deviations of a vendor.
+type OEM interface {
+	// Vendor returns the vendor the strategy is implemented for.
+	Vendor() Vendor
+
+	// PXEBootOnce returns the boot override which boots the system once from the network.
+	PXEBootOnce(system *redfish.ComputerSystem) redfish.Boot
+
+	// HTTPBootOnce returns the boot override which boots the system once via UEFI HTTP boot
+	// from the URI.
+	HTTPBootOnce(system *redfish.ComputerSystem, uri string) redfish.Boot
+
+	// ResetType returns the reset type used to restart the system.
+	ResetType(system *redfish.ComputerSystem) redfish.ResetType
+
+	// SerialConsoleCommand returns the command which attaches the SSH session of the manager
+	// to the serial console of the system. It is empty if the session is attached directly.
+	SerialConsoleCommand() string
+}
+
+// DetectVendor determines the vendor from the manufacturer reported by the service root
+// or the system.
+func DetectVendor(manufacturer string) Vendor {
+	manufacturer = strings.ToLower(manufacturer)
+	switch {
+	case strings.Contains(manufacturer, "dell"):
+		return VendorDell
+	case strings.Contains(manufacturer, "hpe"), strings.Contains(manufacturer, "hewlett"):
+		return VendorHPE
+	case strings.Contains(manufacturer, "supermicro"):
+		return VendorSupermicro
+	default:
+		return VendorGeneric
+	}
+}
+
+// NewOEM returns the strategy for the given vendor.
+func NewOEM(vendor Vendor) OEM {
+	switch vendor {
+	case VendorDell:
+		return dellOEM{}
+	case VendorHPE:
+		return hpeOEM{}
+	case VendorSupermicro:
+		return supermicroOEM{}
+	default:
+		return genericOEM{}
+	}
+}
+
+// genericOEM implements the behavior defined by the Redfish specification.
+type genericOEM struct{}
+
+func (genericOEM) Vendor() Vendor {
+	return VendorGeneric
+}
+
+// PXEBootOnce boots the system once via UEFI PXE boot.
+func (genericOEM) PXEBootOnce(_ *redfish.ComputerSystem) redfish.Boot {
+	return redfish.Boot{
+		BootSourceOverrideEnabled: redfish.OnceBootSourceOverrideEnabled,
+		BootSourceOverrideMode:    redfish.UEFIBootSourceOverrideMode,
+		BootSourceOverrideTarget:  redfish.PxeBootSourceOverrideTarget,
+	}
+}
+
+// HTTPBootOnce sets the UEFI boot mode, as HTTP boot is only defined for UEFI.
+func (genericOEM) HTTPBootOnce(_ *redfish.ComputerSystem, uri string) redfish.Boot {
+	return redfish.Boot{
+		BootSourceOverrideEnabled: redfish.OnceBootSourceOverrideEnabled,
+		BootSourceOverrideMode:    redfish.UEFIBootSourceOverrideMode,
+		BootSourceOverrideTarget:  redfish.UefiHTTPBootSourceOverrideTarget,
+		HTTPBootURI:               uri,
+	}
+}
+
+// ResetType prefers a forced restart and falls back to the restarts supported by the system.
+func (genericOEM) ResetType(system *redfish.ComputerSystem) redfish.ResetType {
+	preferred := []redfish.ResetType{
+		redfish.ForceRestartResetType,
+		redfish.GracefulRestartResetType,
+		redfish.PowerCycleResetType,
+	}
+	if len(system.SupportedResetTypes) == 0 {
+		return preferred[0]
+	}
+	for _, resetType := range preferred {
+		for _, supported := range system.SupportedResetTypes {
+			if resetType == supported {
+				return resetType
+			}
+		}
+	}
+	return preferred[0]
+}
+
+// SerialConsoleCommand is empty since the specification does not define a command. Managers
+// like OpenBMC serve the serial console on a dedicated SSH port instead.
+func (genericOEM) SerialConsoleCommand() string {
+	return ""
+}
+
+// dellOEM implements the deviations of the Dell iDRAC.
+type dellOEM struct {
+	genericOEM
+}
+
+func (dellOEM) Vendor() Vendor {
+	return VendorDell
+}
+
+// PXEBootOnce omits the boot mode. The iDRAC maps the mode to a BIOS attribute which is
+// only applied by a BIOS configuration job and an additional reboot.
+func (dellOEM) PXEBootOnce(_ *redfish.ComputerSystem) redfish.Boot {
+	return redfish.Boot{
+		BootSourceOverrideEnabled: redfish.OnceBootSourceOverrideEnabled,
+		BootSourceOverrideTarget:  redfish.PxeBootSourceOverrideTarget,
+	}
+}
+
+// HTTPBootOnce omits the boot mode for the same reason as PXEBootOnce.
+func (dellOEM) HTTPBootOnce(_ *redfish.ComputerSystem, uri string) redfish.Boot {
+	return redfish.Boot{
+		BootSourceOverrideEnabled: redfish.OnceBootSourceOverrideEnabled,
+		BootSourceOverrideTarget:  redfish.UefiHTTPBootSourceOverrideTarget,
+		HTTPBootURI:               uri,
+	}
+}
+
+// SerialConsoleCommand attaches to the serial console redirected to the second COM port,
+// which is the default of the iDRAC.
+func (dellOEM) SerialConsoleCommand() string {
+	return "console com2"
+}
+
+// hpeOEM implements the deviations of the HPE iLO.
+type hpeOEM struct {
+	genericOEM
+}
+
+func (hpeOEM) Vendor() Vendor {
+	return VendorHPE
+}
+
+// PXEBootOnce omits the boot mode. The boot mode of the iLO is a BIOS setting, so a mode in
+// the override is only applied after an additional reboot.
+func (hpeOEM) PXEBootOnce(_ *redfish.ComputerSystem) redfish.Boot {
+	return redfish.Boot{
+		BootSourceOverrideEnabled: redfish.OnceBootSourceOverrideEnabled,
+		BootSourceOverrideTarget:  redfish.PxeBootSourceOverrideTarget,
+	}
+}
+
+// HTTPBootOnce omits the boot mode for the same reason as PXEBootOnce.
+func (hpeOEM) HTTPBootOnce(_ *redfish.ComputerSystem, uri string) redfish.Boot {
+	return redfish.Boot{
+		BootSourceOverrideEnabled: redfish.OnceBootSourceOverrideEnabled,
+		BootSourceOverrideTarget:  redfish.UefiHTTPBootSourceOverrideTarget,
+		HTTPBootURI:               uri,
+	}
+}
+
+// SerialConsoleCommand attaches to the virtual serial port of the iLO.
+func (hpeOEM) SerialConsoleCommand() string {
+	return "vsp"
+}
+
+// supermicroOEM implements the deviations of the Supermicro BMC.
+type supermicroOEM struct {
+	genericOEM
+}
+
+func (supermicroOEM) Vendor() Vendor {
+	return VendorSupermicro
+}
+
+// PXEBootOnce keeps the legacy boot mode if the system reports it, since the Supermicro BMC
+// rejects boot overrides switching the mode. UEFI is used otherwise.
+func (supermicroOEM) PXEBootOnce(system *redfish.ComputerSystem) redfish.Boot {
+	mode := system.Boot.BootSourceOverrideMode
+	if mode != redfish.LegacyBootSourceOverrideMode {
+		mode = redfish.UEFIBootSourceOverrideMode
+	}
+	return redfish.Boot{
+		BootSourceOverrideEnabled: redfish.OnceBootSourceOverrideEnabled,
+		BootSourceOverrideMode:    mode,
+		BootSourceOverrideTarget:  redfish.PxeBootSourceOverrideTarget,
+	}
+}
+
+// SerialConsoleCommand starts the serial over LAN session of the SMASH CLI.
+func (supermicroOEM) SerialConsoleCommand() string {
+	return "start /system1/sol1"
+}
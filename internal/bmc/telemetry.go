@@ -0,0 +1,245 @@
+package bmc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/stmcginnis/gofish"
+	"github.com/stmcginnis/gofish/common"
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+type odataLink struct {
+	ODataID string `json:"@odata.id"`
+}
+
+type odataCollection struct {
+	Members []odataLink `json:"Members"`
+}
+
+type sensorReading struct {
+	Reading *float64 `json:"Reading"`
+}
+
+// chassisLinks contains the links of a chassis to the legacy Thermal and Power resources
+// as well as to the ThermalSubsystem, PowerSubsystem and EnvironmentMetrics resources
+// which replace them.
+type chassisLinks struct {
+	Thermal            *odataLink `json:"Thermal"`
+	Power              *odataLink `json:"Power"`
+	ThermalSubsystem   *odataLink `json:"ThermalSubsystem"`
+	PowerSubsystem     *odataLink `json:"PowerSubsystem"`
+	EnvironmentMetrics *odataLink `json:"EnvironmentMetrics"`
+}
+
+func getJSON(client *gofish.APIClient, uri string, v any) error {
+	resp, err := client.Get(uri)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", uri, err)
+	}
+	return nil
+}
+
+// getTelemetry reads the sensors of all chassis containing the system with the given ID.
+func getTelemetry(client *gofish.APIClient, systemID string) (Telemetry, error) {
+	systems, err := client.GetService().Systems()
+	if err != nil {
+		return Telemetry{}, fmt.Errorf("failed to get systems: %w", err)
+	}
+	system := getSystemWithSytemID(systems, systemID)
+	if system == nil {
+		return Telemetry{}, fmt.Errorf("no system found for system ID %s", systemID)
+	}
+	chassis, err := getSystemChassis(client, system)
+	if err != nil {
+		return Telemetry{}, err
+	}
+
+	telemetry := Telemetry{}
+	for _, links := range chassis {
+		if err := getThermal(client, links, &telemetry); err != nil {
+			return Telemetry{}, err
+		}
+		if err := getPower(client, links, &telemetry); err != nil {
+			return Telemetry{}, err
+		}
+	}
+	return telemetry, nil
+}
+
+// getSystemChassis reads the links of all chassis containing the system. The links of the
+// system to its chassis are read from the raw resource, as gofish does not expose them.
+func getSystemChassis(client *gofish.APIClient, system *redfish.ComputerSystem) ([]chassisLinks, error) {
+	var systemLinks struct {
+		Links struct {
+			Chassis []odataLink `json:"Chassis"`
+		} `json:"Links"`
+	}
+	if err := getJSON(client, system.ODataID, &systemLinks); err != nil {
+		return nil, fmt.Errorf("failed to get system: %w", err)
+	}
+
+	result := make([]chassisLinks, 0, len(systemLinks.Links.Chassis))
+	for _, chassisLink := range systemLinks.Links.Chassis {
+		links := chassisLinks{}
+		if err := getJSON(client, chassisLink.ODataID, &links); err != nil {
+			return nil, fmt.Errorf("failed to get chassis: %w", err)
+		}
+		result = append(result, links)
+	}
+	return result, nil
+}
+
+func getThermal(client *gofish.APIClient, links chassisLinks, telemetry *Telemetry) error {
+	switch {
+	case links.Thermal != nil:
+		thermal, err := redfish.GetThermal(client, links.Thermal.ODataID)
+		if err != nil {
+			return fmt.Errorf("failed to get thermal: %w", err)
+		}
+		for _, temperature := range thermal.Temperatures {
+			if temperature.Status.State != "" && temperature.Status.State != common.EnabledState {
+				continue
+			}
+			telemetry.Temperatures = append(telemetry.Temperatures, Temperature{
+				Name:           temperature.Name,
+				ReadingCelsius: float64(temperature.ReadingCelsius),
+			})
+		}
+		for _, fan := range thermal.Fans {
+			if fan.ReadingUnits != "" && fan.ReadingUnits != redfish.RPMReadingUnits {
+				continue
+			}
+			telemetry.Fans = append(telemetry.Fans, Fan{
+				Name:       fan.Name,
+				ReadingRPM: float64(fan.Reading),
+			})
+		}
+	case links.ThermalSubsystem != nil:
+		var subsystem struct {
+			Fans           *odataLink `json:"Fans"`
+			ThermalMetrics *odataLink `json:"ThermalMetrics"`
+		}
+		if err := getJSON(client, links.ThermalSubsystem.ODataID, &subsystem); err != nil {
+			return fmt.Errorf("failed to get thermal subsystem: %w", err)
+		}
+		if subsystem.ThermalMetrics != nil {
+			var metrics struct {
+				TemperatureReadingsCelsius []struct {
+					DeviceName    string   `json:"DeviceName"`
+					DataSourceURI string   `json:"DataSourceUri"`
+					Reading       *float64 `json:"Reading"`
+				} `json:"TemperatureReadingsCelsius"`
+			}
+			if err := getJSON(client, subsystem.ThermalMetrics.ODataID, &metrics); err != nil {
+				return fmt.Errorf("failed to get thermal metrics: %w", err)
+			}
+			for _, reading := range metrics.TemperatureReadingsCelsius {
+				if reading.Reading == nil {
+					continue
+				}
+				name := reading.DeviceName
+				if name == "" {
+					name = reading.DataSourceURI
+				}
+				telemetry.Temperatures = append(telemetry.Temperatures, Temperature{Name: name, ReadingCelsius: *reading.Reading})
+			}
+		}
+		if subsystem.Fans != nil {
+			fans := odataCollection{}
+			if err := getJSON(client, subsystem.Fans.ODataID, &fans); err != nil {
+				return fmt.Errorf("failed to get fans: %w", err)
+			}
+			for _, member := range fans.Members {
+				var fan struct {
+					Name         string `json:"Name"`
+					SpeedPercent *struct {
+						SpeedRPM *float64 `json:"SpeedRPM"`
+					} `json:"SpeedPercent"`
+				}
+				if err := getJSON(client, member.ODataID, &fan); err != nil {
+					return fmt.Errorf("failed to get fan: %w", err)
+				}
+				if fan.SpeedPercent == nil || fan.SpeedPercent.SpeedRPM == nil {
+					continue
+				}
+				telemetry.Fans = append(telemetry.Fans, Fan{Name: fan.Name, ReadingRPM: *fan.SpeedPercent.SpeedRPM})
+			}
+		}
+	}
+	return nil
+}
+
+func getPower(client *gofish.APIClient, links chassisLinks, telemetry *Telemetry) error {
+	switch {
+	case links.Power != nil:
+		power, err := redfish.GetPower(client, links.Power.ODataID)
+		if err != nil {
+			return fmt.Errorf("failed to get power: %w", err)
+		}
+		// the first power control reports the total of the chassis
+		if len(power.PowerControl) > 0 {
+			addPowerConsumed(telemetry, float64(power.PowerControl[0].PowerConsumedWatts))
+		}
+		for _, supply := range power.PowerSupplies {
+			telemetry.PowerSupplies = append(telemetry.PowerSupplies, PowerSupply{
+				Name:   supply.Name,
+				Health: supply.Status.Health,
+				State:  supply.Status.State,
+			})
+		}
+	case links.PowerSubsystem != nil || links.EnvironmentMetrics != nil:
+		if links.EnvironmentMetrics != nil {
+			var metrics struct {
+				PowerWatts *sensorReading `json:"PowerWatts"`
+			}
+			if err := getJSON(client, links.EnvironmentMetrics.ODataID, &metrics); err != nil {
+				return fmt.Errorf("failed to get environment metrics: %w", err)
+			}
+			if metrics.PowerWatts != nil && metrics.PowerWatts.Reading != nil {
+				addPowerConsumed(telemetry, *metrics.PowerWatts.Reading)
+			}
+		}
+		if links.PowerSubsystem != nil {
+			var subsystem struct {
+				PowerSupplies *odataLink `json:"PowerSupplies"`
+			}
+			if err := getJSON(client, links.PowerSubsystem.ODataID, &subsystem); err != nil {
+				return fmt.Errorf("failed to get power subsystem: %w", err)
+			}
+			if subsystem.PowerSupplies == nil {
+				return nil
+			}
+			supplies := odataCollection{}
+			if err := getJSON(client, subsystem.PowerSupplies.ODataID, &supplies); err != nil {
+				return fmt.Errorf("failed to get power supplies: %w", err)
+			}
+			for _, member := range supplies.Members {
+				var supply struct {
+					Name   string        `json:"Name"`
+					Status common.Status `json:"Status"`
+				}
+				if err := getJSON(client, member.ODataID, &supply); err != nil {
+					return fmt.Errorf("failed to get power supply: %w", err)
+				}
+				telemetry.PowerSupplies = append(telemetry.PowerSupplies, PowerSupply{
+					Name:   supply.Name,
+					Health: supply.Status.Health,
+					State:  supply.Status.State,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+func addPowerConsumed(telemetry *Telemetry, watts float64) {
+	if telemetry.PowerConsumedWatts == nil {
+		telemetry.PowerConsumedWatts = new(float64)
+	}
+	*telemetry.PowerConsumedWatts += watts
+}
@@ -0,0 +1,123 @@
+package bmc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stmcginnis/gofish/common"
+)
+
+// newTelemetryServer serves a system contained in a chassis with the given resources.
+func newTelemetryServer(chassis string, resources map[string]string) *httptest.Server {
+	mux := http.NewServeMux()
+	serve := func(path, body string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(body))
+		})
+	}
+	serve("/redfish/v1/", `{"@odata.id":"/redfish/v1/","RedfishVersion":"1.11.0","Systems":{"@odata.id":"/redfish/v1/Systems"}}`)
+	serve("/redfish/v1/Systems", `{"Members":[{"@odata.id":"/redfish/v1/Systems/1"}]}`)
+	serve("/redfish/v1/Systems/1", `{"@odata.id":"/redfish/v1/Systems/1","Id":"1","Links":{"Chassis":[{"@odata.id":"/redfish/v1/Chassis/1"}]}}`)
+	serve("/redfish/v1/Chassis/1", chassis)
+	for path, body := range resources {
+		serve(path, body)
+	}
+	server := httptest.NewServer(mux)
+	DeferCleanup(server.Close)
+	return server
+}
+
+var _ = Describe("Telemetry", func() {
+	getTelemetry := func(ctx context.Context, server *httptest.Server) Telemetry {
+		bmcClient, err := NewRedfishBMC(ctx, "1", v1alpha1.BMCSpec{
+			Type:      v1alpha1.BMCTypeRedfish,
+			Address:   server.URL,
+			BasicAuth: true,
+		}, "admin", "secret", nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer bmcClient.Logout()
+
+		telemetry, err := bmcClient.GetTelemetry()
+		Expect(err).NotTo(HaveOccurred())
+		return telemetry
+	}
+
+	It("should read the legacy Thermal and Power resources", func(ctx SpecContext) {
+		server := newTelemetryServer(`{
+			"@odata.id": "/redfish/v1/Chassis/1",
+			"Thermal": {"@odata.id": "/redfish/v1/Chassis/1/Thermal"},
+			"Power": {"@odata.id": "/redfish/v1/Chassis/1/Power"}
+		}`, map[string]string{
+			"/redfish/v1/Chassis/1/Thermal": `{
+				"@odata.id": "/redfish/v1/Chassis/1/Thermal",
+				"Temperatures": [
+					{"MemberId": "0", "Name": "CPU1 Temp", "ReadingCelsius": 41, "Status": {"State": "Enabled", "Health": "OK"}},
+					{"MemberId": "1", "Name": "CPU2 Temp", "Status": {"State": "Absent"}}
+				],
+				"Fans": [
+					{"MemberId": "0", "Name": "Fan1", "Reading": 5400, "ReadingUnits": "RPM"},
+					{"MemberId": "1", "Name": "Fan2", "Reading": 40, "ReadingUnits": "Percent"}
+				]
+			}`,
+			"/redfish/v1/Chassis/1/Power": `{
+				"@odata.id": "/redfish/v1/Chassis/1/Power",
+				"PowerControl": [{"MemberId": "0", "PowerConsumedWatts": 312}],
+				"PowerSupplies": [
+					{"MemberId": "0", "Name": "PSU1", "Status": {"State": "Enabled", "Health": "OK"}},
+					{"MemberId": "1", "Name": "PSU2", "Status": {"State": "Enabled", "Health": "Critical"}}
+				]
+			}`,
+		})
+
+		telemetry := getTelemetry(ctx, server)
+		Expect(telemetry.Temperatures).To(Equal([]Temperature{{Name: "CPU1 Temp", ReadingCelsius: 41}}))
+		Expect(telemetry.Fans).To(Equal([]Fan{{Name: "Fan1", ReadingRPM: 5400}}))
+		Expect(telemetry.PowerSupplies).To(Equal([]PowerSupply{
+			{Name: "PSU1", Health: common.OKHealth, State: common.EnabledState},
+			{Name: "PSU2", Health: common.CriticalHealth, State: common.EnabledState},
+		}))
+		Expect(telemetry.PowerConsumedWatts).To(HaveValue(BeNumerically("==", 312)))
+	})
+
+	It("should read the ThermalSubsystem, PowerSubsystem and EnvironmentMetrics resources", func(ctx SpecContext) {
+		server := newTelemetryServer(`{
+			"@odata.id": "/redfish/v1/Chassis/1",
+			"ThermalSubsystem": {"@odata.id": "/redfish/v1/Chassis/1/ThermalSubsystem"},
+			"PowerSubsystem": {"@odata.id": "/redfish/v1/Chassis/1/PowerSubsystem"},
+			"EnvironmentMetrics": {"@odata.id": "/redfish/v1/Chassis/1/EnvironmentMetrics"}
+		}`, map[string]string{
+			"/redfish/v1/Chassis/1/ThermalSubsystem": `{
+				"Fans": {"@odata.id": "/redfish/v1/Chassis/1/ThermalSubsystem/Fans"},
+				"ThermalMetrics": {"@odata.id": "/redfish/v1/Chassis/1/ThermalSubsystem/ThermalMetrics"}
+			}`,
+			"/redfish/v1/Chassis/1/ThermalSubsystem/ThermalMetrics": `{
+				"TemperatureReadingsCelsius": [
+					{"DeviceName": "Inlet", "Reading": 22.5},
+					{"DataSourceUri": "/redfish/v1/Chassis/1/Sensors/CPU1Temp", "Reading": 48}
+				]
+			}`,
+			"/redfish/v1/Chassis/1/ThermalSubsystem/Fans":   `{"Members": [{"@odata.id": "/redfish/v1/Chassis/1/ThermalSubsystem/Fans/1"}]}`,
+			"/redfish/v1/Chassis/1/ThermalSubsystem/Fans/1": `{"Name": "Fan1", "SpeedPercent": {"Reading": 45, "SpeedRPM": 6100}}`,
+			"/redfish/v1/Chassis/1/PowerSubsystem": `{
+				"PowerSupplies": {"@odata.id": "/redfish/v1/Chassis/1/PowerSubsystem/PowerSupplies"}
+			}`,
+			"/redfish/v1/Chassis/1/PowerSubsystem/PowerSupplies":   `{"Members": [{"@odata.id": "/redfish/v1/Chassis/1/PowerSubsystem/PowerSupplies/1"}]}`,
+			"/redfish/v1/Chassis/1/PowerSubsystem/PowerSupplies/1": `{"Name": "PSU1", "Status": {"State": "Enabled", "Health": "OK"}}`,
+			"/redfish/v1/Chassis/1/EnvironmentMetrics":             `{"PowerWatts": {"Reading": 287}}`,
+		})
+
+		telemetry := getTelemetry(ctx, server)
+		Expect(telemetry.Temperatures).To(Equal([]Temperature{
+			{Name: "Inlet", ReadingCelsius: 22.5},
+			{Name: "/redfish/v1/Chassis/1/Sensors/CPU1Temp", ReadingCelsius: 48},
+		}))
+		Expect(telemetry.Fans).To(Equal([]Fan{{Name: "Fan1", ReadingRPM: 6100}}))
+		Expect(telemetry.PowerSupplies).To(Equal([]PowerSupply{{Name: "PSU1", Health: common.OKHealth, State: common.EnabledState}}))
+		Expect(telemetry.PowerConsumedWatts).To(HaveValue(BeNumerically("==", 287)))
+	})
+})
@@ -1,6 +1,14 @@
 package bmc
 
-import "github.com/stmcginnis/gofish/redfish"
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+
+	"github.com/stmcginnis/gofish"
+	"github.com/stmcginnis/gofish/redfish"
+)
 
 func getSystemWithSytemID(systems []*redfish.ComputerSystem, id string) *redfish.ComputerSystem {
 	for _, system := range systems {
@@ -10,3 +18,76 @@ func getSystemWithSytemID(systems []*redfish.ComputerSystem, id string) *redfish
 	}
 	return nil
 }
+
+func getSystems(client *gofish.APIClient) ([]System, error) {
+	systems, err := client.GetService().Systems()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get systems: %w", err)
+	}
+
+	result := make([]System, 0, len(systems))
+	for _, system := range systems {
+		result = append(result, System{
+			ID:           system.ID,
+			UUID:         system.UUID,
+			Manufacturer: system.Manufacturer,
+			Model:        system.Model,
+			SerialNumber: system.SerialNumber,
+		})
+	}
+	return result, nil
+}
+
+func getManagerInfo(client *gofish.APIClient) (ManagerInfo, error) {
+	managers, err := client.GetService().Managers()
+	if err != nil {
+		return ManagerInfo{}, fmt.Errorf("failed to get managers: %w", err)
+	}
+	if len(managers) == 0 {
+		return ManagerInfo{}, nil
+	}
+
+	return ManagerInfo{
+		Manufacturer:    managers[0].Manufacturer,
+		Model:           managers[0].Model,
+		FirmwareVersion: managers[0].FirmwareVersion,
+	}, nil
+}
+
+// newHTTPClient creates an HTTP client which verifies the BMC certificate against
+// the given PEM encoded CA bundle.
+func newHTTPClient(caBundle []byte) (*http.Client, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBundle) {
+		return nil, fmt.Errorf("failed to parse CA bundle")
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:    pool,
+				MinVersion: tls.VersionTLS12,
+			},
+		},
+	}, nil
+}
+
+// httpBoot is a boot override using the property name of the HTTP boot URI defined by the Redfish
+// schema, which is serialized as HTTPBootURI by redfish.Boot.
+type httpBoot struct {
+	BootSourceOverrideEnabled redfish.BootSourceOverrideEnabled `json:",omitempty"`
+	BootSourceOverrideMode    redfish.BootSourceOverrideMode    `json:",omitempty"`
+	BootSourceOverrideTarget  redfish.BootSourceOverrideTarget  `json:",omitempty"`
+	HTTPBootURI               string                            `json:"HttpBootUri,omitempty"`
+}
+
+// setHTTPBoot sets the UEFI HTTP boot override of the system.
+func setHTTPBoot(system *redfish.ComputerSystem, boot redfish.Boot) error {
+	return system.Patch(system.ODataID, struct {
+		Boot httpBoot
+	}{Boot: httpBoot{
+		BootSourceOverrideEnabled: boot.BootSourceOverrideEnabled,
+		BootSourceOverrideMode:    boot.BootSourceOverrideMode,
+		BootSourceOverrideTarget:  boot.BootSourceOverrideTarget,
+		HTTPBootURI:               boot.HTTPBootURI,
+	}})
+}
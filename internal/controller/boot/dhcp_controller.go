@@ -18,11 +18,12 @@ package boot
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
 )
@@ -31,6 +32,8 @@ import (
 type DHCPReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// DefaultMode is the mode of the configurations which do not specify one.
+	DefaultMode bootv1alpha1.DHCPMode
 }
 
 //+kubebuilder:rbac:groups=boot.afritzler.github.io,resources=dhcps,verbs=get;list;watch;create;update;patch;delete
@@ -40,15 +43,50 @@ type DHCPReconciler struct {
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *DHCPReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	log := ctrl.LoggerFrom(ctx)
+	dhcp := &bootv1alpha1.DHCP{}
+	if err := r.Get(ctx, req.NamespacedName, dhcp); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
 
-	// TODO(user): your logic here
+	return r.reconcileExists(ctx, log, dhcp)
+}
+
+func (r *DHCPReconciler) reconcileExists(ctx context.Context, log logr.Logger, dhcp *bootv1alpha1.DHCP) (ctrl.Result, error) {
+	if !dhcp.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	return r.reconcile(ctx, log, dhcp)
+}
+
+func (r *DHCPReconciler) reconcile(ctx context.Context, log logr.Logger, dhcp *bootv1alpha1.DHCP) (ctrl.Result, error) {
+	log.V(1).Info("Reconciling DHCP configuration")
+
+	mode := dhcp.Spec.Mode
+	if mode == "" {
+		mode = r.DefaultMode
+	}
+	if mode == "" {
+		mode = bootv1alpha1.DHCPModeServer
+	}
+	if dhcp.Status.Mode != mode {
+		dhcpBase := dhcp.DeepCopy()
+		dhcp.Status.Mode = mode
+		if err := r.Status().Patch(ctx, dhcp, client.MergeFrom(dhcpBase)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to patch DHCP mode: %w", err)
+		}
+		log.V(1).Info("Patched DHCP mode", "Mode", mode)
+	}
 
+	log.V(1).Info("Reconciled DHCP configuration")
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *DHCPReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := registerStateCollector(newDHCPCollector(mgr.GetClient())); err != nil {
+		return fmt.Errorf("failed to register DHCP metrics: %w", err)
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&bootv1alpha1.DHCP{}).
 		Complete(r)
@@ -0,0 +1,70 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boot
+
+import (
+	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("DHCP Controller", func() {
+	var dhcp *bootv1alpha1.DHCP
+
+	BeforeEach(func(ctx SpecContext) {
+		dhcp = &bootv1alpha1.DHCP{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", GenerateName: "dhcp-"},
+			Spec: bootv1alpha1.DHCPSpec{
+				BareMetalHostRef: v1.LocalObjectReference{Name: "host"},
+				MACAddress:       "02:00:00:00:00:01",
+			},
+		}
+		Expect(k8sClient.Create(ctx, dhcp)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, dhcp)
+	})
+
+	reconcileMode := func(ctx SpecContext, defaultMode bootv1alpha1.DHCPMode) bootv1alpha1.DHCPMode {
+		GinkgoHelper()
+		reconciler := &DHCPReconciler{Client: k8sClient, Scheme: scheme.Scheme, DefaultMode: defaultMode}
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(dhcp)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(dhcp), dhcp)).To(Succeed())
+		return dhcp.Status.Mode
+	}
+
+	It("should report the mode of the manager", func(ctx SpecContext) {
+		Expect(reconcileMode(ctx, bootv1alpha1.DHCPModeProxy)).To(Equal(bootv1alpha1.DHCPModeProxy))
+		Expect(reconcileMode(ctx, "")).To(Equal(bootv1alpha1.DHCPModeServer))
+	})
+
+	It("should prefer the mode of the configuration", func(ctx SpecContext) {
+		dhcpBase := dhcp.DeepCopy()
+		dhcp.Spec.Mode = bootv1alpha1.DHCPModeServer
+		Expect(k8sClient.Patch(ctx, dhcp, client.MergeFrom(dhcpBase))).To(Succeed())
+		Expect(reconcileMode(ctx, bootv1alpha1.DHCPModeProxy)).To(Equal(bootv1alpha1.DHCPModeServer))
+
+		By("Rejecting unknown modes")
+		dhcpBase = dhcp.DeepCopy()
+		dhcp.Spec.Mode = "Relay"
+		Expect(k8sClient.Patch(ctx, dhcp, client.MergeFrom(dhcpBase))).NotTo(Succeed())
+	})
+})
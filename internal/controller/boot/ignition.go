@@ -0,0 +1,87 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boot
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
+)
+
+const (
+	// ignitionKey is the key of the ignition in the ignition secret of a claim.
+	ignitionKey = "ignition"
+	// defaultIgnitionVersion is the version of the ignition rendered if the ignition of a claim
+	// has no version, e.g. as it is empty.
+	defaultIgnitionVersion = "3.0.0"
+)
+
+// renderStaticAddresses adds a systemd-networkd configuration per address to the ignition, which
+// configures the address statically on the interface with the MAC address of the address.
+func renderStaticAddresses(ignition []byte, addresses []bootv1alpha1.StaticAddress) ([]byte, error) {
+	config := map[string]interface{}{}
+	if len(bytes.TrimSpace(ignition)) > 0 {
+		if err := json.Unmarshal(ignition, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse ignition: %w", err)
+		}
+	}
+
+	ignitionConfig, ok := config["ignition"].(map[string]interface{})
+	if !ok {
+		ignitionConfig = map[string]interface{}{}
+		config["ignition"] = ignitionConfig
+	}
+	if _, ok := ignitionConfig["version"]; !ok {
+		ignitionConfig["version"] = defaultIgnitionVersion
+	}
+	storage, ok := config["storage"].(map[string]interface{})
+	if !ok {
+		storage = map[string]interface{}{}
+		config["storage"] = storage
+	}
+	files, _ := storage["files"].([]interface{})
+	for _, address := range addresses {
+		files = append(files, map[string]interface{}{
+			"path":      fmt.Sprintf("/etc/systemd/network/10-%s.network", address.Name),
+			"mode":      0644,
+			"overwrite": true,
+			"contents": map[string]interface{}{
+				"source": "data:;base64," + base64.StdEncoding.EncodeToString([]byte(networkUnit(address))),
+			},
+		})
+	}
+	storage["files"] = files
+
+	return json.Marshal(config)
+}
+
+// networkUnit returns the systemd-networkd configuration of the address.
+func networkUnit(address bootv1alpha1.StaticAddress) string {
+	var unit strings.Builder
+	fmt.Fprintf(&unit, "[Match]\nMACAddress=%s\n\n[Network]\nAddress=%s\n", address.MACAddress, address.Address)
+	if address.Gateway != "" {
+		fmt.Fprintf(&unit, "Gateway=%s\n", address.Gateway)
+	}
+	for _, dns := range address.DNSServers {
+		fmt.Fprintf(&unit, "DNS=%s\n", dns)
+	}
+	return unit.String()
+}
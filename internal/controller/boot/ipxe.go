@@ -0,0 +1,102 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boot
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
+	"github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+)
+
+const (
+	// ipxeKey is the key of the iPXE script in the PXE secret.
+	ipxeKey = "ipxe"
+	// ipxeShebang is the first line of every iPXE script.
+	ipxeShebang = "#!ipxe"
+)
+
+// defaultBootTemplate boots the kernel of a boot profile without template.
+const defaultBootTemplate = `#!ipxe
+kernel {{ .Kernel }}{{ with .KernelArgs }} {{ . }}{{ end }}
+{{- range .Initrds }}
+initrd {{ . }}
+{{- end }}
+boot
+`
+
+// BootScriptData are the facts of a host the template of a boot profile is rendered with.
+type BootScriptData struct {
+	SystemUUID string
+	// MACAddress is the boot MAC address of the host.
+	MACAddress string
+	// Architecture is the architecture of the processors in GOARCH notation, e.g. amd64 or arm64.
+	Architecture string
+	// Addresses are the static addresses of the host.
+	Addresses  []bootv1alpha1.StaticAddress
+	Image      string
+	Kernel     string
+	Initrds    []string
+	KernelArgs string
+}
+
+// renderBootScript renders the iPXE script of the boot profile and validates that the result is
+// an iPXE script.
+func renderBootScript(profile *bootv1alpha1.BootProfile, data BootScriptData) ([]byte, error) {
+	text := profile.Spec.Template
+	if text == "" {
+		text = defaultBootTemplate
+	}
+	tmpl, err := template.New(profile.Name).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"join": strings.Join}).
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	var script bytes.Buffer
+	if err := tmpl.Execute(&script, data); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	if !strings.HasPrefix(script.String(), ipxeShebang) {
+		return nil, fmt.Errorf("rendered script does not start with %s", ipxeShebang)
+	}
+	return script.Bytes(), nil
+}
+
+// hostArchitecture returns the architecture of the processors of the host in GOARCH notation.
+func hostArchitecture(host *v1alpha1.BareMetalHost) string {
+	for _, processor := range host.Status.Processors {
+		switch processor.InstructionSet {
+		case "x86-64":
+			return "amd64"
+		case "ARM-A64":
+			return "arm64"
+		case "x86":
+			return "386"
+		case "ARM-A32":
+			return "arm"
+		}
+		if processor.ProcessorArchitecture != "" {
+			return strings.ToLower(processor.ProcessorArchitecture)
+		}
+	}
+	return ""
+}
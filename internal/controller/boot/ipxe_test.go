@@ -0,0 +1,88 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boot
+
+import (
+	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
+	"github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("iPXE", func() {
+	data := BootScriptData{
+		SystemUUID:   "16161616-1616-1616-1616-000000000001",
+		MACAddress:   "02:00:00:00:16:01",
+		Architecture: "arm64",
+		Addresses: []bootv1alpha1.StaticAddress{
+			{Name: "provisioning", MACAddress: "02:00:00:00:16:01", Address: "2001:db8::10/64", Gateway: "2001:db8::1"},
+		},
+		Kernel:     "http://[2001:db8::1]/kernel",
+		Initrds:    []string{"http://[2001:db8::1]/initramfs", "http://[2001:db8::1]/rootfs"},
+		KernelArgs: "console=ttyS1 rd.neednet=1",
+	}
+
+	profile := func(template string) *bootv1alpha1.BootProfile {
+		return &bootv1alpha1.BootProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "profile"},
+			Spec:       bootv1alpha1.BootProfileSpec{Template: template},
+		}
+	}
+
+	It("should render the default template", func() {
+		script, err := renderBootScript(profile(""), data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(script)).To(Equal(`#!ipxe
+kernel http://[2001:db8::1]/kernel console=ttyS1 rd.neednet=1
+initrd http://[2001:db8::1]/initramfs
+initrd http://[2001:db8::1]/rootfs
+boot
+`))
+	})
+
+	It("should render the template with the facts of the host", func() {
+		script, err := renderBootScript(profile(`#!ipxe
+chain http://boot/{{ .Architecture }}/{{ .SystemUUID }}?mac={{ .MACAddress }}{{ range .Addresses }}&{{ .Name }}={{ .Address }}{{ end }}&initrds={{ join .Initrds "," }}
+`), data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(script)).To(Equal(`#!ipxe
+chain http://boot/arm64/16161616-1616-1616-1616-000000000001?mac=02:00:00:00:16:01&provisioning=2001:db8::10/64&initrds=http://[2001:db8::1]/initramfs,http://[2001:db8::1]/rootfs
+`))
+	})
+
+	DescribeTable("should reject invalid templates",
+		func(template, message string) {
+			_, err := renderBootScript(profile(template), data)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("with a syntax error", "#!ipxe\nkernel {{ .Kernel", "failed to parse template"),
+		Entry("with an unknown fact", "#!ipxe\nkernel {{ .Initrd }}", "failed to render template"),
+		Entry("without iPXE shebang", "kernel {{ .Kernel }}", "does not start with #!ipxe"),
+	)
+
+	DescribeTable("should determine the architecture of the host",
+		func(processors []v1alpha1.Processor, architecture string) {
+			host := &v1alpha1.BareMetalHost{Status: v1alpha1.BareMetalHostStatus{Processors: processors}}
+			Expect(hostArchitecture(host)).To(Equal(architecture))
+		},
+		Entry("x86-64", []v1alpha1.Processor{{ProcessorArchitecture: "x86", InstructionSet: "x86-64"}}, "amd64"),
+		Entry("ARM", []v1alpha1.Processor{{ProcessorArchitecture: "ARM", InstructionSet: "ARM-A64"}}, "arm64"),
+		Entry("unknown instruction set", []v1alpha1.Processor{{ProcessorArchitecture: "RISC-V"}}, "risc-v"),
+		Entry("without processors", nil, ""),
+	)
+})
@@ -0,0 +1,112 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boot
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	pxesDesc = prometheus.NewDesc(
+		"baremetal_pxes",
+		"Number of PXE configurations per state.",
+		[]string{"state"}, nil,
+	)
+	dhcpsDesc = prometheus.NewDesc(
+		"baremetal_dhcps",
+		"Number of DHCP configurations per state.",
+		[]string{"state"}, nil,
+	)
+)
+
+// stateCollector counts the objects of a list per state whenever the metrics are scraped.
+type stateCollector struct {
+	desc   *prometheus.Desc
+	client client.Reader
+	list   func() client.ObjectList
+	states func(client.ObjectList) []string
+}
+
+// registerStateCollector registers a collector counting the objects read from the given
+// client per state.
+func registerStateCollector(collector *stateCollector) error {
+	err := metrics.Registry.Register(collector)
+	if are := (prometheus.AlreadyRegisteredError{}); errors.As(err, &are) {
+		return nil
+	}
+	return err
+}
+
+func newPXECollector(c client.Reader) *stateCollector {
+	return &stateCollector{
+		desc:   pxesDesc,
+		client: c,
+		list:   func() client.ObjectList { return &bootv1alpha1.PXEList{} },
+		states: func(list client.ObjectList) []string {
+			var states []string
+			for _, pxe := range list.(*bootv1alpha1.PXEList).Items {
+				states = append(states, string(pxe.Status.State))
+			}
+			return states
+		},
+	}
+}
+
+func newDHCPCollector(c client.Reader) *stateCollector {
+	return &stateCollector{
+		desc:   dhcpsDesc,
+		client: c,
+		list:   func() client.ObjectList { return &bootv1alpha1.DHCPList{} },
+		states: func(list client.ObjectList) []string {
+			var states []string
+			for _, dhcp := range list.(*bootv1alpha1.DHCPList).Items {
+				states = append(states, string(dhcp.Status.State))
+			}
+			return states
+		},
+	}
+}
+
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list := c.list()
+	if err := c.client.List(ctx, list); err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+
+	counts := map[string]int{}
+	for _, state := range c.states(list) {
+		counts[state]++
+	}
+	for state, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), state)
+	}
+}
@@ -19,16 +19,21 @@ package boot
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
 	"github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
 	"github.com/go-logr/logr"
 	"github.com/onmetal/controller-utils/clientutils"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 var (
@@ -45,6 +50,7 @@ type PXEReconciler struct {
 //+kubebuilder:rbac:groups=boot.afritzler.github.io,resources=pxes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=boot.afritzler.github.io,resources=pxes/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=boot.afritzler.github.io,resources=pxes/finalizers,verbs=update
+//+kubebuilder:rbac:groups=boot.afritzler.github.io,resources=bootprofiles,verbs=get;list;watch
 //+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhostclaims,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhostclaims/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhosts,verbs=get;list;watch;create;update;patch;delete
@@ -77,7 +83,7 @@ func (r *PXEReconciler) delete(ctx context.Context, _ logr.Logger, pxeConfig *bo
 			Name:      fmt.Sprintf("ipxe-%s", pxeConfig.Spec.SystemUUID),
 		},
 	}
-	if err := r.Delete(ctx, pxeSecret); err != nil {
+	if err := r.Delete(ctx, pxeSecret); client.IgnoreNotFound(err) != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to remove PXE secret: %w", err)
 	}
 
@@ -96,19 +102,56 @@ func (r *PXEReconciler) reconcile(ctx context.Context, log logr.Logger, pxeConfi
 		return ctrl.Result{}, err
 	}
 
+	if pxeConfig.Spec.BareMetalHostClaimRef.Name == "" || (pxeConfig.Spec.IgnitionRef == nil && pxeConfig.Spec.BootProfileRef == nil) {
+		// nothing to do as there is neither an ignition nor a boot profile
+		return ctrl.Result{}, nil
+	}
 	hostClaim := &v1alpha1.BareMetalHostClaim{}
-	ignitionSecret := &v1.Secret{}
-	if pxeConfig.Spec.BareMetalHostClaimRef.Name != "" {
-		if err := r.Get(ctx, client.ObjectKey{Namespace: pxeConfig.Namespace, Name: pxeConfig.Spec.BareMetalHostClaimRef.Name}, hostClaim); err != nil {
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pxeConfig.Namespace, Name: pxeConfig.Spec.BareMetalHostClaimRef.Name}, hostClaim); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	data := map[string][]byte{}
+	if pxeConfig.Spec.IgnitionRef != nil {
+		ignitionSecret := &v1.Secret{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: pxeConfig.Namespace, Name: pxeConfig.Spec.IgnitionRef.Name}, ignitionSecret); err != nil {
 			return ctrl.Result{}, err
 		}
+		for key, value := range ignitionSecret.Data {
+			data[key] = value
+		}
+		if len(pxeConfig.Spec.Addresses) > 0 {
+			log.V(1).Info("Rendering static addresses into ignition", "Addresses", len(pxeConfig.Spec.Addresses))
+			ignition, err := renderStaticAddresses(ignitionSecret.Data[ignitionKey], pxeConfig.Spec.Addresses)
+			if err != nil {
+				// the configuration is rendered again once the claim changes the ignition
+				log.Error(err, "Failed to render static addresses into ignition")
+				pxeConfigBase := pxeConfig.DeepCopy()
+				pxeConfig.Status.State = bootv1alpha1.PXEStateFailed
+				return ctrl.Result{}, r.Status().Patch(ctx, pxeConfig, client.MergeFrom(pxeConfigBase))
+			}
+			data[ignitionKey] = ignition
+		}
+	}
 
-		if err := r.Get(ctx, client.ObjectKey{Namespace: pxeConfig.Namespace, Name: pxeConfig.Spec.IgnitionRef.Name}, ignitionSecret); err != nil {
+	var condition *metav1.Condition
+	if pxeConfig.Spec.BootProfileRef != nil {
+		log.V(1).Info("Rendering iPXE script", "BootProfile", pxeConfig.Spec.BootProfileRef.Name)
+		script, scriptCondition, err := r.renderBootScript(ctx, pxeConfig, hostClaim)
+		if err != nil {
 			return ctrl.Result{}, err
 		}
-	} else {
-		// nothing to do as there is not Igniton
-		return ctrl.Result{}, nil
+		condition = &scriptCondition
+		if script == nil {
+			// the script is rendered again once the boot profile changes
+			log.V(1).Info("Failed to render iPXE script", "Reason", condition.Reason, "Message", condition.Message)
+			pxeConfigBase := pxeConfig.DeepCopy()
+			pxeConfig.Status.State = bootv1alpha1.PXEStateFailed
+			meta.SetStatusCondition(&pxeConfig.Status.Conditions, *condition)
+			return ctrl.Result{}, r.Status().Patch(ctx, pxeConfig, client.MergeFrom(pxeConfigBase))
+		}
+		data[ipxeKey] = script
+		log.V(1).Info("Rendered iPXE script")
 	}
 
 	pxeSecret := &v1.Secret{
@@ -120,7 +163,7 @@ func (r *PXEReconciler) reconcile(ctx context.Context, log logr.Logger, pxeConfi
 			Namespace: r.PXEServiceNamespace,
 			Name:      fmt.Sprintf("ipxe-%s", pxeConfig.Spec.SystemUUID),
 		},
-		Data: ignitionSecret.Data,
+		Data: data,
 	}
 
 	if err := r.Patch(ctx, pxeSecret, client.Apply, pxeConfigFieldOwner); err != nil {
@@ -129,6 +172,12 @@ func (r *PXEReconciler) reconcile(ctx context.Context, log logr.Logger, pxeConfi
 
 	pxeConfigBase := pxeConfig.DeepCopy()
 	pxeConfig.Status.State = bootv1alpha1.PXEStateReady
+	pxeConfig.Status.ObservedGeneration = pxeConfig.Generation
+	if condition != nil {
+		meta.SetStatusCondition(&pxeConfig.Status.Conditions, *condition)
+	} else {
+		meta.RemoveStatusCondition(&pxeConfig.Status.Conditions, bootv1alpha1.PXEConditionBootScriptValid)
+	}
 	if err := r.Status().Patch(ctx, pxeConfig, client.MergeFrom(pxeConfigBase)); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -136,9 +185,80 @@ func (r *PXEReconciler) reconcile(ctx context.Context, log logr.Logger, pxeConfi
 	return ctrl.Result{}, nil
 }
 
+// renderBootScript renders the iPXE script of the boot profile of the configuration with the facts
+// of the claimed host. The returned condition describes why the script is nil.
+func (r *PXEReconciler) renderBootScript(ctx context.Context, pxeConfig *bootv1alpha1.PXE, hostClaim *v1alpha1.BareMetalHostClaim) ([]byte, metav1.Condition, error) {
+	condition := metav1.Condition{
+		Type:               bootv1alpha1.PXEConditionBootScriptValid,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: pxeConfig.Generation,
+	}
+
+	profile := &bootv1alpha1.BootProfile{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: pxeConfig.Namespace, Name: pxeConfig.Spec.BootProfileRef.Name}, profile); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, condition, fmt.Errorf("failed to get boot profile: %w", err)
+		}
+		condition.Reason = bootv1alpha1.PXEReasonBootProfileNotFound
+		condition.Message = fmt.Sprintf("Boot profile %s not found.", pxeConfig.Spec.BootProfileRef.Name)
+		return nil, condition, nil
+	}
+
+	host := &v1alpha1.BareMetalHost{}
+	if err := r.Get(ctx, client.ObjectKey{Name: hostClaim.Spec.BareMetalHostRef.Name}, host); client.IgnoreNotFound(err) != nil {
+		return nil, condition, fmt.Errorf("failed to get host: %w", err)
+	}
+
+	script, err := renderBootScript(profile, BootScriptData{
+		SystemUUID:   pxeConfig.Spec.SystemUUID,
+		MACAddress:   host.Spec.BootMACAddress,
+		Architecture: hostArchitecture(host),
+		Addresses:    pxeConfig.Spec.Addresses,
+		Image:        pxeConfig.Spec.Image,
+		Kernel:       profile.Spec.Kernel,
+		Initrds:      profile.Spec.Initrds,
+		KernelArgs:   strings.Join(append(append([]string{}, profile.Spec.KernelArgs...), pxeConfig.Spec.KernelArgs...), " "),
+	})
+	if err != nil {
+		condition.Reason = bootv1alpha1.PXEReasonTemplateInvalid
+		condition.Message = fmt.Sprintf("Boot profile %s is invalid: %v", profile.Name, err)
+		return nil, condition, nil
+	}
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = bootv1alpha1.PXEReasonBootScriptRendered
+	condition.Message = fmt.Sprintf("The iPXE script was rendered from boot profile %s.", profile.Name)
+	return script, condition, nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *PXEReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := registerStateCollector(newPXECollector(mgr.GetClient())); err != nil {
+		return fmt.Errorf("failed to register PXE metrics: %w", err)
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&bootv1alpha1.PXE{}).
+		Watches(&bootv1alpha1.BootProfile{}, r.enqueuePXEsByBootProfile()).
 		Complete(r)
 }
+
+// enqueuePXEsByBootProfile enqueues the configurations referencing a boot profile, so that their
+// iPXE scripts are rendered again once the profile changes.
+func (r *PXEReconciler) enqueuePXEsByBootProfile() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, object client.Object) []reconcile.Request {
+		log := ctrl.LoggerFrom(ctx)
+
+		pxeList := &bootv1alpha1.PXEList{}
+		if err := r.List(ctx, pxeList, client.InNamespace(object.GetNamespace())); err != nil {
+			log.Error(err, "failed to list PXE configurations")
+			return nil
+		}
+		var req []reconcile.Request
+		for _, pxeConfig := range pxeList.Items {
+			if pxeConfig.Spec.BootProfileRef != nil && pxeConfig.Spec.BootProfileRef.Name == object.GetName() {
+				req = append(req, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&pxeConfig)})
+			}
+		}
+
+		return req
+	})
+}
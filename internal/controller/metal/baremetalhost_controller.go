@@ -18,29 +18,46 @@ package metal
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"time"
 
 	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
 	"github.com/afritzler/baremetal-operator/internal/bmc"
 	"github.com/go-logr/logr"
 	"github.com/stmcginnis/gofish/redfish"
-	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// maxHostHistory is the number of power actions and boot overrides kept in the host status.
+const maxHostHistory = 10
+
 // BareMetalHostReconciler reconciles a BareMetalHost object
 type BareMetalHostReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// FakeBMCs keeps the state of the BMCs of type Fake.
+	FakeBMCs *bmc.FakeBMCs
+	// BootInterfacePattern restricts the network interfaces the boot MAC address of a host is
+	// detected from to the ones whose ID or name matches.
+	BootInterfacePattern *regexp.Regexp
 }
 
 //+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhosts,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhosts/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhosts/finalizers,verbs=update
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=bmcs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhostclaims,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -83,9 +100,23 @@ func (r *BareMetalHostReconciler) reconcile(ctx context.Context, log logr.Logger
 	}
 	log.V(1).Info("Updated host status from system information")
 
+	if err := r.ensureBootMACAddress(ctx, log, host); err != nil {
+		return err
+	}
+
+	if err := r.ensureProvisioningGeneration(ctx, log, bmcClient, host); err != nil {
+		return err
+	}
+
 	log.V(1).Info("Ensuring host power state")
 	if err := r.ensurePowerState(ctx, log, bmcClient, host); err != nil {
-		return err
+		if len(host.Spec.Taints) == 0 {
+			return err
+		}
+		// a tainted host is taken out of service even if it does not power off, e.g. after
+		// its release has been forced
+		_, _, statusErr := r.ensureHostStatus(ctx, log, host)
+		return errors.Join(err, statusErr)
 	}
 	log.V(1).Info("Ensured host power state")
 
@@ -103,17 +134,21 @@ func (r *BareMetalHostReconciler) reconcile(ctx context.Context, log logr.Logger
 func (r *BareMetalHostReconciler) ensurePowerState(ctx context.Context, log logr.Logger, bmcClient bmc.BMC, host *metalv1alpha1.BareMetalHost) error {
 	// TODO: this needs to go into the actual state machine
 	if host.Status.State == metalv1alpha1.StateInitial {
-		log.V(1).Info("Setting PXE boot once for the next start")
-		if err := bmcClient.SetPXEBootOnce(host.Spec.SystemID); err != nil {
-			return fmt.Errorf("failed to set boot PXE once boot order for host: %w", err)
+		log.V(1).Info("Setting network boot once for the next start")
+		if err := r.setNetworkBootOnce(ctx, log, bmcClient, host); err != nil {
+			return err
 		}
 	}
 
 	if host.Spec.Power == metalv1alpha1.PowerStateOn && host.Status.PowerState == redfish.OffPowerState {
 		log.V(1).Info("Powering on host")
+		start := time.Now()
 		if err := bmcClient.PowerOn(); err != nil {
 			return fmt.Errorf("failed to change power state to %s: %w", metalv1alpha1.PowerStateOn, err)
 		}
+		if err := r.recordPowerAction(ctx, host, metalv1alpha1.PowerStateOn); err != nil {
+			return err
+		}
 		// TODO: make the timeout configurable via flag
 		if err := wait.PollUntilContextTimeout(ctx, 5*time.Second, 20*time.Second, true, func(ctx context.Context) (done bool, err error) {
 			sysInfo, err := bmcClient.GetSystemInfo()
@@ -127,14 +162,19 @@ func (r *BareMetalHostReconciler) ensurePowerState(ctx context.Context, log logr
 		}); err != nil {
 			return fmt.Errorf("failed to wait for power on host condition: %w", err)
 		}
+		powerTransitionDuration.WithLabelValues(string(metalv1alpha1.PowerStateOn)).Observe(time.Since(start).Seconds())
 		log.V(1).Info("Powered on host")
 	}
 
 	if host.Spec.Power == metalv1alpha1.PowerStateOff && host.Status.PowerState == redfish.OnPowerState {
 		log.V(1).Info("Powering off host")
+		start := time.Now()
 		if err := bmcClient.PowerOff(); err != nil {
 			return fmt.Errorf("failed to change power state to %s: %w", metalv1alpha1.PowerStateOff, err)
 		}
+		if err := r.recordPowerAction(ctx, host, metalv1alpha1.PowerStateOff); err != nil {
+			return err
+		}
 		// TODO: make the timeout configurable via flag
 		if err := wait.PollUntilContextTimeout(ctx, 5*time.Second, 20*time.Second, true, func(ctx context.Context) (done bool, err error) {
 			sysInfo, err := bmcClient.GetSystemInfo()
@@ -148,46 +188,91 @@ func (r *BareMetalHostReconciler) ensurePowerState(ctx context.Context, log logr
 		}); err != nil {
 			return fmt.Errorf("failed to wait for power off host condition: %w", err)
 		}
+		powerTransitionDuration.WithLabelValues(string(metalv1alpha1.PowerStateOff)).Observe(time.Since(start).Seconds())
 		log.V(1).Info("Powered off host")
 	}
 
 	return nil
 }
 
-func (r *BareMetalHostReconciler) createBMCClient(ctx context.Context, host *metalv1alpha1.BareMetalHost) (bmc.BMC, error) {
-	var err error
-	var bmcClient bmc.BMC
-
-	switch host.Spec.BMC.Type {
-	case metalv1alpha1.BMCTypeRedfishLocal:
-		bmcClient, err = bmc.NewRedfishLocalBMC(ctx, host.Spec.SystemID, host.Spec.BMC.Address)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create redfish local client: %w", err)
-		}
-	case metalv1alpha1.BMCTypeRedfish:
-		bmcSecret := &v1.Secret{}
-		if err := r.Get(ctx, client.ObjectKey{Namespace: host.Spec.BMC.SecretRef.Namespace, Name: host.Spec.BMC.SecretRef.Name}, bmcSecret); err != nil {
-			return nil, fmt.Errorf("failed to get BMC access secret for host: %w", err)
-		}
-		username, ok := bmcSecret.Data["username"]
-		if !ok {
-			return nil, fmt.Errorf("no username provided in BMC access secret")
-		}
-		password, ok := bmcSecret.Data["password"]
-		if !ok {
-			return nil, fmt.Errorf("no password provided in BMC access secret")
+// ensureProvisioningGeneration boots the host from the network once more if its provisioning
+// generation changed. Hosts which are powered on are reset, hosts which are powered off boot from
+// the network once they are powered on. Resetting the generation to zero releases the host without a reboot.
+func (r *BareMetalHostReconciler) ensureProvisioningGeneration(ctx context.Context, log logr.Logger, bmcClient bmc.BMC, host *metalv1alpha1.BareMetalHost) error {
+	if host.Spec.ProvisioningGeneration == host.Status.ObservedProvisioningGeneration {
+		return nil
+	}
+	if host.Spec.ProvisioningGeneration != 0 {
+		log.V(1).Info("Provisioning host again", "ProvisioningGeneration", host.Spec.ProvisioningGeneration)
+		if err := r.setNetworkBootOnce(ctx, log, bmcClient, host); err != nil {
+			return err
 		}
-		bmcClient, err = bmc.NewRedfishBMC(ctx, host.Spec.SystemID, host.Spec.BMC, string(username), string(password))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create redfish client: %w", err)
+		if host.Status.PowerState == redfish.OnPowerState {
+			log.V(1).Info("Resetting host")
+			if err := bmcClient.Reset(); err != nil {
+				return fmt.Errorf("failed to reset host: %w", err)
+			}
 		}
-	default:
-		return nil, fmt.Errorf("BMC type %s is not supported", host.Spec.BMC.Type)
 	}
-	return bmcClient, nil
+
+	hostBase := host.DeepCopy()
+	host.Status.ObservedProvisioningGeneration = host.Spec.ProvisioningGeneration
+	if err := r.Status().Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
+		return fmt.Errorf("failed to patch observed provisioning generation: %w", err)
+	}
+	log.V(1).Info("Observed provisioning generation", "ProvisioningGeneration", host.Spec.ProvisioningGeneration)
+	return nil
+}
+
+// recordPowerAction adds the power action to the history in the host status.
+func (r *BareMetalHostReconciler) recordPowerAction(ctx context.Context, host *metalv1alpha1.BareMetalHost, action metalv1alpha1.PowerState) error {
+	hostBase := host.DeepCopy()
+	host.Status.PowerActions = append(host.Status.PowerActions, metalv1alpha1.PowerAction{
+		Action: action,
+		Time:   metav1.Now(),
+	})
+	if len(host.Status.PowerActions) > maxHostHistory {
+		host.Status.PowerActions = host.Status.PowerActions[len(host.Status.PowerActions)-maxHostHistory:]
+	}
+	if err := r.Status().Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
+		return fmt.Errorf("failed to record power action: %w", err)
+	}
+	return nil
+}
+
+// recordBootOverride adds the boot override to the history in the host status.
+func (r *BareMetalHostReconciler) recordBootOverride(ctx context.Context, host *metalv1alpha1.BareMetalHost, enabled redfish.BootSourceOverrideEnabled, target redfish.BootSourceOverrideTarget, uri string) error {
+	hostBase := host.DeepCopy()
+	host.Status.BootOverrides = append(host.Status.BootOverrides, metalv1alpha1.BootOverride{
+		Enabled: enabled,
+		Target:  target,
+		URI:     uri,
+		Time:    metav1.Now(),
+	})
+	if len(host.Status.BootOverrides) > maxHostHistory {
+		host.Status.BootOverrides = host.Status.BootOverrides[len(host.Status.BootOverrides)-maxHostHistory:]
+	}
+	if err := r.Status().Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
+		return fmt.Errorf("failed to record boot override: %w", err)
+	}
+	return nil
+}
+
+func (r *BareMetalHostReconciler) createBMCClient(ctx context.Context, host *metalv1alpha1.BareMetalHost) (bmc.BMC, error) {
+	bmcObj := &metalv1alpha1.BMC{}
+	if err := r.Get(ctx, client.ObjectKey{Name: host.Spec.BMCRef.Name}, bmcObj); err != nil {
+		return nil, fmt.Errorf("failed to get BMC %s for host: %w", host.Spec.BMCRef.Name, err)
+	}
+	return newBMCClient(ctx, r.Client, r.FakeBMCs, bmcObj, host)
 }
 
 func (r *BareMetalHostReconciler) determineTargetHostStatus(host *metalv1alpha1.BareMetalHost) metalv1alpha1.HostState {
+	if host.Spec.Maintenance {
+		return metalv1alpha1.StateMaintenance
+	}
+	if len(host.Spec.Taints) > 0 {
+		return metalv1alpha1.StateTainted
+	}
 	switch host.Status.State {
 	case metalv1alpha1.StateInitial:
 		if r.isHostReady(host) {
@@ -197,7 +282,7 @@ func (r *BareMetalHostReconciler) determineTargetHostStatus(host *metalv1alpha1.
 	case metalv1alpha1.StateAvailable:
 		return metalv1alpha1.StateAvailable
 	case metalv1alpha1.StateTainted:
-		// TODO: this has to be set later by the controller which is responsible for sanitizing the host
+		// the taints have been removed, so the host is initialized again
 		return metalv1alpha1.StateInitial
 	}
 	return metalv1alpha1.StateInitial
@@ -227,7 +312,9 @@ func (r *BareMetalHostReconciler) ensureHostStatus(ctx context.Context, log logr
 
 	if host.Spec.ClaimRef != nil {
 		host.Status.Phase = metalv1alpha1.PhaseBound
-		host.Status.State = metalv1alpha1.StateReserved
+		if targetHostState != metalv1alpha1.StateMaintenance && targetHostState != metalv1alpha1.StateTainted {
+			host.Status.State = metalv1alpha1.StateReserved
+		}
 	}
 
 	if host.Spec.ClaimRef == nil {
@@ -317,8 +404,10 @@ func updateHostNICsFromSystemInfo(info bmc.SystemInfo, host *metalv1alpha1.BareM
 				// Update existing NIC
 				host.Status.NetworkInterfaces[i] = metalv1alpha1.NetworkInterface{
 					ID:                  newNic.ID,
+					Name:                newNic.Name,
 					MACAddress:          newNic.MACAddress,
 					PermanentMACAddress: newNic.PermanentMACAddress,
+					PXEBoot:             newNic.PXEBoot,
 				}
 				updated = true
 				break
@@ -328,8 +417,10 @@ func updateHostNICsFromSystemInfo(info bmc.SystemInfo, host *metalv1alpha1.BareM
 		if !updated {
 			host.Status.NetworkInterfaces = append(host.Status.NetworkInterfaces, metalv1alpha1.NetworkInterface{
 				ID:                  newNic.ID,
+				Name:                newNic.Name,
 				MACAddress:          newNic.MACAddress,
 				PermanentMACAddress: newNic.PermanentMACAddress,
+				PXEBoot:             newNic.PXEBoot,
 			})
 		}
 	}
@@ -353,7 +444,32 @@ func (r *BareMetalHostReconciler) initializeHost(ctx context.Context, log logr.L
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *BareMetalHostReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := registerHostCollector(mgr.GetClient()); err != nil {
+		return fmt.Errorf("failed to register host metrics: %w", err)
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&metalv1alpha1.BareMetalHost{}).
+		// status changes of claims do not affect their hosts
+		Watches(&metalv1alpha1.BareMetalHostClaim{}, r.enqueueBareMetalHostsByClaim(), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
+
+// enqueueBareMetalHostsByClaim enqueues the hosts claimed by a claim, so that the phase of the
+// hosts follows their claims. Claims competing for a host do not affect it.
+func (r *BareMetalHostReconciler) enqueueBareMetalHostsByClaim() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, object client.Object) []reconcile.Request {
+		log := ctrl.LoggerFrom(ctx)
+
+		hosts, err := listClaimedHosts(ctx, r.Client, object.(*metalv1alpha1.BareMetalHostClaim))
+		if err != nil {
+			log.Error(err, "failed to list claimed hosts")
+			return nil
+		}
+		var req []reconcile.Request
+		for _, host := range hosts {
+			req = append(req, reconcile.Request{NamespacedName: types.NamespacedName{Name: host.Name}})
+		}
+
+		return req
+	})
+}
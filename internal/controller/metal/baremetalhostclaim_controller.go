@@ -18,23 +18,45 @@ package metal
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
 	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
 	"github.com/go-logr/logr"
 	"github.com/onmetal/controller-utils/clientutils"
+	"github.com/stmcginnis/gofish/redfish"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+const (
+	// deprovisionRetryInterval is the interval in which the deprovisioning of a deleted claim
+	// is checked and retried.
+	deprovisionRetryInterval = 30 * time.Second
+	// deprovisionTimeout is the duration after which a pending deprovisioning step is
+	// skipped and the host is released with the DeprovisioningFailed taint.
+	deprovisionTimeout = 10 * time.Minute
+)
+
 var (
 	bareMetalClaimFieldOwner = client.FieldOwner("metal.afritzler.github.io/hostclaim-controller")
 )
@@ -43,6 +65,17 @@ var (
 type BareMetalHostClaimReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// ConsoleRecorder records the console of the hosts during provisioning if set.
+	ConsoleRecorder *ConsoleRecorder
+	// Recorder records the preemption and the lease expiry of claims.
+	Recorder record.EventRecorder
+	// PreemptionGracePeriod is the duration after which a preempted claim is unbound.
+	PreemptionGracePeriod time.Duration
+	// LeaseWarningPeriod is the period before the expiry of a lease in which the claim is warned.
+	LeaseWarningPeriod time.Duration
+	// BootServerURL is the URL of the boot server serving the iPXE scripts of the hosts. The boot
+	// file URL of the DHCP configurations is left empty if unset.
+	BootServerURL *url.URL
 }
 
 //+kubebuilder:rbac:groups=core.afritzler.github.io,resources=baremetalhostclaims,verbs=get;list;watch;create;update;patch;delete
@@ -57,6 +90,10 @@ type BareMetalHostClaimReconciler struct {
 //+kubebuilder:rbac:groups=boot.afritzler.github.io,resources=dhcps,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=boot.afritzler.github.io,resources=dhcps/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=boot.afritzler.github.io,resources=dhcps/finalizers,verbs=update
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhostpools,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=ipaddressclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -74,43 +111,196 @@ func (r *BareMetalHostClaimReconciler) reconcileExists(ctx context.Context, log
 	if !claim.DeletionTimestamp.IsZero() {
 		return r.delete(ctx, log, claim)
 	}
-	return r.reconcile(ctx, log, claim)
+	held, preemptionResult, err := r.ensurePreemption(ctx, log, claim)
+	if err != nil || held {
+		return preemptionResult, err
+	}
+	result, err := r.reconcile(ctx, log, claim)
+	if deadline := preemptionResult.RequeueAfter; err == nil && deadline > 0 && (result.RequeueAfter == 0 || result.RequeueAfter > deadline) {
+		result.RequeueAfter = deadline
+	}
+	return result, err
 }
 
 func (r *BareMetalHostClaimReconciler) delete(ctx context.Context, log logr.Logger, claim *metalv1alpha1.BareMetalHostClaim) (ctrl.Result, error) {
 	log.V(1).Info("Deleting host claim")
+	if r.ConsoleRecorder != nil {
+		r.ConsoleRecorder.Stop(claim)
+	}
+	if claim.Spec.BareMetalHostRef.Name == "" {
+		log.V(1).Info("Removing finalizer of unbound host claim")
+		if _, err := clientutils.PatchEnsureNoFinalizer(ctx, r.Client, claim, metalv1alpha1.BareMetalHostClaimFinalizer); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.V(1).Info("Deleted host claim")
+		return ctrl.Result{}, nil
+	}
+
+	if released, result, err := r.releaseHost(ctx, log, claim, claim.DeletionTimestamp.Time); err != nil || !released {
+		return result, err
+	}
+	if _, err := clientutils.PatchEnsureNoFinalizer(ctx, r.Client, claim, metalv1alpha1.BareMetalHostClaimFinalizer); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.V(1).Info("Deleted host claim")
+
+	return ctrl.Result{}, nil
+}
+
+// releaseHost tears down the host of the claim and removes the claimRef of the host. It reports
+// whether the host has been released, otherwise the returned result retries the pending step.
+// Pending steps are skipped once the release is forced or the deprovisioning timeout passed
+// since the given time.
+func (r *BareMetalHostClaimReconciler) releaseHost(ctx context.Context, log logr.Logger, claim *metalv1alpha1.BareMetalHostClaim, since time.Time) (bool, ctrl.Result, error) {
 	host := &metalv1alpha1.BareMetalHost{}
 	if err := r.Get(ctx, types.NamespacedName{Name: claim.Spec.BareMetalHostRef.Name}, host); err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to get host for claim: %w", err)
+		if !apierrors.IsNotFound(err) {
+			return r.deprovisioningFailed(ctx, log, claim, fmt.Errorf("failed to get host for claim: %w", err))
+		}
+		host = nil
+	}
+	if host != nil && host.Spec.ClaimRef == nil {
+		// the host has not been claimed yet, only the finalizer might have been added
+		log.V(1).Info("Removing finalizer on unclaimed host", "Host", host.Name)
+		if _, err := clientutils.PatchEnsureNoFinalizer(ctx, r.Client, host, metalv1alpha1.BareMetalHostClaimFinalizer); err != nil {
+			return r.deprovisioningFailed(ctx, log, claim, fmt.Errorf("failed to remove finalizer from host: %w", err))
+		}
+		host = nil
+	}
+	// hosts claimed by somebody else are left untouched
+	if host != nil && host.Spec.ClaimRef.UID != claim.UID {
+		host = nil
 	}
 
-	log.V(1).Info("Removing claimRef on host", "Host", host.Name)
-	hostBase := host.DeepCopy()
-	host.Spec.ClaimRef = nil
-	if err := r.Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to remove claimRef from host: %w", err)
+	// a pending step is skipped once it is forced or timed out, the host is then released
+	// with a taint naming the skipped step
+	forced := claim.Annotations[metalv1alpha1.ForceReleaseAnnotation] == "true" || time.Since(since) > deprovisionTimeout
+	var skipped []string
+
+	if host != nil {
+		if host.Spec.Power != metalv1alpha1.PowerStateOff {
+			log.V(1).Info("Powering off host", "Host", host.Name)
+			hostBase := host.DeepCopy()
+			host.Spec.Power = metalv1alpha1.PowerStateOff
+			if err := r.Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
+				return r.deprovisioningFailed(ctx, log, claim, fmt.Errorf("failed to power off host %s: %w", host.Name, err))
+			}
+		}
+		if host.Status.PowerState != redfish.OffPowerState {
+			if !forced {
+				log.V(1).Info("Waiting for host to power off", "Host", host.Name, "PowerState", host.Status.PowerState)
+				// the claim is reconciled again once the power state of the host changes
+				return false, ctrl.Result{RequeueAfter: deprovisionRetryInterval}, r.patchDeprovisioningStatus(ctx, claim,
+					metalv1alpha1.BareMetalHostClaimReasonPoweringOff, fmt.Sprintf("Waiting for host %s to power off", host.Name))
+			}
+			skipped = append(skipped, fmt.Sprintf("host did not power off, its power state is %q", host.Status.PowerState))
+		}
 	}
-	log.V(1).Info("Removed claimRef on host", "Host", host.Name)
 
-	log.V(1).Info("Patching host status", "Host", host.Name, "HostState", host.Status.State)
-	host.Status.State = metalv1alpha1.StateTainted
-	if err := r.Status().Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to patch host status: %w", err)
+	log.V(1).Info("Removing boot configuration")
+	removed, err := r.removeBootConfiguration(ctx, claim)
+	if err != nil {
+		return r.deprovisioningFailed(ctx, log, claim, err)
+	}
+	if !removed {
+		if !forced {
+			log.V(1).Info("Waiting for boot configuration to be removed")
+			// the claim is reconciled again once the owned configurations are gone
+			return false, ctrl.Result{RequeueAfter: deprovisionRetryInterval}, r.patchDeprovisioningStatus(ctx, claim,
+				metalv1alpha1.BareMetalHostClaimReasonRemovingBootConfig, "Waiting for the PXE configuration and the DHCP lease to be removed")
+		}
+		skipped = append(skipped, "boot configuration has not been removed")
+	} else {
+		log.V(1).Info("Removed boot configuration")
 	}
-	log.V(1).Info("Patched host status", "Host", host.Name, "HostState", host.Status.State)
 
-	log.V(1).Info("Removing finalizer on host", "Host", host.Name)
-	if _, err := clientutils.PatchEnsureNoFinalizer(ctx, r.Client, host, metalv1alpha1.BareMetalHostClaimFinalizer); err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to remove finalizer from host: %w", err)
+	// the addresses are only released once the DHCP lease of the host has been withdrawn or
+	// the host is released with a taint
+	log.V(1).Info("Releasing IP addresses")
+	if err := r.deleteIPAddressClaims(ctx, claim, nil); err != nil {
+		return r.deprovisioningFailed(ctx, log, claim, err)
 	}
-	if _, err := clientutils.PatchEnsureNoFinalizer(ctx, r.Client, claim, metalv1alpha1.BareMetalHostClaimFinalizer); err != nil {
-		return ctrl.Result{}, err
+	log.V(1).Info("Released IP addresses")
+
+	if host != nil {
+		log.V(1).Info("Removing claimRef on host", "Host", host.Name)
+		hostBase := host.DeepCopy()
+		host.Spec.ClaimRef = nil
+		host.Spec.ProvisioningGeneration = 0
+		if claim.Spec.DiskCleaning == metalv1alpha1.DiskCleaningPolicyRequired {
+			addTaint(host, metalv1alpha1.HostTaintDiskCleaning, fmt.Sprintf("Disks have to be cleaned after the release of claim %s/%s", claim.Namespace, claim.Name))
+		}
+		if len(skipped) > 0 {
+			message := fmt.Sprintf("Released by claim %s/%s before deprovisioning completed: %s", claim.Namespace, claim.Name, strings.Join(skipped, ", "))
+			addTaint(host, metalv1alpha1.HostTaintDeprovisioningFailed, message)
+			r.Recorder.Event(host, v1.EventTypeWarning, metalv1alpha1.BareMetalHostClaimReasonDeprovisioningFailed, message)
+		}
+		if err := r.Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
+			return r.deprovisioningFailed(ctx, log, claim, fmt.Errorf("failed to remove claimRef from host: %w", err))
+		}
+		log.V(1).Info("Removed claimRef on host", "Host", host.Name, "Taints", len(host.Spec.Taints))
+
+		log.V(1).Info("Removing finalizer on host", "Host", host.Name)
+		if _, err := clientutils.PatchEnsureNoFinalizer(ctx, r.Client, host, metalv1alpha1.BareMetalHostClaimFinalizer); err != nil {
+			return r.deprovisioningFailed(ctx, log, claim, fmt.Errorf("failed to remove finalizer from host: %w", err))
+		}
+		log.V(1).Info("Removed finalizer on host", "Host", host.Name)
 	}
-	log.V(1).Info("Removed finalizer on host", "Host", host.Name)
+	return true, ctrl.Result{}, nil
+}
 
-	log.V(1).Info("Deleted host claim")
+// removeBootConfiguration deletes the PXE and DHCP configurations of the claim and reports
+// whether both are gone. Their finalizers withdraw the iPXE secret and the DHCP lease of the
+// host, so the host cannot boot the image of the claim anymore once it is released.
+func (r *BareMetalHostClaimReconciler) removeBootConfiguration(ctx context.Context, claim *metalv1alpha1.BareMetalHostClaim) (bool, error) {
+	removed := true
+	for _, obj := range []client.Object{&v1alpha1.PXE{}, &v1alpha1.DHCP{}} {
+		if err := r.Get(ctx, client.ObjectKey{Namespace: claim.Namespace, Name: claim.Name}, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return false, fmt.Errorf("failed to get boot configuration: %w", err)
+		}
+		if !metav1.IsControlledBy(obj, claim) {
+			continue
+		}
+		removed = false
+		if obj.GetDeletionTimestamp().IsZero() {
+			if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+				return false, fmt.Errorf("failed to delete boot configuration: %w", err)
+			}
+		}
+	}
+	return removed, nil
+}
 
-	return ctrl.Result{}, nil
+// deprovisioningFailed reports the error in the status of the claim and retries the
+// deprovisioning later instead of failing the reconciliation.
+func (r *BareMetalHostClaimReconciler) deprovisioningFailed(ctx context.Context, log logr.Logger, claim *metalv1alpha1.BareMetalHostClaim, err error) (bool, ctrl.Result, error) {
+	log.Error(err, "Failed to deprovision host claim")
+	if err := r.patchDeprovisioningStatus(ctx, claim, metalv1alpha1.BareMetalHostClaimReasonDeprovisioningFailed, err.Error()); err != nil {
+		return false, ctrl.Result{}, err
+	}
+	return false, ctrl.Result{RequeueAfter: deprovisionRetryInterval}, nil
+}
+
+// patchDeprovisioningStatus moves the claim to the Deprovisioning phase and describes the
+// pending step of the deprovisioning in the Deprovisioned condition.
+func (r *BareMetalHostClaimReconciler) patchDeprovisioningStatus(ctx context.Context, claim *metalv1alpha1.BareMetalHostClaim, reason, message string) error {
+	claimBase := claim.DeepCopy()
+	claim.Status.Phase = metalv1alpha1.PhaseDeprovisioning
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               metalv1alpha1.BareMetalHostClaimConditionDeprovisioned,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: claim.Generation,
+	})
+	if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to patch claim status: %w", err)
+	}
+	return nil
 }
 
 func (r *BareMetalHostClaimReconciler) reconcile(ctx context.Context, log logr.Logger, claim *metalv1alpha1.BareMetalHostClaim) (ctrl.Result, error) {
@@ -118,28 +308,100 @@ func (r *BareMetalHostClaimReconciler) reconcile(ctx context.Context, log logr.L
 	if modified, err := clientutils.PatchEnsureFinalizer(ctx, r.Client, claim, metalv1alpha1.BareMetalHostClaimFinalizer); err != nil || modified {
 		return ctrl.Result{}, err
 	}
+	if claim.Spec.BareMetalHostRef.Name == "" {
+		log.V(1).Info("Selecting host")
+		selected, quotaMessage, err := r.selectHost(ctx, claim)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if selected == nil {
+			log.V(1).Info("No host available for selector", "Quota", quotaMessage)
+			claimBase := claim.DeepCopy()
+			claim.Status.Phase = metalv1alpha1.PhaseUnbound
+			setQuotaCondition(claim, quotaMessage != "", quotaMessage)
+			if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to patch claim status: %w", err)
+			}
+			return ctrl.Result{}, r.preempt(ctx, log, claim)
+		}
+		claimBase := claim.DeepCopy()
+		claim.Spec.BareMetalHostRef = v1.LocalObjectReference{Name: selected.Name}
+		if err := r.Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to patch selected host on claim: %w", err)
+		}
+		log.V(1).Info("Selected host", "Host", selected.Name)
+	}
 	host := &metalv1alpha1.BareMetalHost{}
 	if err := r.Get(ctx, types.NamespacedName{Name: claim.Spec.BareMetalHostRef.Name}, host); err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to get host for claim: %w", err)
 	}
 	if host.Spec.ClaimRef != nil && host.Spec.ClaimRef.UID != claim.UID {
-		return ctrl.Result{}, fmt.Errorf("failed to claim host %s as it is already in claimed by somebody else", host.Name)
+		backoff, err := r.patchConflict(ctx, log, claim, host)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: backoff}, r.preempt(ctx, log, claim)
+	}
+	if host.Spec.ClaimRef == nil {
+		claims, err := listWaitingClaims(ctx, r.Client, host)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		unbound, err := listUnboundClaims(ctx, r.Client)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		reservations, err := listHostReservations(ctx, r.Client, unbound)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if preceding := precedingClaim(claim, host, claims, reservations); preceding != nil {
+			log.V(1).Info("Host is claimed by a preceding claim first: waiting for host", "Host", host.Name, "Preceding", client.ObjectKeyFromObject(preceding))
+			return ctrl.Result{}, r.patchPhase(ctx, claim, metalv1alpha1.PhaseUnbound)
+		}
+		// claims exceeding the quota of a pool are queued until hosts of the pool are released
+		if admitted, err := r.admitClaim(ctx, log, claim, host); err != nil || !admitted {
+			return ctrl.Result{}, err
+		}
 	}
 	if modified, err := clientutils.PatchEnsureFinalizer(ctx, r.Client, host, metalv1alpha1.BareMetalHostClaimFinalizer); err != nil || modified {
 		return ctrl.Result{}, err
 	}
 	log.V(1).Info("Ensured finalizer")
 
+	log.V(1).Info("Ensuring IP addresses")
+	addresses, allocated, err := r.ensureIPAddresses(ctx, log, claim, host)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !allocated {
+		// the claim is reconciled again once its IP address claims change
+		log.V(1).Info("Waiting for IP addresses to be allocated")
+		if claim.Status.Phase == metalv1alpha1.PhaseBound {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, r.patchPhase(ctx, claim, metalv1alpha1.PhaseUnbound)
+	}
+	log.V(1).Info("Ensured IP addresses")
+
+	hash, err := r.provisioningHash(ctx, claim, addresses)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	generation := provisioningGeneration(claim, hash)
+	if claim.Status.ProvisioningGeneration != 0 && generation != claim.Status.ProvisioningGeneration {
+		log.V(1).Info("Image or ignition changed: provisioning host again", "ProvisioningGeneration", generation)
+	}
+
 	log.V(1).Info("Apply PXE configuration")
-	// TODO: we should wait until the PXE configuration is ready
-	if err := r.applyPXEConfiguration(ctx, log, claim, host); err != nil {
+	if err := r.applyPXEConfiguration(ctx, log, claim, host, generation, addresses); err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to apply PXE configuration: %w", err)
 	}
 	log.V(1).Info("Applied PXE configuration")
 
 	log.V(1).Info("Apply DHCP configuration")
 	// TODO: we should wait until the DHCP configuration is ready
-	if err := r.applyDHCPConfiguration(ctx, log, claim); err != nil {
+	if err := r.applyDHCPConfiguration(ctx, log, claim, host, addresses); err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to apply DHCP configuration: %w", err)
 	}
 	log.V(1).Info("Applied DHCP configuration")
@@ -159,6 +421,8 @@ func (r *BareMetalHostClaimReconciler) reconcile(ctx context.Context, log logr.L
 		log.V(1).Info("Applied claimRef on host", "Host", host.Name)
 	}
 
+	provisioned := false
+	provisioning := false
 	if host.Spec.ClaimRef != nil {
 		log.V(1).Info("Ensure host power state")
 		// only power on machine if the PXE configuration is ready
@@ -168,18 +432,40 @@ func (r *BareMetalHostClaimReconciler) reconcile(ctx context.Context, log logr.L
 			if err := r.Get(ctx, client.ObjectKey{Namespace: claim.Namespace, Name: claim.Name}, pxeConfig); err != nil {
 				return ctrl.Result{}, fmt.Errorf("failed to get PXE configuration for claim: %w", err)
 			}
-			if pxeConfig.Status.State == v1alpha1.PXEStateReady {
+			// the configuration has to be served for the generation before the host boots
+			if pxeConfig.Status.State == v1alpha1.PXEStateReady &&
+				pxeConfig.Spec.ProvisioningGeneration == generation &&
+				pxeConfig.Status.ObservedGeneration == pxeConfig.Generation {
+				if err := r.restartConsoleRecording(ctx, claim, generation); err != nil {
+					return ctrl.Result{}, err
+				}
+				if r.ConsoleRecorder != nil {
+					if err := r.ConsoleRecorder.Record(ctx, log, claim, host); err != nil {
+						return ctrl.Result{}, fmt.Errorf("failed to record console: %w", err)
+					}
+				}
 				log.V(1).Info("PXE configuration ready: powering on host")
 				hostBase := host.DeepCopy()
 				host.Spec.Power = claim.Spec.Power
+				host.Spec.ProvisioningGeneration = generation
 				if err := r.Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
 					return ctrl.Result{}, fmt.Errorf("failed to patch the power status on host %s: %w", host.Name, err)
 				}
+				provisioned = host.Status.PowerState == redfish.OnPowerState
+				provisioning = true
 			}
 			log.V(1).Info("Powered on host")
 		} else {
+			if r.ConsoleRecorder != nil && claim.Spec.Power == metalv1alpha1.PowerStateOff {
+				r.ConsoleRecorder.Stop(claim)
+			}
 			hostBase := host.DeepCopy()
 			host.Spec.Power = claim.Spec.Power
+			// hosts which are powered off are provisioned again once they are powered on
+			if claim.Spec.Power == metalv1alpha1.PowerStateOn {
+				host.Spec.ProvisioningGeneration = generation
+				provisioning = true
+			}
 			if err := r.Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
 				return ctrl.Result{}, fmt.Errorf("failed to patch the power status on host %s: %w", host.Name, err)
 			}
@@ -189,14 +475,223 @@ func (r *BareMetalHostClaimReconciler) reconcile(ctx context.Context, log logr.L
 
 	claimBase := claim.DeepCopy()
 	claim.Status.Phase = metalv1alpha1.PhaseBound
+	meta.RemoveStatusCondition(&claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionConflict)
+	if claim.Status.BoundAt == nil {
+		boundAt := metav1.Now().Rfc3339Copy()
+		claim.Status.BoundAt = &boundAt
+	}
+	if provisioned && claim.Status.ProvisionedAt == nil {
+		now := metav1.Now()
+		claim.Status.ProvisionedAt = &now
+	}
+	if provisioning && generation != claim.Status.ProvisioningGeneration {
+		claim.Status.ProvisioningGeneration = generation
+		claim.Status.ProvisioningHash = hash
+	}
 	if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
 		return ctrl.Result{}, err
 	}
+	if claimBase.Status.Phase != metalv1alpha1.PhaseBound {
+		claimTimeToBound.Observe(time.Since(claim.CreationTimestamp.Time).Seconds())
+	}
+	if claimBase.Status.ProvisionedAt == nil && claim.Status.ProvisionedAt != nil {
+		claimTimeToProvisioned.Observe(claim.Status.ProvisionedAt.Sub(claim.CreationTimestamp.Time).Seconds())
+	}
 
-	return ctrl.Result{}, nil
+	requeueAfter, err := r.ensureLease(ctx, log, claim, host)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// provisioningHash returns the hash of the image, the content of the ignition and the addresses
+// of the claim.
+func (r *BareMetalHostClaimReconciler) provisioningHash(ctx context.Context, claim *metalv1alpha1.BareMetalHostClaim, addresses []v1alpha1.StaticAddress) (string, error) {
+	hash := sha256.New()
+	hash.Write([]byte(claim.Spec.Image))
+	if claim.Spec.IgnitionRef != nil {
+		ignition := &v1.Secret{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: claim.Namespace, Name: claim.Spec.IgnitionRef.Name}, ignition); err != nil {
+			return "", fmt.Errorf("failed to get ignition secret for claim: %w", err)
+		}
+		if err := r.ensureIgnitionLabel(ctx, ignition); err != nil {
+			return "", err
+		}
+		keys := make([]string, 0, len(ignition.Data))
+		for key := range ignition.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			hash.Write([]byte{0})
+			hash.Write([]byte(key))
+			hash.Write([]byte{0})
+			hash.Write(ignition.Data[key])
+		}
+	}
+	for _, address := range addresses {
+		hash.Write([]byte{0})
+		hash.Write([]byte(strings.Join(append([]string{address.Name, address.MACAddress, address.Address, address.Gateway}, address.DNSServers...), ",")))
+	}
+	// the boot profile itself is rendered again on changes, but is only used by the next boot
+	if claim.Spec.BootProfileRef != nil {
+		hash.Write([]byte{0})
+		hash.Write([]byte(claim.Spec.BootProfileRef.Name))
+	}
+	for _, arg := range claim.Spec.KernelArgs {
+		hash.Write([]byte{0})
+		hash.Write([]byte(arg))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ensureIgnitionLabel labels the ignition secret, so that its changes are watched.
+func (r *BareMetalHostClaimReconciler) ensureIgnitionLabel(ctx context.Context, ignition *v1.Secret) error {
+	if ignition.Labels[metalv1alpha1.IgnitionSecretLabel] == "true" {
+		return nil
+	}
+	ignitionBase := ignition.DeepCopy()
+	if ignition.Labels == nil {
+		ignition.Labels = map[string]string{}
+	}
+	ignition.Labels[metalv1alpha1.IgnitionSecretLabel] = "true"
+	if err := r.Patch(ctx, ignition, client.MergeFrom(ignitionBase)); err != nil {
+		return fmt.Errorf("failed to label ignition secret: %w", err)
+	}
+	return nil
+}
+
+// provisioningGeneration returns the generation the host has to be provisioned for. The
+// generation is increased if the image, the ignition or the boot configuration changed since the
+// host has been provisioned, unless the claim only applies changes on deletion.
+func provisioningGeneration(claim *metalv1alpha1.BareMetalHostClaim, hash string) int64 {
+	switch {
+	case claim.Status.ProvisioningGeneration == 0:
+		return 1
+	case claim.Status.ProvisioningHash != hash && claim.Spec.ReprovisionPolicy != metalv1alpha1.ReprovisionPolicyOnDelete:
+		return claim.Status.ProvisioningGeneration + 1
+	default:
+		return claim.Status.ProvisioningGeneration
+	}
+}
+
+// restartConsoleRecording discards the console log and the Provisioned condition of the
+// previous provisioning if the host is provisioned again, so that the console is recorded for
+// the new generation.
+func (r *BareMetalHostClaimReconciler) restartConsoleRecording(ctx context.Context, claim *metalv1alpha1.BareMetalHostClaim, generation int64) error {
+	if r.ConsoleRecorder == nil || claim.Status.ConsoleLog == nil ||
+		claim.Status.ProvisioningGeneration == 0 || claim.Status.ProvisioningGeneration == generation {
+		return nil
+	}
+	r.ConsoleRecorder.Stop(claim)
+	claimBase := claim.DeepCopy()
+	claim.Status.ConsoleLog = nil
+	meta.RemoveStatusCondition(&claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionProvisioned)
+	if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+		return fmt.Errorf("failed to reset console log status: %w", err)
+	}
+	return nil
 }
 
-func (r *BareMetalHostClaimReconciler) applyPXEConfiguration(ctx context.Context, _ logr.Logger, claim *metalv1alpha1.BareMetalHostClaim, host *metalv1alpha1.BareMetalHost) error {
+// patchPhase sets the phase of the claim.
+func (r *BareMetalHostClaimReconciler) patchPhase(ctx context.Context, claim *metalv1alpha1.BareMetalHostClaim, phase metalv1alpha1.Phase) error {
+	// claims waiting in the queue are reconciled on every change of their host
+	if claim.Status.Phase == phase && meta.FindStatusCondition(claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionConflict) == nil {
+		return nil
+	}
+	claimBase := claim.DeepCopy()
+	claim.Status.Phase = phase
+	meta.RemoveStatusCondition(&claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionConflict)
+	if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+		return fmt.Errorf("failed to patch claim status: %w", err)
+	}
+	return nil
+}
+
+// admitClaim reports whether the quotas of the pools of the host admit the claim and records
+// the result in the status of the claim. Claims which are not admitted are Unbound.
+func (r *BareMetalHostClaimReconciler) admitClaim(ctx context.Context, log logr.Logger, claim *metalv1alpha1.BareMetalHostClaim, host *metalv1alpha1.BareMetalHost) (bool, error) {
+	usage, err := newQuotaUsage(ctx, r.Client, claim)
+	if err != nil {
+		return false, err
+	}
+	pooled, message := usage.admit(host)
+	claimBase := claim.DeepCopy()
+	setQuotaCondition(claim, pooled, message)
+	if message != "" {
+		log.V(1).Info("Quota exceeded", "Host", host.Name, "Message", message)
+		claim.Status.Phase = metalv1alpha1.PhaseUnbound
+		meta.RemoveStatusCondition(&claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionConflict)
+	}
+	if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+		return false, fmt.Errorf("failed to patch claim status: %w", err)
+	}
+	return message == "", nil
+}
+
+// selectHost returns the first host by name which matches the selector of the claim and can
+// be claimed. Hosts referenced by other claims, wanted by preceding claims or exceeding a quota
+// are skipped. Nil is returned if no host matches, together with the reason why a matching host
+// was not admitted by the quotas of its pools.
+func (r *BareMetalHostClaimReconciler) selectHost(ctx context.Context, claim *metalv1alpha1.BareMetalHostClaim) (*metalv1alpha1.BareMetalHost, string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(claim.Spec.BareMetalHostSelector)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse host selector: %w", err)
+	}
+	hostList := &metalv1alpha1.BareMetalHostList{}
+	if err := r.List(ctx, hostList, client.MatchingLabelsSelector{Selector: selector}, client.UnsafeDisableDeepCopy); err != nil {
+		return nil, "", fmt.Errorf("failed to list hosts: %w", err)
+	}
+	// bound claims are not listed, as their hosts are claimed and cannot be selected anyway
+	claims, err := listUnboundClaims(ctx, r.Client)
+	if err != nil {
+		return nil, "", err
+	}
+	reservations, err := listHostReservations(ctx, r.Client, claims)
+	if err != nil {
+		return nil, "", err
+	}
+	usage, err := newQuotaUsage(ctx, r.Client, claim)
+	if err != nil {
+		return nil, "", err
+	}
+	referenced := map[string]bool{}
+	for _, other := range claims {
+		if other.UID != claim.UID && other.Spec.BareMetalHostRef.Name != "" {
+			referenced[other.Spec.BareMetalHostRef.Name] = true
+		}
+	}
+
+	sort.Slice(hostList.Items, func(i, j int) bool { return hostList.Items[i].Name < hostList.Items[j].Name })
+	quotaMessage := ""
+	for i := range hostList.Items {
+		host := &hostList.Items[i]
+		// hosts wanted by claims preceding the claim in the queue are left to them
+		if !isHostSelectable(host) || referenced[host.Name] || precedingClaim(claim, host, claims, reservations) != nil {
+			continue
+		}
+		if _, message := usage.admit(host); message != "" {
+			if quotaMessage == "" {
+				quotaMessage = message
+			}
+			continue
+		}
+		return host, "", nil
+	}
+	return nil, quotaMessage, nil
+}
+
+// isHostSelectable reports whether the host can be selected by a claim. Unhealthy hosts and
+// hosts in maintenance are skipped.
+func isHostSelectable(host *metalv1alpha1.BareMetalHost) bool {
+	return host.Spec.ClaimRef == nil &&
+		!host.Spec.Maintenance &&
+		host.Status.State == metalv1alpha1.StateAvailable &&
+		!meta.IsStatusConditionFalse(host.Status.Conditions, metalv1alpha1.BareMetalHostConditionHealthy)
+}
+
+func (r *BareMetalHostClaimReconciler) applyPXEConfiguration(ctx context.Context, _ logr.Logger, claim *metalv1alpha1.BareMetalHostClaim, host *metalv1alpha1.BareMetalHost, generation int64, addresses []v1alpha1.StaticAddress) error {
 	pxe := &v1alpha1.PXE{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "PXE",
@@ -207,14 +702,18 @@ func (r *BareMetalHostClaimReconciler) applyPXEConfiguration(ctx context.Context
 			Name:      claim.Name,
 		},
 		Spec: v1alpha1.PXESpec{
-			BareMetalHostClaimRef: v1.LocalObjectReference{Name: claim.Name},
-			IgnitionRef:           claim.Spec.IgnitionRef,
-			Image:                 claim.Spec.Image,
-			SystemUUID:            host.Status.SystemUUID,
+			BareMetalHostClaimRef:  v1.LocalObjectReference{Name: claim.Name},
+			IgnitionRef:            claim.Spec.IgnitionRef,
+			Image:                  claim.Spec.Image,
+			SystemUUID:             host.Status.SystemUUID,
+			ProvisioningGeneration: generation,
+			Addresses:              addresses,
+			BootProfileRef:         claim.Spec.BootProfileRef,
+			KernelArgs:             claim.Spec.KernelArgs,
 		},
 	}
 
-	if err := controllerutil.SetOwnerReference(claim, pxe, r.Scheme); err != nil {
+	if err := controllerutil.SetControllerReference(claim, pxe, r.Scheme); err != nil {
 		return fmt.Errorf("failed to set owner reference on PXE configuration: %w", err)
 	}
 
@@ -222,16 +721,32 @@ func (r *BareMetalHostClaimReconciler) applyPXEConfiguration(ctx context.Context
 		return fmt.Errorf("failed to apply PXE configuration: %w", err)
 	}
 
-	pxeBase := pxe.DeepCopy()
-	pxe.Status.State = v1alpha1.PXEStateCreated
-	if err := r.Status().Patch(ctx, pxe, client.MergeFrom(pxeBase)); err != nil {
-		return fmt.Errorf("failed to apply PXE configuration: %w", err)
+	// the PXE controller owns the state once it has been initialized, so the state must not
+	// be reset if the PXE controller updated it concurrently
+	if pxe.Status.State == "" {
+		pxeBase := pxe.DeepCopy()
+		pxe.Status.State = v1alpha1.PXEStateCreated
+		if err := r.Status().Patch(ctx, pxe, client.MergeFromWithOptions(pxeBase, client.MergeFromWithOptimisticLock{})); err != nil {
+			return fmt.Errorf("failed to apply PXE configuration: %w", err)
+		}
 	}
 
 	return nil
 }
 
-func (r *BareMetalHostClaimReconciler) applyDHCPConfiguration(ctx context.Context, _ logr.Logger, claim *metalv1alpha1.BareMetalHostClaim) error {
+func (r *BareMetalHostClaimReconciler) applyDHCPConfiguration(ctx context.Context, log logr.Logger, claim *metalv1alpha1.BareMetalHostClaim, host *metalv1alpha1.BareMetalHost, addresses []v1alpha1.StaticAddress) error {
+	reservations, err := dhcpv6Reservations(host)
+	if err != nil {
+		// the host is still served over DHCPv4 and by clients matched by their MAC address
+		log.Error(err, "Failed to derive DHCPv6 reservations of host", "Host", host.Name)
+	}
+	// UEFI HTTP boot loads the boot image directly instead of the iPXE script of the host
+	boot := networkBoot(host, claim)
+	fileURL := bootFileURL(r.BootServerURL, host.Status.SystemUUID)
+	if boot.Mode == metalv1alpha1.NetworkBootModeHTTP {
+		fileURL = boot.URL
+	}
+
 	dhcp := &v1alpha1.DHCP{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "DHCP",
@@ -243,10 +758,15 @@ func (r *BareMetalHostClaimReconciler) applyDHCPConfiguration(ctx context.Contex
 		},
 		Spec: v1alpha1.DHCPSpec{
 			BareMetalHostRef: claim.Spec.BareMetalHostRef,
+			MACAddress:       host.Spec.BootMACAddress,
+			Addresses:        addresses,
+			BootFileURL:      fileURL,
+			HTTPBoot:         boot.Mode == metalv1alpha1.NetworkBootModeHTTP,
+			Reservations:     reservations,
 		},
 	}
 
-	if err := controllerutil.SetOwnerReference(claim, dhcp, r.Scheme); err != nil {
+	if err := controllerutil.SetControllerReference(claim, dhcp, r.Scheme); err != nil {
 		return fmt.Errorf("failed to set owner reference on DHCP configuration: %w", err)
 	}
 
@@ -269,31 +789,129 @@ func (r *BareMetalHostClaimReconciler) SetupWithManager(mgr ctrl.Manager) error
 		For(&metalv1alpha1.BareMetalHostClaim{}).
 		Owns(&v1alpha1.PXE{}).
 		Owns(&v1alpha1.DHCP{}).
-		// TODO: watch setup for ignition secret
+		Owns(&metalv1alpha1.IPAddressClaim{}).
 		Watches(&metalv1alpha1.BareMetalHost{}, r.enqueueBareMetalHostClaimsByRefs()).
+		// only the metadata of the labeled ignition secrets is cached, the content is read
+		// from the API server
+		Watches(&v1.Secret{}, r.enqueueBareMetalHostClaimsByIgnition(), builder.OnlyMetadata, builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
+			return object.GetLabels()[metalv1alpha1.IgnitionSecretLabel] == "true"
+		}))).
+		Watches(&metalv1alpha1.BareMetalHostPool{}, r.enqueueBareMetalHostClaimsByPool()).
+		Watches(&metalv1alpha1.BareMetalHostClaim{}, r.enqueuePendingBareMetalHostClaims(), builder.WithPredicates(predicate.Funcs{
+			CreateFunc:  func(event.CreateEvent) bool { return false },
+			DeleteFunc:  func(event.DeleteEvent) bool { return true },
+			GenericFunc: func(event.GenericEvent) bool { return false },
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				// a pending claim leaves the queue once it is bound
+				return e.ObjectOld.(*metalv1alpha1.BareMetalHostClaim).Status.Phase != metalv1alpha1.PhaseBound &&
+					e.ObjectNew.(*metalv1alpha1.BareMetalHostClaim).Status.Phase == metalv1alpha1.PhaseBound
+			},
+		})).
 		Complete(r)
 }
 
-func (r *BareMetalHostClaimReconciler) enqueueBareMetalHostClaimsByRefs() handler.EventHandler {
+// enqueuePendingBareMetalHostClaims enqueues the pending claims once a claim leaves the queue,
+// so that the claims waiting behind it are bound.
+func (r *BareMetalHostClaimReconciler) enqueuePendingBareMetalHostClaims() handler.EventHandler {
 	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, object client.Object) []reconcile.Request {
 		log := ctrl.LoggerFrom(ctx)
 
-		host := object.(*metalv1alpha1.BareMetalHost)
+		claims, err := listQueuedClaims(ctx, r.Client, claimQueuePending)
+		if err != nil {
+			log.Error(err, "failed to list pending host claims")
+			return nil
+		}
 		var req []reconcile.Request
+		for _, claim := range claims {
+			if claim.UID != object.GetUID() {
+				req = append(req, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
+				})
+			}
+		}
+
+		return req
+	})
+}
+
+// isQueuedOnQuota reports whether the claim waits for the quota of a pool.
+func isQueuedOnQuota(claim *metalv1alpha1.BareMetalHostClaim) bool {
+	return meta.IsStatusConditionFalse(claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionWithinQuota)
+}
+
+// enqueueBareMetalHostClaimsByPool enqueues the claims which are not bound to a host yet, so
+// that changed quotas of a pool are applied, and the bound claims, so that a changed maximum
+// lease is applied.
+func (r *BareMetalHostClaimReconciler) enqueueBareMetalHostClaimsByPool() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, _ client.Object) []reconcile.Request {
+		log := ctrl.LoggerFrom(ctx)
+
 		claimList := &metalv1alpha1.BareMetalHostClaimList{}
 		if err := r.List(ctx, claimList); err != nil {
 			log.Error(err, "failed to list host claims")
 			return nil
 		}
+		var req []reconcile.Request
 		for _, claim := range claimList.Items {
-			if claim.Spec.BareMetalHostRef.Name == host.Name {
+			if claim.Spec.BareMetalHostRef.Name == "" || isQueuedOnQuota(&claim) || claim.Status.BoundAt != nil {
 				req = append(req, reconcile.Request{
 					NamespacedName: types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
 				})
-				return req
 			}
 		}
 
 		return req
 	})
 }
+
+func (r *BareMetalHostClaimReconciler) enqueueBareMetalHostClaimsByRefs() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, object client.Object) []reconcile.Request {
+		log := ctrl.LoggerFrom(ctx)
+
+		host := object.(*metalv1alpha1.BareMetalHost)
+		// all claims referencing the host are enqueued, so that the claims competing for it are
+		// bound once it becomes available, as well as the pending claims selecting it by labels
+		// and the claims waiting for hosts of their pools to be released
+		claims, err := listWaitingClaims(ctx, r.Client, host)
+		if err != nil {
+			log.Error(err, "failed to list host claims")
+			return nil
+		}
+		var req []reconcile.Request
+		for _, claim := range claims {
+			req = append(req, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
+			})
+		}
+		if host.Spec.ClaimRef != nil {
+			// the claim the host is claimed by is enqueued even if it references another host
+			req = append(req, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: host.Spec.ClaimRef.Namespace, Name: host.Spec.ClaimRef.Name},
+			})
+		}
+
+		return req
+	})
+}
+
+// enqueueBareMetalHostClaimsByIgnition enqueues the claims referencing an ignition secret, so
+// that changes of the ignition are detected.
+func (r *BareMetalHostClaimReconciler) enqueueBareMetalHostClaimsByIgnition() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, object client.Object) []reconcile.Request {
+		log := ctrl.LoggerFrom(ctx)
+
+		claimList := &metalv1alpha1.BareMetalHostClaimList{}
+		if err := r.List(ctx, claimList, client.InNamespace(object.GetNamespace()), client.MatchingFields{ignitionRefNameField: object.GetName()}); err != nil {
+			log.Error(err, "failed to list host claims")
+			return nil
+		}
+		var req []reconcile.Request
+		for _, claim := range claimList.Items {
+			req = append(req, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
+			})
+		}
+
+		return req
+	})
+}
@@ -0,0 +1,508 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/redfishmock"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stmcginnis/gofish/redfish"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	systemID   = "System-1"
+	systemUUID = "38947555-7742-3448-3784-823347823834"
+	bootMAC    = "bc:55:85:92:de:b7"
+)
+
+// setupBMC starts a Redfish mock serving a single system and registers a BMC for it.
+func setupBMC(ctx SpecContext, ns *v1.Namespace) (*redfishmock.Service, *metalv1alpha1.BMC) {
+	service := redfishmock.NewService([]redfishmock.System{{
+		ID:           systemID,
+		UUID:         systemUUID,
+		Manufacturer: "Contoso",
+		Model:        "3500",
+		NetworkInterfaces: []redfishmock.NetworkInterface{
+			{ID: "1", MACAddress: bootMAC},
+		},
+		Processors: []redfishmock.Processor{
+			{ID: "CPU1", ProcessorArchitecture: "x86", TotalCores: 8, TotalThreads: 16},
+		},
+	}}, redfishmock.WithCredentials("admin", "secret"))
+	server := httptest.NewServer(service)
+	DeferCleanup(server.Close)
+
+	By("Creating the BMC credentials")
+	credentials := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "bmc-"},
+		Data: map[string][]byte{
+			"username": []byte("admin"),
+			"password": []byte("secret"),
+		},
+	}
+	Expect(k8sClient.Create(ctx, credentials)).To(Succeed())
+
+	By("Creating the BMC")
+	bmcObj := &metalv1alpha1.BMC{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "bmc-"},
+		Spec: metalv1alpha1.BMCSpec{
+			Type:      metalv1alpha1.BMCTypeRedfish,
+			Address:   server.URL,
+			SecretRef: v1.SecretReference{Namespace: ns.Name, Name: credentials.Name},
+		},
+	}
+	Expect(k8sClient.Create(ctx, bmcObj)).To(Succeed())
+	DeferCleanup(k8sClient.Delete, bmcObj)
+
+	return service, bmcObj
+}
+
+// fakeSystem is a system in the inventory of a fake BMC.
+type fakeSystem struct {
+	ID                string          `json:"id"`
+	UUID              string          `json:"uuid"`
+	NetworkInterfaces json.RawMessage `json:"networkInterfaces,omitempty"`
+}
+
+// fakeHostOption customizes the inventory of the fake BMC created by setupFakeHost.
+type fakeHostOption func(systems []fakeSystem) []fakeSystem
+
+// withNetworkInterfaces sets the JSON encoded network interfaces of the system of the host.
+func withNetworkInterfaces(nics string) fakeHostOption {
+	return func(systems []fakeSystem) []fakeSystem {
+		systems[0].NetworkInterfaces = json.RawMessage(nics)
+		return systems
+	}
+}
+
+// withSystems adds systems with the UUIDs to the inventory, whose IDs are numbered after the
+// system of the host, i.e. System-2, System-3 and so on.
+func withSystems(uuids ...string) fakeHostOption {
+	return func(systems []fakeSystem) []fakeSystem {
+		for _, uuid := range uuids {
+			systems = append(systems, fakeSystem{ID: fmt.Sprintf("System-%d", len(systems)+1), UUID: uuid})
+		}
+		return systems
+	}
+}
+
+// setupFakeHost registers a fake BMC serving a single system and waits for its host to be
+// available. The hosts of the systems added by the options are available as well.
+func setupFakeHost(ctx SpecContext, uuid string, opts ...fakeHostOption) (*metalv1alpha1.BMC, *metalv1alpha1.BareMetalHost) {
+	systems := []fakeSystem{{ID: systemID, UUID: uuid}}
+	for _, opt := range opts {
+		systems = opt(systems)
+	}
+	inventory, err := json.Marshal(systems)
+	Expect(err).NotTo(HaveOccurred())
+	bmcObj := &metalv1alpha1.BMC{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "fake-",
+			Annotations: map[string]string{
+				metalv1alpha1.FakeInventoryAnnotation: string(inventory),
+			},
+		},
+		Spec: metalv1alpha1.BMCSpec{
+			Type:    metalv1alpha1.BMCTypeFake,
+			Address: "fake://" + uuid,
+		},
+	}
+	Expect(k8sClient.Create(ctx, bmcObj)).To(Succeed())
+	DeferCleanup(k8sClient.Delete, bmcObj)
+
+	By("Waiting for the host to be available")
+	var hosts []*metalv1alpha1.BareMetalHost
+	for _, system := range systems {
+		host := &metalv1alpha1.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{Name: objectName(bmcObj.Name, system.ID)},
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.State).To(Equal(metalv1alpha1.StateAvailable))
+			if system.NetworkInterfaces != nil {
+				g.Expect(host.Status.NetworkInterfaces).NotTo(BeEmpty())
+			}
+		}).Should(Succeed())
+		DeferCleanup(k8sClient.Delete, host)
+		hosts = append(hosts, host)
+	}
+
+	return bmcObj, hosts[0]
+}
+
+// createClaim creates an ignition and a claim powering on the host with it.
+func createClaim(ctx SpecContext, ns *v1.Namespace, host *metalv1alpha1.BareMetalHost, policy metalv1alpha1.ReprovisionPolicy) (*metalv1alpha1.BareMetalHostClaim, *v1.Secret) {
+	ignition := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "ignition-"},
+		Data:       map[string][]byte{"ignition": []byte("{}")},
+	}
+	Expect(k8sClient.Create(ctx, ignition)).To(Succeed())
+	claim := &metalv1alpha1.BareMetalHostClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "claim-"},
+		Spec: metalv1alpha1.BareMetalHostClaimSpec{
+			Power:             metalv1alpha1.PowerStateOn,
+			BareMetalHostRef:  v1.LocalObjectReference{Name: host.Name},
+			IgnitionRef:       &v1.LocalObjectReference{Name: ignition.Name},
+			Image:             "foo:latest",
+			ReprovisionPolicy: policy,
+		},
+	}
+	Expect(k8sClient.Create(ctx, claim)).To(Succeed())
+	return claim, ignition
+}
+
+var _ = Describe("BareMetalHostClaim Controller", func() {
+	var ns *v1.Namespace
+
+	BeforeEach(func(ctx SpecContext) {
+		ns = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ns)
+	})
+
+	It("should discover, provision and power on a host", func(ctx SpecContext) {
+		service, bmcObj := setupBMC(ctx, ns)
+
+		By("Waiting for the host to be discovered")
+		host := &metalv1alpha1.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{Name: objectName(bmcObj.Name, systemID)},
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Spec.SystemID).To(Equal(systemID))
+			g.Expect(metav1.IsControlledBy(host, bmcObj)).To(BeTrue())
+			g.Expect(host.Status.SystemUUID).To(Equal(systemUUID))
+			g.Expect(host.Status.NetworkInterfaces).To(ConsistOf(metalv1alpha1.NetworkInterface{ID: "1", MACAddress: bootMAC}))
+			g.Expect(host.Status.Processors).To(HaveLen(1))
+			g.Expect(host.Status.State).To(Equal(metalv1alpha1.StateAvailable))
+		}).Should(Succeed())
+		DeferCleanup(k8sClient.Delete, host)
+
+		By("Verifying the PXE boot override")
+		Expect(service.BootOverrides(systemID)).To(ContainElement(SatisfyAll(
+			HaveField("BootSourceOverrideEnabled", redfish.OnceBootSourceOverrideEnabled),
+			HaveField("BootSourceOverrideTarget", redfish.PxeBootSourceOverrideTarget),
+		)))
+
+		By("Claiming the host")
+		ignition := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "ignition-"},
+			Data:       map[string][]byte{"ignition": []byte("{}")},
+		}
+		Expect(k8sClient.Create(ctx, ignition)).To(Succeed())
+		claim := &metalv1alpha1.BareMetalHostClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "claim-"},
+			Spec: metalv1alpha1.BareMetalHostClaimSpec{
+				Power:            metalv1alpha1.PowerStateOn,
+				BareMetalHostRef: v1.LocalObjectReference{Name: host.Name},
+				IgnitionRef:      &v1.LocalObjectReference{Name: ignition.Name},
+				Image:            "foo:latest",
+			},
+		}
+		Expect(k8sClient.Create(ctx, claim)).To(Succeed())
+
+		By("Waiting for the PXE configuration to be ready")
+		pxe := &bootv1alpha1.PXE{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: claim.Name},
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pxe), pxe)).To(Succeed())
+			g.Expect(pxe.Spec.SystemUUID).To(Equal(systemUUID))
+			g.Expect(pxe.Status.State).To(Equal(bootv1alpha1.PXEStateReady))
+		}).Should(Succeed())
+		pxeSecret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "oob", Name: fmt.Sprintf("ipxe-%s", systemUUID)},
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pxeSecret), pxeSecret)).To(Succeed())
+			g.Expect(pxeSecret.Data).To(Equal(ignition.Data))
+		}).Should(Succeed())
+
+		By("Waiting for the host to be bound and powered on")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Spec.ClaimRef).NotTo(BeNil())
+			g.Expect(host.Spec.ClaimRef.UID).To(Equal(claim.UID))
+			g.Expect(host.Spec.Power).To(Equal(metalv1alpha1.PowerStateOn))
+		}).Should(Succeed())
+		Eventually(func(g Gomega) {
+			system, ok := service.System(systemID)
+			g.Expect(ok).To(BeTrue())
+			g.Expect(system.PowerState).To(Equal(redfish.OnPowerState))
+		}).Should(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ProvisionedAt).NotTo(BeNil())
+		}).Should(Succeed())
+
+		By("Ensuring that the missing serial console does not block the provisioning")
+		Expect(claim.Status.ConsoleLog).NotTo(BeNil())
+		Expect(claim.Status.ConsoleLog.State).To(Equal(metalv1alpha1.ConsoleLogStateFailed))
+		Expect(claim.Status.ConsoleLog.Message).To(ContainSubstring("serial console is not supported"))
+
+		By("Verifying the operator metrics")
+		for _, name := range []string{
+			"baremetal_bmc_request_duration_seconds",
+			"baremetal_host_power_transition_duration_seconds",
+			"baremetal_claim_time_to_bound_seconds",
+			"baremetal_claim_time_to_provisioned_seconds",
+			"baremetal_hosts",
+			"baremetal_pxes",
+		} {
+			Expect(testutil.GatherAndCount(metrics.Registry, name)).NotTo(BeZero(), name)
+		}
+
+		By("Deleting the claim")
+		Expect(k8sClient.Delete(ctx, claim)).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Spec.ClaimRef).To(BeNil())
+		}).Should(Succeed())
+	})
+
+	It("should report BMC faults in the BMC status", func(ctx SpecContext) {
+		service, bmcObj := setupBMC(ctx, ns)
+		service.InjectFault(redfishmock.Fault{
+			Method:     http.MethodGet,
+			PathPrefix: "/redfish/v1/Systems",
+			StatusCode: http.StatusInternalServerError,
+		})
+
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bmcObj), bmcObj)).To(Succeed())
+			g.Expect(bmcObj.Status.State).To(Equal(metalv1alpha1.BMCStateError))
+		}).Should(Succeed())
+
+		service.ClearFaults()
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bmcObj), bmcObj)).To(Succeed())
+			g.Expect(bmcObj.Status.State).To(Equal(metalv1alpha1.BMCStateReady))
+			g.Expect(bmcObj.Status.Systems).To(ConsistOf(HaveField("ID", systemID)))
+		}).Should(Succeed())
+	})
+
+	It("should provision the host again if the image or the ignition changes", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "88888888-8888-8888-8888-888888888888")
+		claim, ignition := createClaim(ctx, ns, host, metalv1alpha1.ReprovisionPolicyOnChange)
+		DeferCleanup(k8sClient.Delete, claim)
+
+		By("Waiting for the host to be provisioned")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ProvisionedAt).NotTo(BeNil())
+			g.Expect(claim.Status.ProvisioningGeneration).To(BeEquivalentTo(1))
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.ObservedProvisioningGeneration).To(BeEquivalentTo(1))
+		}).Should(Succeed())
+		provisionedHash := claim.Status.ProvisioningHash
+		Expect(provisionedHash).NotTo(BeEmpty())
+		bootOverrides := len(host.Status.BootOverrides)
+
+		By("Changing the image")
+		claimBase := claim.DeepCopy()
+		claim.Spec.Image = "foo:v2"
+		Expect(k8sClient.Patch(ctx, claim, client.MergeFrom(claimBase))).To(Succeed())
+
+		By("Ensuring that the host is booted via PXE again")
+		pxe := &bootv1alpha1.PXE{ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: claim.Name}}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ProvisioningGeneration).To(BeEquivalentTo(2))
+			g.Expect(claim.Status.ProvisioningHash).NotTo(Equal(provisionedHash))
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pxe), pxe)).To(Succeed())
+			g.Expect(pxe.Spec.Image).To(Equal("foo:v2"))
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.ObservedProvisioningGeneration).To(BeEquivalentTo(2))
+			g.Expect(host.Status.BootOverrides).To(HaveLen(bootOverrides + 1))
+		}).Should(Succeed())
+		Expect(host.Status.PowerState).To(Equal(redfish.OnPowerState))
+
+		By("Changing the content of the ignition")
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ignition), ignition)).To(Succeed())
+		Expect(ignition.Labels).To(HaveKeyWithValue(metalv1alpha1.IgnitionSecretLabel, "true"))
+		ignitionBase := ignition.DeepCopy()
+		ignition.Data["ignition"] = []byte(`{"ignition": {"version": "3.0.0"}}`)
+		Expect(k8sClient.Patch(ctx, ignition, client.MergeFrom(ignitionBase))).To(Succeed())
+
+		By("Ensuring that the changed ignition is served and the host is booted via PXE again")
+		pxeSecret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "oob", Name: "ipxe-88888888-8888-8888-8888-888888888888"},
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ProvisioningGeneration).To(BeEquivalentTo(3))
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pxeSecret), pxeSecret)).To(Succeed())
+			g.Expect(pxeSecret.Data).To(Equal(ignition.Data))
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.ObservedProvisioningGeneration).To(BeEquivalentTo(3))
+		}).Should(Succeed())
+
+		By("Ensuring that the console of the new provisioning is recorded")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ConsoleLog).NotTo(BeNil())
+			secret := &v1.Secret{}
+			g.Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: ns.Name, Name: claim.Status.ConsoleLog.SecretRef.Name}, secret)).To(Succeed())
+			g.Expect(string(secret.Data[metalv1alpha1.ConsoleLogSecretKey])).To(Equal("System-1 booting from Pxe\r\n"))
+		}).Should(Succeed())
+	})
+
+	It("should not provision the host again if changes are only applied on deletion", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "99999999-9999-9999-9999-999999999999")
+		claim, _ := createClaim(ctx, ns, host, metalv1alpha1.ReprovisionPolicyOnDelete)
+		DeferCleanup(k8sClient.Delete, claim)
+
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ProvisioningGeneration).To(BeEquivalentTo(1))
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.ObservedProvisioningGeneration).To(BeEquivalentTo(1))
+		}).Should(Succeed())
+		provisionedHash := claim.Status.ProvisioningHash
+
+		claimBase := claim.DeepCopy()
+		claim.Spec.Image = "foo:v2"
+		Expect(k8sClient.Patch(ctx, claim, client.MergeFrom(claimBase))).To(Succeed())
+
+		Consistently(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ProvisioningGeneration).To(BeEquivalentTo(1))
+			g.Expect(claim.Status.ProvisioningHash).To(Equal(provisionedHash))
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Spec.ProvisioningGeneration).To(BeEquivalentTo(1))
+		}).Should(Succeed())
+	})
+
+	It("should power off the host and remove its boot configuration before releasing it", func(ctx SpecContext) {
+		bmcObj, host := setupFakeHost(ctx, "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")
+		claim, _ := createClaim(ctx, ns, host, metalv1alpha1.ReprovisionPolicyOnChange)
+
+		By("Waiting for the host to be provisioned")
+		pxeSecret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "oob", Name: "ipxe-aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"},
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ProvisionedAt).NotTo(BeNil())
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pxeSecret), pxeSecret)).To(Succeed())
+		}).Should(Succeed())
+
+		By("Slowing down the power transitions of the host")
+		bmcBase := bmcObj.DeepCopy()
+		bmcObj.Annotations[metalv1alpha1.FakePowerTransitionDelayAnnotation] = "2s"
+		Expect(k8sClient.Patch(ctx, bmcObj, client.MergeFrom(bmcBase))).To(Succeed())
+
+		By("Deleting the claim")
+		Expect(k8sClient.Delete(ctx, claim)).To(Succeed())
+
+		By("Ensuring that the claim is deprovisioning while the host powers off")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.Phase).To(Equal(metalv1alpha1.PhaseDeprovisioning))
+			condition := meta.FindStatusCondition(claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionDeprovisioned)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Reason).To(Equal(metalv1alpha1.BareMetalHostClaimReasonPoweringOff))
+		}).Should(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+		Expect(host.Spec.ClaimRef).NotTo(BeNil())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pxeSecret), pxeSecret)).To(Succeed())
+
+		By("Ensuring that the host is released once it is powered off and its boot configuration is removed")
+		Eventually(func(g Gomega) {
+			g.Expect(apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim))).To(BeTrue())
+		}).Should(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+		Expect(host.Spec.ClaimRef).To(BeNil())
+		Expect(host.Spec.Taints).To(BeEmpty())
+		Expect(host.Spec.Power).To(Equal(metalv1alpha1.PowerStateOff))
+		Expect(host.Status.PowerState).To(Equal(redfish.OffPowerState))
+		Expect(apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(pxeSecret), pxeSecret))).To(BeTrue())
+		for _, obj := range []client.Object{&bootv1alpha1.PXE{}, &bootv1alpha1.DHCP{}} {
+			Expect(apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKey{Namespace: ns.Name, Name: claim.Name}, obj))).To(BeTrue())
+		}
+	})
+
+	It("should release the host with taints if the release is forced and the disks need cleaning", func(ctx SpecContext) {
+		bmcObj, host := setupFakeHost(ctx, "19191919-1919-1919-1919-191919191919")
+		claim, _ := createClaim(ctx, ns, host, metalv1alpha1.ReprovisionPolicyOnChange)
+
+		By("Requiring the disks to be cleaned")
+		claimBase := claim.DeepCopy()
+		claim.Spec.DiskCleaning = metalv1alpha1.DiskCleaningPolicyRequired
+		Expect(k8sClient.Patch(ctx, claim, client.MergeFrom(claimBase))).To(Succeed())
+
+		By("Waiting for the host to be provisioned")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ProvisionedAt).NotTo(BeNil())
+		}).Should(Succeed())
+
+		By("Preventing the host from powering off")
+		bmcBase := bmcObj.DeepCopy()
+		bmcObj.Annotations[metalv1alpha1.FakePowerTransitionDelayAnnotation] = "1h"
+		Expect(k8sClient.Patch(ctx, bmcObj, client.MergeFrom(bmcBase))).To(Succeed())
+
+		By("Deleting the claim")
+		Expect(k8sClient.Delete(ctx, claim)).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			condition := meta.FindStatusCondition(claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionDeprovisioned)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Reason).To(Equal(metalv1alpha1.BareMetalHostClaimReasonPoweringOff))
+		}).Should(Succeed())
+
+		By("Forcing the release of the host")
+		claimBase = claim.DeepCopy()
+		metav1.SetMetaDataAnnotation(&claim.ObjectMeta, metalv1alpha1.ForceReleaseAnnotation, "true")
+		Expect(k8sClient.Patch(ctx, claim, client.MergeFrom(claimBase))).To(Succeed())
+
+		By("Ensuring that the host is released with taints and stays tainted")
+		Eventually(func(g Gomega) {
+			g.Expect(apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim))).To(BeTrue())
+		}).Should(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Spec.ClaimRef).To(BeNil())
+			g.Expect(host.Spec.Taints).To(ConsistOf(
+				HaveField("Key", metalv1alpha1.HostTaintDiskCleaning),
+				HaveField("Key", metalv1alpha1.HostTaintDeprovisioningFailed),
+			))
+			g.Expect(host.Status.State).To(Equal(metalv1alpha1.StateTainted))
+		}).WithTimeout(time.Minute).Should(Succeed())
+		Consistently(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.State).To(Equal(metalv1alpha1.StateTainted))
+		}, "2s").Should(Succeed())
+	})
+})
@@ -0,0 +1,484 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// DefaultBindingTimeout is the duration within which all members of a set have to be bound
+// if the set does not define its binding timeout.
+const DefaultBindingTimeout = 10 * time.Minute
+
+// BareMetalHostClaimSetReconciler reconciles a BareMetalHostClaimSet object
+type BareMetalHostClaimSetReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhostclaimsets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhostclaimsets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhostclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhosts,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhostpools,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *BareMetalHostClaimSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	set := &metalv1alpha1.BareMetalHostClaimSet{}
+	if err := r.Get(ctx, req.NamespacedName, set); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	return r.reconcileExists(ctx, log, set)
+}
+
+func (r *BareMetalHostClaimSetReconciler) reconcileExists(ctx context.Context, log logr.Logger, set *metalv1alpha1.BareMetalHostClaimSet) (ctrl.Result, error) {
+	if !set.DeletionTimestamp.IsZero() {
+		// the member claims are garbage collected via their owner references
+		return ctrl.Result{}, nil
+	}
+	return r.reconcile(ctx, log, set)
+}
+
+func (r *BareMetalHostClaimSetReconciler) reconcile(ctx context.Context, log logr.Logger, set *metalv1alpha1.BareMetalHostClaimSet) (ctrl.Result, error) {
+	log.V(1).Info("Reconciling host claim set")
+	setBase := set.DeepCopy()
+
+	members, err := r.listMembers(ctx, set)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.V(1).Info("Ensuring members")
+	for index, member := range members {
+		if index < int(set.Spec.Replicas) {
+			if err := r.updateMember(ctx, set, member); err != nil {
+				return ctrl.Result{}, err
+			}
+			continue
+		}
+		log.V(1).Info("Deleting member", "Member", member.Name)
+		if err := r.Delete(ctx, member); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to delete member claim %s: %w", member.Name, err)
+		}
+		delete(members, index)
+	}
+
+	expired, result, err := r.ensureExpiry(ctx, log, set, members)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	timeoutResult, err := r.ensureBindingTimeout(ctx, log, set, members)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if timeoutResult.RequeueAfter > 0 {
+		result = requeueEarlier(result, timeoutResult.RequeueAfter)
+	}
+
+	var missing []int
+	for index := 0; index < int(set.Spec.Replicas) && !expired; index++ {
+		if members[index] == nil {
+			missing = append(missing, index)
+		}
+	}
+	placed := metav1.Condition{
+		Type:               metalv1alpha1.BareMetalHostClaimSetConditionPlaced,
+		Status:             metav1.ConditionTrue,
+		Reason:             metalv1alpha1.BareMetalHostClaimSetReasonPlaced,
+		ObservedGeneration: set.Generation,
+	}
+	if timedOut := meta.FindStatusCondition(set.Status.Conditions, metalv1alpha1.BareMetalHostClaimSetConditionBindingTimedOut); timedOut != nil && len(missing) > 0 {
+		// the released members are placed again once the timeout passed again, so that the
+		// hosts are not claimed and released in a loop
+		if remaining := bindingTimeout(set) - time.Since(timedOut.LastTransitionTime.Time); remaining > 0 {
+			log.V(1).Info("Waiting before placing members again", "Remaining", remaining)
+			missing = nil
+			result = requeueEarlier(result, remaining)
+		}
+	}
+	if len(missing) > 0 {
+		log.V(1).Info("Placing members", "Missing", len(missing))
+		hosts, message, err := r.placeMembers(ctx, set, members, len(missing))
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if hosts == nil {
+			// no member is created unless all of them can be placed
+			log.V(1).Info("Insufficient hosts for members", "Message", message)
+			placed.Status = metav1.ConditionFalse
+			placed.Reason = metalv1alpha1.BareMetalHostClaimSetReasonInsufficientHosts
+			placed.Message = message
+		}
+		for i, host := range hosts {
+			member, err := r.createMember(ctx, set, missing[i], host)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			log.V(1).Info("Created member", "Member", member.Name, "Host", host.Name)
+			members[missing[i]] = member
+		}
+		if len(hosts) > 0 && set.Status.PlacedAt == nil {
+			now := metav1.Now()
+			set.Status.PlacedAt = &now
+			result = requeueEarlier(result, bindingTimeout(set))
+		}
+	}
+
+	set.Status.Replicas = int32(len(members))
+	set.Status.BoundReplicas = 0
+	for _, member := range members {
+		if member.Status.Phase == metalv1alpha1.PhaseBound {
+			set.Status.BoundReplicas++
+		}
+	}
+	set.Status.Phase = metalv1alpha1.PhaseUnbound
+	if set.Status.BoundReplicas == set.Spec.Replicas {
+		set.Status.Phase = metalv1alpha1.PhaseBound
+		set.Status.PlacedAt = nil
+		meta.RemoveStatusCondition(&set.Status.Conditions, metalv1alpha1.BareMetalHostClaimSetConditionBindingTimedOut)
+	}
+	if !expired {
+		meta.SetStatusCondition(&set.Status.Conditions, placed)
+	}
+	if err := r.Status().Patch(ctx, set, client.MergeFrom(setBase)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to patch host claim set status: %w", err)
+	}
+
+	log.V(1).Info("Reconciled host claim set", "Replicas", set.Status.Replicas, "BoundReplicas", set.Status.BoundReplicas)
+	return result, nil
+}
+
+// ensureBindingTimeout releases all members if not all of them are bound within the binding
+// timeout after they have been placed, as the set is only usable once all members are bound.
+// The released members are removed from the given members.
+func (r *BareMetalHostClaimSetReconciler) ensureBindingTimeout(ctx context.Context, log logr.Logger, set *metalv1alpha1.BareMetalHostClaimSet, members map[int]*metalv1alpha1.BareMetalHostClaim) (ctrl.Result, error) {
+	if set.Status.PlacedAt == nil {
+		return ctrl.Result{}, nil
+	}
+	var unbound []string
+	for _, member := range members {
+		if member.Status.Phase != metalv1alpha1.PhaseBound {
+			unbound = append(unbound, member.Name)
+		}
+	}
+	if len(unbound) == 0 {
+		return ctrl.Result{}, nil
+	}
+	timeout := bindingTimeout(set)
+	if remaining := timeout - time.Since(set.Status.PlacedAt.Time); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	sort.Strings(unbound)
+	log.V(1).Info("Releasing members as not all of them were bound in time", "Unbound", unbound)
+	if err := r.releaseMembers(ctx, members); err != nil {
+		return ctrl.Result{}, err
+	}
+	set.Status.PlacedAt = nil
+	meta.SetStatusCondition(&set.Status.Conditions, metav1.Condition{
+		Type:               metalv1alpha1.BareMetalHostClaimSetConditionBindingTimedOut,
+		Status:             metav1.ConditionTrue,
+		Reason:             metalv1alpha1.BareMetalHostClaimSetReasonMembersNotBound,
+		Message:            fmt.Sprintf("Members %s were not bound within %s, all members have been released", strings.Join(unbound, ", "), timeout),
+		ObservedGeneration: set.Generation,
+	})
+	return ctrl.Result{}, nil
+}
+
+// ensureExpiry releases all members once the lease of a member expired and reports whether the
+// set is expired, as the set is only usable with all members. An expired set places no members
+// until its spec changes. Otherwise the returned result requeues the set once the next lease of
+// a member expires.
+func (r *BareMetalHostClaimSetReconciler) ensureExpiry(ctx context.Context, log logr.Logger, set *metalv1alpha1.BareMetalHostClaimSet, members map[int]*metalv1alpha1.BareMetalHostClaim) (bool, ctrl.Result, error) {
+	if condition := meta.FindStatusCondition(set.Status.Conditions, metalv1alpha1.BareMetalHostClaimSetConditionExpired); condition != nil {
+		if condition.ObservedGeneration == set.Generation {
+			return true, ctrl.Result{}, r.releaseMembers(ctx, members)
+		}
+		log.V(1).Info("Spec of expired set changed: placing members again")
+		meta.RemoveStatusCondition(&set.Status.Conditions, metalv1alpha1.BareMetalHostClaimSetConditionExpired)
+	}
+
+	var expired *metalv1alpha1.BareMetalHostClaim
+	var next time.Duration
+	for _, member := range members {
+		if member.Status.ExpiresAt == nil {
+			continue
+		}
+		if remaining := time.Until(member.Status.ExpiresAt.Time); remaining > 0 {
+			if next == 0 || remaining < next {
+				next = remaining
+			}
+		} else if expired == nil || member.Name < expired.Name {
+			expired = member
+		}
+	}
+	if expired == nil {
+		return false, ctrl.Result{RequeueAfter: next}, nil
+	}
+
+	log.V(1).Info("Lease of member expired: releasing all members", "Member", expired.Name, "ExpiresAt", expired.Status.ExpiresAt)
+	set.Status.PlacedAt = nil
+	meta.SetStatusCondition(&set.Status.Conditions, metav1.Condition{
+		Type:   metalv1alpha1.BareMetalHostClaimSetConditionExpired,
+		Status: metav1.ConditionTrue,
+		Reason: metalv1alpha1.BareMetalHostClaimSetReasonLeaseExpired,
+		Message: fmt.Sprintf("The lease of member %s expired at %s, all members have been released",
+			expired.Name, expired.Status.ExpiresAt.UTC().Format(time.RFC3339)),
+		ObservedGeneration: set.Generation,
+	})
+	return true, ctrl.Result{}, r.releaseMembers(ctx, members)
+}
+
+// releaseMembers deletes the members, which deprovisions their hosts, and removes them from the
+// given members.
+func (r *BareMetalHostClaimSetReconciler) releaseMembers(ctx context.Context, members map[int]*metalv1alpha1.BareMetalHostClaim) error {
+	for index, member := range members {
+		if err := r.Delete(ctx, member); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete member claim %s: %w", member.Name, err)
+		}
+		delete(members, index)
+	}
+	return nil
+}
+
+// bindingTimeout returns the duration within which all members of the set have to be bound.
+func bindingTimeout(set *metalv1alpha1.BareMetalHostClaimSet) time.Duration {
+	if set.Spec.BindingTimeout != nil {
+		return set.Spec.BindingTimeout.Duration
+	}
+	return DefaultBindingTimeout
+}
+
+// requeueEarlier returns the result requeued after the duration unless it is requeued earlier.
+func requeueEarlier(result ctrl.Result, after time.Duration) ctrl.Result {
+	if result.RequeueAfter == 0 || after < result.RequeueAfter {
+		result.RequeueAfter = after
+	}
+	return result
+}
+
+// listMembers returns the member claims of the set by their index.
+func (r *BareMetalHostClaimSetReconciler) listMembers(ctx context.Context, set *metalv1alpha1.BareMetalHostClaimSet) (map[int]*metalv1alpha1.BareMetalHostClaim, error) {
+	claimList := &metalv1alpha1.BareMetalHostClaimList{}
+	if err := r.List(ctx, claimList, client.InNamespace(set.Namespace), client.MatchingLabels{metalv1alpha1.ClaimSetLabel: set.Name}); err != nil {
+		return nil, fmt.Errorf("failed to list member claims: %w", err)
+	}
+	members := map[int]*metalv1alpha1.BareMetalHostClaim{}
+	for i := range claimList.Items {
+		member := &claimList.Items[i]
+		index, err := strconv.Atoi(member.Labels[metalv1alpha1.ClaimSetIndexLabel])
+		if err != nil || !metav1.IsControlledBy(member, set) || !member.DeletionTimestamp.IsZero() {
+			continue
+		}
+		members[index] = member
+	}
+	return members, nil
+}
+
+// placeMembers selects hosts for the given number of members. The hosts match the selector of
+// the template, can be claimed, are admitted by the quotas of their pools and have distinct
+// values of the topology label, also compared to the hosts of the existing members. Nil is
+// returned together with the reason if not enough hosts are available.
+func (r *BareMetalHostClaimSetReconciler) placeMembers(ctx context.Context, set *metalv1alpha1.BareMetalHostClaimSet, members map[int]*metalv1alpha1.BareMetalHostClaim, count int) ([]*metalv1alpha1.BareMetalHost, string, error) {
+	if set.Spec.Template.Spec.BareMetalHostSelector == nil {
+		return nil, "The template has to select the hosts by labels", nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(set.Spec.Template.Spec.BareMetalHostSelector)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse host selector: %w", err)
+	}
+	hostList := &metalv1alpha1.BareMetalHostList{}
+	if err := r.List(ctx, hostList, client.MatchingLabelsSelector{Selector: selector}, client.UnsafeDisableDeepCopy); err != nil {
+		return nil, "", fmt.Errorf("failed to list hosts: %w", err)
+	}
+	claims, err := listUnboundClaims(ctx, r.Client)
+	if err != nil {
+		return nil, "", err
+	}
+	// the members are queued like a single claim created with the set
+	template := &metalv1alpha1.BareMetalHostClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         set.Namespace,
+			Name:              set.Name,
+			UID:               set.UID,
+			CreationTimestamp: set.CreationTimestamp,
+		},
+		Spec: set.Spec.Template.Spec,
+	}
+	usage, err := newQuotaUsage(ctx, r.Client, template)
+	if err != nil {
+		return nil, "", err
+	}
+	reservations, err := listHostReservations(ctx, r.Client, claims)
+	if err != nil {
+		return nil, "", err
+	}
+
+	referenced := map[string]bool{}
+	for _, claim := range claims {
+		if claim.Spec.BareMetalHostRef.Name != "" {
+			referenced[claim.Spec.BareMetalHostRef.Name] = true
+		}
+	}
+	topology := map[string]bool{}
+	if set.Spec.TopologyKey != "" {
+		for _, host := range hostList.Items {
+			for _, member := range members {
+				if member.Spec.BareMetalHostRef.Name == host.Name {
+					topology[host.Labels[set.Spec.TopologyKey]] = true
+				}
+			}
+		}
+	}
+
+	sort.Slice(hostList.Items, func(i, j int) bool { return hostList.Items[i].Name < hostList.Items[j].Name })
+	var hosts []*metalv1alpha1.BareMetalHost
+	quotaMessage := ""
+	for i := range hostList.Items {
+		host := &hostList.Items[i]
+		if len(hosts) == count {
+			break
+		}
+		if !isHostSelectable(host) || referenced[host.Name] || precedingClaim(template, host, claims, reservations) != nil {
+			continue
+		}
+		value, ok := host.Labels[set.Spec.TopologyKey]
+		if set.Spec.TopologyKey != "" && (!ok || topology[value]) {
+			continue
+		}
+		if _, message := usage.admit(host); message != "" {
+			quotaMessage = message
+			continue
+		}
+		hosts = append(hosts, host)
+		topology[value] = true
+		usage.add(host)
+	}
+	if len(hosts) < count {
+		message := fmt.Sprintf("%d of %d hosts are available", len(hosts), count)
+		if set.Spec.TopologyKey != "" {
+			message = fmt.Sprintf("%d of %d hosts with distinct values of label %s are available", len(hosts), count, set.Spec.TopologyKey)
+		}
+		if quotaMessage != "" {
+			message = fmt.Sprintf("%s: %s", message, quotaMessage)
+		}
+		return nil, message, nil
+	}
+	return hosts, "", nil
+}
+
+// memberSpec returns the spec of a member claim referencing the host.
+func memberSpec(set *metalv1alpha1.BareMetalHostClaimSet, hostName string) metalv1alpha1.BareMetalHostClaimSpec {
+	spec := *set.Spec.Template.Spec.DeepCopy()
+	spec.BareMetalHostRef = v1.LocalObjectReference{Name: hostName}
+	spec.BareMetalHostSelector = nil
+	return spec
+}
+
+// createMember creates the member claim with the index referencing the host.
+func (r *BareMetalHostClaimSetReconciler) createMember(ctx context.Context, set *metalv1alpha1.BareMetalHostClaimSet, index int, host *metalv1alpha1.BareMetalHost) (*metalv1alpha1.BareMetalHostClaim, error) {
+	member := &metalv1alpha1.BareMetalHostClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: set.Namespace,
+			Name:      fmt.Sprintf("%s-%d", set.Name, index),
+			Labels:    map[string]string{},
+		},
+		Spec: memberSpec(set, host.Name),
+	}
+	for key, value := range set.Spec.Template.Labels {
+		member.Labels[key] = value
+	}
+	member.Labels[metalv1alpha1.ClaimSetLabel] = set.Name
+	member.Labels[metalv1alpha1.ClaimSetIndexLabel] = strconv.Itoa(index)
+	if err := controllerutil.SetControllerReference(set, member, r.Scheme); err != nil {
+		return nil, fmt.Errorf("failed to set owner reference on member claim: %w", err)
+	}
+	if err := r.Create(ctx, member); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create member claim %s: %w", member.Name, err)
+	}
+	return member, nil
+}
+
+// updateMember applies changes of the template to the member claim. The host of the member is
+// kept.
+func (r *BareMetalHostClaimSetReconciler) updateMember(ctx context.Context, set *metalv1alpha1.BareMetalHostClaimSet, member *metalv1alpha1.BareMetalHostClaim) error {
+	spec := memberSpec(set, member.Spec.BareMetalHostRef.Name)
+	if equality.Semantic.DeepEqual(member.Spec, spec) {
+		return nil
+	}
+	memberBase := member.DeepCopy()
+	member.Spec = spec
+	if err := r.Patch(ctx, member, client.MergeFrom(memberBase)); err != nil {
+		return fmt.Errorf("failed to update member claim %s: %w", member.Name, err)
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BareMetalHostClaimSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&metalv1alpha1.BareMetalHostClaimSet{}).
+		Owns(&metalv1alpha1.BareMetalHostClaim{}).
+		Watches(&metalv1alpha1.BareMetalHost{}, r.enqueueUnplacedBareMetalHostClaimSets()).
+		Complete(r)
+}
+
+// enqueueUnplacedBareMetalHostClaimSets enqueues the sets whose members could not be placed,
+// so that they are placed once enough hosts are available.
+func (r *BareMetalHostClaimSetReconciler) enqueueUnplacedBareMetalHostClaimSets() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, _ client.Object) []reconcile.Request {
+		log := ctrl.LoggerFrom(ctx)
+
+		setList := &metalv1alpha1.BareMetalHostClaimSetList{}
+		if err := r.List(ctx, setList); err != nil {
+			log.Error(err, "failed to list host claim sets")
+			return nil
+		}
+		var req []reconcile.Request
+		for _, set := range setList.Items {
+			if meta.IsStatusConditionFalse(set.Status.Conditions, metalv1alpha1.BareMetalHostClaimSetConditionPlaced) {
+				req = append(req, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: set.Namespace, Name: set.Name},
+				})
+			}
+		}
+
+		return req
+	})
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"fmt"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("BareMetalHostClaimSet Controller", func() {
+	var ns *v1.Namespace
+
+	BeforeEach(func(ctx SpecContext) {
+		ns = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ns)
+	})
+
+	// members returns the member claims of the set by name.
+	members := func(ctx SpecContext, g Gomega, set *metalv1alpha1.BareMetalHostClaimSet) map[string]metalv1alpha1.BareMetalHostClaim {
+		claimList := &metalv1alpha1.BareMetalHostClaimList{}
+		g.Expect(k8sClient.List(ctx, claimList, client.InNamespace(ns.Name), client.MatchingLabels{metalv1alpha1.ClaimSetLabel: set.Name})).To(Succeed())
+		claims := map[string]metalv1alpha1.BareMetalHostClaim{}
+		for _, claim := range claimList.Items {
+			if claim.DeletionTimestamp.IsZero() {
+				claims[claim.Name] = claim
+			}
+		}
+		return claims
+	}
+
+	It("should claim hosts in distinct racks all at once", func(ctx SpecContext) {
+		var hosts []*metalv1alpha1.BareMetalHost
+		for i, rack := range []string{"r1", "r1", "r2"} {
+			_, host := setupFakeHost(ctx, fmt.Sprintf("eeeeeeee-eeee-eeee-eeee-00000000000%d", i))
+			labelHost(ctx, host, map[string]string{"gang": "control-plane", "rack": rack})
+			hosts = append(hosts, host)
+		}
+
+		set := &metalv1alpha1.BareMetalHostClaimSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: "control-plane"},
+			Spec: metalv1alpha1.BareMetalHostClaimSetSpec{
+				Replicas:    2,
+				TopologyKey: "rack",
+				Template: metalv1alpha1.BareMetalHostClaimTemplate{
+					Labels: map[string]string{"role": "control-plane"},
+					Spec: metalv1alpha1.BareMetalHostClaimSpec{
+						Power:                 metalv1alpha1.PowerStateOff,
+						BareMetalHostSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"gang": "control-plane"}},
+						Image:                 "foo:latest",
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, set)).To(Succeed())
+		DeferCleanup(func(ctx SpecContext) {
+			// the member claims are not garbage collected by envtest
+			Expect(k8sClient.DeleteAllOf(ctx, &metalv1alpha1.BareMetalHostClaim{}, client.InNamespace(ns.Name))).To(Succeed())
+			Expect(k8sClient.Delete(ctx, set)).To(Succeed())
+		})
+
+		By("Ensuring that the members are bound to hosts in distinct racks")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(set), set)).To(Succeed())
+			g.Expect(set.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+			g.Expect(set.Status.BoundReplicas).To(BeEquivalentTo(2))
+			claims := members(ctx, g, set)
+			g.Expect(claims).To(HaveLen(2))
+			racks := map[string]bool{}
+			for _, claim := range claims {
+				g.Expect(claim.Labels).To(HaveKeyWithValue("role", "control-plane"))
+				host := &metalv1alpha1.BareMetalHost{}
+				g.Expect(k8sClient.Get(ctx, client.ObjectKey{Name: claim.Spec.BareMetalHostRef.Name}, host)).To(Succeed())
+				g.Expect(host.Spec.ClaimRef).NotTo(BeNil())
+				racks[host.Labels["rack"]] = true
+			}
+			g.Expect(racks).To(HaveLen(2))
+		}).Should(Succeed())
+
+		By("Scaling up beyond the available racks")
+		setBase := set.DeepCopy()
+		set.Spec.Replicas = 3
+		Expect(k8sClient.Patch(ctx, set, client.MergeFrom(setBase))).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(set), set)).To(Succeed())
+			condition := meta.FindStatusCondition(set.Status.Conditions, metalv1alpha1.BareMetalHostClaimSetConditionPlaced)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Reason).To(Equal(metalv1alpha1.BareMetalHostClaimSetReasonInsufficientHosts))
+			g.Expect(set.Status.Phase).To(Equal(metalv1alpha1.PhaseUnbound))
+		}).Should(Succeed())
+		Consistently(func(g Gomega) {
+			g.Expect(members(ctx, g, set)).To(HaveLen(2))
+		}, "1s").Should(Succeed())
+
+		By("Moving the free host to another rack")
+		for _, host := range hosts {
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			if host.Spec.ClaimRef == nil {
+				labelHost(ctx, host, map[string]string{"rack": "r3"})
+			}
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(set), set)).To(Succeed())
+			g.Expect(set.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+			g.Expect(set.Status.BoundReplicas).To(BeEquivalentTo(3))
+			g.Expect(members(ctx, g, set)).To(HaveKey("control-plane-2"))
+		}).Should(Succeed())
+
+		By("Scaling down")
+		setBase = set.DeepCopy()
+		set.Spec.Replicas = 1
+		Expect(k8sClient.Patch(ctx, set, client.MergeFrom(setBase))).To(Succeed())
+		Eventually(func(g Gomega) {
+			claims := members(ctx, g, set)
+			g.Expect(claims).To(HaveLen(1))
+			g.Expect(claims).To(HaveKey("control-plane-0"))
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(set), set)).To(Succeed())
+			g.Expect(set.Status.Replicas).To(BeEquivalentTo(1))
+		}).Should(Succeed())
+	})
+
+	It("should release all members if not all of them are bound within the binding timeout", func(ctx SpecContext) {
+		for i := 3; i < 5; i++ {
+			_, host := setupFakeHost(ctx, fmt.Sprintf("eeeeeeee-eeee-eeee-eeee-00000000000%d", i))
+			labelHost(ctx, host, map[string]string{"gang": "binding"})
+		}
+		// the pool has a single address, so that only one member is bound
+		pool := createIPPool(ctx, metalv1alpha1.IPPoolSpec{CIDR: "10.9.0.0/30", Gateway: "10.9.0.1"})
+
+		set := &metalv1alpha1.BareMetalHostClaimSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: "binding"},
+			Spec: metalv1alpha1.BareMetalHostClaimSetSpec{
+				Replicas:       2,
+				BindingTimeout: &metav1.Duration{Duration: 3 * time.Second},
+				Template: metalv1alpha1.BareMetalHostClaimTemplate{
+					Spec: metalv1alpha1.BareMetalHostClaimSpec{
+						Power:                 metalv1alpha1.PowerStateOff,
+						BareMetalHostSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"gang": "binding"}},
+						Image:                 "foo:latest",
+						IPAddresses: []metalv1alpha1.ClaimIPAddress{
+							{Name: "data", IPPoolRef: v1.LocalObjectReference{Name: pool.Name}, MACAddress: "02:00:00:00:09:01"},
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, set)).To(Succeed())
+		DeferCleanup(func(ctx SpecContext) {
+			// the member claims are not garbage collected by envtest
+			Expect(k8sClient.DeleteAllOf(ctx, &metalv1alpha1.BareMetalHostClaim{}, client.InNamespace(ns.Name))).To(Succeed())
+			Expect(k8sClient.Delete(ctx, set)).To(Succeed())
+		})
+
+		By("Ensuring that only one member is bound")
+		placed := map[types.UID]bool{}
+		Eventually(func(g Gomega) {
+			claims := members(ctx, g, set)
+			g.Expect(claims).To(HaveLen(2))
+			phases := map[metalv1alpha1.Phase]int{}
+			for _, claim := range claims {
+				phases[claim.Status.Phase]++
+				placed[claim.UID] = true
+			}
+			g.Expect(phases).To(HaveKeyWithValue(metalv1alpha1.PhaseBound, 1))
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(set), set)).To(Succeed())
+			g.Expect(set.Status.PlacedAt).NotTo(BeNil())
+			g.Expect(set.Status.Phase).To(Equal(metalv1alpha1.PhaseUnbound))
+		}).Should(Succeed())
+
+		By("Ensuring that all members are released after the binding timeout")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(set), set)).To(Succeed())
+			condition := meta.FindStatusCondition(set.Status.Conditions, metalv1alpha1.BareMetalHostClaimSetConditionBindingTimedOut)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			g.Expect(condition.Reason).To(Equal(metalv1alpha1.BareMetalHostClaimSetReasonMembersNotBound))
+			for _, claim := range members(ctx, g, set) {
+				g.Expect(placed).NotTo(HaveKey(claim.UID))
+			}
+		}).Should(Succeed())
+
+		By("Ensuring that the members are placed again after the timeout")
+		Eventually(func(g Gomega) {
+			claims := members(ctx, g, set)
+			g.Expect(claims).To(HaveLen(2))
+			for _, claim := range claims {
+				g.Expect(placed).NotTo(HaveKey(claim.UID))
+			}
+		}).WithTimeout(time.Minute).Should(Succeed())
+	})
+
+	It("should release all members once the lease of a member expired and not place them again", func(ctx SpecContext) {
+		for i := 5; i < 7; i++ {
+			_, host := setupFakeHost(ctx, fmt.Sprintf("eeeeeeee-eeee-eeee-eeee-00000000000%d", i))
+			labelHost(ctx, host, map[string]string{"gang": "lease"})
+		}
+
+		set := &metalv1alpha1.BareMetalHostClaimSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: "lease"},
+			Spec: metalv1alpha1.BareMetalHostClaimSetSpec{
+				Replicas: 2,
+				Template: metalv1alpha1.BareMetalHostClaimTemplate{
+					Spec: metalv1alpha1.BareMetalHostClaimSpec{
+						Power:                 metalv1alpha1.PowerStateOff,
+						BareMetalHostSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"gang": "lease"}},
+						Image:                 "foo:latest",
+						LeaseDuration:         &metav1.Duration{Duration: 5 * time.Second},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, set)).To(Succeed())
+		DeferCleanup(func(ctx SpecContext) {
+			// the member claims are not garbage collected by envtest
+			Expect(k8sClient.DeleteAllOf(ctx, &metalv1alpha1.BareMetalHostClaim{}, client.InNamespace(ns.Name))).To(Succeed())
+			Expect(k8sClient.Delete(ctx, set)).To(Succeed())
+		})
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(set), set)).To(Succeed())
+			g.Expect(set.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+		}).Should(Succeed())
+
+		By("Ensuring that the set expires and releases all members")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(set), set)).To(Succeed())
+			condition := meta.FindStatusCondition(set.Status.Conditions, metalv1alpha1.BareMetalHostClaimSetConditionExpired)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			g.Expect(condition.Reason).To(Equal(metalv1alpha1.BareMetalHostClaimSetReasonLeaseExpired))
+			g.Expect(members(ctx, g, set)).To(BeEmpty())
+		}).WithTimeout(time.Minute).Should(Succeed())
+		Consistently(func(g Gomega) {
+			g.Expect(members(ctx, g, set)).To(BeEmpty())
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(set), set)).To(Succeed())
+			g.Expect(set.Status.Phase).To(Equal(metalv1alpha1.PhaseUnbound))
+		}, "3s").Should(Succeed())
+
+		By("Extending the lease of the template")
+		setBase := set.DeepCopy()
+		set.Spec.Template.Spec.LeaseDuration = &metav1.Duration{Duration: time.Hour}
+		Expect(k8sClient.Patch(ctx, set, client.MergeFrom(setBase))).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(set), set)).To(Succeed())
+			g.Expect(set.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+			g.Expect(meta.FindStatusCondition(set.Status.Conditions, metalv1alpha1.BareMetalHostClaimSetConditionExpired)).To(BeNil())
+			g.Expect(members(ctx, g, set)).To(HaveLen(2))
+		}).WithTimeout(time.Minute).Should(Succeed())
+	})
+})
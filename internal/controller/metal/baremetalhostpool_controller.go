@@ -0,0 +1,172 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// BareMetalHostPoolReconciler reconciles a BareMetalHostPool object
+type BareMetalHostPoolReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhostpools,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhostpools/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhosts,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *BareMetalHostPoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	pool := &metalv1alpha1.BareMetalHostPool{}
+	if err := r.Get(ctx, req.NamespacedName, pool); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	return r.reconcileExists(ctx, log, pool)
+}
+
+func (r *BareMetalHostPoolReconciler) reconcileExists(ctx context.Context, log logr.Logger, pool *metalv1alpha1.BareMetalHostPool) (ctrl.Result, error) {
+	if !pool.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	return r.reconcile(ctx, log, pool)
+}
+
+func (r *BareMetalHostPoolReconciler) reconcile(ctx context.Context, log logr.Logger, pool *metalv1alpha1.BareMetalHostPool) (ctrl.Result, error) {
+	log.V(1).Info("Reconciling host pool")
+
+	selector, err := metav1.LabelSelectorAsSelector(&pool.Spec.HostSelector)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to parse host selector: %w", err)
+	}
+	hostList := &metalv1alpha1.BareMetalHostList{}
+	if err := r.List(ctx, hostList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list hosts: %w", err)
+	}
+
+	poolBase := pool.DeepCopy()
+	pool.Status = poolStatus(pool, hostList.Items)
+	if err := r.Status().Patch(ctx, pool, client.MergeFrom(poolBase)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to patch host pool status: %w", err)
+	}
+
+	log.V(1).Info("Reconciled host pool", "Capacity", pool.Status.Capacity, "Available", pool.Status.Available)
+	return ctrl.Result{}, nil
+}
+
+// poolStatus returns the capacity of the pool and the hosts claimed per namespace.
+func poolStatus(pool *metalv1alpha1.BareMetalHostPool, hosts []metalv1alpha1.BareMetalHost) metalv1alpha1.BareMetalHostPoolStatus {
+	status := metalv1alpha1.BareMetalHostPoolStatus{}
+	classes := map[string]*metalv1alpha1.HardwareClassCapacity{}
+	usage := map[string]*metalv1alpha1.NamespaceUsage{}
+	for _, quota := range pool.Spec.Quotas {
+		usage[quota.Namespace] = &metalv1alpha1.NamespaceUsage{Namespace: quota.Namespace}
+	}
+
+	for i := range hosts {
+		host := &hosts[i]
+		available := isHostSelectable(host)
+		status.Capacity++
+		if available {
+			status.Available++
+		}
+		class := host.Labels[metalv1alpha1.HardwareClassLabel]
+		if class != "" {
+			if classes[class] == nil {
+				classes[class] = &metalv1alpha1.HardwareClassCapacity{Class: class}
+			}
+			classes[class].Capacity++
+			if available {
+				classes[class].Available++
+			}
+		}
+
+		if host.Spec.ClaimRef == nil {
+			continue
+		}
+		namespace := host.Spec.ClaimRef.Namespace
+		if usage[namespace] == nil {
+			usage[namespace] = &metalv1alpha1.NamespaceUsage{Namespace: namespace}
+		}
+		usage[namespace].Hosts++
+		if class != "" {
+			usage[namespace].Classes = addClassUsage(usage[namespace].Classes, class)
+		}
+	}
+
+	for _, class := range classes {
+		status.Classes = append(status.Classes, *class)
+	}
+	sort.Slice(status.Classes, func(i, j int) bool { return status.Classes[i].Class < status.Classes[j].Class })
+	for _, namespaceUsage := range usage {
+		sort.Slice(namespaceUsage.Classes, func(i, j int) bool { return namespaceUsage.Classes[i].Class < namespaceUsage.Classes[j].Class })
+		status.Usage = append(status.Usage, *namespaceUsage)
+	}
+	sort.Slice(status.Usage, func(i, j int) bool { return status.Usage[i].Namespace < status.Usage[j].Namespace })
+	return status
+}
+
+func addClassUsage(classes []metalv1alpha1.HardwareClassUsage, class string) []metalv1alpha1.HardwareClassUsage {
+	for i := range classes {
+		if classes[i].Class == class {
+			classes[i].Hosts++
+			return classes
+		}
+	}
+	return append(classes, metalv1alpha1.HardwareClassUsage{Class: class, Hosts: 1})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BareMetalHostPoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&metalv1alpha1.BareMetalHostPool{}).
+		Watches(&metalv1alpha1.BareMetalHost{}, r.enqueueBareMetalHostPoolsByHost()).
+		Complete(r)
+}
+
+// enqueueBareMetalHostPoolsByHost enqueues all pools, as changed labels of a host might move
+// the host between pools.
+func (r *BareMetalHostPoolReconciler) enqueueBareMetalHostPoolsByHost() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, _ client.Object) []reconcile.Request {
+		log := ctrl.LoggerFrom(ctx)
+
+		poolList := &metalv1alpha1.BareMetalHostPoolList{}
+		if err := r.List(ctx, poolList); err != nil {
+			log.Error(err, "failed to list host pools")
+			return nil
+		}
+		req := make([]reconcile.Request, 0, len(poolList.Items))
+		for _, pool := range poolList.Items {
+			req = append(req, reconcile.Request{NamespacedName: types.NamespacedName{Name: pool.Name}})
+		}
+		return req
+	})
+}
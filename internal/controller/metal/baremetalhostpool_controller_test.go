@@ -0,0 +1,165 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// labelHost adds the labels to the host.
+func labelHost(ctx SpecContext, host *metalv1alpha1.BareMetalHost, labels map[string]string) {
+	hostBase := host.DeepCopy()
+	if host.Labels == nil {
+		host.Labels = map[string]string{}
+	}
+	for key, value := range labels {
+		host.Labels[key] = value
+	}
+	Expect(k8sClient.Patch(ctx, host, client.MergeFrom(hostBase))).To(Succeed())
+}
+
+// createPool creates a pool selecting the hosts with the pool label.
+func createPool(ctx SpecContext, name string, quotas ...metalv1alpha1.NamespaceQuota) *metalv1alpha1.BareMetalHostPool {
+	pool := &metalv1alpha1.BareMetalHostPool{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "pool-"},
+		Spec: metalv1alpha1.BareMetalHostPoolSpec{
+			HostSelector: metav1.LabelSelector{MatchLabels: map[string]string{"pool": name}},
+			Quotas:       quotas,
+		},
+	}
+	Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+	DeferCleanup(k8sClient.Delete, pool)
+	return pool
+}
+
+var _ = Describe("BareMetalHostPool Controller", func() {
+	var ns *v1.Namespace
+
+	BeforeEach(func(ctx SpecContext) {
+		ns = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ns)
+	})
+
+	It("should queue claims exceeding the quota of a namespace until hosts are released", func(ctx SpecContext) {
+		_, host1 := setupFakeHost(ctx, "bbbbbbbb-bbbb-bbbb-bbbb-000000000001")
+		_, host2 := setupFakeHost(ctx, "bbbbbbbb-bbbb-bbbb-bbbb-000000000002")
+		labelHost(ctx, host1, map[string]string{"pool": "quota", metalv1alpha1.HardwareClassLabel: "compute"})
+		labelHost(ctx, host2, map[string]string{"pool": "quota", metalv1alpha1.HardwareClassLabel: "compute"})
+		maxHosts := int32(1)
+		pool := createPool(ctx, "quota", metalv1alpha1.NamespaceQuota{Namespace: ns.Name, MaxHosts: &maxHosts})
+
+		By("Claiming the first host within the quota")
+		claim1, _ := createClaim(ctx, ns, host1, metalv1alpha1.ReprovisionPolicyOnChange)
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim1), claim1)).To(Succeed())
+			g.Expect(claim1.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+			g.Expect(meta.IsStatusConditionTrue(claim1.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionWithinQuota)).To(BeTrue())
+		}).Should(Succeed())
+
+		By("Ensuring that the pool reports its capacity and usage")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pool), pool)).To(Succeed())
+			g.Expect(pool.Status.Capacity).To(BeEquivalentTo(2))
+			g.Expect(pool.Status.Classes).To(ConsistOf(HaveField("Class", "compute")))
+			g.Expect(pool.Status.Usage).To(ConsistOf(metalv1alpha1.NamespaceUsage{
+				Namespace: ns.Name,
+				Hosts:     1,
+				Classes:   []metalv1alpha1.HardwareClassUsage{{Class: "compute", Hosts: 1}},
+			}))
+		}).Should(Succeed())
+
+		By("Ensuring that a second claim is queued")
+		claim2, _ := createClaim(ctx, ns, host2, metalv1alpha1.ReprovisionPolicyOnChange)
+		DeferCleanup(k8sClient.Delete, claim2)
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim2), claim2)).To(Succeed())
+			g.Expect(claim2.Status.Phase).To(Equal(metalv1alpha1.PhaseUnbound))
+			condition := meta.FindStatusCondition(claim2.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionWithinQuota)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			g.Expect(condition.Reason).To(Equal(metalv1alpha1.BareMetalHostClaimReasonQuotaExceeded))
+		}).Should(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host2), host2)).To(Succeed())
+		Expect(host2.Spec.ClaimRef).To(BeNil())
+
+		By("Releasing the first host")
+		Expect(k8sClient.Delete(ctx, claim1)).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim2), claim2)).To(Succeed())
+			g.Expect(claim2.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+			g.Expect(meta.IsStatusConditionTrue(claim2.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionWithinQuota)).To(BeTrue())
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host2), host2)).To(Succeed())
+			g.Expect(host2.Spec.ClaimRef).NotTo(BeNil())
+		}).Should(Succeed())
+	})
+
+	It("should select hosts of hardware classes with remaining quota", func(ctx SpecContext) {
+		_, gpuHost := setupFakeHost(ctx, "cccccccc-cccc-cccc-cccc-000000000001")
+		_, computeHost := setupFakeHost(ctx, "cccccccc-cccc-cccc-cccc-000000000002")
+		labelHost(ctx, gpuHost, map[string]string{"pool": "classes", metalv1alpha1.HardwareClassLabel: "gpu"})
+		labelHost(ctx, computeHost, map[string]string{"pool": "classes", metalv1alpha1.HardwareClassLabel: "compute"})
+		createPool(ctx, "classes", metalv1alpha1.NamespaceQuota{
+			Namespace: ns.Name,
+			Classes:   []metalv1alpha1.HardwareClassQuota{{Class: "gpu", MaxHosts: 0}},
+		})
+
+		claim := &metalv1alpha1.BareMetalHostClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "claim-"},
+			Spec: metalv1alpha1.BareMetalHostClaimSpec{
+				Power:                 metalv1alpha1.PowerStateOff,
+				BareMetalHostSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "classes"}},
+				Image:                 "foo:latest",
+			},
+		}
+		Expect(k8sClient.Create(ctx, claim)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, claim)
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Spec.BareMetalHostRef.Name).To(Equal(computeHost.Name))
+			g.Expect(claim.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+		}).Should(Succeed())
+
+		By("Ensuring that claims of namespaces without quota are refused")
+		otherNs := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, otherNs)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, otherNs)
+		otherClaim := &metalv1alpha1.BareMetalHostClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: otherNs.Name, GenerateName: "claim-"},
+			Spec: metalv1alpha1.BareMetalHostClaimSpec{
+				Power:                 metalv1alpha1.PowerStateOff,
+				BareMetalHostSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "classes"}},
+				Image:                 "foo:latest",
+			},
+		}
+		Expect(k8sClient.Create(ctx, otherClaim)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, otherClaim)
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(otherClaim), otherClaim)).To(Succeed())
+			g.Expect(otherClaim.Spec.BareMetalHostRef.Name).To(BeEmpty())
+			condition := meta.FindStatusCondition(otherClaim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionWithinQuota)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Message).To(ContainSubstring("has no quota"))
+		}).Should(Succeed())
+	})
+})
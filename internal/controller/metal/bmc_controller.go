@@ -0,0 +1,280 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/bmc"
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// BMCReconciler reconciles a BMC object
+type BMCReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// FakeBMCs keeps the state of the BMCs of type Fake.
+	FakeBMCs *bmc.FakeBMCs
+	// APIReader reads the inventory ConfigMaps of BMCs of type Fake, so that not all
+	// ConfigMaps of the cluster are cached.
+	APIReader client.Reader
+	// ResyncInterval is the interval in which the systems of a BMC are rediscovered.
+	ResyncInterval time.Duration
+}
+
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=bmcs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=bmcs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=bmcs/finalizers,verbs=update
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhosts,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *BMCReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	bmcObj := &metalv1alpha1.BMC{}
+	if err := r.Get(ctx, req.NamespacedName, bmcObj); err != nil {
+		if apierrors.IsNotFound(err) && r.FakeBMCs != nil {
+			r.FakeBMCs.Remove(req.Name)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	return r.reconcileExists(ctx, log, bmcObj)
+}
+
+func (r *BMCReconciler) reconcileExists(ctx context.Context, log logr.Logger, bmcObj *metalv1alpha1.BMC) (ctrl.Result, error) {
+	if !bmcObj.DeletionTimestamp.IsZero() {
+		// the discovered hosts are garbage collected via their owner references
+		return ctrl.Result{}, nil
+	}
+	return r.reconcile(ctx, log, bmcObj)
+}
+
+func (r *BMCReconciler) reconcile(ctx context.Context, log logr.Logger, bmcObj *metalv1alpha1.BMC) (ctrl.Result, error) {
+	log.V(1).Info("Reconciling BMC")
+
+	if err := r.syncFakeInventory(ctx, bmcObj); err != nil {
+		return ctrl.Result{}, r.patchError(ctx, bmcObj, err)
+	}
+
+	bmcClient, err := newBMCClient(ctx, r.Client, r.FakeBMCs, bmcObj, nil)
+	if err != nil {
+		return ctrl.Result{}, r.patchError(ctx, bmcObj, fmt.Errorf("failed to create BMC client: %w", err))
+	}
+	defer bmcClient.Logout()
+
+	log.V(1).Info("Getting manager info")
+	managerInfo, err := bmcClient.GetManagerInfo()
+	if err != nil {
+		return ctrl.Result{}, r.patchError(ctx, bmcObj, err)
+	}
+
+	log.V(1).Info("Discovering systems")
+	systems, err := bmcClient.GetSystems()
+	if err != nil {
+		return ctrl.Result{}, r.patchError(ctx, bmcObj, err)
+	}
+	log.V(1).Info("Discovered systems", "Systems", len(systems))
+
+	discovered, conflicts, err := r.ensureHosts(ctx, log, bmcObj, systems)
+	if err != nil {
+		return ctrl.Result{}, r.patchError(ctx, bmcObj, err)
+	}
+
+	bmcBase := bmcObj.DeepCopy()
+	bmcObj.Status.Manufacturer = managerInfo.Manufacturer
+	bmcObj.Status.Model = managerInfo.Model
+	bmcObj.Status.FirmwareVersion = managerInfo.FirmwareVersion
+	bmcObj.Status.State = metalv1alpha1.BMCStateReady
+	bmcObj.Status.Message = ""
+	bmcObj.Status.Systems = discovered
+	setHostConflictCondition(bmcObj, conflicts)
+	if err := r.Status().Patch(ctx, bmcObj, client.MergeFrom(bmcBase)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to patch BMC status: %w", err)
+	}
+
+	log.V(1).Info("Reconciled BMC")
+	return ctrl.Result{RequeueAfter: r.ResyncInterval}, nil
+}
+
+// ensureHosts creates or adopts a BareMetalHost for every discovered system. Systems whose
+// host name is taken by a host of another BMC or system are returned as conflicts and have
+// no host reference.
+func (r *BMCReconciler) ensureHosts(ctx context.Context, log logr.Logger, bmcObj *metalv1alpha1.BMC, systems []bmc.System) ([]metalv1alpha1.BMCSystem, []string, error) {
+	hostList := &metalv1alpha1.BareMetalHostList{}
+	if err := r.List(ctx, hostList); err != nil {
+		return nil, nil, fmt.Errorf("failed to list hosts: %w", err)
+	}
+
+	discovered := make([]metalv1alpha1.BMCSystem, 0, len(systems))
+	var conflicts []string
+	for _, system := range systems {
+		host := findHostForSystem(hostList.Items, bmcObj.Name, system.ID)
+		if host == nil {
+			var err error
+			host, err = r.createHost(ctx, log, bmcObj, system.ID)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if host == nil {
+			log.V(1).Info("Host name of system is taken", "SystemID", system.ID, "Host", objectName(bmcObj.Name, system.ID))
+			conflicts = append(conflicts, system.ID)
+			discovered = append(discovered, metalv1alpha1.BMCSystem{ID: system.ID, UUID: system.UUID})
+			continue
+		}
+		if !metav1.IsControlledBy(host, bmcObj) {
+			log.V(1).Info("Adopting host for system", "SystemID", system.ID, "Host", host.Name)
+			hostBase := host.DeepCopy()
+			if err := controllerutil.SetControllerReference(bmcObj, host, r.Scheme); err != nil {
+				return nil, nil, fmt.Errorf("failed to adopt host %s: %w", host.Name, err)
+			}
+			if err := r.Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
+				return nil, nil, fmt.Errorf("failed to adopt host %s: %w", host.Name, err)
+			}
+			log.V(1).Info("Adopted host for system", "SystemID", system.ID, "Host", host.Name)
+		}
+
+		discovered = append(discovered, metalv1alpha1.BMCSystem{
+			ID:               system.ID,
+			UUID:             system.UUID,
+			BareMetalHostRef: &v1.LocalObjectReference{Name: host.Name},
+		})
+	}
+
+	return discovered, conflicts, nil
+}
+
+// createHost creates the host of a system. If a host with the name of the system already
+// exists, it is returned if it belongs to the system and nil is returned otherwise.
+func (r *BMCReconciler) createHost(ctx context.Context, log logr.Logger, bmcObj *metalv1alpha1.BMC, systemID string) (*metalv1alpha1.BareMetalHost, error) {
+	log.V(1).Info("Creating host for system", "SystemID", systemID)
+	host := &metalv1alpha1.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: objectName(bmcObj.Name, systemID),
+		},
+		Spec: metalv1alpha1.BareMetalHostSpec{
+			SystemID: systemID,
+			Power:    metalv1alpha1.PowerStateOff,
+			BMCRef:   v1.LocalObjectReference{Name: bmcObj.Name},
+		},
+	}
+	if err := controllerutil.SetControllerReference(bmcObj, host, r.Scheme); err != nil {
+		return nil, fmt.Errorf("failed to set owner reference on host: %w", err)
+	}
+	if err := r.Create(ctx, host); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("failed to create host for system %s: %w", systemID, err)
+		}
+		existing := &metalv1alpha1.BareMetalHost{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(host), existing); err != nil {
+			return nil, fmt.Errorf("failed to get host for system %s: %w", systemID, err)
+		}
+		if existing.Spec.BMCRef.Name != bmcObj.Name || existing.Spec.SystemID != systemID {
+			return nil, nil
+		}
+		return existing, nil
+	}
+	log.V(1).Info("Created host for system", "SystemID", systemID, "Host", host.Name)
+	return host, nil
+}
+
+// syncFakeInventory synchronizes the systems of a BMC of type Fake with the inventory of its
+// ConfigMap. The inventory of the annotation is synchronized by every client.
+func (r *BMCReconciler) syncFakeInventory(ctx context.Context, bmcObj *metalv1alpha1.BMC) error {
+	ref, ok := bmcObj.Annotations[metalv1alpha1.FakeInventoryConfigMapAnnotation]
+	if bmcObj.Spec.Type != metalv1alpha1.BMCTypeFake || !ok || r.FakeBMCs == nil {
+		return nil
+	}
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok {
+		return fmt.Errorf("fake inventory ConfigMap %q is not of the form <namespace>/<name>", ref)
+	}
+	configMap := &v1.ConfigMap{}
+	if err := r.APIReader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, configMap); err != nil {
+		return fmt.Errorf("failed to get fake inventory ConfigMap: %w", err)
+	}
+	inventory := []bmc.FakeSystem{}
+	if err := json.Unmarshal([]byte(configMap.Data[metalv1alpha1.FakeInventoryKey]), &inventory); err != nil {
+		return fmt.Errorf("failed to parse fake inventory of ConfigMap %s: %w", ref, err)
+	}
+	r.FakeBMCs.NewFakeBMC(bmcObj.Name, "", inventory, 0)
+	return nil
+}
+
+func (r *BMCReconciler) patchError(ctx context.Context, bmcObj *metalv1alpha1.BMC, err error) error {
+	bmcBase := bmcObj.DeepCopy()
+	bmcObj.Status.State = metalv1alpha1.BMCStateError
+	bmcObj.Status.Message = err.Error()
+	if patchErr := r.Status().Patch(ctx, bmcObj, client.MergeFrom(bmcBase)); patchErr != nil {
+		return fmt.Errorf("failed to patch BMC status: %w", patchErr)
+	}
+	return err
+}
+
+// setHostConflictCondition sets the HostConflict condition of the BMC for the systems whose
+// host name is taken.
+func setHostConflictCondition(bmcObj *metalv1alpha1.BMC, conflicts []string) {
+	if len(conflicts) == 0 {
+		meta.SetStatusCondition(&bmcObj.Status.Conditions, metav1.Condition{
+			Type:               metalv1alpha1.BMCConditionHostConflict,
+			Status:             metav1.ConditionFalse,
+			Reason:             metalv1alpha1.BMCReasonNoConflict,
+			Message:            "All systems have a host",
+			ObservedGeneration: bmcObj.Generation,
+		})
+		return
+	}
+	meta.SetStatusCondition(&bmcObj.Status.Conditions, metav1.Condition{
+		Type:               metalv1alpha1.BMCConditionHostConflict,
+		Status:             metav1.ConditionTrue,
+		Reason:             metalv1alpha1.BMCReasonHostNameTaken,
+		Message:            fmt.Sprintf("Host names of systems %s are taken by other hosts", strings.Join(conflicts, ", ")),
+		ObservedGeneration: bmcObj.Generation,
+	})
+}
+
+func findHostForSystem(hosts []metalv1alpha1.BareMetalHost, bmcName, systemID string) *metalv1alpha1.BareMetalHost {
+	for i := range hosts {
+		if hosts[i].Spec.BMCRef.Name == bmcName && hosts[i].Spec.SystemID == systemID {
+			return &hosts[i]
+		}
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BMCReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&metalv1alpha1.BMC{}).
+		Owns(&metalv1alpha1.BareMetalHost{}).
+		Complete(r)
+}
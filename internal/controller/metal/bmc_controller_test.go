@@ -0,0 +1,255 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"fmt"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stmcginnis/gofish/redfish"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("BMC Controller", func() {
+	It("should manage the hosts of a fake BMC", func(ctx SpecContext) {
+		By("Creating a fake BMC")
+		bmcObj := &metalv1alpha1.BMC{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "fake-",
+				Annotations: map[string]string{
+					metalv1alpha1.FakeInventoryAnnotation: `[
+						{"id": "System-1", "uuid": "11111111-1111-1111-1111-111111111111",
+						 "networkInterfaces": [{"id": "1", "macAddress": "02:00:00:00:00:01"}]},
+						{"id": "System-2", "uuid": "22222222-2222-2222-2222-222222222222"}
+					]`,
+					metalv1alpha1.FakePowerTransitionDelayAnnotation: "0s",
+				},
+			},
+			Spec: metalv1alpha1.BMCSpec{
+				Type:    metalv1alpha1.BMCTypeFake,
+				Address: "fake://test",
+			},
+		}
+		Expect(k8sClient.Create(ctx, bmcObj)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, bmcObj)
+
+		By("Waiting for the systems to be discovered")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bmcObj), bmcObj)).To(Succeed())
+			g.Expect(bmcObj.Status.State).To(Equal(metalv1alpha1.BMCStateReady))
+			g.Expect(bmcObj.Status.Manufacturer).To(Equal("Fake"))
+			g.Expect(bmcObj.Status.Systems).To(ConsistOf(
+				HaveField("ID", "System-1"),
+				HaveField("ID", "System-2"),
+			))
+		}).Should(Succeed())
+
+		host := &metalv1alpha1.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{Name: objectName(bmcObj.Name, "System-1")},
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.SystemUUID).To(Equal("11111111-1111-1111-1111-111111111111"))
+			g.Expect(host.Status.NetworkInterfaces).To(ConsistOf(HaveField("MACAddress", "02:00:00:00:00:01")))
+			g.Expect(host.Status.PowerState).To(Equal(redfish.OffPowerState))
+			g.Expect(host.Status.State).To(Equal(metalv1alpha1.StateAvailable))
+			g.Expect(host.Status.BootOverrides).To(ContainElement(SatisfyAll(
+				HaveField("Enabled", redfish.OnceBootSourceOverrideEnabled),
+				HaveField("Target", redfish.PxeBootSourceOverrideTarget),
+			)))
+		}).Should(Succeed())
+
+		By("Powering on the host")
+		hostBase := host.DeepCopy()
+		host.Spec.Power = metalv1alpha1.PowerStateOn
+		Expect(k8sClient.Patch(ctx, host, client.MergeFrom(hostBase))).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.PowerActions).To(ConsistOf(HaveField("Action", metalv1alpha1.PowerStateOn)))
+		}).Should(Succeed())
+	})
+
+	It("should recreate deleted hosts of a BMC", func(ctx SpecContext) {
+		By("Creating a fake BMC")
+		bmcObj := newFakeBMCWithSystem("17171717-1717-1717-1717-171717171717")
+		Expect(k8sClient.Create(ctx, bmcObj)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, bmcObj)
+
+		host := &metalv1alpha1.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{Name: objectName(bmcObj.Name, "System-1")},
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(metav1.IsControlledBy(host, bmcObj)).To(BeTrue())
+		}).Should(Succeed())
+		uid := host.UID
+
+		By("Deleting the host")
+		Expect(k8sClient.Delete(ctx, host)).To(Succeed())
+
+		By("Expecting the host to be recreated")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.UID).NotTo(Equal(uid))
+			g.Expect(host.DeletionTimestamp).To(BeNil())
+		}).Should(Succeed())
+	})
+
+	It("should adopt an existing host of a system", func(ctx SpecContext) {
+		bmcObj := newFakeBMCWithSystem("18181818-1818-1818-1818-181818181818")
+		bmcObj.GenerateName = ""
+		bmcObj.Name = "fake-adopt"
+
+		By("Creating a host for the system without owner")
+		host := &metalv1alpha1.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{Name: "adopted-host"},
+			Spec: metalv1alpha1.BareMetalHostSpec{
+				SystemID: "System-1",
+				Power:    metalv1alpha1.PowerStateOff,
+				BMCRef:   v1.LocalObjectReference{Name: bmcObj.Name},
+			},
+		}
+		Expect(k8sClient.Create(ctx, host)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, host)
+
+		By("Creating the fake BMC")
+		Expect(k8sClient.Create(ctx, bmcObj)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, bmcObj)
+
+		By("Expecting the host to be adopted")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(metav1.IsControlledBy(host, bmcObj)).To(BeTrue())
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bmcObj), bmcObj)).To(Succeed())
+			g.Expect(bmcObj.Status.Systems).To(ConsistOf(
+				HaveField("BareMetalHostRef", &v1.LocalObjectReference{Name: host.Name}),
+			))
+		}).Should(Succeed())
+	})
+
+	It("should report a host name taken by an unrelated host", func(ctx SpecContext) {
+		bmcObj := newFakeBMCWithSystem("19191919-1919-1919-1919-191919191919")
+		bmcObj.GenerateName = ""
+		bmcObj.Name = "fake-conflict"
+
+		By("Creating an unrelated host with the name of the system host")
+		other := &metalv1alpha1.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{Name: objectName(bmcObj.Name, "System-1")},
+			Spec: metalv1alpha1.BareMetalHostSpec{
+				SystemID: "System-1",
+				Power:    metalv1alpha1.PowerStateOff,
+				BMCRef:   v1.LocalObjectReference{Name: "other"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, other)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, other)
+
+		By("Creating the fake BMC")
+		Expect(k8sClient.Create(ctx, bmcObj)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, bmcObj)
+
+		By("Expecting the conflict to be reported")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bmcObj), bmcObj)).To(Succeed())
+			g.Expect(bmcObj.Status.State).To(Equal(metalv1alpha1.BMCStateReady))
+			g.Expect(bmcObj.Status.Systems).To(ConsistOf(SatisfyAll(
+				HaveField("ID", "System-1"),
+				HaveField("BareMetalHostRef", BeNil()),
+			)))
+			condition := meta.FindStatusCondition(bmcObj.Status.Conditions, metalv1alpha1.BMCConditionHostConflict)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			g.Expect(condition.Reason).To(Equal(metalv1alpha1.BMCReasonHostNameTaken))
+		}).Should(Succeed())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(other), other)).To(Succeed())
+		Expect(other.OwnerReferences).To(BeEmpty())
+	})
+
+	It("should serve the inventory of a ConfigMap and drop the state of a deleted BMC", func(ctx SpecContext) {
+		By("Creating an inventory ConfigMap")
+		configMap := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "inventory-", Namespace: "oob"},
+			Data: map[string]string{
+				metalv1alpha1.FakeInventoryKey: `[{"id": "System-1", "uuid": "20202020-2020-2020-2020-202020202020"}]`,
+			},
+		}
+		Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, configMap)
+
+		By("Creating a fake BMC referencing the ConfigMap")
+		bmcObj := &metalv1alpha1.BMC{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "fake-",
+				Annotations: map[string]string{
+					metalv1alpha1.FakeInventoryConfigMapAnnotation:   configMap.Namespace + "/" + configMap.Name,
+					metalv1alpha1.FakePowerTransitionDelayAnnotation: "0s",
+				},
+			},
+			Spec: metalv1alpha1.BMCSpec{
+				Type:    metalv1alpha1.BMCTypeFake,
+				Address: "fake://test",
+			},
+		}
+		Expect(k8sClient.Create(ctx, bmcObj)).To(Succeed())
+
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bmcObj), bmcObj)).To(Succeed())
+			g.Expect(bmcObj.Status.Systems).To(ConsistOf(SatisfyAll(
+				HaveField("ID", "System-1"),
+				HaveField("UUID", "20202020-2020-2020-2020-202020202020"),
+			)))
+		}).Should(Succeed())
+		host := &metalv1alpha1.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{Name: objectName(bmcObj.Name, "System-1")},
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.SystemUUID).To(Equal("20202020-2020-2020-2020-202020202020"))
+		}).Should(Succeed())
+		Expect(fakeBMCs.AddConsoleOutput(bmcObj.Name, "System-1", "test\r\n")).To(Succeed())
+
+		By("Deleting the BMC")
+		Expect(k8sClient.Delete(ctx, bmcObj)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, host)
+		Eventually(func() error {
+			return fakeBMCs.AddConsoleOutput(bmcObj.Name, "System-1", "test\r\n")
+		}).Should(MatchError(ContainSubstring("no fake BMC found")))
+	})
+})
+
+// newFakeBMCWithSystem returns a fake BMC serving a single system with the given UUID.
+func newFakeBMCWithSystem(uuid string) *metalv1alpha1.BMC {
+	return &metalv1alpha1.BMC{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "fake-",
+			Annotations: map[string]string{
+				metalv1alpha1.FakeInventoryAnnotation:            fmt.Sprintf(`[{"id": "System-1", "uuid": %q}]`, uuid),
+				metalv1alpha1.FakePowerTransitionDelayAnnotation: "0s",
+			},
+		},
+		Spec: metalv1alpha1.BMCSpec{
+			Type:    metalv1alpha1.BMCTypeFake,
+			Address: "fake://test",
+		},
+	}
+}
@@ -0,0 +1,449 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"sort"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/discovery"
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	defaultRedfishPort = 443
+	// defaultDiscoveryChunkSize is the default number of addresses scanned per reconciliation.
+	defaultDiscoveryChunkSize = 256
+	// defaultAuthenticationRetryInterval is the default delay before the candidate credentials
+	// are tried again against an endpoint which accepted none of them.
+	defaultAuthenticationRetryInterval = 30 * time.Second
+	// maxAuthenticationRetryInterval caps the exponential backoff of authentication retries.
+	maxAuthenticationRetryInterval = time.Hour
+	// discoveryChunkInterval is the delay before the next chunk of a scan is scanned. It is
+	// fixed, so that the chunks of large ranges are not slowed down by the rate limiter.
+	discoveryChunkInterval = time.Second
+)
+
+// BMCDiscoveryReconciler reconciles a BMCDiscovery object
+type BMCDiscoveryReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// ChunkSize is the number of addresses scanned per reconciliation, so that large network
+	// ranges do not block a worker. Defaults to 256.
+	ChunkSize int
+	// AuthenticationRetryInterval is the initial delay before the candidate credentials are
+	// tried again against an endpoint which accepted none of them. The delay doubles with every
+	// failed attempt. Defaults to 30s.
+	AuthenticationRetryInterval time.Duration
+}
+
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=bmcdiscoveries,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=bmcdiscoveries/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=bmcdiscoveries/finalizers,verbs=update
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=bmcs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *BMCDiscoveryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	bmcDiscovery := &metalv1alpha1.BMCDiscovery{}
+	if err := r.Get(ctx, req.NamespacedName, bmcDiscovery); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	return r.reconcileExists(ctx, log, bmcDiscovery)
+}
+
+func (r *BMCDiscoveryReconciler) reconcileExists(ctx context.Context, log logr.Logger, bmcDiscovery *metalv1alpha1.BMCDiscovery) (ctrl.Result, error) {
+	if !bmcDiscovery.DeletionTimestamp.IsZero() {
+		// the registered BMCs are garbage collected via their owner references
+		return ctrl.Result{}, nil
+	}
+	return r.reconcile(ctx, log, bmcDiscovery)
+}
+
+func (r *BMCDiscoveryReconciler) reconcile(ctx context.Context, log logr.Logger, bmcDiscovery *metalv1alpha1.BMCDiscovery) (ctrl.Result, error) {
+	log.V(1).Info("Reconciling BMC discovery")
+
+	addrs, err := discovery.ExpandAddresses(bmcDiscovery.Spec.CIDRs)
+	if err != nil {
+		return ctrl.Result{}, r.patchError(ctx, bmcDiscovery, err)
+	}
+	credentials, err := r.getCredentials(ctx, bmcDiscovery)
+	if err != nil {
+		return ctrl.Result{}, r.patchError(ctx, bmcDiscovery, err)
+	}
+	bmcList := &metalv1alpha1.BMCList{}
+	if err := r.List(ctx, bmcList); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list BMCs: %w", err)
+	}
+	scanner := r.newScanner(bmcDiscovery)
+
+	bmcDiscoveryBase := bmcDiscovery.DeepCopy()
+	if requeueAfter, due := r.scanDue(bmcDiscovery); due {
+		if bmcDiscovery.Status.State != metalv1alpha1.BMCDiscoveryStateScanning ||
+			bmcDiscovery.Status.ObservedGeneration != bmcDiscovery.Generation {
+			log.V(1).Info("Starting scan", "Addresses", len(addrs))
+			bmcDiscovery.Status.State = metalv1alpha1.BMCDiscoveryStateScanning
+			bmcDiscovery.Status.Message = ""
+			bmcDiscovery.Status.ObservedGeneration = bmcDiscovery.Generation
+			bmcDiscovery.Status.ScannedAddresses = 0
+			bmcDiscovery.Status.TotalAddresses = int32(len(addrs))
+		}
+		if err := r.scanChunk(ctx, log, bmcDiscovery, bmcList.Items, scanner, addrs, credentials); err != nil {
+			return ctrl.Result{}, r.patchError(ctx, bmcDiscovery, err)
+		}
+	} else {
+		log.V(1).Info("Scan not due yet", "RequeueAfter", requeueAfter)
+	}
+
+	if err := r.retryAuthentication(ctx, log, bmcDiscovery, bmcList.Items, scanner, credentials); err != nil {
+		return ctrl.Result{}, r.patchError(ctx, bmcDiscovery, err)
+	}
+
+	if err := r.Status().Patch(ctx, bmcDiscovery, client.MergeFrom(bmcDiscoveryBase)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to patch BMC discovery status: %w", err)
+	}
+
+	log.V(1).Info("Reconciled BMC discovery")
+	if bmcDiscovery.Status.State == metalv1alpha1.BMCDiscoveryStateScanning {
+		// the next chunk is scanned in a separate reconciliation
+		return ctrl.Result{RequeueAfter: discoveryChunkInterval}, nil
+	}
+	return ctrl.Result{RequeueAfter: r.nextRequeue(bmcDiscovery)}, nil
+}
+
+func (r *BMCDiscoveryReconciler) newScanner(bmcDiscovery *metalv1alpha1.BMCDiscovery) *discovery.Scanner {
+	var timeout metav1.Duration
+	if bmcDiscovery.Spec.Timeout != nil {
+		timeout = *bmcDiscovery.Spec.Timeout
+	}
+	return discovery.NewScanner(int(bmcDiscovery.Spec.Concurrency), timeout.Duration, bmcDiscovery.Spec.ProbeIPMI)
+}
+
+// scanChunk scans the next chunk of addresses of the current scan and updates the endpoints
+// of these addresses. The scan is completed with the last chunk.
+func (r *BMCDiscoveryReconciler) scanChunk(ctx context.Context, log logr.Logger, bmcDiscovery *metalv1alpha1.BMCDiscovery, bmcs []metalv1alpha1.BMC, scanner *discovery.Scanner, addrs []netip.Addr, candidates []credentials) error {
+	ports := bmcDiscovery.Spec.Ports
+	if len(ports) == 0 {
+		ports = []int32{defaultRedfishPort}
+	}
+	scheme := bmcDiscovery.Spec.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	chunkSize := r.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultDiscoveryChunkSize
+	}
+
+	first := min(int(bmcDiscovery.Status.ScannedAddresses), len(addrs))
+	last := min(first+chunkSize, len(addrs))
+	chunk := addrs[first:last]
+
+	log.V(1).Info("Scanning network ranges", "From", first, "To", last, "Addresses", len(addrs), "Ports", ports)
+	endpoints := scanner.Scan(ctx, chunk, scheme, ports)
+	log.V(1).Info("Scanned network ranges", "Endpoints", len(endpoints))
+
+	// the endpoints of the scanned addresses are replaced by the ones found now
+	scanned := make(map[netip.Addr]bool, len(chunk))
+	for _, addr := range chunk {
+		scanned[addr] = true
+	}
+	previous := make(map[string]metalv1alpha1.DiscoveredEndpoint)
+	kept := make([]metalv1alpha1.DiscoveredEndpoint, 0, len(bmcDiscovery.Status.Endpoints))
+	for _, endpoint := range bmcDiscovery.Status.Endpoints {
+		addr, ok := endpointAddr(endpoint.Address)
+		if ok && scanned[addr] {
+			previous[endpoint.Address] = endpoint
+			continue
+		}
+		kept = append(kept, endpoint)
+	}
+
+	for _, endpoint := range endpoints {
+		discoveredEndpoint := metalv1alpha1.DiscoveredEndpoint{
+			Address:  endpoint.Address,
+			Protocol: metalv1alpha1.DiscoveredEndpointProtocol(endpoint.Protocol),
+			Vendor:   endpoint.Vendor,
+			Product:  endpoint.Product,
+		}
+		if endpoint.Protocol != discovery.ProtocolRedfish {
+			discoveredEndpoint.State = metalv1alpha1.DiscoveredEndpointStateUnsupported
+			kept = append(kept, discoveredEndpoint)
+			continue
+		}
+
+		discoveredEndpoint.AuthenticationAttempts = previous[endpoint.Address].AuthenticationAttempts
+		if err := r.registerEndpoint(ctx, log, bmcDiscovery, bmcs, scanner, &discoveredEndpoint, candidates); err != nil {
+			return err
+		}
+		kept = append(kept, discoveredEndpoint)
+	}
+
+	bmcDiscovery.Status.ScannedAddresses = int32(last)
+	if last == len(addrs) {
+		// endpoints outside of the network ranges are left from a scan of a previous spec
+		inRanges := make(map[netip.Addr]bool, len(addrs))
+		for _, addr := range addrs {
+			inRanges[addr] = true
+		}
+		kept = slices.DeleteFunc(kept, func(endpoint metalv1alpha1.DiscoveredEndpoint) bool {
+			addr, ok := endpointAddr(endpoint.Address)
+			return !ok || !inRanges[addr]
+		})
+		now := metav1.Now()
+		bmcDiscovery.Status.State = metalv1alpha1.BMCDiscoveryStateScanned
+		bmcDiscovery.Status.LastScanTime = &now
+		log.V(1).Info("Completed scan")
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Address < kept[j].Address })
+	bmcDiscovery.Status.Endpoints = kept
+	return nil
+}
+
+// retryAuthentication tries the candidate credentials again against the Unauthenticated
+// endpoints whose backoff elapsed.
+func (r *BMCDiscoveryReconciler) retryAuthentication(ctx context.Context, log logr.Logger, bmcDiscovery *metalv1alpha1.BMCDiscovery, bmcs []metalv1alpha1.BMC, scanner *discovery.Scanner, candidates []credentials) error {
+	for i := range bmcDiscovery.Status.Endpoints {
+		endpoint := &bmcDiscovery.Status.Endpoints[i]
+		if endpoint.State != metalv1alpha1.DiscoveredEndpointStateUnauthenticated ||
+			endpoint.NextAuthenticationTime == nil || time.Now().Before(endpoint.NextAuthenticationTime.Time) {
+			continue
+		}
+		log.V(1).Info("Retrying authentication against endpoint", "Address", endpoint.Address, "Attempts", endpoint.AuthenticationAttempts)
+		if err := r.registerEndpoint(ctx, log, bmcDiscovery, bmcs, scanner, endpoint, candidates); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerEndpoint registers a BMC for the given Redfish endpoint and updates its state. If
+// none of the credentials are accepted, the next attempt is scheduled with an exponential
+// backoff.
+func (r *BMCDiscoveryReconciler) registerEndpoint(ctx context.Context, log logr.Logger, bmcDiscovery *metalv1alpha1.BMCDiscovery, bmcs []metalv1alpha1.BMC, scanner *discovery.Scanner, endpoint *metalv1alpha1.DiscoveredEndpoint, candidates []credentials) error {
+	bmcObj, err := r.ensureBMC(ctx, log, bmcDiscovery, bmcs, scanner, endpoint.Address, candidates)
+	if err != nil {
+		return err
+	}
+	if bmcObj != nil {
+		endpoint.State = metalv1alpha1.DiscoveredEndpointStateRegistered
+		endpoint.BMCRef = &v1.LocalObjectReference{Name: bmcObj.Name}
+		endpoint.AuthenticationAttempts = 0
+		endpoint.NextAuthenticationTime = nil
+		return nil
+	}
+	endpoint.State = metalv1alpha1.DiscoveredEndpointStateUnauthenticated
+	endpoint.BMCRef = nil
+	endpoint.AuthenticationAttempts++
+	next := metav1.NewTime(time.Now().Add(r.authenticationBackoff(endpoint.AuthenticationAttempts)))
+	endpoint.NextAuthenticationTime = &next
+	return nil
+}
+
+// authenticationBackoff returns the delay before the next authentication attempt after the
+// given number of failed attempts.
+func (r *BMCDiscoveryReconciler) authenticationBackoff(attempts int32) time.Duration {
+	interval := r.AuthenticationRetryInterval
+	if interval <= 0 {
+		interval = defaultAuthenticationRetryInterval
+	}
+	for i := int32(1); i < attempts && interval < maxAuthenticationRetryInterval; i++ {
+		interval *= 2
+	}
+	return min(interval, maxAuthenticationRetryInterval)
+}
+
+// nextRequeue returns the duration until the next scan or authentication retry is due, or
+// zero if none is due.
+func (r *BMCDiscoveryReconciler) nextRequeue(bmcDiscovery *metalv1alpha1.BMCDiscovery) time.Duration {
+	var next time.Duration
+	if bmcDiscovery.Spec.RescanInterval != nil && bmcDiscovery.Status.LastScanTime != nil {
+		next = max(time.Until(bmcDiscovery.Status.LastScanTime.Add(bmcDiscovery.Spec.RescanInterval.Duration)), time.Second)
+	}
+	for _, endpoint := range bmcDiscovery.Status.Endpoints {
+		if endpoint.State != metalv1alpha1.DiscoveredEndpointStateUnauthenticated || endpoint.NextAuthenticationTime == nil {
+			continue
+		}
+		if retry := max(time.Until(endpoint.NextAuthenticationTime.Time), time.Second); next == 0 || retry < next {
+			next = retry
+		}
+	}
+	return next
+}
+
+// scanDue checks whether the network ranges have to be scanned, either because a scan is in
+// progress or a new scan is due. Otherwise, it returns the duration until the next scan is due.
+func (r *BMCDiscoveryReconciler) scanDue(bmcDiscovery *metalv1alpha1.BMCDiscovery) (time.Duration, bool) {
+	lastScan := bmcDiscovery.Status.LastScanTime
+	if bmcDiscovery.Status.State == metalv1alpha1.BMCDiscoveryStateScanning ||
+		lastScan == nil ||
+		bmcDiscovery.Status.State != metalv1alpha1.BMCDiscoveryStateScanned ||
+		bmcDiscovery.Status.ObservedGeneration != bmcDiscovery.Generation {
+		return 0, true
+	}
+	if bmcDiscovery.Spec.RescanInterval == nil {
+		return 0, false
+	}
+	if remaining := time.Until(lastScan.Add(bmcDiscovery.Spec.RescanInterval.Duration)); remaining > 0 {
+		return remaining, false
+	}
+	return 0, true
+}
+
+// endpointAddr returns the IP address of a Redfish endpoint URL or an IPMI host:port.
+func endpointAddr(address string) (netip.Addr, bool) {
+	host := address
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr, true
+}
+
+type credentials struct {
+	secretRef v1.SecretReference
+	username  string
+	password  string
+}
+
+func (r *BMCDiscoveryReconciler) getCredentials(ctx context.Context, bmcDiscovery *metalv1alpha1.BMCDiscovery) ([]credentials, error) {
+	result := make([]credentials, 0, len(bmcDiscovery.Spec.CredentialsSecretRefs))
+	for _, secretRef := range bmcDiscovery.Spec.CredentialsSecretRefs {
+		secret := &v1.Secret{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: secretRef.Namespace, Name: secretRef.Name}, secret); err != nil {
+			return nil, fmt.Errorf("failed to get credentials secret %s/%s: %w", secretRef.Namespace, secretRef.Name, err)
+		}
+		username, ok := secret.Data["username"]
+		if !ok {
+			return nil, fmt.Errorf("no username provided in credentials secret %s/%s", secretRef.Namespace, secretRef.Name)
+		}
+		password, ok := secret.Data["password"]
+		if !ok {
+			return nil, fmt.Errorf("no password provided in credentials secret %s/%s", secretRef.Namespace, secretRef.Name)
+		}
+		result = append(result, credentials{secretRef: secretRef, username: string(username), password: string(password)})
+	}
+	return result, nil
+}
+
+// ensureBMC registers a BMC for the given endpoint using the first accepted credentials. It
+// returns nil if none of the credentials are accepted.
+func (r *BMCDiscoveryReconciler) ensureBMC(ctx context.Context, log logr.Logger, bmcDiscovery *metalv1alpha1.BMCDiscovery, bmcs []metalv1alpha1.BMC, scanner *discovery.Scanner, address string, candidates []credentials) (*metalv1alpha1.BMC, error) {
+	for i := range bmcs {
+		if bmcs[i].Spec.Address == address {
+			return &bmcs[i], nil
+		}
+	}
+
+	for _, candidate := range candidates {
+		ok, err := scanner.Authenticate(ctx, address, candidate.username, candidate.password)
+		if err != nil {
+			log.V(1).Info("Failed to authenticate against endpoint", "Address", address, "Error", err.Error())
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse endpoint address %s: %w", address, err)
+		}
+		tls := &metalv1alpha1.BMCTLS{InsecureSkipVerify: true}
+		if bmcDiscovery.Spec.TLS != nil {
+			tls = bmcDiscovery.Spec.TLS.DeepCopy()
+		}
+		bmcObj := &metalv1alpha1.BMC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: objectName(bmcDiscovery.Name, u.Hostname(), u.Port()),
+			},
+			Spec: metalv1alpha1.BMCSpec{
+				Type:      metalv1alpha1.BMCTypeRedfish,
+				Address:   address,
+				BasicAuth: true,
+				SecretRef: candidate.secretRef,
+				TLS:       tls,
+			},
+		}
+		if err := controllerutil.SetControllerReference(bmcDiscovery, bmcObj, r.Scheme); err != nil {
+			return nil, fmt.Errorf("failed to set owner reference on BMC: %w", err)
+		}
+		log.V(1).Info("Registering BMC for endpoint", "Address", address, "BMC", bmcObj.Name)
+		if err := r.Create(ctx, bmcObj); err != nil {
+			if !apierrors.IsAlreadyExists(err) {
+				return nil, fmt.Errorf("failed to create BMC for endpoint %s: %w", address, err)
+			}
+			existing := &metalv1alpha1.BMC{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(bmcObj), existing); err != nil {
+				return nil, fmt.Errorf("failed to get BMC %s: %w", bmcObj.Name, err)
+			}
+			if existing.Spec.Address == address {
+				// the BMC has been registered by a previous reconciliation which is not cached yet
+				log.V(1).Info("Adopted BMC for endpoint", "Address", address, "BMC", existing.Name)
+				return existing, nil
+			}
+			// the name is taken by the BMC of another endpoint
+			bmcObj.GenerateName = bmcObj.Name + "-"
+			bmcObj.Name = ""
+			if err := r.Create(ctx, bmcObj); err != nil {
+				return nil, fmt.Errorf("failed to create BMC for endpoint %s: %w", address, err)
+			}
+		}
+		log.V(1).Info("Registered BMC for endpoint", "Address", address, "BMC", bmcObj.Name)
+		return bmcObj, nil
+	}
+	return nil, nil
+}
+
+func (r *BMCDiscoveryReconciler) patchError(ctx context.Context, bmcDiscovery *metalv1alpha1.BMCDiscovery, err error) error {
+	bmcDiscoveryBase := bmcDiscovery.DeepCopy()
+	bmcDiscovery.Status.State = metalv1alpha1.BMCDiscoveryStateFailed
+	bmcDiscovery.Status.Message = err.Error()
+	if patchErr := r.Status().Patch(ctx, bmcDiscovery, client.MergeFrom(bmcDiscoveryBase)); patchErr != nil {
+		return fmt.Errorf("failed to patch BMC discovery status: %w", patchErr)
+	}
+	return err
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BMCDiscoveryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&metalv1alpha1.BMCDiscovery{}).
+		Complete(r)
+}
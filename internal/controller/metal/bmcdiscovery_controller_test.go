@@ -0,0 +1,181 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("BMCDiscovery Controller", func() {
+	// startRedfishEndpoint starts a Redfish endpoint accepting the credentials admin:secret and
+	// returns it with its port.
+	startRedfishEndpoint := func() (*httptest.Server, int) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/redfish/v1/", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"RedfishVersion":"1.11.0","Vendor":"Contoso","Product":"Stub"}`))
+		})
+		mux.HandleFunc("/redfish/v1/Systems", func(w http.ResponseWriter, r *http.Request) {
+			if u, p, ok := r.BasicAuth(); !ok || u != "admin" || p != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"Members":[]}`))
+		})
+		server := httptest.NewServer(mux)
+		DeferCleanup(server.Close)
+		serverURL, err := url.Parse(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		port, err := strconv.Atoi(serverURL.Port())
+		Expect(err).NotTo(HaveOccurred())
+		return server, port
+	}
+
+	It("should scan in chunks and retry the authentication of endpoints", func(ctx SpecContext) {
+		By("Starting a Redfish endpoint")
+		server, port := startRedfishEndpoint()
+
+		By("Creating credentials which are not accepted")
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "credentials-", Namespace: "oob"},
+			Data: map[string][]byte{
+				"username": []byte("admin"),
+				"password": []byte("wrong"),
+			},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, secret)
+
+		By("Creating a BMC discovery")
+		bmcDiscovery := &metalv1alpha1.BMCDiscovery{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "discovery-"},
+			Spec: metalv1alpha1.BMCDiscoverySpec{
+				// contains 127.0.0.1 and 127.0.0.2 which are scanned in separate chunks
+				CIDRs:                 []string{"127.0.0.0/30"},
+				Ports:                 []int32{int32(port)},
+				Scheme:                "http",
+				CredentialsSecretRefs: []v1.SecretReference{{Namespace: secret.Namespace, Name: secret.Name}},
+				Concurrency:           1,
+			},
+		}
+		Expect(k8sClient.Create(ctx, bmcDiscovery)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, bmcDiscovery)
+
+		By("Expecting the endpoint to be unauthenticated after the scan")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bmcDiscovery), bmcDiscovery)).To(Succeed())
+			g.Expect(bmcDiscovery.Status.State).To(Equal(metalv1alpha1.BMCDiscoveryStateScanned))
+			g.Expect(bmcDiscovery.Status.ScannedAddresses).To(Equal(int32(2)))
+			g.Expect(bmcDiscovery.Status.TotalAddresses).To(Equal(int32(2)))
+			g.Expect(bmcDiscovery.Status.Endpoints).To(ConsistOf(SatisfyAll(
+				HaveField("Address", server.URL),
+				HaveField("State", metalv1alpha1.DiscoveredEndpointStateUnauthenticated),
+				HaveField("AuthenticationAttempts", BeNumerically(">=", 1)),
+				HaveField("NextAuthenticationTime", Not(BeNil())),
+			)))
+		}).Should(Succeed())
+
+		By("Fixing the credentials")
+		secretBase := secret.DeepCopy()
+		secret.Data["password"] = []byte("secret")
+		Expect(k8sClient.Patch(ctx, secret, client.MergeFrom(secretBase))).To(Succeed())
+
+		By("Expecting the endpoint to be registered by a retry")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bmcDiscovery), bmcDiscovery)).To(Succeed())
+			g.Expect(bmcDiscovery.Status.Endpoints).To(ConsistOf(SatisfyAll(
+				HaveField("State", metalv1alpha1.DiscoveredEndpointStateRegistered),
+				HaveField("BMCRef", Not(BeNil())),
+				HaveField("AuthenticationAttempts", BeZero()),
+			)))
+		}).Should(Succeed())
+		bmcObj := &metalv1alpha1.BMC{
+			ObjectMeta: metav1.ObjectMeta{Name: bmcDiscovery.Status.Endpoints[0].BMCRef.Name},
+		}
+		// the owner references are not garbage collected by the test environment
+		DeferCleanup(k8sClient.Delete, bmcObj)
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bmcObj), bmcObj)).To(Succeed())
+		Expect(bmcObj.Spec.Address).To(Equal(server.URL))
+	})
+
+	It("should register endpoints whose BMC name is taken by another endpoint", func(ctx SpecContext) {
+		server, port := startRedfishEndpoint()
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "credentials-", Namespace: "oob"},
+			Data: map[string][]byte{
+				"username": []byte("admin"),
+				"password": []byte("secret"),
+			},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, secret)
+
+		By("Creating a BMC of another endpoint with the name of the BMC of the endpoint")
+		taken := &metalv1alpha1.BMC{
+			ObjectMeta: metav1.ObjectMeta{Name: objectName("discovery-taken", "127.0.0.1", strconv.Itoa(port))},
+			Spec: metalv1alpha1.BMCSpec{
+				Type:    metalv1alpha1.BMCTypeFake,
+				Address: "fake://taken",
+			},
+		}
+		Expect(k8sClient.Create(ctx, taken)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, taken)
+
+		bmcDiscovery := &metalv1alpha1.BMCDiscovery{
+			ObjectMeta: metav1.ObjectMeta{Name: "discovery-taken"},
+			Spec: metalv1alpha1.BMCDiscoverySpec{
+				CIDRs:                 []string{"127.0.0.1/32"},
+				Ports:                 []int32{int32(port)},
+				Scheme:                "http",
+				CredentialsSecretRefs: []v1.SecretReference{{Namespace: secret.Namespace, Name: secret.Name}},
+				Concurrency:           1,
+				TLS:                   &metalv1alpha1.BMCTLS{},
+			},
+		}
+		Expect(k8sClient.Create(ctx, bmcDiscovery)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, bmcDiscovery)
+
+		By("Expecting the endpoint to be registered with a generated name")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bmcDiscovery), bmcDiscovery)).To(Succeed())
+			g.Expect(bmcDiscovery.Status.State).To(Equal(metalv1alpha1.BMCDiscoveryStateScanned))
+			g.Expect(bmcDiscovery.Status.Endpoints).To(ConsistOf(SatisfyAll(
+				HaveField("Address", server.URL),
+				HaveField("State", metalv1alpha1.DiscoveredEndpointStateRegistered),
+				HaveField("BMCRef.Name", HavePrefix(taken.Name+"-")),
+			)))
+		}).Should(Succeed())
+		bmcObj := &metalv1alpha1.BMC{
+			ObjectMeta: metav1.ObjectMeta{Name: bmcDiscovery.Status.Endpoints[0].BMCRef.Name},
+		}
+		DeferCleanup(k8sClient.Delete, bmcObj)
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bmcObj), bmcObj)).To(Succeed())
+		Expect(bmcObj.Spec.Address).To(Equal(server.URL))
+		Expect(bmcObj.Spec.TLS).To(Equal(&metalv1alpha1.BMCTLS{}))
+	})
+})
@@ -0,0 +1,133 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/bmc"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ensureBootMACAddress validates the boot MAC address of the host against the network interfaces
+// of the system and detects it if it is unset.
+func (r *BareMetalHostReconciler) ensureBootMACAddress(ctx context.Context, log logr.Logger, host *metalv1alpha1.BareMetalHost) error {
+	condition := metav1.Condition{
+		Type:               metalv1alpha1.BareMetalHostConditionBootMACAddressValid,
+		ObservedGeneration: host.Generation,
+	}
+	switch {
+	case len(host.Status.NetworkInterfaces) == 0:
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = metalv1alpha1.BareMetalHostReasonNoNetworkInterfaces
+		condition.Message = "The system reports no network interfaces."
+	case host.Spec.BootMACAddress != "":
+		if nic := findNetworkInterface(host.Status.NetworkInterfaces, host.Spec.BootMACAddress); nic != nil {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = metalv1alpha1.BareMetalHostReasonBootMACAddressFound
+			condition.Message = fmt.Sprintf("The boot MAC address belongs to network interface %s.", nic.ID)
+		} else {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = metalv1alpha1.BareMetalHostReasonBootMACAddressNotFound
+			condition.Message = fmt.Sprintf("No network interface of the system has the boot MAC address %s.", host.Spec.BootMACAddress)
+		}
+	default:
+		nic := r.detectBootInterface(host.Status.NetworkInterfaces)
+		if nic == nil {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = metalv1alpha1.BareMetalHostReasonBootMACAddressNotDetected
+			condition.Message = "No network interface of the system qualifies as boot interface."
+			break
+		}
+		mac := interfaceMAC(nic)
+		log.V(1).Info("Setting detected boot MAC address", "NetworkInterface", nic.ID, "MACAddress", mac)
+		hostBase := host.DeepCopy()
+		host.Spec.BootMACAddress = mac
+		if err := r.Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
+			return fmt.Errorf("failed to patch boot MAC address: %w", err)
+		}
+		log.V(1).Info("Set detected boot MAC address", "NetworkInterface", nic.ID, "MACAddress", mac)
+		condition.ObservedGeneration = host.Generation
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = metalv1alpha1.BareMetalHostReasonBootMACAddressDetected
+		condition.Message = fmt.Sprintf("The boot MAC address was detected from network interface %s.", nic.ID)
+	}
+
+	hostBase := host.DeepCopy()
+	if !meta.SetStatusCondition(&host.Status.Conditions, condition) {
+		return nil
+	}
+	if err := r.Status().Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
+		return fmt.Errorf("failed to patch boot MAC address condition: %w", err)
+	}
+	log.V(1).Info("Patched boot MAC address condition of host", "Status", condition.Status, "Reason", condition.Reason)
+	return nil
+}
+
+// detectBootInterface returns the network interface the host boots from. Only interfaces matching
+// the boot interface pattern are considered if one is configured. Interfaces referenced by a network
+// boot option are preferred, otherwise the first matching interface is chosen. Without a pattern an
+// interface is only chosen if it is the only one of the system.
+func (r *BareMetalHostReconciler) detectBootInterface(nics []metalv1alpha1.NetworkInterface) *metalv1alpha1.NetworkInterface {
+	var candidates []*metalv1alpha1.NetworkInterface
+	for i := range nics {
+		nic := &nics[i]
+		if interfaceMAC(nic) == "" {
+			continue
+		}
+		if r.BootInterfacePattern != nil && !r.BootInterfacePattern.MatchString(nic.ID) && !r.BootInterfacePattern.MatchString(nic.Name) {
+			continue
+		}
+		candidates = append(candidates, nic)
+	}
+	for _, nic := range candidates {
+		if nic.PXEBoot {
+			return nic
+		}
+	}
+	if len(candidates) > 0 && (r.BootInterfacePattern != nil || len(candidates) == 1) {
+		return candidates[0]
+	}
+	return nil
+}
+
+// findNetworkInterface returns the network interface having the MAC address, comparing both its
+// current and permanent MAC address.
+func findNetworkInterface(nics []metalv1alpha1.NetworkInterface, mac string) *metalv1alpha1.NetworkInterface {
+	mac = bmc.NormalizeMAC(mac)
+	for i := range nics {
+		if (nics[i].MACAddress != "" && bmc.NormalizeMAC(nics[i].MACAddress) == mac) ||
+			(nics[i].PermanentMACAddress != "" && bmc.NormalizeMAC(nics[i].PermanentMACAddress) == mac) {
+			return &nics[i]
+		}
+	}
+	return nil
+}
+
+// interfaceMAC returns the normalized MAC address of the network interface, preferring the
+// permanent MAC address as it survives changes of the current address by the operating system.
+func interfaceMAC(nic *metalv1alpha1.NetworkInterface) string {
+	if nic.PermanentMACAddress != "" {
+		return bmc.NormalizeMAC(nic.PermanentMACAddress)
+	}
+	return bmc.NormalizeMAC(nic.MACAddress)
+}
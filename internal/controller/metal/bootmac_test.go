@@ -0,0 +1,116 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"regexp"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("BareMetalHost Boot MAC Address", func() {
+	expectBootMACCondition := func(ctx SpecContext, host *metalv1alpha1.BareMetalHost, status metav1.ConditionStatus, reason string) {
+		GinkgoHelper()
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			condition := meta.FindStatusCondition(host.Status.Conditions, metalv1alpha1.BareMetalHostConditionBootMACAddressValid)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Status).To(Equal(status))
+			g.Expect(condition.Reason).To(Equal(reason))
+			g.Expect(condition.ObservedGeneration).To(Equal(host.Generation))
+		}).Should(Succeed())
+	}
+
+	setBootMACAddress := func(ctx SpecContext, host *metalv1alpha1.BareMetalHost, mac string) {
+		GinkgoHelper()
+		hostBase := host.DeepCopy()
+		host.Spec.BootMACAddress = mac
+		Expect(k8sClient.Patch(ctx, host, client.MergeFrom(hostBase))).To(Succeed())
+	}
+
+	It("should detect the boot MAC address from the PXE capable interface", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "12121212-1212-1212-1212-000000000001", withNetworkInterfaces(`[
+			{"id": "1", "name": "eno1", "macAddress": "02:00:00:00:00:01"},
+			{"id": "2", "name": "eno2", "macAddress": "02:00:00:00:00:02", "permanentMacAddress": "02:00:00:00:00:12", "pxeBoot": true}
+		]`))
+
+		By("Expecting the permanent MAC address of the PXE capable interface")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Spec.BootMACAddress).To(Equal("02:00:00:00:00:12"))
+		}).Should(Succeed())
+		expectBootMACCondition(ctx, host, metav1.ConditionTrue, metalv1alpha1.BareMetalHostReasonBootMACAddressFound)
+		Expect(host.Status.NetworkInterfaces).To(ContainElement(metalv1alpha1.NetworkInterface{
+			ID:                  "2",
+			Name:                "eno2",
+			MACAddress:          "02:00:00:00:00:02",
+			PermanentMACAddress: "02:00:00:00:00:12",
+			PXEBoot:             true,
+		}))
+	})
+
+	It("should not detect the boot MAC address if multiple interfaces qualify", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "12121212-1212-1212-1212-000000000002", withNetworkInterfaces(`[
+			{"id": "1", "macAddress": "02:00:00:00:00:01"},
+			{"id": "2", "macAddress": "02:00:00:00:00:02"}
+		]`))
+
+		expectBootMACCondition(ctx, host, metav1.ConditionFalse, metalv1alpha1.BareMetalHostReasonBootMACAddressNotDetected)
+		Expect(host.Spec.BootMACAddress).To(BeEmpty())
+	})
+
+	It("should validate the boot MAC address against the interfaces of the system", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "12121212-1212-1212-1212-000000000003", withNetworkInterfaces(`[
+			{"id": "1", "macAddress": "02:00:00:00:00:01"},
+			{"id": "2", "macAddress": "02:00:00:00:00:0a"}
+		]`))
+
+		By("Setting a boot MAC address not belonging to the system")
+		setBootMACAddress(ctx, host, "02:00:00:00:00:03")
+		expectBootMACCondition(ctx, host, metav1.ConditionFalse, metalv1alpha1.BareMetalHostReasonBootMACAddressNotFound)
+
+		By("Setting the boot MAC address of an interface in another notation")
+		setBootMACAddress(ctx, host, "02:00:00:00:00:0A")
+		expectBootMACCondition(ctx, host, metav1.ConditionTrue, metalv1alpha1.BareMetalHostReasonBootMACAddressFound)
+		Expect(host.Spec.BootMACAddress).To(Equal("02:00:00:00:00:0A"))
+	})
+
+	It("should detect the boot interface matching the boot interface pattern", func() {
+		nics := []metalv1alpha1.NetworkInterface{
+			{ID: "NIC.Embedded.1", Name: "eno1", MACAddress: "02:00:00:00:00:01", PXEBoot: true},
+			{ID: "NIC.Slot.1", Name: "ens1f0", MACAddress: "02:00:00:00:00:02"},
+			{ID: "NIC.Slot.2", Name: "ens2f0", MACAddress: "02:00:00:00:00:03", PXEBoot: true},
+		}
+
+		r := &BareMetalHostReconciler{}
+		Expect(r.detectBootInterface(nics)).To(HaveField("ID", "NIC.Embedded.1"))
+
+		r.BootInterfacePattern = regexp.MustCompile(`^NIC\.Slot\.`)
+		Expect(r.detectBootInterface(nics)).To(HaveField("ID", "NIC.Slot.2"))
+
+		r.BootInterfacePattern = regexp.MustCompile(`^ens1`)
+		Expect(r.detectBootInterface(nics)).To(HaveField("ID", "NIC.Slot.1"))
+
+		r.BootInterfacePattern = regexp.MustCompile(`^eth`)
+		Expect(r.detectBootInterface(nics)).To(BeNil())
+	})
+})
@@ -0,0 +1,103 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Boot Profile", func() {
+	var ns *v1.Namespace
+
+	BeforeEach(func(ctx SpecContext) {
+		ns = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ns)
+	})
+
+	It("should serve the iPXE script rendered from the boot profile", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "16161616-1616-1616-1616-000000000002")
+
+		By("Creating a boot profile with an invalid template")
+		profile := &bootv1alpha1.BootProfile{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "profile-"},
+			Spec: bootv1alpha1.BootProfileSpec{
+				Template:   "#!ipxe\nkernel {{ .Kernel }} {{ .KernelArgs }} {{ .Console }}\nboot\n",
+				Kernel:     "http://[2001:db8::1]/kernel",
+				KernelArgs: []string{"console=ttyS1"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, profile)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, profile)
+
+		By("Claiming the host with the boot profile")
+		ignition := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "ignition-"},
+			Data:       map[string][]byte{"ignition": []byte("{}")},
+		}
+		Expect(k8sClient.Create(ctx, ignition)).To(Succeed())
+		claim := &metalv1alpha1.BareMetalHostClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "claim-"},
+			Spec: metalv1alpha1.BareMetalHostClaimSpec{
+				Power:            metalv1alpha1.PowerStateOn,
+				BareMetalHostRef: v1.LocalObjectReference{Name: host.Name},
+				IgnitionRef:      &v1.LocalObjectReference{Name: ignition.Name},
+				Image:            "foo:latest",
+				BootProfileRef:   &v1.LocalObjectReference{Name: profile.Name},
+				KernelArgs:       []string{"ignition.platform.id=metal"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, claim)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, claim)
+
+		By("Expecting the template error in the PXE status")
+		pxe := &bootv1alpha1.PXE{ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: claim.Name}}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pxe), pxe)).To(Succeed())
+			g.Expect(pxe.Status.State).To(Equal(bootv1alpha1.PXEStateFailed))
+			condition := meta.FindStatusCondition(pxe.Status.Conditions, bootv1alpha1.PXEConditionBootScriptValid)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			g.Expect(condition.Reason).To(Equal(bootv1alpha1.PXEReasonTemplateInvalid))
+			g.Expect(condition.Message).To(ContainSubstring("Console"))
+		}).Should(Succeed())
+
+		By("Fixing the template")
+		profileBase := profile.DeepCopy()
+		profile.Spec.Template = "#!ipxe\nkernel {{ .Kernel }} {{ .KernelArgs }} uuid={{ .SystemUUID }}\nboot\n"
+		Expect(k8sClient.Patch(ctx, profile, client.MergeFrom(profileBase))).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pxe), pxe)).To(Succeed())
+			g.Expect(pxe.Status.State).To(Equal(bootv1alpha1.PXEStateReady))
+			g.Expect(meta.IsStatusConditionTrue(pxe.Status.Conditions, bootv1alpha1.PXEConditionBootScriptValid)).To(BeTrue())
+		}).Should(Succeed())
+		expectPhase(ctx, claim, metalv1alpha1.PhaseBound)
+
+		By("Expecting the rendered script in the PXE secret")
+		pxeSecret := &v1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "oob", Name: "ipxe-" + host.Status.SystemUUID}, pxeSecret)).To(Succeed())
+		Expect(string(pxeSecret.Data["ipxe"])).To(Equal("#!ipxe\nkernel http://[2001:db8::1]/kernel console=ttyS1 ignition.platform.id=metal uuid=" + host.Status.SystemUUID + "\nboot\n"))
+		Expect(pxeSecret.Data["ignition"]).To(Equal([]byte("{}")))
+	})
+})
@@ -0,0 +1,624 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/bmc"
+	"github.com/go-logr/logr"
+	"golang.org/x/net/websocket"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultConsoleBufferSize is the default number of bytes of recent console output kept
+	// per host.
+	DefaultConsoleBufferSize = 64 * 1024
+	// DefaultConsoleMaxBuffers is the default number of hosts whose recent console output is
+	// kept.
+	DefaultConsoleMaxBuffers = 256
+
+	// consoleSubresource is the subresource of the hosts callers need permissions for. The
+	// verb create is required to attach to the console and get to read the recent output.
+	consoleSubresource = "console"
+	// consoleSubscriberBuffer is the number of output chunks buffered for an attached client
+	// before it is detached as too slow.
+	consoleSubscriberBuffer = 256
+)
+
+// errConsoleServerNotStarted is returned if a console is attached before the server started.
+var errConsoleServerNotStarted = errors.New("console server is not started")
+
+// errConsoleServerInsecure is returned if the endpoint would serve plain HTTP without being
+// allowed to.
+var errConsoleServerInsecure = errors.New("console endpoint requires a certificate directory unless it is allowed to serve plain HTTP")
+
+// ConsoleServer serves the serial consoles of the hosts via websockets. Callers authenticate
+// with a bearer token which is verified with a TokenReview and are authorized for the
+// console subresource of the host with a SubjectAccessReview.
+//
+// The server serves the following endpoints:
+//   - /hosts/<host>/console attaches to the console via a websocket. The recent output is
+//     replayed first. Binary and text messages are written to the console.
+//   - /hosts/<host>/console/log returns the recent output.
+type ConsoleServer struct {
+	client.Client
+	// FakeBMCs keeps the state of the BMCs of type Fake.
+	FakeBMCs *bmc.FakeBMCs
+	// BindAddress is the address the server listens on. The endpoints are not served if it
+	// is empty, e.g. if the consoles are only recorded by a ConsoleRecorder.
+	BindAddress string
+	// CertDir contains the tls.crt and tls.key files used to serve TLS.
+	CertDir string
+	// Insecure allows serving plain HTTP if CertDir is empty, which exposes the bearer tokens
+	// of the callers. The server refuses to start without TLS otherwise.
+	Insecure bool
+	// BufferSize is the number of bytes of recent console output kept per host.
+	BufferSize int
+	// MaxBuffers is the number of hosts whose recent console output is kept. The output of
+	// the hosts without attached clients which was written least recently is dropped first.
+	MaxBuffers int
+
+	mu       sync.Mutex
+	ctx      context.Context
+	sessions map[string]*consoleSession
+	buffers  map[string]*consoleBuffer
+}
+
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhosts,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=bmcs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=bmcs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+//+kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// Start serves the consoles until the context is done. All attached consoles are closed
+// when the server stops.
+func (s *ConsoleServer) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("console")
+	s.init(ctx)
+
+	if s.BindAddress == "" {
+		// the consoles are only recorded
+		<-ctx.Done()
+		s.closeSessions()
+		return nil
+	}
+	if s.CertDir == "" && !s.Insecure {
+		return errConsoleServerInsecure
+	}
+	server := &http.Server{
+		Addr:              s.BindAddress,
+		Handler:           s.handler(log),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+		s.closeSessions()
+	}()
+
+	log.Info("Serving consoles", "Address", s.BindAddress)
+	var err error
+	if s.CertDir != "" {
+		err = server.ListenAndServeTLS(filepath.Join(s.CertDir, "tls.crt"), filepath.Join(s.CertDir, "tls.key"))
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve consoles: %w", err)
+	}
+	return nil
+}
+
+// init prepares the server for attaching consoles which stay open until the context is done.
+func (s *ConsoleServer) init(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx = ctx
+	if s.sessions == nil {
+		s.sessions = map[string]*consoleSession{}
+	}
+	if s.buffers == nil {
+		s.buffers = map[string]*consoleBuffer{}
+	}
+}
+
+func (s *ConsoleServer) handler(log logr.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) < 3 || parts[0] != "hosts" || parts[2] != consoleSubresource || len(parts) > 4 {
+			http.NotFound(w, r)
+			return
+		}
+		hostName := parts[1]
+		hostLog := log.WithValues("Host", hostName)
+
+		switch {
+		case len(parts) == 3:
+			if !s.authorize(w, r, hostLog, hostName, "create") {
+				return
+			}
+			s.serveAttach(w, r, hostLog, hostName)
+		case parts[3] == "log" && r.Method == http.MethodGet:
+			if !s.authorize(w, r, hostLog, hostName, "get") {
+				return
+			}
+			s.serveLog(w, hostName)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// authorize verifies the bearer token of the request and checks whether its user may use the
+// verb on the console of the host. An error response is written if not.
+func (s *ConsoleServer) authorize(w http.ResponseWriter, r *http.Request, log logr.Logger, hostName, verb string) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return false
+	}
+	review := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	if err := s.Create(r.Context(), review); err != nil {
+		log.Error(err, "Failed to review token")
+		http.Error(w, "failed to review token", http.StatusInternalServerError)
+		return false
+	}
+	if !review.Status.Authenticated {
+		http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+		return false
+	}
+
+	user := review.Status.User
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	access := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Group:       metalv1alpha1.GroupVersion.Group,
+				Resource:    "baremetalhosts",
+				Subresource: consoleSubresource,
+				Name:        hostName,
+				Verb:        verb,
+			},
+		},
+	}
+	if err := s.Create(r.Context(), access); err != nil {
+		log.Error(err, "Failed to review access")
+		http.Error(w, "failed to review access", http.StatusInternalServerError)
+		return false
+	}
+	if !access.Status.Allowed {
+		http.Error(w, fmt.Sprintf("user %s may not %s the console of host %s", user.Username, verb, hostName), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (s *ConsoleServer) serveAttach(w http.ResponseWriter, r *http.Request, log logr.Logger, hostName string) {
+	session, err := s.attach(r.Context(), hostName)
+	switch {
+	case apierrors.IsNotFound(err):
+		http.Error(w, fmt.Sprintf("host %s not found", hostName), http.StatusNotFound)
+		return
+	case errors.Is(err, bmc.ErrConsoleNotSupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	case err != nil:
+		log.Error(err, "Failed to attach to console")
+		http.Error(w, "failed to attach to console", http.StatusBadGateway)
+		return
+	}
+	defer s.release(session)
+
+	websocket.Server{Handshake: checkConsoleOrigin, Handler: func(ws *websocket.Conn) {
+		ws.PayloadType = websocket.BinaryFrame
+		output, replay := session.subscribe()
+		defer session.unsubscribe(output)
+		log.V(1).Info("Attached to console")
+
+		go func() {
+			defer session.unsubscribe(output)
+			for {
+				var input []byte
+				if err := websocket.Message.Receive(ws, &input); err != nil {
+					return
+				}
+				if _, err := session.console.Write(input); err != nil {
+					log.V(1).Info("Failed to write to console", "Error", err)
+					return
+				}
+			}
+		}()
+
+		if len(replay) > 0 {
+			if _, err := ws.Write(replay); err != nil {
+				return
+			}
+		}
+		for data := range output {
+			if _, err := ws.Write(data); err != nil {
+				return
+			}
+		}
+		log.V(1).Info("Detached from console")
+	}}.ServeHTTP(w, r)
+}
+
+// checkConsoleOrigin rejects websockets opened by browsers on behalf of other sites. Clients
+// which send no origin, e.g. command line tools, are accepted.
+func checkConsoleOrigin(config *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(config, r)
+	if err != nil {
+		return fmt.Errorf("failed to parse origin: %w", err)
+	}
+	if origin != nil && origin.Host != r.Host {
+		return fmt.Errorf("origin %s is not allowed", origin)
+	}
+	return nil
+}
+
+func (s *ConsoleServer) serveLog(w http.ResponseWriter, hostName string) {
+	s.mu.Lock()
+	buffer, ok := s.buffers[hostName]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("no console output recorded for host %s", hostName), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write(buffer.Bytes())
+}
+
+// attach returns the console session of the host and opens the console if the host has no
+// session yet. Every session returned has to be released.
+func (s *ConsoleServer) attach(ctx context.Context, hostName string) (*consoleSession, error) {
+	s.mu.Lock()
+	if session, ok := s.sessions[hostName]; ok {
+		session.refs++
+		s.mu.Unlock()
+		return session, nil
+	}
+	consoleCtx := s.ctx
+	s.mu.Unlock()
+	if consoleCtx == nil {
+		return nil, errConsoleServerNotStarted
+	}
+
+	// the console is opened without holding the lock as connecting to the BMC may take long
+	console, err := s.openConsole(ctx, consoleCtx, hostName)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session, ok := s.sessions[hostName]; ok {
+		// the console was opened concurrently
+		_ = console.Close()
+		session.refs++
+		return session, nil
+	}
+	buffer, ok := s.buffers[hostName]
+	if !ok {
+		s.evictBuffers()
+		buffer = newConsoleBuffer(s.bufferSize())
+		s.buffers[hostName] = buffer
+	}
+	session := &consoleSession{
+		host:        hostName,
+		console:     console,
+		buffer:      buffer,
+		subscribers: map[chan []byte]struct{}{},
+		refs:        1,
+	}
+	s.sessions[hostName] = session
+	go s.read(session)
+	return session, nil
+}
+
+// openConsole opens the console of the host which stays open until the console context is
+// done or the console is closed.
+func (s *ConsoleServer) openConsole(ctx, consoleCtx context.Context, hostName string) (io.ReadWriteCloser, error) {
+	host := &metalv1alpha1.BareMetalHost{}
+	if err := s.Get(ctx, client.ObjectKey{Name: hostName}, host); err != nil {
+		return nil, err
+	}
+	bmcObj := &metalv1alpha1.BMC{}
+	if err := s.Get(ctx, client.ObjectKey{Name: host.Spec.BMCRef.Name}, bmcObj); err != nil {
+		return nil, fmt.Errorf("failed to get BMC %s for host: %w", host.Spec.BMCRef.Name, err)
+	}
+	options, err := s.consoleOptions(ctx, bmcObj)
+	if err != nil {
+		return nil, err
+	}
+	bmcClient, err := newBMCClient(ctx, s.Client, s.FakeBMCs, bmcObj, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create BMC client: %w", err)
+	}
+	defer bmcClient.Logout()
+	console, err := bmcClient.OpenConsole(consoleCtx, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open console: %w", err)
+	}
+	return console, nil
+}
+
+// consoleOptions returns the credentials and the SSH host key of the BMC. The host key is
+// taken from the BMC secret or the BMC status. If neither has one, the key presented by the
+// BMC is trusted on first use and recorded in the status.
+func (s *ConsoleServer) consoleOptions(ctx context.Context, bmcObj *metalv1alpha1.BMC) (bmc.ConsoleOptions, error) {
+	if bmcObj.Spec.Type != metalv1alpha1.BMCTypeRedfish {
+		return bmc.ConsoleOptions{}, nil
+	}
+	bmcSecret, err := getBMCSecret(ctx, s.Client, bmcObj)
+	if err != nil {
+		return bmc.ConsoleOptions{}, err
+	}
+	options := bmc.ConsoleOptions{
+		Username: string(bmcSecret.Data["username"]),
+		Password: string(bmcSecret.Data["password"]),
+		HostKey:  string(bmcSecret.Data["sshHostKey"]),
+	}
+	if options.HostKey == "" {
+		options.HostKey = bmcObj.Status.SSHHostKey
+	}
+	if options.HostKey == "" {
+		options.TrustHostKey = func(key string) error {
+			bmcObj := bmcObj.DeepCopy()
+			if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				if err := s.Get(ctx, client.ObjectKeyFromObject(bmcObj), bmcObj); err != nil {
+					return err
+				}
+				if bmcObj.Status.SSHHostKey != "" {
+					// a key has been recorded concurrently
+					if bmcObj.Status.SSHHostKey != key {
+						return fmt.Errorf("host key %s does not match the recorded host key", key)
+					}
+					return nil
+				}
+				bmcBase := bmcObj.DeepCopy()
+				bmcObj.Status.SSHHostKey = key
+				// the lock ensures that a key recorded concurrently is not overwritten
+				return s.Status().Patch(ctx, bmcObj, client.MergeFromWithOptions(bmcBase, client.MergeFromWithOptimisticLock{}))
+			}); err != nil {
+				return fmt.Errorf("failed to record SSH host key: %w", err)
+			}
+			return nil
+		}
+	}
+	return options, nil
+}
+
+// release detaches from the session and closes the console once the session is unused.
+func (s *ConsoleServer) release(session *consoleSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session.refs--
+	if session.refs > 0 {
+		return
+	}
+	if s.sessions[session.host] == session {
+		delete(s.sessions, session.host)
+	}
+	_ = session.console.Close()
+}
+
+// read copies the console output to the buffer and the subscribers until the console is
+// closed.
+func (s *ConsoleServer) read(session *consoleSession) {
+	data := make([]byte, 4096)
+	for {
+		n, err := session.console.Read(data)
+		if n > 0 {
+			session.publish(data[:n])
+		}
+		if err != nil {
+			break
+		}
+	}
+	s.mu.Lock()
+	if s.sessions[session.host] == session {
+		delete(s.sessions, session.host)
+	}
+	s.mu.Unlock()
+	session.close()
+}
+
+func (s *ConsoleServer) closeSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for host, session := range s.sessions {
+		_ = session.console.Close()
+		delete(s.sessions, host)
+	}
+}
+
+// evictBuffers drops the output of the hosts without session which was written least
+// recently until a buffer can be added. The caller has to hold the lock.
+func (s *ConsoleServer) evictBuffers() {
+	for len(s.buffers) >= s.maxBuffers() {
+		var oldestHost string
+		var oldest time.Time
+		for host, buffer := range s.buffers {
+			if _, ok := s.sessions[host]; ok {
+				continue
+			}
+			if updated := buffer.Updated(); oldestHost == "" || updated.Before(oldest) {
+				oldestHost, oldest = host, updated
+			}
+		}
+		if oldestHost == "" {
+			// all buffers are in use
+			return
+		}
+		delete(s.buffers, oldestHost)
+	}
+}
+
+func (s *ConsoleServer) maxBuffers() int {
+	if s.MaxBuffers > 0 {
+		return s.MaxBuffers
+	}
+	return DefaultConsoleMaxBuffers
+}
+
+func (s *ConsoleServer) bufferSize() int {
+	if s.BufferSize > 0 {
+		return s.BufferSize
+	}
+	return DefaultConsoleBufferSize
+}
+
+// consoleSession is an open console of a host shared by all attached clients.
+type consoleSession struct {
+	host    string
+	console io.ReadWriteCloser
+	buffer  *consoleBuffer
+	// refs is guarded by the mutex of the server.
+	refs int
+
+	mu          sync.Mutex
+	subscribers map[chan []byte]struct{}
+	closed      bool
+}
+
+// subscribe returns a channel receiving the output of the console and the output recorded
+// before.
+func (c *consoleSession) subscribe() (chan []byte, []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	output := make(chan []byte, consoleSubscriberBuffer)
+	if c.closed {
+		close(output)
+	} else {
+		c.subscribers[output] = struct{}{}
+	}
+	return output, c.buffer.Bytes()
+}
+
+func (c *consoleSession) unsubscribe(output chan []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.subscribers[output]; ok {
+		delete(c.subscribers, output)
+		close(output)
+	}
+}
+
+// publish records the output and sends it to all subscribers. Subscribers which do not keep
+// up are detached.
+func (c *consoleSession) publish(data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, _ = c.buffer.Write(data)
+	for output := range c.subscribers {
+		select {
+		case output <- append([]byte(nil), data...):
+		default:
+			delete(c.subscribers, output)
+			close(output)
+		}
+	}
+}
+
+func (c *consoleSession) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for output := range c.subscribers {
+		delete(c.subscribers, output)
+		close(output)
+	}
+}
+
+// consoleBuffer is a ring buffer keeping the most recent output of a console.
+type consoleBuffer struct {
+	mu      sync.Mutex
+	data    []byte
+	pos     int
+	full    bool
+	updated time.Time
+}
+
+func newConsoleBuffer(size int) *consoleBuffer {
+	return &consoleBuffer{data: make([]byte, size), updated: time.Now()}
+}
+
+// Updated returns the time the buffer was created or written last.
+func (b *consoleBuffer) Updated() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.updated
+}
+
+// Write appends the output and overwrites the oldest output once the buffer is full.
+func (b *consoleBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.updated = time.Now()
+	n := len(p)
+	if len(p) >= len(b.data) {
+		copy(b.data, p[len(p)-len(b.data):])
+		b.pos, b.full = 0, true
+		return n, nil
+	}
+	copied := copy(b.data[b.pos:], p)
+	if copied < len(p) {
+		copy(b.data, p[copied:])
+		b.full = true
+	}
+	b.pos = (b.pos + len(p)) % len(b.data)
+	if b.pos == 0 {
+		b.full = true
+	}
+	return n, nil
+}
+
+// Bytes returns a copy of the recorded output, oldest first.
+func (b *consoleBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.full {
+		return append([]byte(nil), b.data[:b.pos]...)
+	}
+	return append(append([]byte(nil), b.data[b.pos:]...), b.data[:b.pos]...)
+}
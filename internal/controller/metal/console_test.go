@@ -0,0 +1,229 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/websocket"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Console Server", func() {
+	var (
+		ns     *v1.Namespace
+		bmcObj *metalv1alpha1.BMC
+		host   *metalv1alpha1.BareMetalHost
+		server *httptest.Server
+	)
+
+	BeforeEach(func(ctx SpecContext) {
+		ns = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ns)
+
+		bmcObj, host = setupFakeHost(ctx, "66666666-6666-6666-6666-666666666666")
+
+		consoleServer := &ConsoleServer{Client: k8sClient, FakeBMCs: fakeBMCs, BufferSize: 32}
+		consoleServer.init(ctx)
+		server = httptest.NewServer(consoleServer.handler(GinkgoLogr))
+		DeferCleanup(server.Close)
+	})
+
+	// serviceAccountToken returns a token of a new service account which may use the given
+	// verbs on the console of the host.
+	serviceAccountToken := func(ctx SpecContext, verbs ...string) string {
+		sa := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "console-"}}
+		Expect(k8sClient.Create(ctx, sa)).To(Succeed())
+		if len(verbs) > 0 {
+			role := &rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{GenerateName: "console-"},
+				Rules: []rbacv1.PolicyRule{{
+					APIGroups:     []string{metalv1alpha1.GroupVersion.Group},
+					Resources:     []string{"baremetalhosts/console"},
+					ResourceNames: []string{host.Name},
+					Verbs:         verbs,
+				}},
+			}
+			Expect(k8sClient.Create(ctx, role)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, role)
+			binding := &rbacv1.ClusterRoleBinding{
+				ObjectMeta: metav1.ObjectMeta{GenerateName: "console-"},
+				RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: role.Name},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: ns.Name, Name: sa.Name}},
+			}
+			Expect(k8sClient.Create(ctx, binding)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, binding)
+		}
+		tokenRequest := &authenticationv1.TokenRequest{}
+		Expect(k8sClient.SubResource("token").Create(ctx, sa, tokenRequest)).To(Succeed())
+		return tokenRequest.Status.Token
+	}
+
+	dial := func(token string) (*websocket.Conn, error) {
+		config, err := websocket.NewConfig("ws"+strings.TrimPrefix(server.URL, "http")+"/hosts/"+host.Name+"/console", server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		config.Header.Set("Authorization", "Bearer "+token)
+		return websocket.DialConfig(config)
+	}
+
+	getLog := func(token string) (int, string) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/hosts/"+host.Name+"/console/log", nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return resp.StatusCode, string(body)
+	}
+
+	It("should stream the console of a host to authorized users", func(ctx SpecContext) {
+		By("Attaching to the console")
+		token := serviceAccountToken(ctx, "create", "get")
+		ws, err := dial(token)
+		Expect(err).NotTo(HaveOccurred())
+		defer ws.Close()
+
+		var received strings.Builder
+		read := func() string {
+			Expect(ws.SetReadDeadline(time.Now().Add(100 * time.Millisecond))).To(Succeed())
+			data := make([]byte, 1024)
+			if n, err := ws.Read(data); err == nil {
+				received.Write(data[:n])
+			}
+			return received.String()
+		}
+
+		By("Ensuring that the console output is streamed")
+		Expect(fakeBMCs.AddConsoleOutput(bmcObj.Name, "System-1", "iPXE initialising devices...\r\n")).To(Succeed())
+		Eventually(read).Should(ContainSubstring("iPXE initialising"))
+
+		By("Ensuring that the input is written to the console")
+		Expect(websocket.Message.Send(ws, []byte("help\r\n"))).To(Succeed())
+		Eventually(read).Should(ContainSubstring("help"))
+
+		By("Ensuring that the recent output is kept after detaching")
+		Expect(ws.Close()).To(Succeed())
+		status, log := getLog(token)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(log).To(HaveLen(32))
+		Expect("iPXE initialising devices...\r\nhelp\r\n").To(HaveSuffix(log))
+	})
+
+	It("should reject unauthenticated and unauthorized users", func(ctx SpecContext) {
+		_, err := dial("invalid")
+		Expect(err).To(HaveOccurred())
+		status, _ := getLog("invalid")
+		Expect(status).To(Equal(http.StatusUnauthorized))
+
+		token := serviceAccountToken(ctx, "get")
+		_, err = dial(token)
+		Expect(err).To(HaveOccurred())
+		status, _ = getLog(token)
+		Expect(status).To(Equal(http.StatusNotFound))
+
+		status, _ = getLog(serviceAccountToken(ctx))
+		Expect(status).To(Equal(http.StatusForbidden))
+	})
+
+	It("should reject websockets opened on behalf of other sites", func(ctx SpecContext) {
+		config, err := websocket.NewConfig("ws"+strings.TrimPrefix(server.URL, "http")+"/hosts/"+host.Name+"/console", "https://evil.example.com")
+		Expect(err).NotTo(HaveOccurred())
+		config.Header.Set("Authorization", "Bearer "+serviceAccountToken(ctx, "create"))
+		_, err = websocket.DialConfig(config)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should trust the SSH host key of a BMC on first use unless it is configured", func(ctx SpecContext) {
+		credentials := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "credentials-"},
+			Data: map[string][]byte{
+				"username": []byte("admin"),
+				"password": []byte("secret"),
+			},
+		}
+		Expect(k8sClient.Create(ctx, credentials)).To(Succeed())
+		redfishBMC := &metalv1alpha1.BMC{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "console-redfish-"},
+			Spec: metalv1alpha1.BMCSpec{
+				Type:      metalv1alpha1.BMCTypeRedfish,
+				Address:   "https://127.0.0.1:1",
+				SecretRef: v1.SecretReference{Namespace: ns.Name, Name: credentials.Name},
+			},
+		}
+		Expect(k8sClient.Create(ctx, redfishBMC)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, redfishBMC)
+
+		consoleServer := &ConsoleServer{Client: k8sClient}
+		By("Recording the key presented first")
+		options, err := consoleServer.consoleOptions(ctx, redfishBMC)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(options.Username).To(Equal("admin"))
+		Expect(options.HostKey).To(BeEmpty())
+		Expect(options.TrustHostKey("ssh-ed25519 first")).To(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(redfishBMC), redfishBMC)).To(Succeed())
+		Expect(redfishBMC.Status.SSHHostKey).To(Equal("ssh-ed25519 first"))
+
+		By("Ensuring that the recorded key is required afterwards")
+		options, err = consoleServer.consoleOptions(ctx, redfishBMC)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(options.HostKey).To(Equal("ssh-ed25519 first"))
+		Expect(options.TrustHostKey).To(BeNil())
+
+		By("Ensuring that the key of the BMC secret takes precedence")
+		credentialsBase := credentials.DeepCopy()
+		credentials.Data["sshHostKey"] = []byte("ssh-ed25519 configured")
+		Expect(k8sClient.Patch(ctx, credentials, client.MergeFrom(credentialsBase))).To(Succeed())
+		options, err = consoleServer.consoleOptions(ctx, redfishBMC)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(options.HostKey).To(Equal("ssh-ed25519 configured"))
+	})
+
+	It("should refuse to serve plain HTTP unless allowed", func(ctx SpecContext) {
+		consoleServer := &ConsoleServer{Client: k8sClient, BindAddress: "127.0.0.1:0"}
+		Expect(consoleServer.Start(ctx)).To(MatchError(errConsoleServerInsecure))
+	})
+})
+
+var _ = Describe("Console Buffers", func() {
+	It("should drop the output of the hosts written least recently without session", func(ctx SpecContext) {
+		consoleServer := &ConsoleServer{MaxBuffers: 2, BufferSize: 8}
+		consoleServer.init(ctx)
+		consoleServer.buffers["idle"] = newConsoleBuffer(8)
+		consoleServer.buffers["attached"] = newConsoleBuffer(8)
+		consoleServer.sessions["attached"] = &consoleSession{host: "attached"}
+		_, _ = consoleServer.buffers["attached"].Write([]byte("older"))
+		_, _ = consoleServer.buffers["idle"].Write([]byte("newer"))
+
+		consoleServer.evictBuffers()
+		Expect(consoleServer.buffers).To(HaveLen(1))
+		Expect(consoleServer.buffers).To(HaveKey("attached"))
+	})
+})
@@ -0,0 +1,407 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// DefaultConsoleLogMaxDuration is the default duration after which the recording ends if
+	// the console output showed neither that the provisioning succeeded nor that it failed.
+	DefaultConsoleLogMaxDuration = time.Hour
+	// DefaultConsoleLogFlushInterval is the default interval in which the recorded console
+	// output is stored.
+	DefaultConsoleLogFlushInterval = 10 * time.Second
+	// DefaultConsoleLogSucceededPattern matches the console output of a host which has been
+	// provisioned successfully.
+	DefaultConsoleLogSucceededPattern = `(?i)ignition finished successfully`
+	// DefaultConsoleLogFailedPattern matches the console output of a host whose provisioning
+	// failed.
+	DefaultConsoleLogFailedPattern = `(?i)kernel panic|ignition failed|emergency mode`
+	// MaxConsoleLogSize is the maximum number of bytes of output which fit into the console
+	// log secret.
+	MaxConsoleLogSize = v1.MaxSecretSize - len(metalv1alpha1.ConsoleLogSecretKey)
+)
+
+var (
+	consoleRecorderFieldOwner = client.FieldOwner("metal.afritzler.github.io/console-recorder")
+)
+
+// ConsoleRecorder records the serial console output of hosts while they are provisioned for a
+// claim. The most recent output is stored in a secret of the claim which is referenced in the
+// status of the claim, so that the boot of an image can be debugged without access to the BMC.
+//
+// The recording starts before the host is powered on for the claim and ends once a line of
+// the output shows that the provisioning succeeded or failed, which is reported in the
+// Provisioned condition of the claim. It also ends after the maximum duration, once the claim
+// powers off the host or is deleted. The console session is shared with the clients attached
+// via the ConsoleServer.
+type ConsoleRecorder struct {
+	client.Client
+	// Consoles opens the console sessions of the hosts.
+	Consoles *ConsoleServer
+	// MaxDuration is how long the output is recorded at most after the host has been powered on.
+	MaxDuration time.Duration
+	// SucceededPattern matches a line of the output once the provisioning succeeded.
+	SucceededPattern *regexp.Regexp
+	// FailedPattern matches a line of the output once the provisioning failed.
+	FailedPattern *regexp.Regexp
+	// FlushInterval is the interval in which the recorded output is stored in the secret.
+	FlushInterval time.Duration
+	// BufferSize is the number of bytes of the most recent output which are stored. It must not
+	// exceed MaxConsoleLogSize.
+	BufferSize int
+
+	mu         sync.Mutex
+	ctx        context.Context
+	recordings map[types.NamespacedName]*consoleRecording
+}
+
+// consoleRecording is a running recording of the console of a claimed host.
+type consoleRecording struct {
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhostclaims,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhostclaims/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch
+
+// Start runs the recordings until the context is done.
+func (r *ConsoleRecorder) Start(ctx context.Context) error {
+	if r.bufferSize() > MaxConsoleLogSize {
+		return fmt.Errorf("console log size %d exceeds the maximum of %d bytes", r.bufferSize(), MaxConsoleLogSize)
+	}
+	r.mu.Lock()
+	r.ctx = ctx
+	if r.recordings == nil {
+		r.recordings = map[types.NamespacedName]*consoleRecording{}
+	}
+	r.mu.Unlock()
+
+	<-ctx.Done()
+	return nil
+}
+
+// Record starts recording the console of the host claimed by the claim unless the console has
+// already been recorded for the claim. A running recording is resumed, e.g. after a restart of
+// the operator.
+func (r *ConsoleRecorder) Record(ctx context.Context, log logr.Logger, claim *metalv1alpha1.BareMetalHostClaim, host *metalv1alpha1.BareMetalHost) error {
+	status := claim.Status.ConsoleLog
+	if status != nil && status.State != metalv1alpha1.ConsoleLogStateRecording {
+		return nil
+	}
+	if meta.FindStatusCondition(claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionProvisioned) != nil {
+		// the provisioning completed before the recording
+		return nil
+	}
+	key := client.ObjectKeyFromObject(claim)
+	r.mu.Lock()
+	recordCtx := r.ctx
+	_, running := r.recordings[key]
+	r.mu.Unlock()
+	if recordCtx == nil {
+		return errors.New("console recorder is not started")
+	}
+	if running {
+		return nil
+	}
+
+	secretName := claim.Name + "-console-log"
+	// the start is stored with the precision of the API to identify the recording
+	startedAt := metav1.Now().Rfc3339Copy()
+	buffer := newConsoleBuffer(r.bufferSize())
+	if status != nil {
+		startedAt = status.StartedAt
+		secretName = status.SecretRef.Name
+		if !time.Now().Before(startedAt.Add(r.maxDuration())) {
+			log.V(1).Info("Console recording expired")
+			return r.patchStatus(ctx, claim, metalv1alpha1.ConsoleLogStateRecorded, "", nil)
+		}
+		// continue the recording with the output stored before
+		secret := &v1.Secret{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: claim.Namespace, Name: secretName}, secret); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to get console log secret: %w", err)
+		}
+		_, _ = buffer.Write(secret.Data[metalv1alpha1.ConsoleLogSecretKey])
+	}
+	if err := r.store(ctx, claim, secretName, buffer); err != nil {
+		return err
+	}
+
+	state, message := metalv1alpha1.ConsoleLogStateRecording, ""
+	session, err := r.Consoles.attach(ctx, host.Name)
+	if errors.Is(err, errConsoleServerNotStarted) {
+		return err
+	}
+	if err != nil {
+		// the provisioning continues without recording the console
+		log.Error(err, "Failed to attach to console for recording")
+		state, message = metalv1alpha1.ConsoleLogStateFailed, err.Error()
+	}
+	claimBase := claim.DeepCopy()
+	claim.Status.ConsoleLog = &metalv1alpha1.ConsoleLogStatus{
+		State:     state,
+		SecretRef: v1.LocalObjectReference{Name: secretName},
+		StartedAt: startedAt,
+		Message:   message,
+	}
+	if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+		if session != nil {
+			r.Consoles.release(session)
+		}
+		return fmt.Errorf("failed to patch console log status: %w", err)
+	}
+	if session == nil {
+		return nil
+	}
+
+	// the output is subscribed before the host is powered on to record the whole boot
+	output, _ := session.subscribe()
+	recording := &consoleRecording{stop: make(chan struct{})}
+	r.mu.Lock()
+	r.recordings[key] = recording
+	r.mu.Unlock()
+
+	recordLog := ctrl.LoggerFrom(recordCtx).WithName("console-recorder").WithValues("BareMetalHostClaim", key, "Host", host.Name)
+	go r.record(recordCtx, recordLog, claim.DeepCopy(), secretName, session, output, buffer, startedAt, recording)
+	log.V(1).Info("Recording console", "Secret", secretName)
+	return nil
+}
+
+// Stop ends the recording of the console for the claim. A new recording can be started
+// right away.
+func (r *ConsoleRecorder) Stop(claim *metalv1alpha1.BareMetalHostClaim) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := client.ObjectKeyFromObject(claim)
+	if recording, ok := r.recordings[key]; ok {
+		delete(r.recordings, key)
+		recording.stopOnce.Do(func() { close(recording.stop) })
+	}
+}
+
+// record copies the output of the console session to the buffer and stores it periodically
+// until the output shows that the provisioning completed, the deadline has passed or the
+// recording is stopped.
+func (r *ConsoleRecorder) record(ctx context.Context, log logr.Logger, claim *metalv1alpha1.BareMetalHostClaim, secretName string, session *consoleSession, output chan []byte, buffer *consoleBuffer, startedAt metav1.Time, recording *consoleRecording) {
+	key := client.ObjectKeyFromObject(claim)
+	defer func() {
+		session.unsubscribe(output)
+		r.Consoles.release(session)
+		r.mu.Lock()
+		if r.recordings[key] == recording {
+			delete(r.recordings, key)
+		}
+		r.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(r.flushInterval())
+	defer ticker.Stop()
+	timer := time.NewTimer(time.Until(startedAt.Add(r.maxDuration())))
+	defer timer.Stop()
+
+	state, message := metalv1alpha1.ConsoleLogStateRecorded, ""
+	var provisioned *metav1.Condition
+	var lines consoleLines
+	// the output is stored once in any case to replace the output of a superseded recording
+	modified := true
+loop:
+	for {
+		select {
+		case data, ok := <-output:
+			if !ok {
+				state, message = metalv1alpha1.ConsoleLogStateFailed, "console session closed"
+				break loop
+			}
+			_, _ = buffer.Write(data)
+			modified = true
+			if provisioned = r.matchProvisioned(lines.Split(data)); provisioned != nil {
+				log.V(1).Info("Console output shows that the provisioning completed", "Reason", provisioned.Reason)
+				break loop
+			}
+		case <-ticker.C:
+			if !modified {
+				continue
+			}
+			if err := r.store(ctx, claim, secretName, buffer); err != nil {
+				log.Error(err, "Failed to store console log")
+				continue
+			}
+			modified = false
+		case <-timer.C:
+			break loop
+		case <-recording.stop:
+			break loop
+		case <-ctx.Done():
+			// the recording is resumed once the claim is reconciled again
+			return
+		}
+	}
+
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &metalv1alpha1.BareMetalHostClaim{}
+		if err := r.Get(ctx, key, latest); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if latest.Status.ConsoleLog == nil || !latest.Status.ConsoleLog.StartedAt.Equal(&startedAt) {
+			// the recording has been superseded, e.g. as the host is provisioned again
+			return nil
+		}
+		if err := r.store(ctx, claim, secretName, buffer); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		return r.patchStatus(ctx, latest, state, message, provisioned)
+	}); err != nil {
+		log.Error(err, "Failed to complete console recording")
+		return
+	}
+	log.V(1).Info("Recorded console", "State", state)
+}
+
+// store applies the secret of the claim containing the recorded output.
+func (r *ConsoleRecorder) store(ctx context.Context, claim *metalv1alpha1.BareMetalHostClaim, secretName string, buffer *consoleBuffer) error {
+	secret := &v1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: claim.Namespace,
+			Name:      secretName,
+		},
+		Data: map[string][]byte{
+			metalv1alpha1.ConsoleLogSecretKey: buffer.Bytes(),
+		},
+	}
+	if err := controllerutil.SetOwnerReference(claim, secret, r.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference on console log secret: %w", err)
+	}
+	if err := r.Patch(ctx, secret, client.Apply, consoleRecorderFieldOwner, client.ForceOwnership); err != nil {
+		return fmt.Errorf("failed to apply console log secret: %w", err)
+	}
+	return nil
+}
+
+// patchStatus sets the state of the console log of the claim and the Provisioned condition if
+// given. The patch fails with a conflict if the claim is outdated.
+func (r *ConsoleRecorder) patchStatus(ctx context.Context, claim *metalv1alpha1.BareMetalHostClaim, state metalv1alpha1.ConsoleLogState, message string, provisioned *metav1.Condition) error {
+	if claim.Status.ConsoleLog == nil {
+		return nil
+	}
+	claimBase := claim.DeepCopy()
+	claim.Status.ConsoleLog.State = state
+	claim.Status.ConsoleLog.Message = message
+	if provisioned != nil {
+		provisioned.ObservedGeneration = claim.Generation
+		meta.SetStatusCondition(&claim.Status.Conditions, *provisioned)
+	}
+	if err := r.Status().Patch(ctx, claim, client.MergeFromWithOptions(claimBase, client.MergeFromWithOptimisticLock{})); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to patch console log status: %w", err)
+	}
+	return nil
+}
+
+// matchProvisioned returns the Provisioned condition if one of the lines shows that the
+// provisioning succeeded or failed.
+func (r *ConsoleRecorder) matchProvisioned(lines []string) *metav1.Condition {
+	for _, line := range lines {
+		if r.FailedPattern != nil && r.FailedPattern.MatchString(line) {
+			return &metav1.Condition{
+				Type:    metalv1alpha1.BareMetalHostClaimConditionProvisioned,
+				Status:  metav1.ConditionFalse,
+				Reason:  metalv1alpha1.BareMetalHostClaimReasonProvisioningFailed,
+				Message: line,
+			}
+		}
+		if r.SucceededPattern != nil && r.SucceededPattern.MatchString(line) {
+			return &metav1.Condition{
+				Type:    metalv1alpha1.BareMetalHostClaimConditionProvisioned,
+				Status:  metav1.ConditionTrue,
+				Reason:  metalv1alpha1.BareMetalHostClaimReasonProvisioningSucceeded,
+				Message: line,
+			}
+		}
+	}
+	return nil
+}
+
+func (r *ConsoleRecorder) maxDuration() time.Duration {
+	if r.MaxDuration > 0 {
+		return r.MaxDuration
+	}
+	return DefaultConsoleLogMaxDuration
+}
+
+func (r *ConsoleRecorder) bufferSize() int {
+	if r.BufferSize > 0 {
+		return r.BufferSize
+	}
+	return DefaultConsoleBufferSize
+}
+
+func (r *ConsoleRecorder) flushInterval() time.Duration {
+	if r.FlushInterval > 0 {
+		return r.FlushInterval
+	}
+	return DefaultConsoleLogFlushInterval
+}
+
+// consoleLines splits console output into lines. Output of incomplete lines is kept until the
+// line is complete.
+type consoleLines struct {
+	partial []byte
+}
+
+// maxConsoleLineLength is the length after which an incomplete line is dropped.
+const maxConsoleLineLength = 4096
+
+// Split returns the lines completed by the output without line endings.
+func (l *consoleLines) Split(data []byte) []string {
+	var lines []string
+	l.partial = append(l.partial, data...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		lines = append(lines, string(bytes.TrimRight(l.partial[:i], "\r")))
+		l.partial = l.partial[i+1:]
+	}
+	if len(l.partial) > maxConsoleLineLength {
+		l.partial = nil
+	}
+	return lines
+}
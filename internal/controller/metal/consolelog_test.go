@@ -0,0 +1,132 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"regexp"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Console Recorder", func() {
+	var ns *v1.Namespace
+
+	BeforeEach(func(ctx SpecContext) {
+		ns = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ns)
+	})
+
+	It("should record the console of a host during provisioning", func(ctx SpecContext) {
+		bmcObj, host := setupFakeHost(ctx, "77777777-7777-7777-7777-777777777777")
+		claim, _ := createClaim(ctx, ns, host, metalv1alpha1.ReprovisionPolicyOnChange)
+
+		By("Ensuring that the boot of the host is recorded")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ConsoleLog).NotTo(BeNil())
+			g.Expect(claim.Status.ConsoleLog.State).To(Equal(metalv1alpha1.ConsoleLogStateRecording))
+		}).Should(Succeed())
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: claim.Status.ConsoleLog.SecretRef.Name},
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), secret)).To(Succeed())
+			g.Expect(secret.OwnerReferences).To(ContainElement(HaveField("UID", claim.UID)))
+			g.Expect(string(secret.Data[metalv1alpha1.ConsoleLogSecretKey])).To(ContainSubstring("System-1 booting from Pxe"))
+		}).Should(Succeed())
+
+		Expect(fakeBMCs.AddConsoleOutput(bmcObj.Name, "System-1", "Loading kernel\r\n")).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), secret)).To(Succeed())
+			g.Expect(string(secret.Data[metalv1alpha1.ConsoleLogSecretKey])).To(ContainSubstring("Loading kernel"))
+		}).Should(Succeed())
+
+		By("Powering off the host")
+		claimBase := claim.DeepCopy()
+		claim.Spec.Power = metalv1alpha1.PowerStateOff
+		Expect(k8sClient.Patch(ctx, claim, client.MergeFrom(claimBase))).To(Succeed())
+
+		By("Ensuring that the recording completed")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ConsoleLog.State).To(Equal(metalv1alpha1.ConsoleLogStateRecorded))
+		}).Should(Succeed())
+		Expect(fakeBMCs.AddConsoleOutput(bmcObj.Name, "System-1", "after recording\r\n")).To(Succeed())
+		Consistently(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), secret)).To(Succeed())
+			g.Expect(string(secret.Data[metalv1alpha1.ConsoleLogSecretKey])).NotTo(ContainSubstring("after recording"))
+		}).Should(Succeed())
+
+		Expect(k8sClient.Delete(ctx, claim)).To(Succeed())
+	})
+
+	It("should stop recording once the console output shows that the provisioning succeeded", func(ctx SpecContext) {
+		bmcObj, host := setupFakeHost(ctx, "18181818-1818-1818-1818-181818181818")
+		claim, _ := createClaim(ctx, ns, host, metalv1alpha1.ReprovisionPolicyOnChange)
+		DeferCleanup(k8sClient.Delete, claim)
+
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ConsoleLog).NotTo(BeNil())
+			g.Expect(claim.Status.ConsoleLog.State).To(Equal(metalv1alpha1.ConsoleLogStateRecording))
+		}).Should(Succeed())
+
+		By("Finishing the provisioning with an output split across chunks")
+		Expect(fakeBMCs.AddConsoleOutput(bmcObj.Name, "System-1", "ignition[812]: Ignition fin")).To(Succeed())
+		Expect(fakeBMCs.AddConsoleOutput(bmcObj.Name, "System-1", "ished successfully\r\n")).To(Succeed())
+
+		By("Ensuring that the recording completed and the claim is provisioned")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ConsoleLog.State).To(Equal(metalv1alpha1.ConsoleLogStateRecorded))
+			g.Expect(claim.Status.Conditions).To(ContainElement(SatisfyAll(
+				HaveField("Type", metalv1alpha1.BareMetalHostClaimConditionProvisioned),
+				HaveField("Status", metav1.ConditionTrue),
+				HaveField("Reason", metalv1alpha1.BareMetalHostClaimReasonProvisioningSucceeded),
+			)))
+		}).Should(Succeed())
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: claim.Status.ConsoleLog.SecretRef.Name},
+		}
+		Expect(fakeBMCs.AddConsoleOutput(bmcObj.Name, "System-1", "after provisioning\r\n")).To(Succeed())
+		Consistently(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), secret)).To(Succeed())
+			g.Expect(string(secret.Data[metalv1alpha1.ConsoleLogSecretKey])).To(ContainSubstring("Ignition finished successfully"))
+			g.Expect(string(secret.Data[metalv1alpha1.ConsoleLogSecretKey])).NotTo(ContainSubstring("after provisioning"))
+		}).Should(Succeed())
+	})
+
+	It("should report failed provisionings found in the console output", func() {
+		recorder := &ConsoleRecorder{
+			SucceededPattern: regexp.MustCompile(DefaultConsoleLogSucceededPattern),
+			FailedPattern:    regexp.MustCompile(DefaultConsoleLogFailedPattern),
+		}
+		var lines consoleLines
+		Expect(recorder.matchProvisioned(lines.Split([]byte("Booting\r\nKernel pa")))).To(BeNil())
+		Expect(recorder.matchProvisioned(lines.Split([]byte("nic - not syncing\r\n")))).To(SatisfyAll(
+			HaveField("Status", metav1.ConditionFalse),
+			HaveField("Reason", metalv1alpha1.BareMetalHostClaimReasonProvisioningFailed),
+			HaveField("Message", "Kernel panic - not syncing"),
+		))
+	})
+})
@@ -0,0 +1,133 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+
+	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+)
+
+// duidTypeUUID is the DUID type of a DUID-UUID (RFC 6355).
+const duidTypeUUID = 4
+
+// ParseBootServerURL parses the URL of the boot server. The scheme defaults to http, and IPv6
+// addresses are accepted with or without brackets, e.g. 2001:db8::1 or [2001:db8::1]:8082.
+func ParseBootServerURL(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	scheme, rest, _ := strings.Cut(raw, "://")
+	hostport, path, _ := strings.Cut(rest, "/")
+	if addr, err := netip.ParseAddr(hostport); err == nil && addr.Is6() {
+		// an IPv6 literal has to be enclosed in brackets to be distinguished from a port
+		hostport = "[" + addr.String() + "]"
+	}
+	u, err := url.Parse(scheme + "://" + hostport + "/" + path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse boot server URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported boot server URL scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("boot server URL %q has no host", raw)
+	}
+	return u, nil
+}
+
+// bootFileURL returns the URL the boot server serves the iPXE script of the system at.
+func bootFileURL(server *url.URL, systemUUID string) string {
+	if server == nil || systemUUID == "" {
+		return ""
+	}
+	return server.JoinPath("ipxe", systemUUID).String()
+}
+
+// dhcpv6Reservations returns the DHCPv6 reservations of the network interfaces of the host.
+func dhcpv6Reservations(host *metalv1alpha1.BareMetalHost) ([]bootv1alpha1.DHCPv6Reservation, error) {
+	if len(host.Status.NetworkInterfaces) == 0 {
+		return nil, nil
+	}
+	duid, err := uuidDUID(host.Status.SystemUUID)
+	if err != nil {
+		return nil, err
+	}
+	var reservations []bootv1alpha1.DHCPv6Reservation
+	for i := range host.Status.NetworkInterfaces {
+		nic := &host.Status.NetworkInterfaces[i]
+		mac := interfaceMAC(nic)
+		if mac == "" {
+			continue
+		}
+		iaid, err := macIAID(mac)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive IAID of network interface %s: %w", nic.ID, err)
+		}
+		reservations = append(reservations, bootv1alpha1.DHCPv6Reservation{
+			Interface:  nic.ID,
+			MACAddress: mac,
+			DUID:       duid,
+			IAID:       int64(iaid),
+		})
+	}
+	return reservations, nil
+}
+
+// uuidDUID returns the DUID-UUID of the system UUID in colon separated hex notation. The bytes of
+// the first three fields of the UUID are swapped, i.e. they are encoded little-endian as stored
+// by SMBIOS, while the remaining bytes are kept in the order of the UUID string.
+func uuidDUID(systemUUID string) (string, error) {
+	id, err := hex.DecodeString(strings.ReplaceAll(systemUUID, "-", ""))
+	if err != nil || len(id) != 16 {
+		return "", fmt.Errorf("invalid system UUID %q", systemUUID)
+	}
+	slices.Reverse(id[0:4])
+	slices.Reverse(id[4:6])
+	slices.Reverse(id[6:8])
+	duid := binary.BigEndian.AppendUint16(nil, duidTypeUUID)
+	return colonHex(append(duid, id...)), nil
+}
+
+// macIAID returns the IAID of the interface with the MAC address, which consists of the last four
+// bytes of the address as chosen by iPXE.
+func macIAID(mac string) (uint32, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return 0, err
+	}
+	if len(hw) < 4 {
+		return 0, fmt.Errorf("invalid MAC address %q", mac)
+	}
+	return binary.BigEndian.Uint32(hw[len(hw)-4:]), nil
+}
+
+func colonHex(b []byte) string {
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02x", v)
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("DHCPv6", func() {
+	DescribeTable("should parse boot server URLs",
+		func(raw, expected string) {
+			u, err := ParseBootServerURL(raw)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bootFileURL(u, "1234")).To(Equal(expected))
+		},
+		Entry("with an IPv4 address", "http://10.0.0.1:8082", "http://10.0.0.1:8082/ipxe/1234"),
+		Entry("with a bracketed IPv6 address", "https://[2001:db8::1]:8443/boot", "https://[2001:db8::1]:8443/boot/ipxe/1234"),
+		Entry("with a bare IPv6 address", "2001:db8::1", "http://[2001:db8::1]/ipxe/1234"),
+		Entry("with a bare IPv6 address and path", "http://2001:DB8:0::1/boot/", "http://[2001:db8::1]/boot/ipxe/1234"),
+		Entry("with a host name", "boot.example.com:8082", "http://boot.example.com:8082/ipxe/1234"),
+	)
+
+	DescribeTable("should reject invalid boot server URLs",
+		func(raw string) {
+			_, err := ParseBootServerURL(raw)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("with an unsupported scheme", "tftp://10.0.0.1"),
+		Entry("without a host", "http:///ipxe"),
+		Entry("with an invalid port", "http://[2001:db8::1]:boot"),
+	)
+
+	It("should convert the system UUID to a DUID-UUID", func() {
+		duid, err := uuidDUID("00112233-4455-6677-8899-AABBCCDDEEFF")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(duid).To(Equal("00:04:33:22:11:00:55:44:77:66:88:99:aa:bb:cc:dd:ee:ff"))
+
+		_, err = uuidDUID("00112233-4455-6677-8899")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should derive the reservations of the network interfaces", func() {
+		host := &metalv1alpha1.BareMetalHost{
+			Status: metalv1alpha1.BareMetalHostStatus{
+				SystemUUID: "14141414-1414-1414-1414-000000000001",
+				NetworkInterfaces: []metalv1alpha1.NetworkInterface{
+					{ID: "1", MACAddress: "02:00:00:00:14:01"},
+					{ID: "2", MACAddress: "02:00:00:00:14:02", PermanentMACAddress: "02:00:A0:B0:C0:D0"},
+					{ID: "3"},
+				},
+			},
+		}
+		reservations, err := dhcpv6Reservations(host)
+		Expect(err).NotTo(HaveOccurred())
+		duid := "00:04:14:14:14:14:14:14:14:14:14:14:00:00:00:00:00:01"
+		Expect(reservations).To(Equal([]bootv1alpha1.DHCPv6Reservation{
+			{Interface: "1", MACAddress: "02:00:00:00:14:01", DUID: duid, IAID: 0x00001401},
+			{Interface: "2", MACAddress: "02:00:a0:b0:c0:d0", DUID: duid, IAID: 0xa0b0c0d0},
+		}))
+
+		host.Status.SystemUUID = "invalid"
+		_, err = dhcpv6Reservations(host)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should serve the boot file URL and reservations of the host", func(ctx SpecContext) {
+		ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ns)
+
+		uuid := "14141414-1414-1414-1414-000000000002"
+		_, host := setupFakeHost(ctx, uuid, withNetworkInterfaces(`[{"id": "1", "macAddress": "02:00:00:00:14:03", "pxeBoot": true}]`))
+
+		claim, _ := createClaim(ctx, ns, host, "")
+		expectPhase(ctx, claim, metalv1alpha1.PhaseBound)
+
+		dhcp := &bootv1alpha1.DHCP{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), dhcp)).To(Succeed())
+		Expect(dhcp.Spec.MACAddress).To(Equal("02:00:00:00:14:03"))
+		Expect(dhcp.Spec.BootFileURL).To(Equal("http://[2001:db8::1]:8082/ipxe/" + uuid))
+		Expect(dhcp.Spec.Reservations).To(Equal([]bootv1alpha1.DHCPv6Reservation{{
+			Interface:  "1",
+			MACAddress: "02:00:00:00:14:03",
+			DUID:       "00:04:14:14:14:14:14:14:14:14:14:14:00:00:00:00:00:02",
+			IAID:       0x00001403,
+		}}))
+	})
+})
@@ -0,0 +1,299 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/bmc"
+	"github.com/go-logr/logr"
+	"github.com/stmcginnis/gofish/common"
+	"github.com/stmcginnis/gofish/redfish"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// hostUnhealthyReason is the reason of the events for hosts which became unhealthy.
+	hostUnhealthyReason = "HostUnhealthy"
+	// hostHealthyReason is the reason of the events for hosts which recovered.
+	hostHealthyReason = "HostHealthy"
+	// remediatedReason is the reason of the events for applied remediation actions.
+	remediatedReason = "Remediated"
+)
+
+// HealthMonitor periodically evaluates the health of all hosts against the health policies
+// selecting them and sets the Healthy condition of the hosts accordingly.
+type HealthMonitor struct {
+	client.Client
+	// FakeBMCs keeps the state of the BMCs of type Fake.
+	FakeBMCs *bmc.FakeBMCs
+	Recorder record.EventRecorder
+	// Interval is the interval in which the hosts are evaluated.
+	Interval time.Duration
+	// Timeout bounds the evaluation of a single host.
+	Timeout time.Duration
+	// Concurrency is the maximum number of hosts which are evaluated in parallel.
+	Concurrency int
+}
+
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhosts,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhosts/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhostclaims,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=healthpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=bmcs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Start runs the monitor until the context is done.
+func (m *HealthMonitor) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("health")
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := m.evaluate(ctx, log); err != nil {
+			log.Error(err, "Failed to evaluate host health")
+		}
+	}, m.Interval)
+	return nil
+}
+
+// evaluate evaluates the health of all hosts.
+func (m *HealthMonitor) evaluate(ctx context.Context, log logr.Logger) error {
+	hostList := &metalv1alpha1.BareMetalHostList{}
+	if err := m.List(ctx, hostList); err != nil {
+		return fmt.Errorf("failed to list hosts: %w", err)
+	}
+	policyList := &metalv1alpha1.HealthPolicyList{}
+	if err := m.List(ctx, policyList); err != nil {
+		return fmt.Errorf("failed to list health policies: %w", err)
+	}
+	// the remediation of the first policy by name is applied if several policies fail
+	sort.Slice(policyList.Items, func(i, j int) bool { return policyList.Items[i].Name < policyList.Items[j].Name })
+
+	concurrency := m.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range hostList.Items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(host *metalv1alpha1.BareMetalHost) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			hostLog := log.WithValues("Host", host.Name)
+			if err := m.evaluateHost(ctx, hostLog, host, policyList.Items); err != nil {
+				hostLog.Error(err, "Failed to evaluate host health")
+			}
+		}(&hostList.Items[i])
+	}
+	wg.Wait()
+	return nil
+}
+
+func (m *HealthMonitor) evaluateHost(ctx context.Context, log logr.Logger, host *metalv1alpha1.BareMetalHost, policies []metalv1alpha1.HealthPolicy) error {
+	var selected []metalv1alpha1.HealthPolicy
+	for _, policy := range policies {
+		if policy.Spec.HostSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(policy.Spec.HostSelector)
+			if err != nil {
+				log.Error(err, "Invalid host selector of health policy", "HealthPolicy", policy.Name)
+				continue
+			}
+			if !selector.Matches(labels.Set(host.Labels)) {
+				continue
+			}
+		}
+		selected = append(selected, policy)
+	}
+
+	hostBase := host.DeepCopy()
+	if len(selected) == 0 {
+		if meta.RemoveStatusCondition(&host.Status.Conditions, metalv1alpha1.BareMetalHostConditionHealthy) {
+			if err := m.Status().Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
+				return fmt.Errorf("failed to remove health condition: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if m.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.Timeout)
+		defer cancel()
+	}
+	bmcObj := &metalv1alpha1.BMC{}
+	if err := m.Get(ctx, client.ObjectKey{Name: host.Spec.BMCRef.Name}, bmcObj); err != nil {
+		return fmt.Errorf("failed to get BMC %s for host: %w", host.Spec.BMCRef.Name, err)
+	}
+	bmcClient, err := newBMCClient(ctx, m.Client, m.FakeBMCs, bmcObj, host)
+	if err != nil {
+		return fmt.Errorf("failed to create BMC client: %w", err)
+	}
+	defer bmcClient.Logout()
+
+	health, err := bmcClient.GetHealth()
+	if err != nil {
+		return fmt.Errorf("failed to get health: %w", err)
+	}
+
+	var findings []string
+	var remediation metalv1alpha1.RemediationAction
+	for _, policy := range selected {
+		policyFindings := evaluateHealthPolicy(&policy, health, host, time.Now())
+		if len(policyFindings) == 0 {
+			continue
+		}
+		findings = append(findings, fmt.Sprintf("health policy %s: %s", policy.Name, strings.Join(policyFindings, ", ")))
+		if remediation == "" {
+			remediation = policy.Spec.Remediation
+		}
+	}
+
+	wasUnhealthy := meta.IsStatusConditionFalse(host.Status.Conditions, metalv1alpha1.BareMetalHostConditionHealthy)
+	condition := metav1.Condition{
+		Type:               metalv1alpha1.BareMetalHostConditionHealthy,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: host.Generation,
+		Reason:             metalv1alpha1.BareMetalHostReasonHealthy,
+		Message:            "The host satisfies all health policies selecting it.",
+	}
+	if len(findings) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = metalv1alpha1.BareMetalHostReasonUnhealthy
+		condition.Message = strings.Join(findings, "; ")
+	}
+	meta.SetStatusCondition(&host.Status.Conditions, condition)
+	log.V(1).Info("Patching health condition of host", "Status", condition.Status)
+	if err := m.Status().Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
+		return fmt.Errorf("failed to patch health condition: %w", err)
+	}
+	log.V(1).Info("Patched health condition of host", "Status", condition.Status)
+
+	switch {
+	case len(findings) > 0 && !wasUnhealthy:
+		m.Recorder.Event(host, v1.EventTypeWarning, hostUnhealthyReason, condition.Message)
+		m.recordClaimEvent(ctx, log, host, condition.Message)
+		return m.remediate(log, host, bmcClient, remediation)
+	case len(findings) == 0 && wasUnhealthy:
+		m.Recorder.Event(host, v1.EventTypeNormal, hostHealthyReason, condition.Message)
+	}
+	return nil
+}
+
+// recordClaimEvent reports the unhealthy host on the claim bound to it.
+func (m *HealthMonitor) recordClaimEvent(ctx context.Context, log logr.Logger, host *metalv1alpha1.BareMetalHost, message string) {
+	if host.Spec.ClaimRef == nil {
+		return
+	}
+	claim := &metalv1alpha1.BareMetalHostClaim{}
+	if err := m.Get(ctx, client.ObjectKey{Namespace: host.Spec.ClaimRef.Namespace, Name: host.Spec.ClaimRef.Name}, claim); err != nil {
+		log.Error(err, "Failed to get claim of host")
+		return
+	}
+	m.Recorder.Eventf(claim, v1.EventTypeWarning, hostUnhealthyReason, "Host %s is unhealthy: %s", host.Name, message)
+}
+
+// remediate applies the remediation action to the host which became unhealthy. Hosts which
+// are powered off are not power cycled.
+func (m *HealthMonitor) remediate(log logr.Logger, host *metalv1alpha1.BareMetalHost, bmcClient bmc.BMC, action metalv1alpha1.RemediationAction) error {
+	switch action {
+	case metalv1alpha1.RemediationActionPowerCycle:
+		if host.Status.PowerState != redfish.OnPowerState {
+			log.V(1).Info("Skipping power cycle of host which is not powered on")
+			return nil
+		}
+		log.V(1).Info("Power cycling host")
+		if err := bmcClient.Reset(); err != nil {
+			return fmt.Errorf("failed to power cycle host: %w", err)
+		}
+		log.V(1).Info("Power cycled host")
+	case metalv1alpha1.RemediationActionResetBMC:
+		log.V(1).Info("Resetting BMC of host")
+		if err := bmcClient.ResetManager(); err != nil {
+			return fmt.Errorf("failed to reset BMC of host: %w", err)
+		}
+		log.V(1).Info("Reset BMC of host")
+	default:
+		return nil
+	}
+	m.Recorder.Eventf(host, v1.EventTypeNormal, remediatedReason, "Applied remediation %s", action)
+	return nil
+}
+
+// evaluateHealthPolicy returns the thresholds of the policy which the host exceeds.
+func evaluateHealthPolicy(policy *metalv1alpha1.HealthPolicy, health bmc.Health, host *metalv1alpha1.BareMetalHost, now time.Time) []string {
+	var findings []string
+	if policy.Spec.Rollup != "" && healthRank(health.Rollup) >= healthRank(policy.Spec.Rollup) {
+		findings = append(findings, fmt.Sprintf("health rollup is %s", health.Rollup))
+	}
+
+	componentHealth := policy.Spec.ComponentHealth
+	if componentHealth == "" {
+		componentHealth = common.CriticalHealth
+	}
+	unhealthy := map[metalv1alpha1.ComponentType][]string{}
+	for _, component := range health.Components {
+		if healthRank(component.Health) >= healthRank(componentHealth) {
+			componentType := metalv1alpha1.ComponentType(component.Type)
+			unhealthy[componentType] = append(unhealthy[componentType], component.Name)
+		}
+	}
+	for _, threshold := range policy.Spec.Components {
+		if names := unhealthy[threshold.Type]; int32(len(names)) > threshold.MaxUnhealthy {
+			findings = append(findings, fmt.Sprintf("%d unhealthy %s components (%s)", len(names), threshold.Type, strings.Join(names, ", ")))
+		}
+	}
+
+	if policy.Spec.Logs != nil && host.Status.LogSummary != nil {
+		since := now.Add(-policy.Spec.Logs.Window.Duration)
+		var count int32
+		for _, entry := range host.Status.LogSummary.LatestCritical {
+			if entry.Created.Time.After(since) {
+				count++
+			}
+		}
+		if count > policy.Spec.Logs.MaxCritical {
+			findings = append(findings, fmt.Sprintf("%d critical log entries within %s", count, policy.Spec.Logs.Window.Duration))
+		}
+	}
+	return findings
+}
+
+// healthRank orders the health values. An unknown health is treated as OK.
+func healthRank(health common.Health) int {
+	switch health {
+	case common.CriticalHealth:
+		return 2
+	case common.WarningHealth:
+		return 1
+	default:
+		return 0
+	}
+}
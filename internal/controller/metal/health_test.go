@@ -0,0 +1,177 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"fmt"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/bmc"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stmcginnis/gofish/common"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Health Monitor", func() {
+	var ns *v1.Namespace
+
+	BeforeEach(func(ctx SpecContext) {
+		ns = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ns)
+	})
+
+	It("should reject log thresholds above the number of critical entries kept in the host status", func(ctx SpecContext) {
+		policy := &metalv1alpha1.HealthPolicy{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "logs-"},
+			Spec: metalv1alpha1.HealthPolicySpec{
+				Logs: &metalv1alpha1.LogThreshold{
+					MaxCritical: maxLatestCritical,
+					Window:      metav1.Duration{Duration: time.Hour},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, policy)).NotTo(Succeed())
+
+		policy.Spec.Logs.MaxCritical = maxLatestCritical - 1
+		Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, policy)
+	})
+
+	It("should quarantine unhealthy hosts and skip them when selecting hosts for claims", func(ctx SpecContext) {
+		By("Creating a fake BMC with two systems")
+		bmcObj, host := setupFakeHost(ctx, "55555555-5555-5555-5555-555555555551", withSystems("55555555-5555-5555-5555-555555555552"))
+		other := &metalv1alpha1.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{Name: objectName(bmcObj.Name, "System-2")},
+		}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(other), other)).To(Succeed())
+
+		By("Labeling the available hosts")
+		hostLabels := map[string]string{"health-test": bmcObj.Name}
+		hosts := []*metalv1alpha1.BareMetalHost{host, other}
+		for _, host := range hosts {
+			hostBase := host.DeepCopy()
+			host.Labels = hostLabels
+			Expect(k8sClient.Patch(ctx, host, client.MergeFrom(hostBase))).To(Succeed())
+		}
+
+		By("Creating a health policy for memory faults")
+		policy := &metalv1alpha1.HealthPolicy{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "memory-"},
+			Spec: metalv1alpha1.HealthPolicySpec{
+				HostSelector: &metav1.LabelSelector{MatchLabels: hostLabels},
+				Components: []metalv1alpha1.ComponentThreshold{{
+					Type:         metalv1alpha1.ComponentTypeMemory,
+					MaxUnhealthy: 0,
+				}},
+				Remediation: metalv1alpha1.RemediationActionResetBMC,
+			},
+		}
+		Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, policy)
+
+		By("Failing a memory module of the first system")
+		Expect(fakeBMCs.SetComponentHealth(bmcObj.Name, "System-1", []bmc.ComponentHealth{
+			{Type: bmc.ComponentTypeMemory, Name: "DIMM A1", Health: common.CriticalHealth},
+			{Type: bmc.ComponentTypeProcessor, Name: "CPU1", Health: common.OKHealth},
+		})).To(Succeed())
+
+		recorder := record.NewFakeRecorder(100)
+		monitor := &HealthMonitor{
+			Client:      k8sClient,
+			FakeBMCs:    fakeBMCs,
+			Recorder:    recorder,
+			Concurrency: 1,
+		}
+		Expect(monitor.evaluate(ctx, GinkgoLogr)).To(Succeed())
+
+		By("Ensuring that only the first host is unhealthy and remediated")
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(hosts[0]), hosts[0])).To(Succeed())
+		condition := meta.FindStatusCondition(hosts[0].Status.Conditions, metalv1alpha1.BareMetalHostConditionHealthy)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Message).To(ContainSubstring("1 unhealthy Memory components (DIMM A1)"))
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(hosts[1]), hosts[1])).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(hosts[1].Status.Conditions, metalv1alpha1.BareMetalHostConditionHealthy)).To(BeTrue())
+		Expect(fakeBMCs.ManagerResets(bmcObj.Name)).To(Equal(1))
+		events := collectEvents(recorder)
+		Expect(events).To(ContainElement(HavePrefix("Warning HostUnhealthy health policy " + policy.Name)))
+		Expect(events).To(ContainElement("Normal Remediated Applied remediation ResetBMC"))
+
+		By("Creating a claim selecting the labeled hosts")
+		claim := &metalv1alpha1.BareMetalHostClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "health-"},
+			Spec: metalv1alpha1.BareMetalHostClaimSpec{
+				Power:                 metalv1alpha1.PowerStateOff,
+				BareMetalHostSelector: &metav1.LabelSelector{MatchLabels: hostLabels},
+				Image:                 "my-image",
+			},
+		}
+		Expect(k8sClient.Create(ctx, claim)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, claim)
+
+		By("Ensuring that the claim skips the unhealthy host")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Spec.BareMetalHostRef.Name).To(Equal(hosts[1].Name))
+			g.Expect(claim.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+		}).Should(Succeed())
+
+		By("Creating a second claim while no host is selectable")
+		pending := &metalv1alpha1.BareMetalHostClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "health-"},
+			Spec: metalv1alpha1.BareMetalHostClaimSpec{
+				Power:                 metalv1alpha1.PowerStateOff,
+				BareMetalHostSelector: &metav1.LabelSelector{MatchLabels: hostLabels},
+				Image:                 "my-image",
+			},
+		}
+		Expect(k8sClient.Create(ctx, pending)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, pending)
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pending), pending)).To(Succeed())
+			g.Expect(pending.Status.Phase).To(Equal(metalv1alpha1.PhaseUnbound))
+		}).Should(Succeed())
+		Expect(pending.Spec.BareMetalHostRef.Name).To(BeEmpty())
+
+		By("Failing a memory module of the claimed host")
+		Expect(fakeBMCs.SetComponentHealth(bmcObj.Name, "System-2", []bmc.ComponentHealth{
+			{Type: bmc.ComponentTypeMemory, Name: "DIMM B1", Health: common.CriticalHealth},
+		})).To(Succeed())
+		Expect(monitor.evaluate(ctx, GinkgoLogr)).To(Succeed())
+		Expect(collectEvents(recorder)).To(ContainElement(
+			HavePrefix(fmt.Sprintf("%s %s Host %s is unhealthy", v1.EventTypeWarning, hostUnhealthyReason, hosts[1].Name))))
+
+		By("Recovering the first host")
+		Expect(fakeBMCs.SetComponentHealth(bmcObj.Name, "System-1", nil)).To(Succeed())
+		Expect(monitor.evaluate(ctx, GinkgoLogr)).To(Succeed())
+		Expect(collectEvents(recorder)).To(ContainElement(HavePrefix("Normal HostHealthy")))
+
+		By("Ensuring that the pending claim selects the recovered host")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pending), pending)).To(Succeed())
+			g.Expect(pending.Spec.BareMetalHostRef.Name).To(Equal(hosts[0].Name))
+			g.Expect(pending.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+		}).Should(Succeed())
+	})
+})
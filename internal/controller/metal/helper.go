@@ -0,0 +1,152 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/bmc"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// newBMCClient creates an instrumented BMC client for the system of the given host. The host
+// may be nil if only BMC wide operations are performed.
+func newBMCClient(ctx context.Context, c client.Client, fakeBMCs *bmc.FakeBMCs, bmcObj *metalv1alpha1.BMC, host *metalv1alpha1.BareMetalHost) (bmc.BMC, error) {
+	var systemID, hostName string
+	if host != nil {
+		systemID, hostName = host.Spec.SystemID, host.Name
+	}
+	start := time.Now()
+	bmcClient, err := connectBMC(ctx, c, fakeBMCs, bmcObj, systemID)
+	bmc.ObserveRequest("Connect", string(bmcObj.Spec.Type), hostName, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return bmc.Instrument(bmcClient, string(bmcObj.Spec.Type), hostName), nil
+}
+
+func connectBMC(ctx context.Context, c client.Client, fakeBMCs *bmc.FakeBMCs, bmcObj *metalv1alpha1.BMC, systemID string) (bmc.BMC, error) {
+	var err error
+	var bmcClient bmc.BMC
+
+	switch bmcObj.Spec.Type {
+	case metalv1alpha1.BMCTypeRedfishLocal:
+		bmcClient, err = bmc.NewRedfishLocalBMC(ctx, systemID, bmcObj.Spec.Address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create redfish local client: %w", err)
+		}
+	case metalv1alpha1.BMCTypeRedfish:
+		bmcSecret, err := getBMCSecret(ctx, c, bmcObj)
+		if err != nil {
+			return nil, err
+		}
+		var caBundle []byte
+		var ok bool
+		if bmcObj.Spec.TLS != nil && bmcObj.Spec.TLS.CASecretRef != nil {
+			caSecret := &v1.Secret{}
+			if err := c.Get(ctx, client.ObjectKey{Namespace: bmcObj.Spec.TLS.CASecretRef.Namespace, Name: bmcObj.Spec.TLS.CASecretRef.Name}, caSecret); err != nil {
+				return nil, fmt.Errorf("failed to get BMC CA secret: %w", err)
+			}
+			if caBundle, ok = caSecret.Data["ca.crt"]; !ok {
+				return nil, fmt.Errorf("no ca.crt provided in BMC CA secret")
+			}
+		}
+		bmcClient, err = bmc.NewRedfishBMC(ctx, systemID, bmcObj.Spec, string(bmcSecret.Data["username"]), string(bmcSecret.Data["password"]), caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create redfish client: %w", err)
+		}
+	case metalv1alpha1.BMCTypeFake:
+		if fakeBMCs == nil {
+			return nil, fmt.Errorf("BMCs of type %s are not enabled", bmcObj.Spec.Type)
+		}
+		// the inventory of a ConfigMap is synchronized by the BMC controller
+		var inventory []bmc.FakeSystem
+		if _, ok := bmcObj.Annotations[metalv1alpha1.FakeInventoryConfigMapAnnotation]; !ok {
+			if err := json.Unmarshal([]byte(bmcObj.Annotations[metalv1alpha1.FakeInventoryAnnotation]), &inventory); err != nil {
+				return nil, fmt.Errorf("failed to parse fake inventory: %w", err)
+			}
+		}
+		var powerTransitionDelay time.Duration
+		if value, ok := bmcObj.Annotations[metalv1alpha1.FakePowerTransitionDelayAnnotation]; ok {
+			if powerTransitionDelay, err = time.ParseDuration(value); err != nil {
+				return nil, fmt.Errorf("failed to parse fake power transition delay: %w", err)
+			}
+		}
+		bmcClient = fakeBMCs.NewFakeBMC(bmcObj.Name, systemID, inventory, powerTransitionDelay)
+	default:
+		return nil, fmt.Errorf("BMC type %s is not supported", bmcObj.Spec.Type)
+	}
+	return bmcClient, nil
+}
+
+// getBMCSecret returns the access secret of the BMC, which has to provide a username and a
+// password.
+func getBMCSecret(ctx context.Context, c client.Client, bmcObj *metalv1alpha1.BMC) (*v1.Secret, error) {
+	bmcSecret := &v1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: bmcObj.Spec.SecretRef.Namespace, Name: bmcObj.Spec.SecretRef.Name}, bmcSecret); err != nil {
+		return nil, fmt.Errorf("failed to get BMC access secret: %w", err)
+	}
+	if _, ok := bmcSecret.Data["username"]; !ok {
+		return nil, fmt.Errorf("no username provided in BMC access secret")
+	}
+	if _, ok := bmcSecret.Data["password"]; !ok {
+		return nil, fmt.Errorf("no password provided in BMC access secret")
+	}
+	return bmcSecret, nil
+}
+
+// objectName joins the given parts into a valid object name.
+func objectName(parts ...string) string {
+	name := strings.ToLower(strings.Join(parts, "-"))
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, name)
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		name = name[:validation.DNS1123SubdomainMaxLength]
+	}
+	return strings.Trim(name, "-.")
+}
+
+// hasTaint reports whether the host has a taint with the given key.
+func hasTaint(host *metalv1alpha1.BareMetalHost, key string) bool {
+	for _, taint := range host.Spec.Taints {
+		if taint.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// addTaint adds a taint with the given key to the host unless it already has one.
+func addTaint(host *metalv1alpha1.BareMetalHost, key, message string) {
+	if hasTaint(host, key) {
+		return
+	}
+	now := metav1.Now()
+	host.Spec.Taints = append(host.Spec.Taints, metalv1alpha1.HostTaint{Key: key, Message: message, TimeAdded: &now})
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// bareMetalHostRefNameField indexes the claims by the name of the host they reference.
+	bareMetalHostRefNameField = "spec.bareMetalHostRef.name"
+	// claimRefUIDField indexes the hosts by the UID of the claim they are claimed by.
+	claimRefUIDField = "spec.claimRef.uid"
+	// claimQueueField indexes the claims waiting in the queue.
+	claimQueueField = "status.queue"
+	// ignitionRefNameField indexes the claims by the name of their ignition secret.
+	ignitionRefNameField = "spec.ignitionRef.name"
+	// ipPoolRefNameField indexes the IP address claims by the name of their pool.
+	ipPoolRefNameField = "spec.ipPoolRef.name"
+
+	// claimQueuePending is the index value of the claims waiting for a host.
+	claimQueuePending = "Pending"
+	// claimQueueAnyHost is the index value of the claims which may be bound once another host
+	// changes, i.e. the pending claims selecting their host by labels and the claims exceeding a
+	// quota.
+	claimQueueAnyHost = "AnyHost"
+)
+
+// SetupFieldIndexes registers the field indexes used by the claim and host controllers to map
+// hosts and claims to each other, the ignition secrets to their claims and the IP address
+// claims to their pools.
+func SetupFieldIndexes(ctx context.Context, indexer client.FieldIndexer) error {
+	if err := indexer.IndexField(ctx, &metalv1alpha1.BareMetalHostClaim{}, bareMetalHostRefNameField, func(object client.Object) []string {
+		claim := object.(*metalv1alpha1.BareMetalHostClaim)
+		if claim.Spec.BareMetalHostRef.Name == "" {
+			return nil
+		}
+		return []string{claim.Spec.BareMetalHostRef.Name}
+	}); err != nil {
+		return fmt.Errorf("failed to index host claims by host: %w", err)
+	}
+	if err := indexer.IndexField(ctx, &metalv1alpha1.BareMetalHostClaim{}, claimQueueField, func(object client.Object) []string {
+		claim := object.(*metalv1alpha1.BareMetalHostClaim)
+		var queues []string
+		if isClaimPending(claim) {
+			queues = append(queues, claimQueuePending)
+		}
+		if (isClaimPending(claim) && claim.Spec.BareMetalHostRef.Name == "") || isQueuedOnQuota(claim) {
+			queues = append(queues, claimQueueAnyHost)
+		}
+		return queues
+	}); err != nil {
+		return fmt.Errorf("failed to index host claims by queue: %w", err)
+	}
+	if err := indexer.IndexField(ctx, &metalv1alpha1.BareMetalHostClaim{}, ignitionRefNameField, func(object client.Object) []string {
+		claim := object.(*metalv1alpha1.BareMetalHostClaim)
+		if claim.Spec.IgnitionRef == nil || claim.Spec.IgnitionRef.Name == "" {
+			return nil
+		}
+		return []string{claim.Spec.IgnitionRef.Name}
+	}); err != nil {
+		return fmt.Errorf("failed to index host claims by ignition: %w", err)
+	}
+	if err := indexer.IndexField(ctx, &metalv1alpha1.BareMetalHost{}, claimRefUIDField, func(object client.Object) []string {
+		host := object.(*metalv1alpha1.BareMetalHost)
+		if host.Spec.ClaimRef == nil || host.Spec.ClaimRef.UID == "" {
+			return nil
+		}
+		return []string{string(host.Spec.ClaimRef.UID)}
+	}); err != nil {
+		return fmt.Errorf("failed to index hosts by claim: %w", err)
+	}
+	if err := indexer.IndexField(ctx, &metalv1alpha1.IPAddressClaim{}, ipPoolRefNameField, func(object client.Object) []string {
+		claim := object.(*metalv1alpha1.IPAddressClaim)
+		return []string{claim.Spec.IPPoolRef.Name}
+	}); err != nil {
+		return fmt.Errorf("failed to index IP address claims by pool: %w", err)
+	}
+	return nil
+}
+
+// ConfigureSecretCache restricts the secrets cached by the manager to the ignition secrets,
+// whose metadata is watched by the claim controller. All secrets are read from the API server,
+// so that the content of secrets is never cached.
+func ConfigureSecretCache(options *ctrl.Options) {
+	if options.Cache.ByObject == nil {
+		options.Cache.ByObject = map[client.Object]cache.ByObject{}
+	}
+	options.Cache.ByObject[&v1.Secret{}] = cache.ByObject{
+		Label: labels.SelectorFromSet(labels.Set{metalv1alpha1.IgnitionSecretLabel: "true"}),
+	}
+	if options.Client.Cache == nil {
+		options.Client.Cache = &client.CacheOptions{}
+	}
+	options.Client.Cache.DisableFor = append(options.Client.Cache.DisableFor, &v1.Secret{})
+}
+
+// The claims and hosts returned by the following functions are shared with the cache and must
+// not be modified, as they are looked up for every host or claim event.
+
+// listHostClaims lists all claims referencing the host, including the claims competing with
+// the claim the host is claimed by.
+func listHostClaims(ctx context.Context, c client.Client, host *metalv1alpha1.BareMetalHost) ([]metalv1alpha1.BareMetalHostClaim, error) {
+	claimList := &metalv1alpha1.BareMetalHostClaimList{}
+	if err := c.List(ctx, claimList, client.MatchingFields{bareMetalHostRefNameField: host.Name}, client.UnsafeDisableDeepCopy); err != nil {
+		return nil, fmt.Errorf("failed to list host claims referencing host %s: %w", host.Name, err)
+	}
+	return claimList.Items, nil
+}
+
+// listQueuedClaims lists the claims in the queue.
+func listQueuedClaims(ctx context.Context, c client.Client, queue string) ([]metalv1alpha1.BareMetalHostClaim, error) {
+	claimList := &metalv1alpha1.BareMetalHostClaimList{}
+	if err := c.List(ctx, claimList, client.MatchingFields{claimQueueField: queue}, client.UnsafeDisableDeepCopy); err != nil {
+		return nil, fmt.Errorf("failed to list queued host claims: %w", err)
+	}
+	return claimList.Items, nil
+}
+
+// listUnboundClaims lists the claims which are not bound to a host yet: the pending claims and
+// the claims exceeding a quota.
+func listUnboundClaims(ctx context.Context, c client.Client) ([]metalv1alpha1.BareMetalHostClaim, error) {
+	claims, err := listQueuedClaims(ctx, c, claimQueuePending)
+	if err != nil {
+		return nil, err
+	}
+	anyHostClaims, err := listQueuedClaims(ctx, c, claimQueueAnyHost)
+	if err != nil {
+		return nil, err
+	}
+	for _, claim := range anyHostClaims {
+		if isQueuedOnQuota(&claim) {
+			claims = append(claims, claim)
+		}
+	}
+	return claims, nil
+}
+
+// listWaitingClaims lists the claims which may be bound to the host once it changes: the claims
+// referencing the host, the pending claims selecting it by labels and the claims waiting for
+// hosts of their pools to be released.
+func listWaitingClaims(ctx context.Context, c client.Client, host *metalv1alpha1.BareMetalHost) ([]metalv1alpha1.BareMetalHostClaim, error) {
+	claims, err := listHostClaims(ctx, c, host)
+	if err != nil {
+		return nil, err
+	}
+	anyHostClaims, err := listQueuedClaims(ctx, c, claimQueueAnyHost)
+	if err != nil {
+		return nil, err
+	}
+	for _, claim := range anyHostClaims {
+		// claims exceeding a quota may reference the host as well
+		if claim.Spec.BareMetalHostRef.Name != host.Name && (isQueuedOnQuota(&claim) || claimWantsHost(&claim, host)) {
+			claims = append(claims, claim)
+		}
+	}
+	return claims, nil
+}
+
+// listClaimedHosts lists the hosts claimed by the claim.
+func listClaimedHosts(ctx context.Context, c client.Client, claim *metalv1alpha1.BareMetalHostClaim) ([]metalv1alpha1.BareMetalHost, error) {
+	hostList := &metalv1alpha1.BareMetalHostList{}
+	if err := c.List(ctx, hostList, client.MatchingFields{claimRefUIDField: string(claim.UID)}, client.UnsafeDisableDeepCopy); err != nil {
+		return nil, fmt.Errorf("failed to list hosts claimed by claim: %w", err)
+	}
+	return hostList.Items, nil
+}
+
+// listPoolIPAddressClaims lists the IP address claims of the pool.
+func listPoolIPAddressClaims(ctx context.Context, c client.Client, pool string) ([]metalv1alpha1.IPAddressClaim, error) {
+	claimList := &metalv1alpha1.IPAddressClaimList{}
+	if err := c.List(ctx, claimList, client.MatchingFields{ipPoolRefNameField: pool}, client.UnsafeDisableDeepCopy); err != nil {
+		return nil, fmt.Errorf("failed to list IP address claims of pool %s: %w", pool, err)
+	}
+	return claimList.Items, nil
+}
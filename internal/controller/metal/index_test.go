@@ -0,0 +1,165 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// inNamespace returns the names of the claims of the namespace.
+func inNamespace(claims []metalv1alpha1.BareMetalHostClaim, ns *v1.Namespace) []string {
+	var names []string
+	for _, claim := range claims {
+		if claim.Namespace == ns.Name {
+			names = append(names, claim.Name)
+		}
+	}
+	return names
+}
+
+var _ = Describe("Field Indexes", func() {
+	const competingClaims = 1000
+
+	var ns *v1.Namespace
+
+	BeforeEach(func(ctx SpecContext) {
+		ns = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ns)
+	})
+
+	It("should map hosts and claims to each other with many competing claims", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "ffffffff-ffff-ffff-ffff-000000000001")
+		_, otherHost := setupFakeHost(ctx, "ffffffff-ffff-ffff-ffff-000000000002")
+		owner, _ := createClaim(ctx, ns, host, metalv1alpha1.ReprovisionPolicyOnChange)
+		expectPhase(ctx, owner, metalv1alpha1.PhaseBound)
+
+		By("Creating claims competing for the host")
+		DeferCleanup(func(ctx SpecContext) {
+			By("Deleting the competing claims")
+			Expect(k8sClient.DeleteAllOf(ctx, &metalv1alpha1.BareMetalHostClaim{}, client.InNamespace(ns.Name))).To(Succeed())
+			Eventually(func(g Gomega) {
+				claimList := &metalv1alpha1.BareMetalHostClaimList{}
+				g.Expect(k8sClient.List(ctx, claimList, client.InNamespace(ns.Name))).To(Succeed())
+				g.Expect(len(claimList.Items)).To(BeZero())
+			}).WithTimeout(time.Minute).Should(Succeed())
+		})
+		// the first claim is created first, so that it precedes the other claims in the queue
+		createCompeting := func(i int) error {
+			return k8sClient.Create(ctx, &metalv1alpha1.BareMetalHostClaim{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: fmt.Sprintf("competing-%04d", i)},
+				Spec: metalv1alpha1.BareMetalHostClaimSpec{
+					Power:            metalv1alpha1.PowerStateOff,
+					BareMetalHostRef: v1.LocalObjectReference{Name: host.Name},
+					Image:            "foo:latest",
+				},
+			})
+		}
+		Expect(createCompeting(0)).To(Succeed())
+		var wg sync.WaitGroup
+		next := make(chan int)
+		errs := make(chan error, competingClaims)
+		for w := 0; w < 16; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					errs <- createCompeting(i)
+				}
+			}()
+		}
+		for i := 1; i < competingClaims; i++ {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		By("Ensuring that all claims referencing the host are mapped to it")
+		Eventually(func(g Gomega) {
+			claims, err := listHostClaims(ctx, k8sManagerClient, host)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(claims).To(HaveLen(competingClaims + 1))
+		}).Should(Succeed())
+		hosts, err := listClaimedHosts(ctx, k8sManagerClient, owner)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hosts).To(ConsistOf(HaveField("Name", host.Name)))
+
+		By("Ensuring that the competing claims are queued")
+		Eventually(func(g Gomega) {
+			claims, err := listQueuedClaims(ctx, k8sManagerClient, claimQueuePending)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(inNamespace(claims, ns)).To(HaveLen(competingClaims))
+		}).WithTimeout(time.Minute).Should(Succeed())
+
+		By("Ensuring that the competing claims are not mapped to other hosts")
+		claims, err := listWaitingClaims(ctx, k8sManagerClient, otherHost)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inNamespace(claims, ns)).To(BeEmpty())
+
+		By("Releasing the host")
+		Expect(k8sClient.Delete(ctx, owner)).To(Succeed())
+		first := &metalv1alpha1.BareMetalHostClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: fmt.Sprintf("competing-%04d", 0)},
+		}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Spec.ClaimRef).NotTo(BeNil())
+			g.Expect(host.Spec.ClaimRef.Name).To(Equal(first.Name))
+		}).WithTimeout(time.Minute).Should(Succeed())
+		expectPhase(ctx, first, metalv1alpha1.PhaseBound)
+		Eventually(func(g Gomega) {
+			hosts, err := listClaimedHosts(ctx, k8sManagerClient, first)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(hosts).To(ConsistOf(HaveField("Name", host.Name)))
+			claims, err := listQueuedClaims(ctx, k8sManagerClient, claimQueuePending)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(inNamespace(claims, ns)).To(HaveLen(competingClaims - 1))
+		}).Should(Succeed())
+	})
+
+	It("should only map hosts to the pending claims selecting them", func(ctx SpecContext) {
+		selecting := createSelectorClaim(ctx, ns, map[string]string{"index": "a"}, 0, "")
+		other := createSelectorClaim(ctx, ns, map[string]string{"index": "b"}, 0, "")
+		expectPhase(ctx, selecting, metalv1alpha1.PhaseUnbound)
+		expectPhase(ctx, other, metalv1alpha1.PhaseUnbound)
+		Eventually(func(g Gomega) {
+			claims, err := listUnboundClaims(ctx, k8sManagerClient)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(inNamespace(claims, ns)).To(ConsistOf(selecting.Name, other.Name))
+		}).Should(Succeed())
+
+		host := &metalv1alpha1.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{Name: "index-host", Labels: map[string]string{"index": "a"}},
+		}
+		claims, err := listWaitingClaims(ctx, k8sManagerClient, host)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inNamespace(claims, ns)).To(ConsistOf(selecting.Name))
+	})
+})
@@ -0,0 +1,179 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/bmc"
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// ipAddressClaimName returns the name of the IP address claim of the claim for the network.
+func ipAddressClaimName(claim *metalv1alpha1.BareMetalHostClaim, network string) string {
+	return claim.Name + "-" + network
+}
+
+// ensureIPAddresses creates an IP address claim per address of the claim and returns the allocated
+// addresses. It reports whether all addresses are allocated, otherwise the host must not be
+// provisioned yet. IP address claims of removed or changed addresses are deleted, which releases
+// their addresses.
+func (r *BareMetalHostClaimReconciler) ensureIPAddresses(ctx context.Context, log logr.Logger, claim *metalv1alpha1.BareMetalHostClaim, host *metalv1alpha1.BareMetalHost) ([]v1alpha1.StaticAddress, bool, error) {
+	desired := map[string]bool{}
+	var addresses []v1alpha1.StaticAddress
+	var pending []string
+	for _, request := range claim.Spec.IPAddresses {
+		name := ipAddressClaimName(claim, request.Name)
+		desired[name] = true
+		spec := metalv1alpha1.IPAddressClaimSpec{
+			IPPoolRef:        request.IPPoolRef,
+			Address:          request.Address,
+			BareMetalHostRef: &v1.LocalObjectReference{Name: host.Name},
+		}
+
+		ipClaim := &metalv1alpha1.IPAddressClaim{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: claim.Namespace, Name: name}, ipClaim)
+		switch {
+		case apierrors.IsNotFound(err):
+			ipClaim = &metalv1alpha1.IPAddressClaim{
+				ObjectMeta: metav1.ObjectMeta{Namespace: claim.Namespace, Name: name},
+				Spec:       spec,
+			}
+			if err := controllerutil.SetControllerReference(claim, ipClaim, r.Scheme); err != nil {
+				return nil, false, fmt.Errorf("failed to set owner reference on IP address claim: %w", err)
+			}
+			log.V(1).Info("Creating IP address claim", "IPAddressClaim", name)
+			if err := r.Create(ctx, ipClaim); err != nil {
+				return nil, false, fmt.Errorf("failed to create IP address claim: %w", err)
+			}
+			pending = append(pending, fmt.Sprintf("%s: waiting for allocation", request.Name))
+			continue
+		case err != nil:
+			return nil, false, fmt.Errorf("failed to get IP address claim: %w", err)
+		case !metav1.IsControlledBy(ipClaim, claim):
+			pending = append(pending, fmt.Sprintf("%s: IP address claim %s is not owned by the claim", request.Name, name))
+			continue
+		case !equality.Semantic.DeepEqual(ipClaim.Spec, spec):
+			// the pool and the address of an IP address claim are immutable, so the claim is
+			// created again once the changed claim has released its address
+			if ipClaim.DeletionTimestamp.IsZero() {
+				log.V(1).Info("Deleting changed IP address claim", "IPAddressClaim", name)
+				if err := r.Delete(ctx, ipClaim); client.IgnoreNotFound(err) != nil {
+					return nil, false, fmt.Errorf("failed to delete changed IP address claim: %w", err)
+				}
+			}
+			pending = append(pending, fmt.Sprintf("%s: waiting for the changed address to be released", request.Name))
+			continue
+		}
+
+		if ipClaim.Status.Phase != metalv1alpha1.PhaseBound {
+			message := "waiting for allocation"
+			if condition := meta.FindStatusCondition(ipClaim.Status.Conditions, metalv1alpha1.IPAddressClaimConditionAllocated); condition != nil {
+				message = condition.Message
+			}
+			pending = append(pending, fmt.Sprintf("%s: %s", request.Name, message))
+			continue
+		}
+		mac := request.MACAddress
+		if mac == "" {
+			mac = host.Spec.BootMACAddress
+		}
+		if mac == "" {
+			pending = append(pending, fmt.Sprintf("%s: host %s has no boot MAC address", request.Name, host.Name))
+			continue
+		}
+		addresses = append(addresses, v1alpha1.StaticAddress{
+			Name:       request.Name,
+			MACAddress: bmc.NormalizeMAC(mac),
+			Address:    fmt.Sprintf("%s/%d", ipClaim.Status.Address, ipClaim.Status.Prefix),
+			Gateway:    ipClaim.Status.Gateway,
+			DNSServers: ipClaim.Status.DNSServers,
+		})
+	}
+
+	if err := r.deleteIPAddressClaims(ctx, claim, desired); err != nil {
+		return nil, false, err
+	}
+	if err := r.patchIPAddressesCondition(ctx, claim, pending); err != nil {
+		return nil, false, err
+	}
+	return addresses, len(pending) == 0, nil
+}
+
+// patchIPAddressesCondition reports the addresses of the claim which are not allocated yet. The
+// condition is only set if the claim requests addresses.
+func (r *BareMetalHostClaimReconciler) patchIPAddressesCondition(ctx context.Context, claim *metalv1alpha1.BareMetalHostClaim, pending []string) error {
+	claimBase := claim.DeepCopy()
+	var changed bool
+	switch {
+	case len(claim.Spec.IPAddresses) == 0:
+		changed = meta.RemoveStatusCondition(&claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionIPAddressesAllocated)
+	case len(pending) == 0:
+		changed = meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+			Type:               metalv1alpha1.BareMetalHostClaimConditionIPAddressesAllocated,
+			Status:             metav1.ConditionTrue,
+			Reason:             metalv1alpha1.BareMetalHostClaimReasonIPAddressesAllocated,
+			Message:            "All addresses are allocated.",
+			ObservedGeneration: claim.Generation,
+		})
+	default:
+		changed = meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+			Type:               metalv1alpha1.BareMetalHostClaimConditionIPAddressesAllocated,
+			Status:             metav1.ConditionFalse,
+			Reason:             metalv1alpha1.BareMetalHostClaimReasonIPAddressesPending,
+			Message:            strings.Join(pending, "; "),
+			ObservedGeneration: claim.Generation,
+		})
+	}
+	if !changed {
+		return nil
+	}
+	if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+		return fmt.Errorf("failed to patch IP addresses condition: %w", err)
+	}
+	return nil
+}
+
+// deleteIPAddressClaims deletes the IP address claims of the claim which are not desired, which
+// releases their addresses.
+func (r *BareMetalHostClaimReconciler) deleteIPAddressClaims(ctx context.Context, claim *metalv1alpha1.BareMetalHostClaim, desired map[string]bool) error {
+	claimList := &metalv1alpha1.IPAddressClaimList{}
+	if err := r.List(ctx, claimList, client.InNamespace(claim.Namespace)); err != nil {
+		return fmt.Errorf("failed to list IP address claims: %w", err)
+	}
+	for i := range claimList.Items {
+		ipClaim := &claimList.Items[i]
+		if desired[ipClaim.Name] || !metav1.IsControlledBy(ipClaim, claim) || !ipClaim.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if err := r.Delete(ctx, ipClaim); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete IP address claim: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/onmetal/controller-utils/clientutils"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// addressInUseReason is the event reason of a claim requesting an address allocated to
+	// another claim.
+	addressInUseReason = "AddressInUse"
+)
+
+// IPAddressClaimReconciler reconciles a IPAddressClaim object
+type IPAddressClaimReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=ipaddressclaims,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=ipaddressclaims/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=ipaddressclaims/finalizers,verbs=update
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=ippools,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=ippools/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *IPAddressClaimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	claim := &metalv1alpha1.IPAddressClaim{}
+	if err := r.Get(ctx, req.NamespacedName, claim); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	return r.reconcileExists(ctx, log, claim)
+}
+
+func (r *IPAddressClaimReconciler) reconcileExists(ctx context.Context, log logr.Logger, claim *metalv1alpha1.IPAddressClaim) (ctrl.Result, error) {
+	if !claim.DeletionTimestamp.IsZero() {
+		return r.delete(ctx, log, claim)
+	}
+	return r.reconcile(ctx, log, claim)
+}
+
+func (r *IPAddressClaimReconciler) delete(ctx context.Context, log logr.Logger, claim *metalv1alpha1.IPAddressClaim) (ctrl.Result, error) {
+	log.V(1).Info("Releasing address")
+	pool := &metalv1alpha1.IPPool{}
+	if err := r.Get(ctx, client.ObjectKey{Name: claim.Spec.IPPoolRef.Name}, pool); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get IP pool: %w", err)
+	} else if err == nil {
+		poolBase := pool.DeepCopy()
+		pool.Status.Allocations = removeAllocation(pool.Status.Allocations, claim.UID)
+		pool.Status.Allocated = int32(len(pool.Status.Allocations))
+		if len(pool.Status.Allocations) != len(poolBase.Status.Allocations) {
+			if err := r.Status().Patch(ctx, pool, client.MergeFromWithOptions(poolBase, client.MergeFromWithOptimisticLock{})); err != nil {
+				if apierrors.IsConflict(err) {
+					return ctrl.Result{Requeue: true}, nil
+				}
+				return ctrl.Result{}, fmt.Errorf("failed to release address: %w", err)
+			}
+		}
+	}
+	log.V(1).Info("Released address", "Address", claim.Status.Address)
+
+	if _, err := clientutils.PatchEnsureNoFinalizer(ctx, r.Client, claim, metalv1alpha1.IPAddressClaimFinalizer); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *IPAddressClaimReconciler) reconcile(ctx context.Context, log logr.Logger, claim *metalv1alpha1.IPAddressClaim) (ctrl.Result, error) {
+	log.V(1).Info("Reconciling IP address claim")
+
+	if modified, err := clientutils.PatchEnsureFinalizer(ctx, r.Client, claim, metalv1alpha1.IPAddressClaimFinalizer); err != nil || modified {
+		return ctrl.Result{}, err
+	}
+
+	pool := &metalv1alpha1.IPPool{}
+	if err := r.Get(ctx, client.ObjectKey{Name: claim.Spec.IPPoolRef.Name}, pool); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("failed to get IP pool: %w", err)
+		}
+		// the claim is reconciled again once the pool is created
+		return ctrl.Result{}, r.patchUnallocated(ctx, log, claim, metalv1alpha1.IPAddressClaimReasonPoolNotFound,
+			fmt.Sprintf("IP pool %s does not exist", claim.Spec.IPPoolRef.Name))
+	}
+	parsed, err := parseIPPool(pool)
+	if err != nil {
+		return ctrl.Result{}, r.patchUnallocated(ctx, log, claim, metalv1alpha1.IPAddressClaimReasonPoolInvalid,
+			fmt.Sprintf("IP pool %s is invalid: %s", pool.Name, err))
+	}
+
+	address := ""
+	for _, allocation := range pool.Status.Allocations {
+		if allocation.ClaimRef.UID == claim.UID {
+			address = allocation.Address
+			break
+		}
+	}
+	if address == "" {
+		if len(pool.Spec.Namespaces) > 0 && !slices.Contains(pool.Spec.Namespaces, claim.Namespace) {
+			return ctrl.Result{}, r.patchUnallocated(ctx, log, claim, metalv1alpha1.IPAddressClaimReasonNamespaceDenied,
+				fmt.Sprintf("IP pool %s does not allow claims of namespace %s", pool.Name, claim.Namespace))
+		}
+		addr, reason, message := r.selectAddress(parsed, pool, claim)
+		if reason != "" {
+			return ctrl.Result{}, r.patchUnallocated(ctx, log, claim, reason, message)
+		}
+		log.V(1).Info("Allocating address", "Address", addr)
+		poolBase := pool.DeepCopy()
+		pool.Status.Allocations = append(pool.Status.Allocations, metalv1alpha1.IPAllocation{
+			Address: addr.String(),
+			ClaimRef: v1.ObjectReference{
+				Kind:      "IPAddressClaim",
+				Namespace: claim.Namespace,
+				Name:      claim.Name,
+				UID:       claim.UID,
+			},
+		})
+		pool.Status.Allocated = int32(len(pool.Status.Allocations))
+		// the optimistic lock rejects the allocation if the pool has been changed concurrently,
+		// e.g. by another claim allocating the same address
+		if err := r.Status().Patch(ctx, pool, client.MergeFromWithOptions(poolBase, client.MergeFromWithOptimisticLock{})); err != nil {
+			if apierrors.IsConflict(err) {
+				log.V(1).Info("IP pool changed concurrently: retrying allocation")
+				return ctrl.Result{Requeue: true}, nil
+			}
+			return ctrl.Result{}, fmt.Errorf("failed to allocate address: %w", err)
+		}
+		log.V(1).Info("Allocated address", "Address", addr)
+		address = addr.String()
+	}
+
+	claimBase := claim.DeepCopy()
+	claim.Status.Phase = metalv1alpha1.PhaseBound
+	claim.Status.Address = address
+	claim.Status.Prefix = int32(parsed.prefix.Bits())
+	claim.Status.Gateway = pool.Spec.Gateway
+	claim.Status.DNSServers = pool.Spec.DNSServers
+	meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               metalv1alpha1.IPAddressClaimConditionAllocated,
+		Status:             metav1.ConditionTrue,
+		Reason:             metalv1alpha1.IPAddressClaimReasonAllocated,
+		Message:            fmt.Sprintf("Address %s is allocated from IP pool %s", address, pool.Name),
+		ObservedGeneration: claim.Generation,
+	})
+	if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to patch IP address claim status: %w", err)
+	}
+
+	log.V(1).Info("Reconciled IP address claim", "Address", address)
+	return ctrl.Result{}, nil
+}
+
+// selectAddress returns the requested address or a free address of the pool. The reason and the
+// message describe why no address can be allocated.
+func (r *IPAddressClaimReconciler) selectAddress(parsed *ipPool, pool *metalv1alpha1.IPPool, claim *metalv1alpha1.IPAddressClaim) (netip.Addr, string, string) {
+	allocated := map[netip.Addr]*v1.ObjectReference{}
+	for i := range pool.Status.Allocations {
+		if addr, err := netip.ParseAddr(pool.Status.Allocations[i].Address); err == nil {
+			allocated[addr] = &pool.Status.Allocations[i].ClaimRef
+		}
+	}
+
+	if claim.Spec.Address != "" {
+		addr, err := netip.ParseAddr(claim.Spec.Address)
+		if err != nil || !parsed.usable(addr) {
+			return netip.Addr{}, metalv1alpha1.IPAddressClaimReasonAddressNotInPool,
+				fmt.Sprintf("Address %s cannot be allocated from IP pool %s", claim.Spec.Address, pool.Name)
+		}
+		if owner := allocated[addr]; owner != nil {
+			return netip.Addr{}, metalv1alpha1.IPAddressClaimReasonAddressInUse,
+				fmt.Sprintf("Address %s is allocated to claim %s/%s", addr, owner.Namespace, owner.Name)
+		}
+		return addr, "", ""
+	}
+
+	// a bound claim whose allocation is missing, e.g. as the status of the pool has not been
+	// restored from a backup, is allocated its address again
+	if addr, err := netip.ParseAddr(claim.Status.Address); err == nil && parsed.usable(addr) && allocated[addr] == nil {
+		return addr, "", ""
+	}
+
+	// hosts are allocated the same address whenever it is free, independent of their claim
+	key := "claim/" + claim.Namespace + "/" + claim.Name
+	if claim.Spec.BareMetalHostRef != nil && claim.Spec.BareMetalHostRef.Name != "" {
+		key = "host/" + claim.Spec.BareMetalHostRef.Name
+	}
+	addr, ok := parsed.allocate(key, func(addr netip.Addr) bool { return allocated[addr] != nil })
+	if !ok {
+		return netip.Addr{}, metalv1alpha1.IPAddressClaimReasonPoolExhausted,
+			fmt.Sprintf("IP pool %s has no free address", pool.Name)
+	}
+	return addr, "", ""
+}
+
+// patchUnallocated reports why no address is allocated to the claim. A claim requesting an address
+// allocated to another claim is moved to the Conflict phase.
+func (r *IPAddressClaimReconciler) patchUnallocated(ctx context.Context, log logr.Logger, claim *metalv1alpha1.IPAddressClaim, reason, message string) error {
+	claimBase := claim.DeepCopy()
+	claim.Status.Phase = metalv1alpha1.PhaseUnbound
+	if reason == metalv1alpha1.IPAddressClaimReasonAddressInUse {
+		claim.Status.Phase = metalv1alpha1.PhaseConflict
+	}
+	changed := meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               metalv1alpha1.IPAddressClaimConditionAllocated,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: claim.Generation,
+	})
+	if !changed && claimBase.Status.Phase == claim.Status.Phase {
+		return nil
+	}
+	log.V(1).Info("No address allocated", "Reason", reason, "Message", message)
+	if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+		return fmt.Errorf("failed to patch IP address claim status: %w", err)
+	}
+	if claim.Status.Phase == metalv1alpha1.PhaseConflict && claimBase.Status.Phase != metalv1alpha1.PhaseConflict {
+		r.Recorder.Event(claim, v1.EventTypeWarning, addressInUseReason, message)
+	}
+	return nil
+}
+
+// removeAllocation removes the allocation of the claim from the allocations.
+func removeAllocation(allocations []metalv1alpha1.IPAllocation, uid types.UID) []metalv1alpha1.IPAllocation {
+	var remaining []metalv1alpha1.IPAllocation
+	for _, allocation := range allocations {
+		if allocation.ClaimRef.UID != uid {
+			remaining = append(remaining, allocation)
+		}
+	}
+	return remaining
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IPAddressClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&metalv1alpha1.IPAddressClaim{}).
+		Watches(&metalv1alpha1.IPPool{}, r.enqueueIPAddressClaimsByPool()).
+		Complete(r)
+}
+
+// enqueueIPAddressClaimsByPool enqueues the claims of a pool without an address once the pool
+// changes, e.g. as it has been created or an address has been released.
+func (r *IPAddressClaimReconciler) enqueueIPAddressClaimsByPool() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, object client.Object) []reconcile.Request {
+		log := ctrl.LoggerFrom(ctx)
+
+		claims, err := listPoolIPAddressClaims(ctx, r.Client, object.GetName())
+		if err != nil {
+			log.Error(err, "failed to list IP address claims of pool")
+			return nil
+		}
+		var req []reconcile.Request
+		for _, claim := range claims {
+			if claim.Status.Phase != metalv1alpha1.PhaseBound {
+				req = append(req, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
+				})
+			}
+		}
+		return req
+	})
+}
@@ -0,0 +1,338 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// createIPPool creates a pool of the network.
+func createIPPool(ctx SpecContext, spec metalv1alpha1.IPPoolSpec) *metalv1alpha1.IPPool {
+	pool := &metalv1alpha1.IPPool{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "ippool-"},
+		Spec:       spec,
+	}
+	Expect(k8sClient.Create(ctx, pool)).To(Succeed())
+	DeferCleanup(k8sClient.Delete, pool)
+	return pool
+}
+
+// createIPAddressClaim creates a claim for an address of the pool.
+func createIPAddressClaim(ctx SpecContext, ns *v1.Namespace, pool *metalv1alpha1.IPPool, address, host string) *metalv1alpha1.IPAddressClaim {
+	claim := &metalv1alpha1.IPAddressClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "ipclaim-"},
+		Spec: metalv1alpha1.IPAddressClaimSpec{
+			IPPoolRef: v1.LocalObjectReference{Name: pool.Name},
+			Address:   address,
+		},
+	}
+	if host != "" {
+		claim.Spec.BareMetalHostRef = &v1.LocalObjectReference{Name: host}
+	}
+	Expect(k8sClient.Create(ctx, claim)).To(Succeed())
+	DeferCleanup(func(ctx SpecContext) {
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, claim))).To(Succeed())
+	})
+	return claim
+}
+
+// expectAllocated waits for an address to be allocated to the claim and returns it.
+func expectAllocated(ctx SpecContext, claim *metalv1alpha1.IPAddressClaim) string {
+	GinkgoHelper()
+	Eventually(func(g Gomega) {
+		g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+		g.Expect(claim.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+		g.Expect(meta.IsStatusConditionTrue(claim.Status.Conditions, metalv1alpha1.IPAddressClaimConditionAllocated)).To(BeTrue())
+	}).Should(Succeed())
+	return claim.Status.Address
+}
+
+// expectDeleted deletes the claim and waits until its address has been released.
+func expectDeleted(ctx SpecContext, obj client.Object) {
+	GinkgoHelper()
+	Expect(k8sClient.Delete(ctx, obj)).To(Succeed())
+	Eventually(func() bool {
+		return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), obj))
+	}).Should(BeTrue())
+}
+
+var _ = Describe("IPAddressClaim Controller", func() {
+	var ns *v1.Namespace
+
+	BeforeEach(func(ctx SpecContext) {
+		ns = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ns)
+	})
+
+	It("should allocate free addresses of the pool", func(ctx SpecContext) {
+		pool := createIPPool(ctx, metalv1alpha1.IPPoolSpec{
+			CIDR:       "10.0.0.0/29",
+			Gateway:    "10.0.0.1",
+			DNSServers: []string{"10.0.0.2"},
+			Reserved:   []metalv1alpha1.IPRange{{Start: "10.0.0.2", End: "10.0.0.4"}},
+		})
+
+		By("Allocating the usable addresses")
+		claim1 := createIPAddressClaim(ctx, ns, pool, "", "")
+		claim2 := createIPAddressClaim(ctx, ns, pool, "", "")
+		Expect([]string{expectAllocated(ctx, claim1), expectAllocated(ctx, claim2)}).To(ConsistOf("10.0.0.5", "10.0.0.6"))
+		Expect(claim1.Status.Prefix).To(BeEquivalentTo(29))
+		Expect(claim1.Status.Gateway).To(Equal("10.0.0.1"))
+		Expect(claim1.Status.DNSServers).To(Equal([]string{"10.0.0.2"}))
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pool), pool)).To(Succeed())
+			g.Expect(pool.Status.Allocated).To(BeEquivalentTo(2))
+			g.Expect(meta.IsStatusConditionTrue(pool.Status.Conditions, metalv1alpha1.IPPoolConditionValid)).To(BeTrue())
+		}).Should(Succeed())
+
+		By("Expecting the pool to be exhausted")
+		claim3 := createIPAddressClaim(ctx, ns, pool, "", "")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim3), claim3)).To(Succeed())
+			g.Expect(claim3.Status.Phase).To(Equal(metalv1alpha1.PhaseUnbound))
+			condition := meta.FindStatusCondition(claim3.Status.Conditions, metalv1alpha1.IPAddressClaimConditionAllocated)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Reason).To(Equal(metalv1alpha1.IPAddressClaimReasonPoolExhausted))
+		}).Should(Succeed())
+
+		By("Releasing an address on deletion")
+		released := claim1.Status.Address
+		expectDeleted(ctx, claim1)
+		Expect(expectAllocated(ctx, claim3)).To(Equal(released))
+	})
+
+	It("should allocate a host the same address again", func(ctx SpecContext) {
+		pool := createIPPool(ctx, metalv1alpha1.IPPoolSpec{CIDR: "10.0.1.0/24"})
+
+		claim := createIPAddressClaim(ctx, ns, pool, "", "host-1")
+		address := expectAllocated(ctx, claim)
+		expectDeleted(ctx, claim)
+
+		claim = createIPAddressClaim(ctx, ns, pool, "", "host-1")
+		Expect(expectAllocated(ctx, claim)).To(Equal(address))
+	})
+
+	It("should report claims requesting an allocated address as conflicts", func(ctx SpecContext) {
+		pool := createIPPool(ctx, metalv1alpha1.IPPoolSpec{CIDR: "10.0.2.0/24", Gateway: "10.0.2.1"})
+
+		By("Requesting an address outside of the pool")
+		invalid := createIPAddressClaim(ctx, ns, pool, "10.0.2.1", "")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(invalid), invalid)).To(Succeed())
+			condition := meta.FindStatusCondition(invalid.Status.Conditions, metalv1alpha1.IPAddressClaimConditionAllocated)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Reason).To(Equal(metalv1alpha1.IPAddressClaimReasonAddressNotInPool))
+		}).Should(Succeed())
+
+		By("Requesting the same address twice")
+		owner := createIPAddressClaim(ctx, ns, pool, "10.0.2.10", "")
+		Expect(expectAllocated(ctx, owner)).To(Equal("10.0.2.10"))
+		conflicting := createIPAddressClaim(ctx, ns, pool, "10.0.2.10", "")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(conflicting), conflicting)).To(Succeed())
+			g.Expect(conflicting.Status.Phase).To(Equal(metalv1alpha1.PhaseConflict))
+			condition := meta.FindStatusCondition(conflicting.Status.Conditions, metalv1alpha1.IPAddressClaimConditionAllocated)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Reason).To(Equal(metalv1alpha1.IPAddressClaimReasonAddressInUse))
+			g.Expect(condition.Message).To(ContainSubstring(owner.Name))
+		}).Should(Succeed())
+		Eventually(func(g Gomega) {
+			eventList := &v1.EventList{}
+			g.Expect(k8sClient.List(ctx, eventList, client.InNamespace(ns.Name))).To(Succeed())
+			g.Expect(eventList.Items).To(ContainElement(And(
+				HaveField("InvolvedObject.Name", conflicting.Name),
+				HaveField("Reason", addressInUseReason),
+			)))
+		}).Should(Succeed())
+
+		By("Allocating the address once it is released")
+		expectDeleted(ctx, owner)
+		Expect(expectAllocated(ctx, conflicting)).To(Equal("10.0.2.10"))
+	})
+
+	It("should release addresses of removed claims", func(ctx SpecContext) {
+		pool := createIPPool(ctx, metalv1alpha1.IPPoolSpec{CIDR: "10.0.3.0/24"})
+		claim := createIPAddressClaim(ctx, ns, pool, "", "")
+		expectAllocated(ctx, claim)
+
+		By("Removing the claim without releasing its address")
+		Expect(k8sClient.Delete(ctx, claim)).To(Succeed())
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim))
+		}).Should(BeTrue())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pool), pool)).To(Succeed())
+		poolBase := pool.DeepCopy()
+		pool.Status.Allocations = append(pool.Status.Allocations, metalv1alpha1.IPAllocation{
+			Address:  "10.0.3.100",
+			ClaimRef: v1.ObjectReference{Kind: "IPAddressClaim", Namespace: ns.Name, Name: claim.Name, UID: claim.UID},
+		})
+		Expect(k8sClient.Status().Patch(ctx, pool, client.MergeFrom(poolBase))).To(Succeed())
+
+		By("Expecting the stale allocation to be released")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pool), pool)).To(Succeed())
+			g.Expect(pool.Status.Allocations).To(BeEmpty())
+			g.Expect(pool.Status.Allocated).To(BeZero())
+		}).Should(Succeed())
+	})
+
+	It("should rebuild the allocations of the pool from its claims", func(ctx SpecContext) {
+		pool := createIPPool(ctx, metalv1alpha1.IPPoolSpec{CIDR: "10.0.6.0/24"})
+		claim := createIPAddressClaim(ctx, ns, pool, "", "")
+		address := expectAllocated(ctx, claim)
+
+		By("Removing the allocations from the status of the pool")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pool), pool)).To(Succeed())
+			g.Expect(pool.Status.Allocations).To(HaveLen(1))
+			poolBase := pool.DeepCopy()
+			pool.Status.Allocations = nil
+			pool.Status.Allocated = 0
+			g.Expect(k8sClient.Status().Patch(ctx, pool, client.MergeFromWithOptions(poolBase, client.MergeFromWithOptimisticLock{}))).To(Succeed())
+		}).Should(Succeed())
+
+		By("Expecting the allocation of the claim to be rebuilt")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pool), pool)).To(Succeed())
+			g.Expect(pool.Status.Allocations).To(ConsistOf(And(
+				HaveField("Address", address),
+				HaveField("ClaimRef.UID", claim.UID),
+			)))
+			g.Expect(pool.Status.Allocated).To(BeEquivalentTo(1))
+		}).Should(Succeed())
+		Expect(expectAllocated(ctx, claim)).To(Equal(address))
+	})
+
+	It("should only allocate addresses to claims of the namespaces of the pool", func(ctx SpecContext) {
+		pool := createIPPool(ctx, metalv1alpha1.IPPoolSpec{CIDR: "10.0.7.0/24", Namespaces: []string{"other"}})
+		claim := createIPAddressClaim(ctx, ns, pool, "", "")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.Phase).To(Equal(metalv1alpha1.PhaseUnbound))
+			condition := meta.FindStatusCondition(claim.Status.Conditions, metalv1alpha1.IPAddressClaimConditionAllocated)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Reason).To(Equal(metalv1alpha1.IPAddressClaimReasonNamespaceDenied))
+		}).Should(Succeed())
+		Consistently(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pool), pool)).To(Succeed())
+			g.Expect(pool.Status.Allocations).To(BeEmpty())
+			g.Expect(meta.IsStatusConditionTrue(pool.Status.Conditions, metalv1alpha1.IPPoolConditionValid)).To(BeTrue())
+		}, "2s").Should(Succeed())
+
+		By("Allowing the namespace of the claim")
+		poolBase := pool.DeepCopy()
+		pool.Spec.Namespaces = append(pool.Spec.Namespaces, ns.Name)
+		Expect(k8sClient.Patch(ctx, pool, client.MergeFrom(poolBase))).To(Succeed())
+		expectAllocated(ctx, claim)
+	})
+
+	It("should configure the addresses of a host claim in its boot configuration", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "13131313-1313-1313-1313-000000000001")
+		hostBase := host.DeepCopy()
+		host.Spec.BootMACAddress = "02:00:00:00:13:01"
+		Expect(k8sClient.Patch(ctx, host, client.MergeFrom(hostBase))).To(Succeed())
+		provisioning := createIPPool(ctx, metalv1alpha1.IPPoolSpec{CIDR: "10.0.4.0/24", Gateway: "10.0.4.1", DNSServers: []string{"10.0.4.2"}})
+		tenant := createIPPool(ctx, metalv1alpha1.IPPoolSpec{CIDR: "2001:db8::/64"})
+
+		By("Creating a claim requesting addresses of both pools")
+		ignition := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "ignition-"},
+			Data:       map[string][]byte{"ignition": []byte(`{"ignition": {"version": "3.2.0"}}`)},
+		}
+		Expect(k8sClient.Create(ctx, ignition)).To(Succeed())
+		claim := &metalv1alpha1.BareMetalHostClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "claim-"},
+			Spec: metalv1alpha1.BareMetalHostClaimSpec{
+				Power:            metalv1alpha1.PowerStateOn,
+				BareMetalHostRef: v1.LocalObjectReference{Name: host.Name},
+				IgnitionRef:      &v1.LocalObjectReference{Name: ignition.Name},
+				Image:            "foo:latest",
+				IPAddresses: []metalv1alpha1.ClaimIPAddress{
+					{Name: "provisioning", IPPoolRef: v1.LocalObjectReference{Name: provisioning.Name}, Address: "10.0.4.10"},
+					{Name: "tenant", IPPoolRef: v1.LocalObjectReference{Name: tenant.Name}, MACAddress: "02:00:00:00:13:02"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, claim)).To(Succeed())
+		expectPhase(ctx, claim, metalv1alpha1.PhaseBound)
+		Expect(meta.IsStatusConditionTrue(claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionIPAddressesAllocated)).To(BeTrue())
+
+		tenantClaim := &metalv1alpha1.IPAddressClaim{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: ns.Name, Name: claim.Name + "-tenant"}, tenantClaim)).To(Succeed())
+		Expect(tenantClaim.Spec.BareMetalHostRef).To(Equal(&v1.LocalObjectReference{Name: host.Name}))
+		tenantAddress := expectAllocated(ctx, tenantClaim)
+		Expect(tenantAddress).To(HavePrefix("2001:db8::"))
+		addresses := []bootv1alpha1.StaticAddress{
+			{Name: "provisioning", MACAddress: "02:00:00:00:13:01", Address: "10.0.4.10/24", Gateway: "10.0.4.1", DNSServers: []string{"10.0.4.2"}},
+			{Name: "tenant", MACAddress: "02:00:00:00:13:02", Address: tenantAddress + "/64"},
+		}
+
+		By("Expecting the addresses in the DHCP configuration")
+		dhcp := &bootv1alpha1.DHCP{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), dhcp)).To(Succeed())
+		Expect(dhcp.Spec.MACAddress).To(Equal("02:00:00:00:13:01"))
+		Expect(dhcp.Spec.Addresses).To(Equal(addresses))
+
+		By("Expecting the addresses to be configured by the served ignition")
+		pxeSecret := &v1.Secret{}
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "oob", Name: "ipxe-" + host.Status.SystemUUID}, pxeSecret)).To(Succeed())
+		}).Should(Succeed())
+		config := struct {
+			Ignition struct {
+				Version string `json:"version"`
+			} `json:"ignition"`
+			Storage struct {
+				Files []struct {
+					Path     string `json:"path"`
+					Contents struct {
+						Source string `json:"source"`
+					} `json:"contents"`
+				} `json:"files"`
+			} `json:"storage"`
+		}{}
+		Expect(json.Unmarshal(pxeSecret.Data["ignition"], &config)).To(Succeed())
+		Expect(config.Ignition.Version).To(Equal("3.2.0"))
+		Expect(config.Storage.Files).To(HaveLen(2))
+		Expect(config.Storage.Files[0].Path).To(Equal("/etc/systemd/network/10-provisioning.network"))
+		unit, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(config.Storage.Files[0].Contents.Source, "data:;base64,"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(unit)).To(Equal("[Match]\nMACAddress=02:00:00:00:13:01\n\n[Network]\nAddress=10.0.4.10/24\nGateway=10.0.4.1\nDNS=10.0.4.2\n"))
+
+		By("Expecting the addresses to be released once the claim is deleted")
+		expectDeleted(ctx, claim)
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(provisioning), provisioning)).To(Succeed())
+			g.Expect(provisioning.Status.Allocations).To(BeEmpty())
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(tenant), tenant)).To(Succeed())
+			g.Expect(tenant.Status.Allocations).To(BeEmpty())
+		}).Should(Succeed())
+	})
+})
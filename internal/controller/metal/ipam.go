@@ -0,0 +1,145 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"net/netip"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+)
+
+// maxPoolSearch limits the addresses searched for a free address, so that allocating from huge
+// IPv6 networks does not iterate the whole network.
+const maxPoolSearch = 1 << 16
+
+// ipPool is the parsed network of an IPPool.
+type ipPool struct {
+	prefix   netip.Prefix
+	gateway  netip.Addr
+	reserved [][2]netip.Addr
+	// size is the number of addresses searched for a free address.
+	size uint64
+}
+
+// parseIPPool parses and validates the network, the gateway and the reserved ranges of the pool.
+func parseIPPool(pool *metalv1alpha1.IPPool) (*ipPool, error) {
+	prefix, err := netip.ParsePrefix(pool.Spec.CIDR)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q: %w", pool.Spec.CIDR, err)
+	}
+	p := &ipPool{prefix: prefix.Masked()}
+	if hostBits := prefix.Addr().BitLen() - prefix.Bits(); hostBits >= 16 {
+		p.size = maxPoolSearch
+	} else {
+		p.size = 1 << hostBits
+	}
+	if pool.Spec.Gateway != "" {
+		if p.gateway, err = netip.ParseAddr(pool.Spec.Gateway); err != nil {
+			return nil, fmt.Errorf("invalid gateway %q: %w", pool.Spec.Gateway, err)
+		}
+		if !p.prefix.Contains(p.gateway) {
+			return nil, fmt.Errorf("gateway %s is not in %s", p.gateway, p.prefix)
+		}
+	}
+	for _, dns := range pool.Spec.DNSServers {
+		if _, err := netip.ParseAddr(dns); err != nil {
+			return nil, fmt.Errorf("invalid DNS server %q: %w", dns, err)
+		}
+	}
+	for _, r := range pool.Spec.Reserved {
+		start, err := netip.ParseAddr(r.Start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reserved range start %q: %w", r.Start, err)
+		}
+		end := start
+		if r.End != "" {
+			if end, err = netip.ParseAddr(r.End); err != nil {
+				return nil, fmt.Errorf("invalid reserved range end %q: %w", r.End, err)
+			}
+		}
+		if start.BitLen() != p.prefix.Addr().BitLen() || end.Less(start) {
+			return nil, fmt.Errorf("invalid reserved range %s-%s", start, end)
+		}
+		p.reserved = append(p.reserved, [2]netip.Addr{start, end})
+	}
+	return p, nil
+}
+
+// usable reports whether the address can be allocated, i.e. it is in the network and neither the
+// network or broadcast address, the gateway nor reserved. Point-to-point networks with two or
+// less addresses have no network or broadcast address.
+func (p *ipPool) usable(addr netip.Addr) bool {
+	if !p.prefix.Contains(addr) || addr == p.gateway {
+		return false
+	}
+	if p.prefix.Bits() < p.prefix.Addr().BitLen()-1 {
+		if addr == p.prefix.Addr() || (addr.Is4() && addr == p.lastAddr()) {
+			return false
+		}
+	}
+	for _, r := range p.reserved {
+		if !addr.Less(r[0]) && !r[1].Less(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// lastAddr returns the last address of the network, which is the broadcast address of IPv4
+// networks.
+func (p *ipPool) lastAddr() netip.Addr {
+	b := p.prefix.Addr().As16()
+	for bit := p.prefix.Addr().BitLen() - p.prefix.Bits(); bit > 0; bit-- {
+		i := 16 - (bit+7)/8
+		b[i] |= 1 << ((bit - 1) % 8)
+	}
+	addr := netip.AddrFrom16(b)
+	if p.prefix.Addr().Is4() {
+		return addr.Unmap()
+	}
+	return addr
+}
+
+// nth returns the address at the offset from the network address.
+func (p *ipPool) nth(offset uint64) netip.Addr {
+	b := p.prefix.Addr().As16()
+	low := binary.BigEndian.Uint64(b[8:]) + offset
+	binary.BigEndian.PutUint64(b[8:], low)
+	addr := netip.AddrFrom16(b)
+	if p.prefix.Addr().Is4() {
+		return addr.Unmap()
+	}
+	return addr
+}
+
+// allocate returns a free address of the pool. The search starts at an offset derived from the
+// key, so that the same key is allocated the same address whenever it is free.
+func (p *ipPool) allocate(key string, allocated func(netip.Addr) bool) (netip.Addr, bool) {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+	start := hash.Sum64() % p.size
+	for i := uint64(0); i < p.size; i++ {
+		addr := p.nth((start + i) % p.size)
+		if p.usable(addr) && !allocated(addr) {
+			return addr, true
+		}
+	}
+	return netip.Addr{}, false
+}
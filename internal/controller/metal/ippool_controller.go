@@ -0,0 +1,180 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// IPPoolReconciler reconciles a IPPool object
+type IPPoolReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=ippools,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=ippools/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=ipaddressclaims,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *IPPoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	pool := &metalv1alpha1.IPPool{}
+	if err := r.Get(ctx, req.NamespacedName, pool); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	return r.reconcileExists(ctx, log, pool)
+}
+
+func (r *IPPoolReconciler) reconcileExists(ctx context.Context, log logr.Logger, pool *metalv1alpha1.IPPool) (ctrl.Result, error) {
+	if !pool.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	return r.reconcile(ctx, log, pool)
+}
+
+func (r *IPPoolReconciler) reconcile(ctx context.Context, log logr.Logger, pool *metalv1alpha1.IPPool) (ctrl.Result, error) {
+	log.V(1).Info("Reconciling IP pool")
+
+	poolBase := pool.DeepCopy()
+	condition := metav1.Condition{
+		Type:               metalv1alpha1.IPPoolConditionValid,
+		Status:             metav1.ConditionTrue,
+		Reason:             metalv1alpha1.IPPoolReasonValid,
+		Message:            "The network of the pool is valid.",
+		ObservedGeneration: pool.Generation,
+	}
+	parsed, err := parseIPPool(pool)
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = metalv1alpha1.IPPoolReasonInvalid
+		condition.Message = err.Error()
+	}
+	meta.SetStatusCondition(&pool.Status.Conditions, condition)
+
+	// addresses of claims which have been removed without releasing their address, e.g. by
+	// removing their finalizer, are released
+	var allocations []metalv1alpha1.IPAllocation
+	for _, allocation := range pool.Status.Allocations {
+		claim := &metalv1alpha1.IPAddressClaim{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: allocation.ClaimRef.Namespace, Name: allocation.ClaimRef.Name}, claim)
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to get IP address claim: %w", err)
+		}
+		if apierrors.IsNotFound(err) || claim.UID != allocation.ClaimRef.UID {
+			log.V(1).Info("Releasing address of removed claim", "Address", allocation.Address, "Claim", client.ObjectKey{Namespace: allocation.ClaimRef.Namespace, Name: allocation.ClaimRef.Name})
+			continue
+		}
+		allocations = append(allocations, allocation)
+	}
+	if parsed != nil {
+		rebuilt, err := r.rebuildAllocations(ctx, log, parsed, pool, allocations)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		allocations = rebuilt
+	}
+	pool.Status.Allocations = allocations
+	pool.Status.Allocated = int32(len(allocations))
+
+	if equality.Semantic.DeepEqual(pool.Status, poolBase.Status) {
+		log.V(1).Info("Reconciled IP pool", "Allocated", pool.Status.Allocated)
+		return ctrl.Result{}, nil
+	}
+	if err := r.Status().Patch(ctx, pool, client.MergeFromWithOptions(poolBase, client.MergeFromWithOptimisticLock{})); err != nil {
+		if apierrors.IsConflict(err) {
+			return ctrl.Result{Requeue: true}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to patch IP pool status: %w", err)
+	}
+
+	log.V(1).Info("Reconciled IP pool", "Allocated", pool.Status.Allocated)
+	return ctrl.Result{}, nil
+}
+
+// rebuildAllocations adds the missing allocations of the addresses of bound claims, e.g. as the
+// status of the pool has not been restored from a backup. Addresses which are not usable or are
+// allocated to another claim are left to the claim controller.
+func (r *IPPoolReconciler) rebuildAllocations(ctx context.Context, log logr.Logger, parsed *ipPool, pool *metalv1alpha1.IPPool, allocations []metalv1alpha1.IPAllocation) ([]metalv1alpha1.IPAllocation, error) {
+	claims, err := listPoolIPAddressClaims(ctx, r.Client, pool.Name)
+	if err != nil {
+		return nil, err
+	}
+	allocated := map[netip.Addr]bool{}
+	claimed := map[types.UID]bool{}
+	for _, allocation := range allocations {
+		if addr, err := netip.ParseAddr(allocation.Address); err == nil {
+			allocated[addr] = true
+		}
+		claimed[allocation.ClaimRef.UID] = true
+	}
+	for _, claim := range claims {
+		if !claim.DeletionTimestamp.IsZero() || claim.Status.Phase != metalv1alpha1.PhaseBound || claimed[claim.UID] {
+			continue
+		}
+		addr, err := netip.ParseAddr(claim.Status.Address)
+		if err != nil || !parsed.usable(addr) || allocated[addr] {
+			continue
+		}
+		log.V(1).Info("Rebuilding allocation of claim", "Address", addr, "Claim", client.ObjectKeyFromObject(&claim))
+		allocations = append(allocations, metalv1alpha1.IPAllocation{
+			Address: addr.String(),
+			ClaimRef: v1.ObjectReference{
+				Kind:      "IPAddressClaim",
+				Namespace: claim.Namespace,
+				Name:      claim.Name,
+				UID:       claim.UID,
+			},
+		})
+		allocated[addr] = true
+	}
+	return allocations, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IPPoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&metalv1alpha1.IPPool{}).
+		Watches(&metalv1alpha1.IPAddressClaim{}, r.enqueueIPPoolByClaim()).
+		Complete(r)
+}
+
+// enqueueIPPoolByClaim enqueues the pool of a claim, so that the address of a removed claim is
+// released and the allocation of a bound claim is rebuilt.
+func (r *IPPoolReconciler) enqueueIPPoolByClaim() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(_ context.Context, object client.Object) []reconcile.Request {
+		claim := object.(*metalv1alpha1.IPAddressClaim)
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: claim.Spec.IPPoolRef.Name}}}
+	})
+}
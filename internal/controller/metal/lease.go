@@ -0,0 +1,195 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultLeaseWarningPeriod is the default period before the expiry of a claim in which
+	// the claim is warned about the expiry.
+	DefaultLeaseWarningPeriod = time.Hour
+
+	// leaseExpiringReason is the reason of the event of a claim whose lease expires soon.
+	leaseExpiringReason = "LeaseExpiring"
+	// leaseExpiredReason is the reason of the event of a claim which is deleted as its lease
+	// expired.
+	leaseExpiredReason = "LeaseExpired"
+)
+
+// maxLeaseDuration returns the maximum lease of the pools of the host for claims of the
+// namespace. Zero is returned if the lease is not limited.
+func maxLeaseDuration(ctx context.Context, c client.Client, namespace string, host *metalv1alpha1.BareMetalHost) (time.Duration, error) {
+	poolList := &metalv1alpha1.BareMetalHostPoolList{}
+	if err := c.List(ctx, poolList); err != nil {
+		return 0, fmt.Errorf("failed to list host pools: %w", err)
+	}
+	var maxLease time.Duration
+	limit := func(duration *metav1.Duration) {
+		if duration != nil && (maxLease == 0 || duration.Duration < maxLease) {
+			maxLease = duration.Duration
+		}
+	}
+	for i := range poolList.Items {
+		pool := &poolList.Items[i]
+		selector, err := metav1.LabelSelectorAsSelector(&pool.Spec.HostSelector)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse host selector of pool %s: %w", pool.Name, err)
+		}
+		if !selector.Matches(labels.Set(host.Labels)) {
+			continue
+		}
+		limit(pool.Spec.MaxLeaseDuration)
+		if quota := findNamespaceQuota(pool, namespace); quota != nil {
+			limit(quota.MaxLeaseDuration)
+		}
+	}
+	return maxLease, nil
+}
+
+// leaseExpiry returns the time the lease of the bound claim expires, which is the earliest of
+// the expiry of the claim, the end of its lease duration and the end of the maximum lease. Nil
+// is returned if the lease does not expire.
+func leaseExpiry(claim *metalv1alpha1.BareMetalHostClaim, maxLease time.Duration) *metav1.Time {
+	var expiresAt *metav1.Time
+	limit := func(t time.Time) {
+		if expiresAt == nil || t.Before(expiresAt.Time) {
+			expiresAt = &metav1.Time{Time: t}
+		}
+	}
+	boundAt := claim.Status.BoundAt.Time
+	if claim.Spec.ExpiresAt != nil {
+		limit(claim.Spec.ExpiresAt.Time)
+	}
+	if claim.Spec.LeaseDuration != nil {
+		limit(boundAt.Add(claim.Spec.LeaseDuration.Duration))
+	}
+	if maxLease > 0 {
+		limit(boundAt.Add(maxLease))
+	}
+	if expiresAt != nil {
+		rounded := expiresAt.Rfc3339Copy()
+		expiresAt = &rounded
+	}
+	return expiresAt
+}
+
+// ensureLease reports the expiry of the lease of the bound claim and deletes the claim once its
+// lease expired, which deprovisions its host. Members of a claim set are released by the set. A
+// warning is recorded once the lease expires within the warning period. The duration until the
+// next check is returned.
+func (r *BareMetalHostClaimReconciler) ensureLease(ctx context.Context, log logr.Logger, claim *metalv1alpha1.BareMetalHostClaim, host *metalv1alpha1.BareMetalHost) (time.Duration, error) {
+	if claim.Status.BoundAt == nil {
+		return 0, nil
+	}
+	maxLease, err := maxLeaseDuration(ctx, r.Client, claim.Namespace, host)
+	if err != nil {
+		return 0, err
+	}
+	expiresAt := leaseExpiry(claim, maxLease)
+
+	claimBase := claim.DeepCopy()
+	claim.Status.ExpiresAt = expiresAt
+	var remaining time.Duration
+	warn := false
+	if expiresAt == nil {
+		meta.RemoveStatusCondition(&claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionLeaseExpiring)
+	} else if remaining = time.Until(expiresAt.Time); remaining > r.leaseWarningPeriod() {
+		meta.RemoveStatusCondition(&claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionLeaseExpiring)
+	} else {
+		warn = !meta.IsStatusConditionTrue(claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionLeaseExpiring)
+		condition := metav1.Condition{
+			Type:               metalv1alpha1.BareMetalHostClaimConditionLeaseExpiring,
+			Status:             metav1.ConditionTrue,
+			Reason:             metalv1alpha1.BareMetalHostClaimReasonLeaseExpiring,
+			Message:            fmt.Sprintf("The lease expires at %s", expiresAt.UTC().Format(time.RFC3339)),
+			ObservedGeneration: claim.Generation,
+		}
+		if remaining <= 0 && claimSetOf(claim) != "" {
+			// the set is reconciled on the changed condition and releases all its members
+			condition.Reason = metalv1alpha1.BareMetalHostClaimReasonLeaseExpired
+			condition.Message = fmt.Sprintf("The lease expired at %s, the members of claim set %s are released",
+				expiresAt.UTC().Format(time.RFC3339), claimSetOf(claim))
+		}
+		meta.SetStatusCondition(&claim.Status.Conditions, condition)
+	}
+	if !equality.Semantic.DeepEqual(claimBase.Status, claim.Status) {
+		if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+			return 0, fmt.Errorf("failed to patch lease status: %w", err)
+		}
+	}
+	if expiresAt == nil {
+		return 0, nil
+	}
+	if warn && remaining > 0 {
+		r.Recorder.Eventf(claim, v1.EventTypeWarning, leaseExpiringReason, "The lease expires at %s, extend it to keep host %s",
+			expiresAt.UTC().Format(time.RFC3339), host.Name)
+	}
+
+	if remaining > 0 {
+		if remaining > r.leaseWarningPeriod() {
+			return remaining - r.leaseWarningPeriod(), nil
+		}
+		return remaining, nil
+	}
+	if set := claimSetOf(claim); set != "" {
+		// members are not deleted, as the set would place them again
+		log.V(1).Info("Lease expired: waiting for claim set to release its members", "ExpiresAt", expiresAt, "ClaimSet", set)
+		if condition := meta.FindStatusCondition(claimBase.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionLeaseExpiring); condition == nil ||
+			condition.Reason != metalv1alpha1.BareMetalHostClaimReasonLeaseExpired {
+			r.Recorder.Eventf(claim, v1.EventTypeWarning, leaseExpiredReason, "The lease expired at %s, releasing the members of claim set %s",
+				expiresAt.UTC().Format(time.RFC3339), set)
+		}
+		return 0, nil
+	}
+	log.V(1).Info("Lease expired: deleting claim", "ExpiresAt", expiresAt)
+	r.Recorder.Eventf(claim, v1.EventTypeWarning, leaseExpiredReason, "The lease expired at %s, deprovisioning host %s",
+		expiresAt.UTC().Format(time.RFC3339), host.Name)
+	if err := r.Delete(ctx, claim); client.IgnoreNotFound(err) != nil {
+		return 0, fmt.Errorf("failed to delete expired claim: %w", err)
+	}
+	return 0, nil
+}
+
+// claimSetOf returns the name of the claim set the claim is a member of. An empty name is
+// returned if the claim is not controlled by a set.
+func claimSetOf(claim *metalv1alpha1.BareMetalHostClaim) string {
+	owner := metav1.GetControllerOf(claim)
+	if owner == nil || owner.Kind != "BareMetalHostClaimSet" || owner.APIVersion != metalv1alpha1.GroupVersion.String() {
+		return ""
+	}
+	return owner.Name
+}
+
+func (r *BareMetalHostClaimReconciler) leaseWarningPeriod() time.Duration {
+	if r.LeaseWarningPeriod > 0 {
+		return r.LeaseWarningPeriod
+	}
+	return DefaultLeaseWarningPeriod
+}
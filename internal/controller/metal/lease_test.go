@@ -0,0 +1,135 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("BareMetalHostClaim Lease", func() {
+	var ns *v1.Namespace
+
+	BeforeEach(func(ctx SpecContext) {
+		ns = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ns)
+	})
+
+	It("should warn about the expiry of a lease and deprovision the host once it expired", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "eeeeeeee-eeee-eeee-eeee-000000000001")
+		labels := map[string]string{"lease": "expiry"}
+		labelHost(ctx, host, labels)
+
+		claim := createSelectorClaim(ctx, ns, labels, 0, metalv1alpha1.PreemptionPolicyNever)
+		expectPhase(ctx, claim, metalv1alpha1.PhaseBound)
+		Expect(claim.Status.BoundAt).NotTo(BeNil())
+		Expect(claim.Status.ExpiresAt).To(BeNil())
+
+		By("Setting a lease duration")
+		claimBase := claim.DeepCopy()
+		claim.Spec.LeaseDuration = &metav1.Duration{Duration: time.Since(claim.Status.BoundAt.Time) + 5*time.Second}
+		Expect(k8sClient.Patch(ctx, claim, client.MergeFrom(claimBase))).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ExpiresAt).NotTo(BeNil())
+			g.Expect(claim.Status.ExpiresAt.Time).To(BeTemporally("==", claim.Status.BoundAt.Add(claim.Spec.LeaseDuration.Duration).Truncate(time.Second)))
+		}).Should(Succeed())
+
+		By("Ensuring that the claim is warned before the lease expires")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(meta.IsStatusConditionTrue(claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionLeaseExpiring)).To(BeTrue())
+		}).Should(Succeed())
+		Eventually(func(g Gomega) {
+			events := &v1.EventList{}
+			g.Expect(k8sClient.List(ctx, events, client.InNamespace(ns.Name))).To(Succeed())
+			g.Expect(events.Items).To(ContainElement(
+				SatisfyAll(HaveField("InvolvedObject.Name", claim.Name), HaveField("Reason", leaseExpiringReason)),
+			))
+		}).Should(Succeed())
+
+		By("Ensuring that the claim is deleted and the host released once the lease expired")
+		Eventually(func(g Gomega) {
+			g.Expect(apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim))).To(BeTrue())
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Spec.ClaimRef).To(BeNil())
+		}).Should(Succeed())
+		Eventually(func(g Gomega) {
+			events := &v1.EventList{}
+			g.Expect(k8sClient.List(ctx, events, client.InNamespace(ns.Name))).To(Succeed())
+			g.Expect(events.Items).To(ContainElement(
+				SatisfyAll(HaveField("InvolvedObject.Name", claim.Name), HaveField("Reason", leaseExpiredReason)),
+			))
+		}).Should(Succeed())
+	})
+
+	It("should limit leases to the maximum of the pool and allow extending them", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "eeeeeeee-eeee-eeee-eeee-000000000002")
+		labelHost(ctx, host, map[string]string{"pool": "lease"})
+		maxHosts := int32(1)
+		pool := createPool(ctx, "lease", metalv1alpha1.NamespaceQuota{
+			Namespace:        ns.Name,
+			MaxHosts:         &maxHosts,
+			MaxLeaseDuration: &metav1.Duration{Duration: 2 * time.Hour},
+		})
+		poolBase := pool.DeepCopy()
+		pool.Spec.MaxLeaseDuration = &metav1.Duration{Duration: time.Hour}
+		Expect(k8sClient.Patch(ctx, pool, client.MergeFrom(poolBase))).To(Succeed())
+
+		claim := createSelectorClaim(ctx, ns, map[string]string{"pool": "lease"}, 0, metalv1alpha1.PreemptionPolicyNever)
+		expectPhase(ctx, claim, metalv1alpha1.PhaseBound)
+
+		By("Ensuring that the lease is limited to the maximum of the pool")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ExpiresAt).NotTo(BeNil())
+			g.Expect(claim.Status.ExpiresAt.Time).To(BeTemporally("==", claim.Status.BoundAt.Add(time.Hour)))
+		}).Should(Succeed())
+
+		By("Shortening the lease by an expiry of the claim")
+		expiresAt := metav1.NewTime(claim.Status.BoundAt.Add(30 * time.Minute))
+		claimBase := claim.DeepCopy()
+		claim.Spec.ExpiresAt = &expiresAt
+		Expect(k8sClient.Patch(ctx, claim, client.MergeFrom(claimBase))).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ExpiresAt).NotTo(BeNil())
+			g.Expect(claim.Status.ExpiresAt.Time).To(BeTemporally("==", expiresAt.Time))
+		}).Should(Succeed())
+
+		By("Extending the lease up to the maximum of the pool")
+		expiresAt = metav1.NewTime(claim.Status.BoundAt.Add(3 * time.Hour))
+		claimBase = claim.DeepCopy()
+		claim.Spec.ExpiresAt = &expiresAt
+		Expect(k8sClient.Patch(ctx, claim, client.MergeFrom(claimBase))).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+			g.Expect(claim.Status.ExpiresAt).NotTo(BeNil())
+			g.Expect(claim.Status.ExpiresAt.Time).To(BeTemporally("==", claim.Status.BoundAt.Add(time.Hour)))
+		}).Should(Succeed())
+		Expect(meta.FindStatusCondition(claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionLeaseExpiring)).To(BeNil())
+	})
+})
@@ -0,0 +1,302 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/bmc"
+	"github.com/go-logr/logr"
+	"github.com/stmcginnis/gofish/redfish"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxLatestCritical is the number of critical log entries kept in the host status. The maximum
+// of LogThreshold.MaxCritical has to stay below it.
+const maxLatestCritical = 5
+
+const (
+	// hardwareFaultReason is the reason of the events for critical log entries.
+	hardwareFaultReason = "HardwareFault"
+	// faultPolicyAppliedReason is the reason of the events for actions of a fault policy.
+	faultPolicyAppliedReason = "FaultPolicyApplied"
+)
+
+// LogCollector periodically reads the new entries of the log services of all hosts. Critical
+// entries are reported as events and the actions of matching fault policies are applied.
+type LogCollector struct {
+	client.Client
+	// FakeBMCs keeps the state of the BMCs of type Fake.
+	FakeBMCs *bmc.FakeBMCs
+	Recorder record.EventRecorder
+	// Interval is the interval in which the log services are read.
+	Interval time.Duration
+	// Timeout bounds the collection of a single host.
+	Timeout time.Duration
+	// Services are the IDs of the log services which are read. The default log services of
+	// the bmc package are read if empty.
+	Services []string
+	// Concurrency is the maximum number of hosts which are read in parallel.
+	Concurrency int
+}
+
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhosts,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhosts/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=faultpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=bmcs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+// Start runs the collector until the context is done.
+func (c *LogCollector) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("logs")
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.collect(ctx, log); err != nil {
+			log.Error(err, "Failed to collect logs")
+		}
+	}, c.Interval)
+	return nil
+}
+
+// collect reads the new log entries of all hosts.
+func (c *LogCollector) collect(ctx context.Context, log logr.Logger) error {
+	hostList := &metalv1alpha1.BareMetalHostList{}
+	if err := c.List(ctx, hostList); err != nil {
+		return fmt.Errorf("failed to list hosts: %w", err)
+	}
+	policyList := &metalv1alpha1.FaultPolicyList{}
+	if err := c.List(ctx, policyList); err != nil {
+		return fmt.Errorf("failed to list fault policies: %w", err)
+	}
+
+	concurrency := c.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range hostList.Items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(host *metalv1alpha1.BareMetalHost) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			hostLog := log.WithValues("Host", host.Name)
+			if err := c.collectHost(ctx, hostLog, host, policyList.Items); err != nil {
+				hostLog.Error(err, "Failed to collect logs")
+			}
+		}(&hostList.Items[i])
+	}
+	wg.Wait()
+	return nil
+}
+
+func (c *LogCollector) collectHost(ctx context.Context, log logr.Logger, host *metalv1alpha1.BareMetalHost, policies []metalv1alpha1.FaultPolicy) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	bmcObj := &metalv1alpha1.BMC{}
+	if err := c.Get(ctx, client.ObjectKey{Name: host.Spec.BMCRef.Name}, bmcObj); err != nil {
+		return fmt.Errorf("failed to get BMC %s for host: %w", host.Spec.BMCRef.Name, err)
+	}
+	bmcClient, err := newBMCClient(ctx, c.Client, c.FakeBMCs, bmcObj, host)
+	if err != nil {
+		return fmt.Errorf("failed to create BMC client: %w", err)
+	}
+	defer bmcClient.Logout()
+
+	cursors := make([]bmc.LogCursor, 0, len(host.Status.LogCursors))
+	for _, cursor := range host.Status.LogCursors {
+		cursors = append(cursors, bmc.LogCursor{Service: cursor.Service, Created: cursor.Created.Time, ID: cursor.ID})
+	}
+	entries, err := bmcClient.GetLogEntries(c.Services, cursors)
+	if err != nil {
+		return fmt.Errorf("failed to get log entries: %w", err)
+	}
+	log.V(1).Info("Read log entries", "Entries", len(entries))
+
+	if host.Status.LogSummary == nil {
+		// the entries written before the first collection are history, e.g. faults which have
+		// been repaired already, so only the cursors are moved past them
+		log.V(1).Info("Skipping entries present at first collection", "Entries", len(entries))
+		return c.updateLogStatus(ctx, host, entries, false)
+	}
+
+	log.V(1).Info("Patching log summary in host status")
+	if err := c.updateLogStatus(ctx, host, entries, true); err != nil {
+		return err
+	}
+	log.V(1).Info("Patched log summary in host status")
+
+	for _, entry := range entries {
+		if entry.Severity == redfish.CriticalEventSeverity {
+			c.Recorder.Eventf(host, v1.EventTypeWarning, hardwareFaultReason, "%s entry %s: %s", entry.Service, entry.ID, entry.Message)
+		}
+	}
+
+	return c.applyFaultPolicies(ctx, log, host, policies, entries)
+}
+
+// updateLogStatus advances the cursors of the host and, if summarize is set, adds the entries
+// to its log summary.
+func (c *LogCollector) updateLogStatus(ctx context.Context, host *metalv1alpha1.BareMetalHost, entries []bmc.LogEntry, summarize bool) error {
+	hostBase := host.DeepCopy()
+	now := metav1.Now()
+	if host.Status.LogSummary == nil {
+		host.Status.LogSummary = &metalv1alpha1.LogSummary{FirstCollectionTime: &now}
+	}
+	summary := host.Status.LogSummary
+	for _, entry := range entries {
+		setLogCursor(host, entry)
+		if !summarize {
+			continue
+		}
+		switch entry.Severity {
+		case redfish.CriticalEventSeverity:
+			summary.CriticalCount++
+			summary.LatestCritical = append(summary.LatestCritical, metalv1alpha1.LogEntry{
+				Service:   entry.Service,
+				ID:        entry.ID,
+				Created:   metav1.NewTime(entry.Created),
+				Severity:  entry.Severity,
+				MessageID: entry.MessageID,
+				Message:   entry.Message,
+			})
+		case redfish.WarningEventSeverity:
+			summary.WarningCount++
+		}
+	}
+	if len(summary.LatestCritical) > maxLatestCritical {
+		summary.LatestCritical = summary.LatestCritical[len(summary.LatestCritical)-maxLatestCritical:]
+	}
+	summary.LastCollectionTime = &now
+
+	if err := c.Status().Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
+		return fmt.Errorf("failed to patch log summary: %w", err)
+	}
+	return nil
+}
+
+// setLogCursor moves the cursor of the service of the entry to the entry. The entries are
+// ordered, so the last entry of a service determines its cursor.
+func setLogCursor(host *metalv1alpha1.BareMetalHost, entry bmc.LogEntry) {
+	cursor := metalv1alpha1.LogCursor{Service: entry.Service, Created: metav1.NewTime(entry.Created), ID: entry.ID}
+	for i := range host.Status.LogCursors {
+		if host.Status.LogCursors[i].Service == entry.Service {
+			host.Status.LogCursors[i] = cursor
+			return
+		}
+	}
+	host.Status.LogCursors = append(host.Status.LogCursors, cursor)
+}
+
+// faultMatch is a log entry matched by a rule of a fault policy.
+type faultMatch struct {
+	policy string
+	rule   string
+	entry  bmc.LogEntry
+}
+
+// applyFaultPolicies applies the action of every rule of the policies selecting the host
+// which matches one of the entries.
+func (c *LogCollector) applyFaultPolicies(ctx context.Context, log logr.Logger, host *metalv1alpha1.BareMetalHost, policies []metalv1alpha1.FaultPolicy, entries []bmc.LogEntry) error {
+	matches := map[metalv1alpha1.FaultAction]faultMatch{}
+	for _, policy := range policies {
+		if policy.Spec.HostSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(policy.Spec.HostSelector)
+			if err != nil {
+				log.Error(err, "Invalid host selector of fault policy", "FaultPolicy", policy.Name)
+				continue
+			}
+			if !selector.Matches(labels.Set(host.Labels)) {
+				continue
+			}
+		}
+		for _, rule := range policy.Spec.Rules {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				log.Error(err, "Invalid pattern of fault rule", "FaultPolicy", policy.Name, "Rule", rule.Name)
+				continue
+			}
+			for _, entry := range entries {
+				if _, ok := matches[rule.Action]; ok {
+					break
+				}
+				if severityRank(entry.Severity) < severityRank(rule.Severity) {
+					continue
+				}
+				if pattern.MatchString(entry.MessageID) || pattern.MatchString(entry.Message) {
+					matches[rule.Action] = faultMatch{policy: policy.Name, rule: rule.Name, entry: entry}
+				}
+			}
+		}
+	}
+
+	if match, ok := matches[metalv1alpha1.FaultActionMaintenance]; ok && !host.Spec.Maintenance {
+		log.V(1).Info("Enabling maintenance of host", "FaultPolicy", match.policy, "Rule", match.rule)
+		hostBase := host.DeepCopy()
+		host.Spec.Maintenance = true
+		if err := c.Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
+			return fmt.Errorf("failed to enable maintenance of host: %w", err)
+		}
+		c.recordFaultAction(host, metalv1alpha1.FaultActionMaintenance, match)
+		log.V(1).Info("Enabled maintenance of host")
+	}
+	if match, ok := matches[metalv1alpha1.FaultActionTaint]; ok && !hasTaint(host, metalv1alpha1.HostTaintHardwareFault) {
+		log.V(1).Info("Tainting host", "FaultPolicy", match.policy, "Rule", match.rule)
+		hostBase := host.DeepCopy()
+		addTaint(host, metalv1alpha1.HostTaintHardwareFault, fmt.Sprintf("%s entry %s: %s", match.entry.Service, match.entry.ID, match.entry.Message))
+		if err := c.Patch(ctx, host, client.MergeFrom(hostBase)); err != nil {
+			return fmt.Errorf("failed to taint host: %w", err)
+		}
+		c.recordFaultAction(host, metalv1alpha1.FaultActionTaint, match)
+		log.V(1).Info("Tainted host")
+	}
+	return nil
+}
+
+func (c *LogCollector) recordFaultAction(host *metalv1alpha1.BareMetalHost, action metalv1alpha1.FaultAction, match faultMatch) {
+	c.Recorder.Eventf(host, v1.EventTypeWarning, faultPolicyAppliedReason,
+		"Applied action %s of rule %s of fault policy %s for %s entry %s: %s",
+		action, match.rule, match.policy, match.entry.Service, match.entry.ID, match.entry.Message)
+}
+
+// severityRank orders the event severities. Entries without a severity are treated as OK.
+func severityRank(severity redfish.EventSeverity) int {
+	switch severity {
+	case redfish.CriticalEventSeverity:
+		return 2
+	case redfish.WarningEventSeverity:
+		return 1
+	default:
+		return 0
+	}
+}
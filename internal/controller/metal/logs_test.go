@@ -0,0 +1,192 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/bmc"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stmcginnis/gofish/redfish"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Log Collector", func() {
+	It("should report new critical log entries and apply the fault policies", func(ctx SpecContext) {
+		By("Creating a fake BMC")
+		bmcObj, host := setupFakeHost(ctx, "44444444-4444-4444-4444-444444444444")
+
+		By("Creating a fault policy for ECC errors")
+		policy := &metalv1alpha1.FaultPolicy{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "ecc-"},
+			Spec: metalv1alpha1.FaultPolicySpec{
+				Rules: []metalv1alpha1.FaultRule{{
+					Name:    "uncorrectable-ecc",
+					Pattern: "(?i)uncorrectable ECC",
+					Action:  metalv1alpha1.FaultActionMaintenance,
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, policy)
+		Expect(policy.Spec.Rules[0].Severity).To(Equal(redfish.CriticalEventSeverity))
+
+		By("Adding a historic log entry to the system")
+		Expect(fakeBMCs.AddLogEntry(bmcObj.Name, "System-1", bmc.LogEntry{
+			Service:  "SEL",
+			Severity: redfish.CriticalEventSeverity,
+			Message:  "Uncorrectable ECC error on DIMM B2.",
+		})).To(Succeed())
+
+		recorder := record.NewFakeRecorder(100)
+		collector := &LogCollector{
+			Client:      k8sClient,
+			FakeBMCs:    fakeBMCs,
+			Recorder:    recorder,
+			Concurrency: 1,
+		}
+		Expect(collector.collect(ctx, GinkgoLogr)).To(Succeed())
+
+		By("Ensuring that the entries present at the first collection only set the cursors")
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+		Expect(host.Status.LogCursors).To(ConsistOf(HaveField("ID", "1")))
+		Expect(host.Status.LogSummary).NotTo(BeNil())
+		Expect(host.Status.LogSummary.FirstCollectionTime).NotTo(BeNil())
+		Expect(host.Status.LogSummary.CriticalCount).To(BeNumerically("==", 0))
+		Expect(host.Spec.Maintenance).To(BeFalse())
+		Expect(collectEvents(recorder)).To(BeEmpty())
+
+		By("Adding new log entries to the system")
+		Expect(fakeBMCs.AddLogEntry(bmcObj.Name, "System-1", bmc.LogEntry{
+			Service:  "SEL",
+			Severity: redfish.WarningEventSeverity,
+			Message:  "Correctable ECC error on DIMM A1.",
+		})).To(Succeed())
+		Expect(fakeBMCs.AddLogEntry(bmcObj.Name, "System-1", bmc.LogEntry{
+			Service:   "SEL",
+			Severity:  redfish.CriticalEventSeverity,
+			MessageID: "MEM0001",
+			Message:   "Uncorrectable ECC error on DIMM A1.",
+		})).To(Succeed())
+		Expect(collector.collect(ctx, GinkgoLogr)).To(Succeed())
+
+		By("Ensuring that the new entries are summarized in the host status")
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+		Expect(host.Status.LogCursors).To(ConsistOf(HaveField("ID", "3")))
+		Expect(host.Status.LogSummary).NotTo(BeNil())
+		Expect(host.Status.LogSummary.CriticalCount).To(BeNumerically("==", 1))
+		Expect(host.Status.LogSummary.WarningCount).To(BeNumerically("==", 1))
+		Expect(host.Status.LogSummary.LatestCritical).To(ConsistOf(HaveField("MessageID", "MEM0001")))
+
+		By("Ensuring that the critical entry and the applied action are reported as events")
+		events := collectEvents(recorder)
+		Expect(events).To(ContainElement(HavePrefix("Warning HardwareFault SEL entry 3: Uncorrectable ECC error")))
+		Expect(events).To(ContainElement(ContainSubstring("FaultPolicyApplied Applied action Maintenance of rule uncorrectable-ecc")))
+
+		By("Ensuring that the host is moved to maintenance")
+		Expect(host.Spec.Maintenance).To(BeTrue())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.State).To(Equal(metalv1alpha1.StateMaintenance))
+		}).Should(Succeed())
+
+		By("Ensuring that entries are not reported twice")
+		Expect(collector.collect(ctx, GinkgoLogr)).To(Succeed())
+		Expect(collectEvents(recorder)).To(BeEmpty())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+		Expect(host.Status.LogSummary.CriticalCount).To(BeNumerically("==", 1))
+	})
+
+	It("should taint the host and keep it tainted until the taint is removed", func(ctx SpecContext) {
+		By("Creating a fake BMC")
+		bmcObj, host := setupFakeHost(ctx, "17171717-1717-1717-1717-171717171717")
+
+		By("Creating a fault policy which taints the host on PSU failures")
+		policy := &metalv1alpha1.FaultPolicy{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "psu-"},
+			Spec: metalv1alpha1.FaultPolicySpec{
+				Rules: []metalv1alpha1.FaultRule{{
+					Name:    "psu-failure",
+					Pattern: "(?i)power supply .* failed",
+					Action:  metalv1alpha1.FaultActionTaint,
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, policy)
+
+		collector := &LogCollector{
+			Client:      k8sClient,
+			FakeBMCs:    fakeBMCs,
+			Recorder:    record.NewFakeRecorder(100),
+			Concurrency: 1,
+		}
+		Expect(collector.collect(ctx, GinkgoLogr)).To(Succeed())
+
+		By("Adding a PSU failure to the log")
+		Expect(fakeBMCs.AddLogEntry(bmcObj.Name, "System-1", bmc.LogEntry{
+			Service:  "SEL",
+			Severity: redfish.CriticalEventSeverity,
+			Message:  "Power supply PSU1 failed.",
+		})).To(Succeed())
+		Expect(collector.collect(ctx, GinkgoLogr)).To(Succeed())
+
+		By("Ensuring that the host is tainted")
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+		Expect(host.Spec.Taints).To(ConsistOf(SatisfyAll(
+			HaveField("Key", metalv1alpha1.HostTaintHardwareFault),
+			HaveField("Message", ContainSubstring("Power supply PSU1 failed")),
+		)))
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.State).To(Equal(metalv1alpha1.StateTainted))
+		}).Should(Succeed())
+
+		By("Ensuring that the host controller keeps the host tainted")
+		hostBase := host.DeepCopy()
+		host.Labels = map[string]string{"touched": "true"}
+		Expect(k8sClient.Patch(ctx, host, client.MergeFrom(hostBase))).To(Succeed())
+		Consistently(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.State).To(Equal(metalv1alpha1.StateTainted))
+		}, "2s").Should(Succeed())
+
+		By("Removing the taint")
+		hostBase = host.DeepCopy()
+		host.Spec.Taints = nil
+		Expect(k8sClient.Patch(ctx, host, client.MergeFrom(hostBase))).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.State).To(Equal(metalv1alpha1.StateAvailable))
+		}).Should(Succeed())
+	})
+})
+
+// collectEvents drains the events recorded so far.
+func collectEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	powerTransitionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "baremetal_host_power_transition_duration_seconds",
+		Help:    "Duration until a host reached the requested power state.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+	}, []string{"target"})
+	claimTimeToBound = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "baremetal_claim_time_to_bound_seconds",
+		Help:    "Duration from the creation of a claim until it is bound to its host.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	})
+	claimTimeToProvisioned = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "baremetal_claim_time_to_provisioned_seconds",
+		Help:    "Duration from the creation of a claim until its host is powered on with the PXE configuration of the claim.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	})
+
+	hostsDesc = prometheus.NewDesc(
+		"baremetal_hosts",
+		"Number of hosts per state and phase.",
+		[]string{"state", "phase"}, nil,
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(powerTransitionDuration, claimTimeToBound, claimTimeToProvisioned)
+}
+
+// hostCollector counts the hosts per state and phase whenever the metrics are scraped.
+type hostCollector struct {
+	client client.Reader
+}
+
+// registerHostCollector registers a collector counting the hosts read from the given client.
+func registerHostCollector(c client.Reader) error {
+	err := metrics.Registry.Register(&hostCollector{client: c})
+	if are := (prometheus.AlreadyRegisteredError{}); errors.As(err, &are) {
+		return nil
+	}
+	return err
+}
+
+func (c *hostCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hostsDesc
+}
+
+func (c *hostCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	hostList := &metalv1alpha1.BareMetalHostList{}
+	if err := c.client.List(ctx, hostList); err != nil {
+		ch <- prometheus.NewInvalidMetric(hostsDesc, err)
+		return
+	}
+
+	type key struct {
+		state metalv1alpha1.HostState
+		phase metalv1alpha1.Phase
+	}
+	counts := map[key]int{}
+	for _, host := range hostList.Items {
+		counts[key{host.Status.State, host.Status.Phase}]++
+	}
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(hostsDesc, prometheus.GaugeValue, float64(count), string(k.state), string(k.phase))
+	}
+}
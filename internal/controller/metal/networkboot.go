@@ -0,0 +1,75 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/bmc"
+	"github.com/go-logr/logr"
+	"github.com/stmcginnis/gofish/redfish"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// networkBoot returns how the host boots from the network. The network boot of the claim takes
+// precedence over the one of the host, and hosts boot via PXE if neither configures one.
+func networkBoot(host *metalv1alpha1.BareMetalHost, claim *metalv1alpha1.BareMetalHostClaim) metalv1alpha1.NetworkBoot {
+	boot := metalv1alpha1.NetworkBoot{Mode: metalv1alpha1.NetworkBootModePXE}
+	switch {
+	case claim != nil && claim.Spec.NetworkBoot != nil:
+		boot = *claim.Spec.NetworkBoot
+	case host.Spec.NetworkBoot != nil:
+		boot = *host.Spec.NetworkBoot
+	}
+	if boot.Mode == "" {
+		boot.Mode = metalv1alpha1.NetworkBootModePXE
+	}
+	return boot
+}
+
+// setNetworkBootOnce sets a one time boot override which boots the host from the network as
+// configured by the host and its claim.
+func (r *BareMetalHostReconciler) setNetworkBootOnce(ctx context.Context, log logr.Logger, bmcClient bmc.BMC, host *metalv1alpha1.BareMetalHost) error {
+	var claim *metalv1alpha1.BareMetalHostClaim
+	if host.Spec.ClaimRef != nil {
+		claim = &metalv1alpha1.BareMetalHostClaim{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: host.Spec.ClaimRef.Namespace, Name: host.Spec.ClaimRef.Name}, claim); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to get claim of host: %w", err)
+			}
+			claim = nil
+		}
+	}
+
+	boot := networkBoot(host, claim)
+	if boot.Mode == metalv1alpha1.NetworkBootModeHTTP {
+		log.V(1).Info("Setting HTTP boot once", "URL", boot.URL)
+		if err := bmcClient.SetHTTPBootOnce(host.Spec.SystemID, boot.URL); err != nil {
+			return fmt.Errorf("failed to set HTTP boot once boot order for host: %w", err)
+		}
+		return r.recordBootOverride(ctx, host, redfish.OnceBootSourceOverrideEnabled, redfish.UefiHTTPBootSourceOverrideTarget, boot.URL)
+	}
+
+	log.V(1).Info("Setting PXE boot once")
+	if err := bmcClient.SetPXEBootOnce(host.Spec.SystemID); err != nil {
+		return fmt.Errorf("failed to set boot PXE once boot order for host: %w", err)
+	}
+	return r.recordBootOverride(ctx, host, redfish.OnceBootSourceOverrideEnabled, redfish.PxeBootSourceOverrideTarget, "")
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stmcginnis/gofish/redfish"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Network Boot", func() {
+	var ns *v1.Namespace
+
+	BeforeEach(func(ctx SpecContext) {
+		ns = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ns)
+	})
+
+	setNetworkBoot := func(ctx SpecContext, host *metalv1alpha1.BareMetalHost, boot *metalv1alpha1.NetworkBoot) error {
+		hostBase := host.DeepCopy()
+		host.Spec.NetworkBoot = boot
+		return k8sClient.Patch(ctx, host, client.MergeFrom(hostBase))
+	}
+
+	expectBootOverride := func(ctx SpecContext, host *metalv1alpha1.BareMetalHost, target redfish.BootSourceOverrideTarget, uri string) {
+		GinkgoHelper()
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+			g.Expect(host.Status.BootOverrides).NotTo(BeEmpty())
+			override := host.Status.BootOverrides[len(host.Status.BootOverrides)-1]
+			g.Expect(override.Target).To(Equal(target))
+			g.Expect(override.URI).To(Equal(uri))
+		}).Should(Succeed())
+	}
+
+	It("should boot the host via UEFI HTTP boot", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "15151515-1515-1515-1515-000000000001")
+
+		By("Rejecting HTTP boot without URL")
+		Expect(setNetworkBoot(ctx, host, &metalv1alpha1.NetworkBoot{Mode: metalv1alpha1.NetworkBootModeHTTP})).NotTo(Succeed())
+
+		By("Configuring HTTP boot for the host")
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+		Expect(setNetworkBoot(ctx, host, &metalv1alpha1.NetworkBoot{
+			Mode: metalv1alpha1.NetworkBootModeHTTP,
+			URL:  "http://[2001:db8::1]/images/boot.efi",
+		})).To(Succeed())
+
+		By("Expecting the host to boot from the URL once it is claimed")
+		claim, _ := createClaim(ctx, ns, host, "")
+		expectPhase(ctx, claim, metalv1alpha1.PhaseBound)
+		expectBootOverride(ctx, host, redfish.UefiHTTPBootSourceOverrideTarget, "http://[2001:db8::1]/images/boot.efi")
+
+		dhcp := &bootv1alpha1.DHCP{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), dhcp)).To(Succeed())
+		Expect(dhcp.Spec.HTTPBoot).To(BeTrue())
+		Expect(dhcp.Spec.BootFileURL).To(Equal("http://[2001:db8::1]/images/boot.efi"))
+	})
+
+	It("should prefer the network boot of the claim", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "15151515-1515-1515-1515-000000000002")
+		Expect(setNetworkBoot(ctx, host, &metalv1alpha1.NetworkBoot{
+			Mode: metalv1alpha1.NetworkBootModeHTTP,
+			URL:  "http://10.0.0.1/images/boot.efi",
+		})).To(Succeed())
+
+		By("Claiming the host with PXE boot")
+		ignition := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "ignition-"},
+			Data:       map[string][]byte{"ignition": []byte("{}")},
+		}
+		Expect(k8sClient.Create(ctx, ignition)).To(Succeed())
+		claim := &metalv1alpha1.BareMetalHostClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "claim-"},
+			Spec: metalv1alpha1.BareMetalHostClaimSpec{
+				Power:            metalv1alpha1.PowerStateOn,
+				BareMetalHostRef: v1.LocalObjectReference{Name: host.Name},
+				IgnitionRef:      &v1.LocalObjectReference{Name: ignition.Name},
+				Image:            "foo:latest",
+				NetworkBoot:      &metalv1alpha1.NetworkBoot{Mode: metalv1alpha1.NetworkBootModePXE},
+			},
+		}
+		Expect(k8sClient.Create(ctx, claim)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, claim)
+		expectPhase(ctx, claim, metalv1alpha1.PhaseBound)
+		expectBootOverride(ctx, host, redfish.PxeBootSourceOverrideTarget, "")
+
+		dhcp := &bootv1alpha1.DHCP{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), dhcp)).To(Succeed())
+		Expect(dhcp.Spec.HTTPBoot).To(BeFalse())
+		Expect(dhcp.Spec.BootFileURL).To(Equal("http://[2001:db8::1]:8082/ipxe/" + host.Status.SystemUUID))
+	})
+})
@@ -0,0 +1,362 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultPreemptionGracePeriod is the default duration after which a preempted claim is
+	// unbound.
+	DefaultPreemptionGracePeriod = 5 * time.Minute
+
+	// preemptedReason is the reason of the events of a preempted claim.
+	preemptedReason = "Preempted"
+	// preemptingReason is the reason of the event of a claim preempting another claim.
+	preemptingReason = "Preempting"
+	// preemptionCancelledReason is the reason of the event of a claim which is not preempted
+	// anymore.
+	preemptionCancelledReason = "PreemptionCancelled"
+	// conflictReason is the reason of the event of a claim referencing a host claimed by another
+	// claim.
+	conflictReason = "Conflict"
+
+	// minConflictBackoff and maxConflictBackoff bound the interval in which a claim in conflict
+	// is checked again in addition to the changes of its host.
+	minConflictBackoff = 5 * time.Second
+	maxConflictBackoff = 5 * time.Minute
+)
+
+// claimPrecedes reports whether claim a is bound before claim b if both wait for a host. Claims
+// are ordered by priority, then by their creation.
+func claimPrecedes(a, b *metalv1alpha1.BareMetalHostClaim) bool {
+	if a.Spec.Priority != b.Spec.Priority {
+		return a.Spec.Priority > b.Spec.Priority
+	}
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// claimWantsHost reports whether the claim references the host or selects it by labels.
+func claimWantsHost(claim *metalv1alpha1.BareMetalHostClaim, host *metalv1alpha1.BareMetalHost) bool {
+	if claim.Spec.BareMetalHostRef.Name != "" {
+		return claim.Spec.BareMetalHostRef.Name == host.Name
+	}
+	selector, err := metav1.LabelSelectorAsSelector(claim.Spec.BareMetalHostSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(host.Labels))
+}
+
+// isClaimPending reports whether the claim waits for a host.
+func isClaimPending(claim *metalv1alpha1.BareMetalHostClaim) bool {
+	return claim.DeletionTimestamp.IsZero() && claim.Status.Phase != metalv1alpha1.PhaseBound && !isQueuedOnQuota(claim)
+}
+
+// hostReservations returns the hosts reserved by the pending claims selecting their host by
+// labels. In the order of the queue, each claim reserves the first host it would select, so
+// that it only blocks that host for the claims after it.
+func hostReservations(hosts []metalv1alpha1.BareMetalHost, claims []metalv1alpha1.BareMetalHostClaim) map[types.UID]string {
+	referenced := map[string]bool{}
+	var pending []*metalv1alpha1.BareMetalHostClaim
+	for i := range claims {
+		claim := &claims[i]
+		if claim.Spec.BareMetalHostRef.Name != "" {
+			referenced[claim.Spec.BareMetalHostRef.Name] = true
+		} else if isClaimPending(claim) && claim.Status.PreemptedBy == nil {
+			pending = append(pending, claim)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return claimPrecedes(pending[i], pending[j]) })
+	sorted := make([]*metalv1alpha1.BareMetalHost, 0, len(hosts))
+	for i := range hosts {
+		if isHostSelectable(&hosts[i]) && !referenced[hosts[i].Name] {
+			sorted = append(sorted, &hosts[i])
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	reservations := map[types.UID]string{}
+	for _, claim := range pending {
+		for _, host := range sorted {
+			if !referenced[host.Name] && claimWantsHost(claim, host) {
+				reservations[claim.UID] = host.Name
+				referenced[host.Name] = true
+				break
+			}
+		}
+	}
+	return reservations
+}
+
+// listHostReservations determines the hosts reserved by the unbound claims selecting their host
+// by labels. Only the hosts matching the selectors of the claims are listed.
+func listHostReservations(ctx context.Context, c client.Client, claims []metalv1alpha1.BareMetalHostClaim) (map[types.UID]string, error) {
+	var hosts []metalv1alpha1.BareMetalHost
+	listed := map[string]bool{}
+	for i := range claims {
+		claim := &claims[i]
+		if claim.Spec.BareMetalHostRef.Name != "" || !isClaimPending(claim) || claim.Status.PreemptedBy != nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(claim.Spec.BareMetalHostSelector)
+		if err != nil || listed[selector.String()] {
+			continue
+		}
+		listed[selector.String()] = true
+		hostList := &metalv1alpha1.BareMetalHostList{}
+		if err := c.List(ctx, hostList, client.MatchingLabelsSelector{Selector: selector}, client.UnsafeDisableDeepCopy); err != nil {
+			return nil, fmt.Errorf("failed to list hosts: %w", err)
+		}
+		hosts = append(hosts, hostList.Items...)
+	}
+	return hostReservations(hosts, claims), nil
+}
+
+// precedingClaim returns a pending claim which is bound to the host before the claim. Nil is
+// returned if the claim is the first in the queue of the host. Claims selecting their host by
+// labels only precede the claim on the host they reserved.
+func precedingClaim(claim *metalv1alpha1.BareMetalHostClaim, host *metalv1alpha1.BareMetalHost, claims []metalv1alpha1.BareMetalHostClaim, reservations map[types.UID]string) *metalv1alpha1.BareMetalHostClaim {
+	for i := range claims {
+		other := &claims[i]
+		// preempted claims wait until their preemptor is bound
+		if other.UID == claim.UID || !isClaimPending(other) || other.Status.PreemptedBy != nil || !claimWantsHost(other, host) {
+			continue
+		}
+		if other.Spec.BareMetalHostRef.Name == "" && reservations[other.UID] != host.Name {
+			continue
+		}
+		if claimPrecedes(other, claim) {
+			return other
+		}
+	}
+	return nil
+}
+
+// preempt marks the claim with the lowest priority which is bound to a host wanted by the claim
+// for preemption, unless the claim already preempts another claim. Only claims of the namespace
+// of the claim are preempted, as the priority is set by the owner of the claim. The preempted
+// claim is unbound once the grace period passed, which releases its host.
+func (r *BareMetalHostClaimReconciler) preempt(ctx context.Context, log logr.Logger, claim *metalv1alpha1.BareMetalHostClaim) error {
+	if claim.Spec.PreemptionPolicy != metalv1alpha1.PreemptionPolicyPreemptLowerPriority || isQueuedOnQuota(claim) {
+		return nil
+	}
+	claimList := &metalv1alpha1.BareMetalHostClaimList{}
+	if err := r.List(ctx, claimList, client.InNamespace(claim.Namespace)); err != nil {
+		return fmt.Errorf("failed to list host claims: %w", err)
+	}
+
+	var victim *metalv1alpha1.BareMetalHostClaim
+	for i := range claimList.Items {
+		other := &claimList.Items[i]
+		if other.Status.PreemptedBy != nil && other.Status.PreemptedBy.UID == claim.UID {
+			log.V(1).Info("Waiting for preempted claim to be unbound", "Preempted", client.ObjectKeyFromObject(other))
+			return nil
+		}
+		if other.UID == claim.UID || other.Spec.Priority >= claim.Spec.Priority ||
+			other.Status.PreemptedBy != nil || !other.DeletionTimestamp.IsZero() || other.Spec.BareMetalHostRef.Name == "" {
+			continue
+		}
+		host := &metalv1alpha1.BareMetalHost{}
+		if err := r.Get(ctx, types.NamespacedName{Name: other.Spec.BareMetalHostRef.Name}, host); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get host of claim %s: %w", client.ObjectKeyFromObject(other), err)
+		}
+		if host.Spec.ClaimRef == nil || host.Spec.ClaimRef.UID != other.UID || !claimWantsHost(claim, host) {
+			continue
+		}
+		if claim.Spec.BareMetalHostRef.Name == "" &&
+			(host.Spec.Maintenance || meta.IsStatusConditionFalse(host.Status.Conditions, metalv1alpha1.BareMetalHostConditionHealthy)) {
+			continue
+		}
+		if victim == nil || claimPrecedes(victim, other) {
+			victim = other
+		}
+	}
+	if victim == nil {
+		return nil
+	}
+
+	log.V(1).Info("Preempting claim", "Preempted", client.ObjectKeyFromObject(victim), "Host", victim.Spec.BareMetalHostRef.Name)
+	victimBase := victim.DeepCopy()
+	victim.Status.PreemptedBy = &v1.ObjectReference{
+		Kind:      "BareMetalHostClaim",
+		Namespace: claim.Namespace,
+		Name:      claim.Name,
+		UID:       claim.UID,
+	}
+	meta.SetStatusCondition(&victim.Status.Conditions, metav1.Condition{
+		Type:               metalv1alpha1.BareMetalHostClaimConditionPreempted,
+		Status:             metav1.ConditionTrue,
+		Reason:             metalv1alpha1.BareMetalHostClaimReasonPreemptedByHigherPriority,
+		Message:            fmt.Sprintf("Preempted by claim %s/%s with priority %d", claim.Namespace, claim.Name, claim.Spec.Priority),
+		ObservedGeneration: victim.Generation,
+	})
+	if err := r.Status().Patch(ctx, victim, client.MergeFromWithOptions(victimBase, client.MergeFromWithOptimisticLock{})); err != nil {
+		return fmt.Errorf("failed to mark claim %s as preempted: %w", client.ObjectKeyFromObject(victim), err)
+	}
+	r.Recorder.Eventf(victim, v1.EventTypeWarning, preemptedReason, "Preempted by claim %s/%s with priority %d, unbinding the claim in %s",
+		claim.Namespace, claim.Name, claim.Spec.Priority, r.preemptionGracePeriod())
+	r.Recorder.Eventf(claim, v1.EventTypeNormal, preemptingReason, "Preempting claim %s/%s bound to host %s",
+		victim.Namespace, victim.Name, victim.Spec.BareMetalHostRef.Name)
+	return nil
+}
+
+// ensurePreemption unbinds the claim once the grace period of its preemption passed and
+// returns the duration until then otherwise. It reports whether the claim is held back, i.e.
+// it is unbound or waits for the preempting claim to be bound, so that it is not bound again
+// in the meantime. The preemption is cancelled once the preempting claim does not wait for a
+// host anymore.
+func (r *BareMetalHostClaimReconciler) ensurePreemption(ctx context.Context, log logr.Logger, claim *metalv1alpha1.BareMetalHostClaim) (bool, ctrl.Result, error) {
+	preemptedBy := claim.Status.PreemptedBy
+	if preemptedBy == nil {
+		return false, ctrl.Result{}, nil
+	}
+	preemptor := &metalv1alpha1.BareMetalHostClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: preemptedBy.Namespace, Name: preemptedBy.Name}, preemptor); client.IgnoreNotFound(err) != nil {
+		return false, ctrl.Result{}, fmt.Errorf("failed to get preempting claim: %w", err)
+	}
+	if preemptor.UID != preemptedBy.UID || !isClaimPending(preemptor) {
+		log.V(1).Info("Cancelling preemption", "Preemptor", client.ObjectKey{Namespace: preemptedBy.Namespace, Name: preemptedBy.Name})
+		claimBase := claim.DeepCopy()
+		claim.Status.PreemptedBy = nil
+		meta.RemoveStatusCondition(&claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionPreempted)
+		if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+			return false, ctrl.Result{}, fmt.Errorf("failed to cancel preemption: %w", err)
+		}
+		r.Recorder.Eventf(claim, v1.EventTypeNormal, preemptionCancelledReason, "Claim %s/%s does not wait for a host anymore",
+			preemptedBy.Namespace, preemptedBy.Name)
+		return false, ctrl.Result{}, nil
+	}
+
+	preemptedAt := time.Now()
+	if condition := meta.FindStatusCondition(claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionPreempted); condition != nil {
+		preemptedAt = condition.LastTransitionTime.Time
+	}
+	if remaining := r.preemptionGracePeriod() - time.Since(preemptedAt); remaining > 0 {
+		return false, ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	if claim.Status.Phase == metalv1alpha1.PhaseUnbound {
+		log.V(1).Info("Waiting for preempting claim to be bound", "Preemptor", client.ObjectKeyFromObject(preemptor))
+		return true, ctrl.Result{}, nil
+	}
+
+	log.V(1).Info("Unbinding preempted claim", "Preemptor", client.ObjectKeyFromObject(preemptor))
+	if r.ConsoleRecorder != nil {
+		r.ConsoleRecorder.Stop(claim)
+	}
+	if released, result, err := r.releaseHost(ctx, log, claim, preemptedAt.Add(r.preemptionGracePeriod())); err != nil || !released {
+		return true, result, err
+	}
+	if claim.Spec.BareMetalHostSelector != nil {
+		// claims selecting their host by labels select a host again, claims referencing their
+		// host wait until it is released by the preempting claim
+		claimBase := claim.DeepCopy()
+		claim.Spec.BareMetalHostRef = v1.LocalObjectReference{}
+		if err := r.Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+			return true, ctrl.Result{}, fmt.Errorf("failed to remove host from preempted claim: %w", err)
+		}
+	}
+	claimBase := claim.DeepCopy()
+	claim.Status.Phase = metalv1alpha1.PhaseUnbound
+	claim.Status.ProvisionedAt = nil
+	claim.Status.ProvisioningHash = ""
+	claim.Status.ConsoleLog = nil
+	claim.Status.BoundAt = nil
+	claim.Status.ExpiresAt = nil
+	for _, conditionType := range []string{
+		metalv1alpha1.BareMetalHostClaimConditionDeprovisioned,
+		metalv1alpha1.BareMetalHostClaimConditionProvisioned,
+		metalv1alpha1.BareMetalHostClaimConditionIPAddressesAllocated,
+		metalv1alpha1.BareMetalHostClaimConditionLeaseExpiring,
+	} {
+		meta.RemoveStatusCondition(&claim.Status.Conditions, conditionType)
+	}
+	if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+		return true, ctrl.Result{}, fmt.Errorf("failed to patch status of preempted claim: %w", err)
+	}
+	r.Recorder.Eventf(claim, v1.EventTypeWarning, preemptedReason, "Unbound claim preempted by claim %s/%s", preemptor.Namespace, preemptor.Name)
+	return true, ctrl.Result{}, nil
+}
+
+func (r *BareMetalHostClaimReconciler) preemptionGracePeriod() time.Duration {
+	if r.PreemptionGracePeriod > 0 {
+		return r.PreemptionGracePeriod
+	}
+	return DefaultPreemptionGracePeriod
+}
+
+// patchConflict moves the claim to the Conflict phase as its host is claimed by another claim.
+// The claim is bound once the host is released. The returned backoff doubles the longer the
+// conflict lasts.
+func (r *BareMetalHostClaimReconciler) patchConflict(ctx context.Context, log logr.Logger, claim *metalv1alpha1.BareMetalHostClaim, host *metalv1alpha1.BareMetalHost) (time.Duration, error) {
+	owner := host.Spec.ClaimRef
+	message := fmt.Sprintf("Host %s is claimed by claim %s/%s", host.Name, owner.Namespace, owner.Name)
+	claimBase := claim.DeepCopy()
+	claim.Status.Phase = metalv1alpha1.PhaseConflict
+	changed := meta.SetStatusCondition(&claim.Status.Conditions, metav1.Condition{
+		Type:               metalv1alpha1.BareMetalHostClaimConditionConflict,
+		Status:             metav1.ConditionTrue,
+		Reason:             metalv1alpha1.BareMetalHostClaimReasonHostClaimed,
+		Message:            message,
+		ObservedGeneration: claim.Generation,
+	})
+	if changed || claimBase.Status.Phase != claim.Status.Phase {
+		if err := r.Status().Patch(ctx, claim, client.MergeFrom(claimBase)); err != nil {
+			return 0, fmt.Errorf("failed to patch conflict status: %w", err)
+		}
+	}
+	if !meta.IsStatusConditionTrue(claimBase.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionConflict) {
+		log.V(1).Info("Host is claimed by another claim", "Host", host.Name, "Owner", client.ObjectKey{Namespace: owner.Namespace, Name: owner.Name})
+		r.Recorder.Event(claim, v1.EventTypeWarning, conflictReason, message)
+	}
+
+	backoff := minConflictBackoff
+	if condition := meta.FindStatusCondition(claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionConflict); condition != nil {
+		// the conflict is checked again after the time it already lasted
+		if lasted := time.Since(condition.LastTransitionTime.Time); lasted > backoff {
+			backoff = lasted
+		}
+	}
+	if backoff > maxConflictBackoff {
+		backoff = maxConflictBackoff
+	}
+	return backoff, nil
+}
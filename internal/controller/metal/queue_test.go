@@ -0,0 +1,216 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// createSelectorClaim creates a claim selecting its host by the labels.
+func createSelectorClaim(ctx SpecContext, ns *v1.Namespace, labels map[string]string, priority int32, policy metalv1alpha1.PreemptionPolicy) *metalv1alpha1.BareMetalHostClaim {
+	claim := &metalv1alpha1.BareMetalHostClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, GenerateName: "claim-"},
+		Spec: metalv1alpha1.BareMetalHostClaimSpec{
+			Power:                 metalv1alpha1.PowerStateOff,
+			BareMetalHostSelector: &metav1.LabelSelector{MatchLabels: labels},
+			Image:                 "foo:latest",
+			Priority:              priority,
+			PreemptionPolicy:      policy,
+		},
+	}
+	Expect(k8sClient.Create(ctx, claim)).To(Succeed())
+	DeferCleanup(func(ctx SpecContext) {
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, claim))).To(Succeed())
+	})
+	return claim
+}
+
+// expectPhase waits for the claim to reach the phase.
+func expectPhase(ctx SpecContext, claim *metalv1alpha1.BareMetalHostClaim, phase metalv1alpha1.Phase) {
+	Eventually(func(g Gomega) {
+		g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(Succeed())
+		g.Expect(claim.Status.Phase).To(Equal(phase))
+	}).Should(Succeed())
+}
+
+var _ = Describe("BareMetalHostClaim Queue", func() {
+	var ns *v1.Namespace
+
+	BeforeEach(func(ctx SpecContext) {
+		ns = &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ns)
+	})
+
+	It("should bind pending claims by priority once a host becomes available", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "dddddddd-dddd-dddd-dddd-000000000001")
+		labels := map[string]string{"queue": "priority"}
+		labelHost(ctx, host, labels)
+
+		claim := createSelectorClaim(ctx, ns, labels, 0, metalv1alpha1.PreemptionPolicyNever)
+		expectPhase(ctx, claim, metalv1alpha1.PhaseBound)
+
+		By("Queueing a claim with a low priority before a claim with a high priority")
+		low := createSelectorClaim(ctx, ns, labels, 0, metalv1alpha1.PreemptionPolicyNever)
+		expectPhase(ctx, low, metalv1alpha1.PhaseUnbound)
+		high := createSelectorClaim(ctx, ns, labels, 10, metalv1alpha1.PreemptionPolicyNever)
+		expectPhase(ctx, high, metalv1alpha1.PhaseUnbound)
+
+		By("Releasing the host")
+		Expect(k8sClient.Delete(ctx, claim)).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(high), high)).To(Succeed())
+			g.Expect(high.Spec.BareMetalHostRef.Name).To(Equal(host.Name))
+			g.Expect(high.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+		}).Should(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(low), low)).To(Succeed())
+		Expect(low.Spec.BareMetalHostRef.Name).To(BeEmpty())
+		Expect(low.Status.Phase).To(Equal(metalv1alpha1.PhaseUnbound))
+	})
+
+	It("should queue claims referencing a claimed host", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "dddddddd-dddd-dddd-dddd-000000000002")
+		claim, _ := createClaim(ctx, ns, host, metalv1alpha1.ReprovisionPolicyOnChange)
+		expectPhase(ctx, claim, metalv1alpha1.PhaseBound)
+
+		other, _ := createClaim(ctx, ns, host, metalv1alpha1.ReprovisionPolicyOnChange)
+		DeferCleanup(k8sClient.Delete, other)
+		expectPhase(ctx, other, metalv1alpha1.PhaseConflict)
+
+		By("Ensuring that the conflict names the claim the host is claimed by")
+		condition := meta.FindStatusCondition(other.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionConflict)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(metalv1alpha1.BareMetalHostClaimReasonHostClaimed))
+		Expect(condition.Message).To(ContainSubstring(ns.Name + "/" + claim.Name))
+		Eventually(func(g Gomega) {
+			events := &v1.EventList{}
+			g.Expect(k8sClient.List(ctx, events, client.InNamespace(ns.Name))).To(Succeed())
+			g.Expect(events.Items).To(ContainElement(
+				SatisfyAll(HaveField("InvolvedObject.Name", other.Name), HaveField("Reason", conflictReason)),
+			))
+		}).Should(Succeed())
+
+		By("Releasing the host")
+		Expect(k8sClient.Delete(ctx, claim)).To(Succeed())
+		expectPhase(ctx, other, metalv1alpha1.PhaseBound)
+		Expect(meta.FindStatusCondition(other.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionConflict)).To(BeNil())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(host), host)).To(Succeed())
+		Expect(host.Spec.ClaimRef).NotTo(BeNil())
+		Expect(host.Spec.ClaimRef.UID).To(Equal(other.UID))
+	})
+
+	It("should unbind a claim of the namespace with a lower priority after the grace period", func(ctx SpecContext) {
+		_, host := setupFakeHost(ctx, "dddddddd-dddd-dddd-dddd-000000000003")
+		_, otherHost := setupFakeHost(ctx, "dddddddd-dddd-dddd-dddd-000000000004")
+		labels := map[string]string{"queue": "preemption"}
+		labelHost(ctx, otherHost, labels)
+
+		By("Binding a claim with the lowest priority in another namespace")
+		otherNs := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "test-"}}
+		Expect(k8sClient.Create(ctx, otherNs)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, otherNs)
+		foreign := createSelectorClaim(ctx, otherNs, labels, -10, metalv1alpha1.PreemptionPolicyNever)
+		expectPhase(ctx, foreign, metalv1alpha1.PhaseBound)
+
+		labelHost(ctx, host, labels)
+		victim := createSelectorClaim(ctx, ns, labels, 0, metalv1alpha1.PreemptionPolicyNever)
+		expectPhase(ctx, victim, metalv1alpha1.PhaseBound)
+		Expect(victim.Spec.BareMetalHostRef.Name).To(Equal(host.Name))
+
+		By("Creating a claim preempting claims with a lower priority")
+		preemptor := createSelectorClaim(ctx, ns, labels, 10, metalv1alpha1.PreemptionPolicyPreemptLowerPriority)
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(victim), victim)).To(Succeed())
+			g.Expect(victim.Status.PreemptedBy).NotTo(BeNil())
+			g.Expect(victim.Status.PreemptedBy.UID).To(Equal(preemptor.UID))
+		}).Should(Succeed())
+
+		By("Ensuring that events are recorded on both claims")
+		Eventually(func(g Gomega) {
+			events := &v1.EventList{}
+			g.Expect(k8sClient.List(ctx, events, client.InNamespace(ns.Name))).To(Succeed())
+			g.Expect(events.Items).To(ContainElements(
+				SatisfyAll(HaveField("InvolvedObject.Name", victim.Name), HaveField("Reason", preemptedReason)),
+				SatisfyAll(HaveField("InvolvedObject.Name", preemptor.Name), HaveField("Reason", preemptingReason)),
+			))
+		}).Should(Succeed())
+
+		By("Ensuring that the host of the preempted claim is bound to the preempting claim")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(preemptor), preemptor)).To(Succeed())
+			g.Expect(preemptor.Spec.BareMetalHostRef.Name).To(Equal(host.Name))
+			g.Expect(preemptor.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+		}).Should(Succeed())
+
+		By("Ensuring that the preempted claim is unbound instead of deleted")
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(victim), victim)).To(Succeed())
+			g.Expect(victim.DeletionTimestamp).To(BeNil())
+			g.Expect(victim.Spec.BareMetalHostRef.Name).To(BeEmpty())
+			g.Expect(victim.Status.Phase).To(Equal(metalv1alpha1.PhaseUnbound))
+			g.Expect(victim.Status.BoundAt).To(BeNil())
+			g.Expect(victim.Status.PreemptedBy).To(BeNil())
+			g.Expect(meta.FindStatusCondition(victim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionPreempted)).To(BeNil())
+		}).Should(Succeed())
+
+		By("Ensuring that the claim of the other namespace is not preempted")
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(foreign), foreign)).To(Succeed())
+		Expect(foreign.Status.PreemptedBy).To(BeNil())
+		Expect(foreign.Status.Phase).To(Equal(metalv1alpha1.PhaseBound))
+		Expect(foreign.Spec.BareMetalHostRef.Name).To(Equal(otherHost.Name))
+	})
+
+	It("should only block the host reserved by a pending claim selecting its host", func() {
+		available := func(name string) metalv1alpha1.BareMetalHost {
+			return metalv1alpha1.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"rack": "r1"}},
+				Status:     metalv1alpha1.BareMetalHostStatus{State: metalv1alpha1.StateAvailable},
+			}
+		}
+		hosts := []metalv1alpha1.BareMetalHost{available("host-b"), available("host-a"), available("host-c")}
+		claims := []metalv1alpha1.BareMetalHostClaim{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "selector", UID: "selector"},
+				Spec: metalv1alpha1.BareMetalHostClaimSpec{
+					BareMetalHostSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"rack": "r1"}},
+					Priority:              10,
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "reference", UID: "reference"},
+				Spec: metalv1alpha1.BareMetalHostClaimSpec{
+					BareMetalHostRef: v1.LocalObjectReference{Name: "host-c"},
+				},
+			},
+		}
+		reservations := hostReservations(hosts, claims)
+		Expect(reservations).To(Equal(map[types.UID]string{"selector": "host-a"}))
+
+		By("Ensuring that a claim with a lower priority is only blocked on the reserved host")
+		low := &metalv1alpha1.BareMetalHostClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "low", UID: "low"}}
+		Expect(precedingClaim(low, &hosts[1], claims, reservations)).To(HaveField("Name", "selector"))
+		Expect(precedingClaim(low, &hosts[0], claims, reservations)).To(BeNil())
+	})
+})
@@ -0,0 +1,159 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// quotaUsage is the number of hosts a namespace claims in every pool. It decides whether the
+// quotas of the pools admit a host for a claim of the namespace.
+type quotaUsage struct {
+	namespace string
+	pools     []poolUsage
+}
+
+// poolUsage is the number of hosts of a pool claimed by a namespace.
+type poolUsage struct {
+	pool     *metalv1alpha1.BareMetalHostPool
+	selector labels.Selector
+	hosts    int32
+	classes  map[string]int32
+}
+
+// newQuotaUsage counts the hosts of every pool which are claimed by the namespace of the claim,
+// excluding the host of the claim itself. Hosts referenced by admitted claims count as claimed
+// before their claimRef is set, so that concurrent claims cannot exceed a quota.
+func newQuotaUsage(ctx context.Context, c client.Client, claim *metalv1alpha1.BareMetalHostClaim) (*quotaUsage, error) {
+	usage := &quotaUsage{namespace: claim.Namespace}
+	poolList := &metalv1alpha1.BareMetalHostPoolList{}
+	if err := c.List(ctx, poolList); err != nil {
+		return nil, fmt.Errorf("failed to list host pools: %w", err)
+	}
+	if len(poolList.Items) == 0 {
+		return usage, nil
+	}
+	hostList := &metalv1alpha1.BareMetalHostList{}
+	if err := c.List(ctx, hostList); err != nil {
+		return nil, fmt.Errorf("failed to list hosts: %w", err)
+	}
+	claimList := &metalv1alpha1.BareMetalHostClaimList{}
+	if err := c.List(ctx, claimList, client.InNamespace(claim.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list host claims: %w", err)
+	}
+	admitted := map[string]bool{}
+	for _, other := range claimList.Items {
+		if other.UID != claim.UID && other.Spec.BareMetalHostRef.Name != "" &&
+			meta.IsStatusConditionTrue(other.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionWithinQuota) {
+			admitted[other.Spec.BareMetalHostRef.Name] = true
+		}
+	}
+
+	for i := range poolList.Items {
+		pool := &poolList.Items[i]
+		selector, err := metav1.LabelSelectorAsSelector(&pool.Spec.HostSelector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse host selector of pool %s: %w", pool.Name, err)
+		}
+		poolUsage := poolUsage{pool: pool, selector: selector, classes: map[string]int32{}}
+		for _, host := range hostList.Items {
+			if !selector.Matches(labels.Set(host.Labels)) {
+				continue
+			}
+			claimRef := host.Spec.ClaimRef
+			if (claimRef != nil && claimRef.Namespace == claim.Namespace && claimRef.UID != claim.UID) || admitted[host.Name] {
+				poolUsage.hosts++
+				poolUsage.classes[host.Labels[metalv1alpha1.HardwareClassLabel]]++
+			}
+		}
+		usage.pools = append(usage.pools, poolUsage)
+	}
+	return usage, nil
+}
+
+// admit reports whether the host belongs to any pool and describes why the quotas of its pools
+// do not admit another host for the namespace. The message is empty if the host is admitted.
+func (u *quotaUsage) admit(host *metalv1alpha1.BareMetalHost) (bool, string) {
+	pooled := false
+	for _, pool := range u.pools {
+		if !pool.selector.Matches(labels.Set(host.Labels)) {
+			continue
+		}
+		pooled = true
+		quota := findNamespaceQuota(pool.pool, u.namespace)
+		if quota == nil {
+			return true, fmt.Sprintf("Namespace %s has no quota in pool %s", u.namespace, pool.pool.Name)
+		}
+		if quota.MaxHosts != nil && pool.hosts >= *quota.MaxHosts {
+			return true, fmt.Sprintf("Quota of %d hosts of namespace %s in pool %s is exhausted", *quota.MaxHosts, u.namespace, pool.pool.Name)
+		}
+		class := host.Labels[metalv1alpha1.HardwareClassLabel]
+		for _, classQuota := range quota.Classes {
+			if class != "" && classQuota.Class == class && pool.classes[class] >= classQuota.MaxHosts {
+				return true, fmt.Sprintf("Quota of %d hosts of class %s of namespace %s in pool %s is exhausted", classQuota.MaxHosts, class, u.namespace, pool.pool.Name)
+			}
+		}
+	}
+	return pooled, ""
+}
+
+// add counts the host as claimed by the namespace in the pools of the host.
+func (u *quotaUsage) add(host *metalv1alpha1.BareMetalHost) {
+	for i := range u.pools {
+		if u.pools[i].selector.Matches(labels.Set(host.Labels)) {
+			u.pools[i].hosts++
+			u.pools[i].classes[host.Labels[metalv1alpha1.HardwareClassLabel]]++
+		}
+	}
+}
+
+func findNamespaceQuota(pool *metalv1alpha1.BareMetalHostPool, namespace string) *metalv1alpha1.NamespaceQuota {
+	for i := range pool.Spec.Quotas {
+		if pool.Spec.Quotas[i].Namespace == namespace {
+			return &pool.Spec.Quotas[i]
+		}
+	}
+	return nil
+}
+
+// setQuotaCondition reports in the WithinQuota condition of the claim whether it has been
+// admitted by the quotas of the pools of its host.
+func setQuotaCondition(claim *metalv1alpha1.BareMetalHostClaim, pooled bool, message string) {
+	if !pooled {
+		meta.RemoveStatusCondition(&claim.Status.Conditions, metalv1alpha1.BareMetalHostClaimConditionWithinQuota)
+		return
+	}
+	condition := metav1.Condition{
+		Type:               metalv1alpha1.BareMetalHostClaimConditionWithinQuota,
+		Status:             metav1.ConditionTrue,
+		Reason:             metalv1alpha1.BareMetalHostClaimReasonQuotaAvailable,
+		ObservedGeneration: claim.Generation,
+	}
+	if message != "" {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = metalv1alpha1.BareMetalHostClaimReasonQuotaExceeded
+		condition.Message = message
+	}
+	meta.SetStatusCondition(&claim.Status.Conditions, condition)
+}
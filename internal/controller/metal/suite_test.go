@@ -17,30 +17,50 @@ limitations under the License.
 package metal
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"testing"
+	"time"
 
+	"github.com/afritzler/baremetal-operator/internal/bmc"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	bootv1alpha1 "github.com/afritzler/baremetal-operator/api/boot/v1alpha1"
 	coreafritzlergithubiov1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	bootcontroller "github.com/afritzler/baremetal-operator/internal/controller/boot"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	//+kubebuilder:scaffold:imports
 )
 
+const (
+	pollingInterval      = 50 * time.Millisecond
+	eventuallyTimeout    = 10 * time.Second
+	consistentlyDuration = 1 * time.Second
+)
+
 // These tests use Ginkgo (BDD-style Go testing framework). Refer to
 // http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
 
 var cfg *rest.Config
 var k8sClient client.Client
+var k8sManagerClient client.Client
 var testEnv *envtest.Environment
+var cancel context.CancelFunc
+var fakeBMCs *bmc.FakeBMCs
 
 func TestControllers(t *testing.T) {
 	RegisterFailHandler(Fail)
@@ -53,7 +73,7 @@ var _ = BeforeSuite(func() {
 
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{
-		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "config", "crd", "bases")},
+		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "..", "config", "crd", "bases")},
 		ErrorIfCRDPathMissing: true,
 
 		// The BinaryAssetsDirectory is only required if you want to run the tests directly
@@ -61,7 +81,7 @@ var _ = BeforeSuite(func() {
 		// default path defined in controller-runtime which is /usr/local/kubebuilder/.
 		// Note that you must have the required binaries setup under the bin directory to perform
 		// the tests directly. When we run make test it will be setup and used automatically.
-		BinaryAssetsDirectory: filepath.Join("..", "..", "bin", "k8s",
+		BinaryAssetsDirectory: filepath.Join("..", "..", "..", "bin", "k8s",
 			fmt.Sprintf("1.28.3-%s-%s", runtime.GOOS, runtime.GOARCH)),
 	}
 
@@ -73,6 +93,8 @@ var _ = BeforeSuite(func() {
 
 	err = coreafritzlergithubiov1alpha1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
+	err = bootv1alpha1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
 
 	//+kubebuilder:scaffold:scheme
 
@@ -80,10 +102,103 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
 
+	SetDefaultEventuallyPollingInterval(pollingInterval)
+	SetDefaultEventuallyTimeout(eventuallyTimeout)
+	SetDefaultConsistentlyPollingInterval(pollingInterval)
+	SetDefaultConsistentlyDuration(consistentlyDuration)
+
+	pxeNamespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "oob"}}
+	Expect(k8sClient.Create(context.Background(), pxeNamespace)).To(Succeed())
+
+	mgrOptions := ctrl.Options{
+		Scheme:  scheme.Scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	}
+	ConfigureSecretCache(&mgrOptions)
+	k8sManager, err := ctrl.NewManager(cfg, mgrOptions)
+	Expect(err).NotTo(HaveOccurred())
+	k8sManagerClient = k8sManager.GetClient()
+
+	Expect(SetupFieldIndexes(context.Background(), k8sManager.GetFieldIndexer())).To(Succeed())
+
+	fakeBMCs = bmc.NewFakeBMCs()
+	Expect((&BMCReconciler{
+		Client:         k8sManager.GetClient(),
+		Scheme:         k8sManager.GetScheme(),
+		FakeBMCs:       fakeBMCs,
+		APIReader:      k8sManager.GetAPIReader(),
+		ResyncInterval: time.Minute,
+	}).SetupWithManager(k8sManager)).To(Succeed())
+	Expect((&BMCDiscoveryReconciler{
+		Client:                      k8sManager.GetClient(),
+		Scheme:                      k8sManager.GetScheme(),
+		ChunkSize:                   1,
+		AuthenticationRetryInterval: time.Second,
+	}).SetupWithManager(k8sManager)).To(Succeed())
+	Expect((&BareMetalHostReconciler{
+		Client:   k8sManager.GetClient(),
+		Scheme:   k8sManager.GetScheme(),
+		FakeBMCs: fakeBMCs,
+	}).SetupWithManager(k8sManager)).To(Succeed())
+	consoleServer := &ConsoleServer{Client: k8sManager.GetClient(), FakeBMCs: fakeBMCs}
+	Expect(k8sManager.Add(consoleServer)).To(Succeed())
+	consoleRecorder := &ConsoleRecorder{
+		Client:           k8sManager.GetClient(),
+		Consoles:         consoleServer,
+		MaxDuration:      time.Minute,
+		SucceededPattern: regexp.MustCompile(DefaultConsoleLogSucceededPattern),
+		FailedPattern:    regexp.MustCompile(DefaultConsoleLogFailedPattern),
+		FlushInterval:    100 * time.Millisecond,
+	}
+	Expect(k8sManager.Add(consoleRecorder)).To(Succeed())
+	Expect((&BareMetalHostClaimReconciler{
+		Client:                k8sManager.GetClient(),
+		Scheme:                k8sManager.GetScheme(),
+		ConsoleRecorder:       consoleRecorder,
+		Recorder:              k8sManager.GetEventRecorderFor("hostclaim-controller"),
+		PreemptionGracePeriod: 2 * time.Second,
+		LeaseWarningPeriod:    2 * time.Second,
+		BootServerURL:         &url.URL{Scheme: "http", Host: "[2001:db8::1]:8082"},
+	}).SetupWithManager(k8sManager)).To(Succeed())
+	Expect((&BareMetalHostPoolReconciler{
+		Client: k8sManager.GetClient(),
+		Scheme: k8sManager.GetScheme(),
+	}).SetupWithManager(k8sManager)).To(Succeed())
+	Expect((&BareMetalHostClaimSetReconciler{
+		Client: k8sManager.GetClient(),
+		Scheme: k8sManager.GetScheme(),
+	}).SetupWithManager(k8sManager)).To(Succeed())
+	Expect((&IPPoolReconciler{
+		Client: k8sManager.GetClient(),
+		Scheme: k8sManager.GetScheme(),
+	}).SetupWithManager(k8sManager)).To(Succeed())
+	Expect((&IPAddressClaimReconciler{
+		Client:   k8sManager.GetClient(),
+		Scheme:   k8sManager.GetScheme(),
+		Recorder: k8sManager.GetEventRecorderFor("ipaddressclaim-controller"),
+	}).SetupWithManager(k8sManager)).To(Succeed())
+	Expect((&bootcontroller.PXEReconciler{
+		Client:              k8sManager.GetClient(),
+		Scheme:              k8sManager.GetScheme(),
+		PXEServiceNamespace: pxeNamespace.Name,
+	}).SetupWithManager(k8sManager)).To(Succeed())
+
+	var mgrCtx context.Context
+	mgrCtx, cancel = context.WithCancel(context.Background())
+	go func() {
+		defer GinkgoRecover()
+		Expect(k8sManager.Start(mgrCtx)).To(Succeed(), "failed to start manager")
+	}()
+})
+
+var _ = BeforeEach(func() {
+	// the fake BMCs of previous specs are dropped
+	fakeBMCs.Reset()
 })
 
 var _ = AfterSuite(func() {
 	By("tearing down the test environment")
+	cancel()
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
 })
@@ -0,0 +1,224 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	metalv1alpha1 "github.com/afritzler/baremetal-operator/api/metal/v1alpha1"
+	"github.com/afritzler/baremetal-operator/internal/bmc"
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stmcginnis/gofish/common"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	hostTemperature = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "baremetal_host_temperature_celsius",
+		Help: "Temperature reported by a sensor of the chassis containing the host.",
+	}, []string{"host", "sensor"})
+	hostFanSpeed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "baremetal_host_fan_speed_rpm",
+		Help: "Speed of a fan of the chassis containing the host.",
+	}, []string{"host", "fan"})
+	hostPowerSupplyHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "baremetal_host_power_supply_healthy",
+		Help: "Whether a power supply of the chassis containing the host is enabled and healthy.",
+	}, []string{"host", "power_supply"})
+	hostPowerConsumed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "baremetal_host_power_consumed_watts",
+		Help: "Power draw of the chassis containing the host.",
+	}, []string{"host"})
+	telemetryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "baremetal_telemetry_errors_total",
+		Help: "Number of failed telemetry collections per BMC.",
+	}, []string{"bmc"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(hostTemperature, hostFanSpeed, hostPowerSupplyHealthy, hostPowerConsumed, telemetryErrors)
+}
+
+// TelemetryCollector periodically reads the sensors of all hosts and exports them as
+// Prometheus metrics.
+type TelemetryCollector struct {
+	client.Client
+	// FakeBMCs keeps the state of the BMCs of type Fake.
+	FakeBMCs *bmc.FakeBMCs
+	// Interval is the interval in which the sensors are read.
+	Interval time.Duration
+	// Timeout bounds the collection of a single host.
+	Timeout time.Duration
+	// MaxHostsPerBMC is the maximum number of hosts of a single BMC which are read per
+	// interval. The hosts of larger BMCs are read in turns.
+	MaxHostsPerBMC int
+	// Concurrency is the maximum number of BMCs which are read in parallel.
+	Concurrency int
+
+	mu      sync.Mutex
+	offsets map[string]int
+	hosts   map[string]bool
+}
+
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=baremetalhosts,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal.afritzler.github.io,resources=bmcs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+
+// Start runs the collector until the context is done.
+func (c *TelemetryCollector) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("telemetry")
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.collect(ctx, log); err != nil {
+			log.Error(err, "Failed to collect telemetry")
+		}
+	}, c.Interval)
+	return nil
+}
+
+// collect reads the sensors of the hosts of all BMCs.
+func (c *TelemetryCollector) collect(ctx context.Context, log logr.Logger) error {
+	hostList := &metalv1alpha1.BareMetalHostList{}
+	if err := c.List(ctx, hostList); err != nil {
+		return fmt.Errorf("failed to list hosts: %w", err)
+	}
+
+	hostsByBMC := map[string][]metalv1alpha1.BareMetalHost{}
+	current := map[string]bool{}
+	for _, host := range hostList.Items {
+		hostsByBMC[host.Spec.BMCRef.Name] = append(hostsByBMC[host.Spec.BMCRef.Name], host)
+		current[host.Name] = true
+	}
+
+	c.mu.Lock()
+	for name := range c.hosts {
+		if !current[name] {
+			deleteHostTelemetry(name)
+		}
+	}
+	c.hosts = current
+	c.mu.Unlock()
+
+	concurrency := c.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for bmcName, hosts := range hostsByBMC {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(bmcName string, hosts []metalv1alpha1.BareMetalHost) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			c.collectBMC(ctx, log.WithValues("BMC", bmcName), bmcName, hosts)
+		}(bmcName, hosts)
+	}
+	wg.Wait()
+	return nil
+}
+
+// collectBMC reads the sensors of at most MaxHostsPerBMC hosts of the given BMC.
+func (c *TelemetryCollector) collectBMC(ctx context.Context, log logr.Logger, bmcName string, hosts []metalv1alpha1.BareMetalHost) {
+	bmcObj := &metalv1alpha1.BMC{}
+	if err := c.Get(ctx, client.ObjectKey{Name: bmcName}, bmcObj); err != nil {
+		log.Error(err, "Failed to get BMC")
+		telemetryErrors.WithLabelValues(bmcName).Inc()
+		return
+	}
+
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Name < hosts[j].Name })
+	batch := hosts
+	if c.MaxHostsPerBMC > 0 && len(hosts) > c.MaxHostsPerBMC {
+		c.mu.Lock()
+		if c.offsets == nil {
+			c.offsets = map[string]int{}
+		}
+		offset := c.offsets[bmcName] % len(hosts)
+		c.offsets[bmcName] = offset + c.MaxHostsPerBMC
+		c.mu.Unlock()
+
+		batch = make([]metalv1alpha1.BareMetalHost, 0, c.MaxHostsPerBMC)
+		for i := 0; i < c.MaxHostsPerBMC; i++ {
+			batch = append(batch, hosts[(offset+i)%len(hosts)])
+		}
+	}
+
+	for _, host := range batch {
+		if err := c.collectHost(ctx, bmcObj, &host); err != nil {
+			log.Error(err, "Failed to collect telemetry", "Host", host.Name)
+			telemetryErrors.WithLabelValues(bmcName).Inc()
+		}
+	}
+}
+
+func (c *TelemetryCollector) collectHost(ctx context.Context, bmcObj *metalv1alpha1.BMC, host *metalv1alpha1.BareMetalHost) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	bmcClient, err := newBMCClient(ctx, c.Client, c.FakeBMCs, bmcObj, host)
+	if err != nil {
+		return fmt.Errorf("failed to create BMC client: %w", err)
+	}
+	defer bmcClient.Logout()
+
+	telemetry, err := bmcClient.GetTelemetry()
+	if err != nil {
+		return fmt.Errorf("failed to get telemetry: %w", err)
+	}
+	setHostTelemetry(host.Name, telemetry)
+	return nil
+}
+
+// setHostTelemetry replaces the metrics of the host, so that removed sensors disappear.
+func setHostTelemetry(host string, telemetry bmc.Telemetry) {
+	deleteHostTelemetry(host)
+	for _, temperature := range telemetry.Temperatures {
+		hostTemperature.WithLabelValues(host, temperature.Name).Set(temperature.ReadingCelsius)
+	}
+	for _, fan := range telemetry.Fans {
+		hostFanSpeed.WithLabelValues(host, fan.Name).Set(fan.ReadingRPM)
+	}
+	for _, supply := range telemetry.PowerSupplies {
+		healthy := 0.0
+		if supply.Health == common.OKHealth && (supply.State == "" || supply.State == common.EnabledState) {
+			healthy = 1
+		}
+		hostPowerSupplyHealthy.WithLabelValues(host, supply.Name).Set(healthy)
+	}
+	if telemetry.PowerConsumedWatts != nil {
+		hostPowerConsumed.WithLabelValues(host).Set(*telemetry.PowerConsumedWatts)
+	}
+}
+
+func deleteHostTelemetry(host string) {
+	labels := prometheus.Labels{"host": host}
+	hostTemperature.DeletePartialMatch(labels)
+	hostFanSpeed.DeletePartialMatch(labels)
+	hostPowerSupplyHealthy.DeletePartialMatch(labels)
+	hostPowerConsumed.DeletePartialMatch(labels)
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metal
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("Telemetry Collector", func() {
+	It("should export the sensors of a bounded number of hosts per BMC", func(ctx SpecContext) {
+		By("Creating a fake BMC with three systems")
+		bmcObj, _ := setupFakeHost(ctx, "11111111-1111-1111-1111-111111111111",
+			withSystems("22222222-2222-2222-2222-222222222222", "33333333-3333-3333-3333-333333333333"))
+		var hosts []string
+		for i := 1; i <= 3; i++ {
+			hosts = append(hosts, objectName(bmcObj.Name, fmt.Sprintf("System-%d", i)))
+		}
+
+		collector := &TelemetryCollector{
+			Client:         k8sClient,
+			FakeBMCs:       fakeBMCs,
+			MaxHostsPerBMC: 2,
+			Concurrency:    1,
+		}
+		By("Collecting the first two hosts")
+		Expect(collector.collect(ctx, GinkgoLogr)).To(Succeed())
+		Expect(testutil.ToFloat64(hostPowerConsumed.WithLabelValues(hosts[0]))).To(BeNumerically("==", 10))
+		Expect(testutil.ToFloat64(hostTemperature.WithLabelValues(hosts[1], "CPU1 Temp"))).To(BeNumerically("==", 25))
+		Expect(testutil.ToFloat64(hostPowerSupplyHealthy.WithLabelValues(hosts[1], "PSU1"))).To(BeNumerically("==", 1))
+		Expect(hostFanSpeed.DeletePartialMatch(map[string]string{"host": hosts[2]})).To(BeZero())
+
+		By("Collecting the remaining host in the next interval")
+		Expect(collector.collect(ctx, GinkgoLogr)).To(Succeed())
+		Expect(testutil.ToFloat64(hostFanSpeed.WithLabelValues(hosts[2], "Fan1"))).To(BeNumerically("==", 0))
+	})
+})
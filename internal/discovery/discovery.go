@@ -0,0 +1,169 @@
+package discovery
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultConcurrency is the default number of endpoints probed in parallel.
+	DefaultConcurrency = 16
+	// DefaultTimeout is the default timeout of a single probe.
+	DefaultTimeout = 2 * time.Second
+	// MaxTargets is the maximum number of addresses which are scanned in one pass.
+	MaxTargets = 1 << 16
+)
+
+type Protocol string
+
+const (
+	ProtocolRedfish Protocol = "Redfish"
+	ProtocolIPMI    Protocol = "IPMI"
+)
+
+// Endpoint is a responsive management endpoint.
+type Endpoint struct {
+	// Address is the URL of a Redfish endpoint or the host:port of an IPMI endpoint.
+	Address  string
+	Protocol Protocol
+	Vendor   string
+	Product  string
+	UUID     string
+}
+
+// Scanner probes network ranges for Redfish and IPMI endpoints.
+type Scanner struct {
+	// Concurrency is the maximum number of endpoints probed in parallel.
+	Concurrency int
+	// Timeout is the timeout of a single probe.
+	Timeout time.Duration
+	// ProbeIPMI enables probing for IPMI endpoints.
+	ProbeIPMI bool
+	// IPMIPort is the UDP port probed for IPMI endpoints.
+	IPMIPort int
+	// HTTPClient is the client used to probe Redfish endpoints.
+	HTTPClient *http.Client
+}
+
+// NewScanner creates a new Scanner which does not verify the certificates of the
+// probed endpoints.
+func NewScanner(concurrency int, timeout time.Duration, probeIPMI bool) *Scanner {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Scanner{
+		Concurrency: concurrency,
+		Timeout:     timeout,
+		ProbeIPMI:   probeIPMI,
+		IPMIPort:    IPMIPort,
+		HTTPClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				// discovered endpoints typically use self signed certificates
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+			},
+		},
+	}
+}
+
+// ExpandAddresses returns all host addresses contained in the given CIDRs.
+func ExpandAddresses(cidrs []string) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CIDR %s: %w", cidr, err)
+		}
+		prefix = prefix.Masked()
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		if hostBits > 16 || len(addrs)+(1<<hostBits) > MaxTargets {
+			return nil, fmt.Errorf("CIDR %s exceeds the maximum of %d addresses", cidr, MaxTargets)
+		}
+		for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+			// skip the network and broadcast address of IPv4 ranges
+			if addr.Is4() && hostBits > 1 && (addr == prefix.Addr() || !prefix.Contains(addr.Next())) {
+				continue
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+// Scan probes the given addresses for Redfish service roots on the given ports and,
+// if enabled, for IPMI endpoints. The returned endpoints are sorted by address.
+func (s *Scanner) Scan(ctx context.Context, addrs []netip.Addr, scheme string, ports []int32) []Endpoint {
+	type target struct {
+		addr netip.Addr
+		port int
+		ipmi bool
+	}
+
+	targets := make(chan target)
+	go func() {
+		defer close(targets)
+		for _, addr := range addrs {
+			for _, port := range ports {
+				select {
+				case targets <- target{addr: addr, port: int(port)}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if s.ProbeIPMI {
+				select {
+				case targets <- target{addr: addr, port: s.IPMIPort, ipmi: true}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		endpoints []Endpoint
+	)
+	for i := 0; i < s.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range targets {
+				hostPort := net.JoinHostPort(t.addr.String(), strconv.Itoa(t.port))
+				var (
+					endpoint *Endpoint
+					err      error
+				)
+				if t.ipmi {
+					endpoint, err = s.probeIPMI(ctx, hostPort)
+				} else {
+					endpoint, err = s.probeRedfish(ctx, fmt.Sprintf("%s://%s", scheme, hostPort))
+				}
+				if err != nil || endpoint == nil {
+					continue
+				}
+				mu.Lock()
+				endpoints = append(endpoints, *endpoint)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].Address < endpoints[j].Address
+	})
+	return endpoints
+}
@@ -0,0 +1,120 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// newRedfishStub creates a server serving a Redfish service root which only accepts
+// the given credentials.
+func newRedfishStub(username, password string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redfish/v1/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"RedfishVersion":"1.11.0","UUID":"92384634-2938-2342-8820-489239905423","Vendor":"Contoso","Product":"Stub"}`))
+	})
+	mux.HandleFunc("/redfish/v1/Systems", func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != username || p != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"Members":[]}`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func serverPort(server *httptest.Server) int32 {
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	Expect(err).NotTo(HaveOccurred())
+	p, err := strconv.Atoi(port)
+	Expect(err).NotTo(HaveOccurred())
+	return int32(p)
+}
+
+var _ = Describe("ExpandAddresses", func() {
+	It("should skip the network and broadcast addresses", func() {
+		addrs, err := ExpandAddresses([]string{"10.0.0.0/30"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(Equal([]netip.Addr{
+			netip.MustParseAddr("10.0.0.1"),
+			netip.MustParseAddr("10.0.0.2"),
+		}))
+	})
+
+	It("should return a single address for a host prefix", func() {
+		addrs, err := ExpandAddresses([]string{"10.0.0.5/32"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(Equal([]netip.Addr{netip.MustParseAddr("10.0.0.5")}))
+	})
+
+	It("should reject invalid and too large ranges", func() {
+		_, err := ExpandAddresses([]string{"10.0.0.0"})
+		Expect(err).To(HaveOccurred())
+		_, err = ExpandAddresses([]string{"10.0.0.0/8"})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Scanner", func() {
+	var (
+		server  *httptest.Server
+		scanner *Scanner
+	)
+
+	BeforeEach(func() {
+		server = newRedfishStub("admin", "secret")
+		DeferCleanup(server.Close)
+		scanner = NewScanner(4, time.Second, false)
+	})
+
+	It("should find the Redfish service root", func(ctx SpecContext) {
+		addrs, err := ExpandAddresses([]string{"127.0.0.1/32"})
+		Expect(err).NotTo(HaveOccurred())
+
+		endpoints := scanner.Scan(ctx, addrs, "http", []int32{serverPort(server)})
+		Expect(endpoints).To(Equal([]Endpoint{{
+			Address:  server.URL,
+			Protocol: ProtocolRedfish,
+			Vendor:   "Contoso",
+			Product:  "Stub",
+			UUID:     "92384634-2938-2342-8820-489239905423",
+		}}))
+	})
+
+	It("should ignore endpoints which do not serve a Redfish service root", func(ctx SpecContext) {
+		other := httptest.NewServer(http.NotFoundHandler())
+		DeferCleanup(other.Close)
+		addrs, err := ExpandAddresses([]string{"127.0.0.1/32"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(scanner.Scan(ctx, addrs, "http", []int32{serverPort(other)})).To(BeEmpty())
+	})
+
+	It("should stop scanning once the context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		addrs, err := ExpandAddresses([]string{"127.0.0.0/24"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(scanner.Scan(ctx, addrs, "http", []int32{serverPort(server)})).To(BeEmpty())
+	})
+
+	It("should authenticate with the accepted credentials only", func(ctx SpecContext) {
+		ok, err := scanner.Authenticate(ctx, server.URL, "admin", "secret")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		ok, err = scanner.Authenticate(ctx, server.URL, "admin", "wrong")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+})
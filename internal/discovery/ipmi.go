@@ -0,0 +1,52 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"time"
+)
+
+// IPMIPort is the default RMCP port of IPMI endpoints.
+const IPMIPort = 623
+
+// rmcpPresencePing is an ASF presence ping wrapped in an RMCP header.
+var rmcpPresencePing = []byte{
+	0x06, 0x00, 0xff, 0x06, // RMCP header: version, reserved, sequence, class ASF
+	0x00, 0x00, 0x11, 0xbe, // IANA enterprise number of ASF
+	0x80, 0x00, 0x00, 0x00, // message type presence ping, tag, reserved, data length
+}
+
+// asfPresencePong is the message type of an ASF presence pong.
+const asfPresencePong = 0x40
+
+// probeIPMI sends an RMCP presence ping to the given address and waits for a pong.
+func (s *Scanner) probeIPMI(ctx context.Context, hostPort string) (*Endpoint, error) {
+	dialer := &net.Dialer{Timeout: s.Timeout}
+	conn, err := dialer.DialContext(ctx, "udp", hostPort)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(s.Timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(rmcpPresencePing); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	if n < 9 || !bytes.Equal(buf[:4], rmcpPresencePing[:4]) || buf[8] != asfPresencePong {
+		return nil, nil
+	}
+
+	return &Endpoint{
+		Address:  hostPort,
+		Protocol: ProtocolIPMI,
+	}, nil
+}
@@ -0,0 +1,86 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	serviceRootPath = "/redfish/v1/"
+	systemsPath     = "/redfish/v1/Systems"
+)
+
+type serviceRoot struct {
+	RedfishVersion string `json:"RedfishVersion"`
+	UUID           string `json:"UUID"`
+	Vendor         string `json:"Vendor"`
+	Product        string `json:"Product"`
+}
+
+// probeRedfish checks whether a Redfish service root is served at the given URL. The
+// service root does not require authentication.
+func (s *Scanner) probeRedfish(ctx context.Context, url string) (*Endpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+serviceRootPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	root := &serviceRoot{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(root); err != nil {
+		return nil, fmt.Errorf("failed to decode service root: %w", err)
+	}
+	if root.RedfishVersion == "" {
+		return nil, fmt.Errorf("no Redfish service root found at %s", url)
+	}
+
+	return &Endpoint{
+		Address:  url,
+		Protocol: ProtocolRedfish,
+		Vendor:   root.Vendor,
+		Product:  root.Product,
+		UUID:     root.UUID,
+	}, nil
+}
+
+// Authenticate checks whether the Redfish endpoint at the given URL accepts the given credentials.
+func (s *Scanner) Authenticate(ctx context.Context, url, username, password string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+systemsPath, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(username, password)
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+}
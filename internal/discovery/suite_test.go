@@ -0,0 +1,14 @@
+package discovery
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDiscovery(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Discovery Suite")
+}
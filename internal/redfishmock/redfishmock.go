@@ -0,0 +1,525 @@
+// Package redfishmock provides an in-process Redfish service for tests and local development.
+package redfishmock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stmcginnis/gofish/common"
+	"github.com/stmcginnis/gofish/redfish"
+)
+
+const (
+	rootPath     = "/redfish/v1/"
+	systemsPath  = "/redfish/v1/Systems"
+	managersPath = "/redfish/v1/Managers"
+	sessionsPath = "/redfish/v1/SessionService/Sessions"
+)
+
+// NetworkInterface is an ethernet interface of a mocked system.
+type NetworkInterface struct {
+	ID                  string `json:"id"`
+	MACAddress          string `json:"macAddress"`
+	PermanentMACAddress string `json:"permanentMacAddress,omitempty"`
+}
+
+// Processor is a processor of a mocked system.
+type Processor struct {
+	ID                    string `json:"id"`
+	ProcessorType         string `json:"processorType,omitempty"`
+	ProcessorArchitecture string `json:"processorArchitecture,omitempty"`
+	InstructionSet        string `json:"instructionSet,omitempty"`
+	Manufacturer          string `json:"manufacturer,omitempty"`
+	Model                 string `json:"model,omitempty"`
+	MaxSpeedMHz           int    `json:"maxSpeedMHz,omitempty"`
+	TotalCores            int    `json:"totalCores,omitempty"`
+	TotalThreads          int    `json:"totalThreads,omitempty"`
+}
+
+// System is a mocked computer system.
+type System struct {
+	ID                string             `json:"id"`
+	UUID              string             `json:"uuid"`
+	Manufacturer      string             `json:"manufacturer,omitempty"`
+	Model             string             `json:"model,omitempty"`
+	SerialNumber      string             `json:"serialNumber,omitempty"`
+	PowerState        redfish.PowerState `json:"powerState,omitempty"`
+	Health            common.Health      `json:"health,omitempty"`
+	NetworkInterfaces []NetworkInterface `json:"networkInterfaces,omitempty"`
+	Processors        []Processor        `json:"processors,omitempty"`
+	Boot              redfish.Boot       `json:"-"`
+}
+
+// Manager is the mocked BMC.
+type Manager struct {
+	ID              string `json:"id"`
+	Manufacturer    string `json:"manufacturer,omitempty"`
+	Model           string `json:"model,omitempty"`
+	FirmwareVersion string `json:"firmwareVersion,omitempty"`
+}
+
+// Fault makes the service answer matching requests with an error.
+type Fault struct {
+	// Method is the HTTP method to match. An empty method matches all methods.
+	Method string
+	// PathPrefix is the prefix of the request path to match.
+	PathPrefix string
+	// StatusCode is the status code returned for matching requests.
+	StatusCode int
+	// Count is the number of requests failed before the fault is removed. Zero fails all
+	// matching requests.
+	Count int
+}
+
+type powerTransition struct {
+	target   redfish.PowerState
+	deadline time.Time
+}
+
+// Service is an http.Handler serving a mocked Redfish API.
+type Service struct {
+	mu sync.Mutex
+
+	manager          Manager
+	systems          []*System
+	transitions      map[string]*powerTransition
+	bootOverrides    map[string][]redfish.Boot
+	faults           []*Fault
+	sessions         map[string]bool
+	username         string
+	password         string
+	transitionPeriod time.Duration
+}
+
+// Option configures a Service.
+type Option func(*Service)
+
+// WithCredentials requires the given credentials for all requests except the service root.
+func WithCredentials(username, password string) Option {
+	return func(s *Service) {
+		s.username = username
+		s.password = password
+	}
+}
+
+// WithPowerTransitionDelay sets the duration a system needs to reach a requested power state.
+func WithPowerTransitionDelay(delay time.Duration) Option {
+	return func(s *Service) {
+		s.transitionPeriod = delay
+	}
+}
+
+// WithManager sets the mocked BMC information.
+func WithManager(manager Manager) Option {
+	return func(s *Service) {
+		s.manager = manager
+	}
+}
+
+// NewService creates a new Service serving the given systems.
+func NewService(systems []System, opts ...Option) *Service {
+	s := &Service{
+		manager: Manager{
+			ID:              "BMC",
+			Manufacturer:    "Contoso",
+			Model:           "Mock BMC",
+			FirmwareVersion: "1.0.0",
+		},
+		transitions:   map[string]*powerTransition{},
+		bootOverrides: map[string][]redfish.Boot{},
+		sessions:      map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for i := range systems {
+		s.AddSystem(systems[i])
+	}
+	return s
+}
+
+// AddSystem adds a system to the service.
+func (s *Service) AddSystem(system System) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if system.PowerState == "" {
+		system.PowerState = redfish.OffPowerState
+	}
+	if system.Health == "" {
+		system.Health = common.OKHealth
+	}
+	s.systems = append(s.systems, &system)
+}
+
+// System returns a copy of the system with the given ID.
+func (s *Service) System(id string) (System, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	system := s.getSystem(id)
+	if system == nil {
+		return System{}, false
+	}
+	return *system, true
+}
+
+// SetPowerState sets the power state of a system immediately.
+func (s *Service) SetPowerState(id string, state redfish.PowerState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if system := s.getSystem(id); system != nil {
+		delete(s.transitions, id)
+		system.PowerState = state
+	}
+}
+
+// BootOverrides returns all boot overrides set on the system with the given ID.
+func (s *Service) BootOverrides(id string) []redfish.Boot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]redfish.Boot(nil), s.bootOverrides[id]...)
+}
+
+// InjectFault adds a fault to the service.
+func (s *Service) InjectFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes all faults from the service.
+func (s *Service) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// getSystem returns the system with the given ID and applies due power transitions. The
+// caller must hold the lock.
+func (s *Service) getSystem(id string) *System {
+	for _, system := range s.systems {
+		if system.ID != id {
+			continue
+		}
+		if transition, ok := s.transitions[id]; ok && !time.Now().Before(transition.deadline) {
+			system.PowerState = transition.target
+			delete(s.transitions, id)
+		}
+		return system
+	}
+	return nil
+}
+
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if statusCode, ok := s.matchFault(r); ok {
+		writeError(w, statusCode, "injected fault")
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path+"/" == rootPath:
+		s.serveRoot(w, r)
+		return
+	case path == sessionsPath && r.Method == http.MethodPost:
+		s.createSession(w, r)
+		return
+	}
+
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	segments := strings.Split(strings.TrimPrefix(path, "/redfish/v1/"), "/")
+	switch {
+	case strings.HasPrefix(path, sessionsPath+"/") && r.Method == http.MethodDelete:
+		delete(s.sessions, r.Header.Get("X-Auth-Token"))
+		w.WriteHeader(http.StatusNoContent)
+	case path == managersPath:
+		writeCollection(w, managersPath, []string{s.manager.ID})
+	case path == managersPath+"/"+s.manager.ID:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"@odata.id":       path,
+			"Id":              s.manager.ID,
+			"Manufacturer":    s.manager.Manufacturer,
+			"Model":           s.manager.Model,
+			"FirmwareVersion": s.manager.FirmwareVersion,
+		})
+	case path == systemsPath:
+		ids := make([]string, 0, len(s.systems))
+		for _, system := range s.systems {
+			ids = append(ids, system.ID)
+		}
+		writeCollection(w, systemsPath, ids)
+	case segments[0] == "Systems" && len(segments) > 1:
+		system := s.getSystem(segments[1])
+		if system == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("system %s not found", segments[1]))
+			return
+		}
+		s.serveSystem(w, r, system, segments[2:])
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("resource %s not found", path))
+	}
+}
+
+func (s *Service) matchFault(r *http.Request) (int, bool) {
+	for i, fault := range s.faults {
+		if (fault.Method == "" || fault.Method == r.Method) && strings.HasPrefix(r.URL.Path, fault.PathPrefix) {
+			if fault.Count > 0 {
+				fault.Count--
+				if fault.Count == 0 {
+					s.faults = append(s.faults[:i], s.faults[i+1:]...)
+				}
+			}
+			return fault.StatusCode, true
+		}
+	}
+	return 0, false
+}
+
+func (s *Service) authorized(r *http.Request) bool {
+	if s.username == "" {
+		return true
+	}
+	if s.sessions[r.Header.Get("X-Auth-Token")] {
+		return true
+	}
+	username, password, ok := r.BasicAuth()
+	return ok && username == s.username && password == s.password
+}
+
+func (s *Service) serveRoot(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"@odata.id":      rootPath,
+		"Id":             "RootService",
+		"RedfishVersion": "1.11.0",
+		"UUID":           "00000000-0000-0000-0000-000000000000",
+		"Vendor":         s.manager.Manufacturer,
+		"Product":        s.manager.Model,
+		"Systems":        link(systemsPath),
+		"Managers":       link(managersPath),
+		"SessionService": link("/redfish/v1/SessionService"),
+		"Links": map[string]any{
+			"Sessions": link(sessionsPath),
+		},
+	})
+}
+
+func (s *Service) createSession(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		UserName string
+		Password string
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if s.username != "" && (payload.UserName != s.username || payload.Password != s.password) {
+		writeError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	token := hex.EncodeToString(buf)
+	s.sessions[token] = true
+
+	w.Header().Set("X-Auth-Token", token)
+	w.Header().Set("Location", sessionsPath+"/"+token[:8])
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"@odata.id": sessionsPath + "/" + token[:8],
+		"Id":        token[:8],
+		"UserName":  payload.UserName,
+	})
+}
+
+func (s *Service) serveSystem(w http.ResponseWriter, r *http.Request, system *System, segments []string) {
+	systemPath := systemsPath + "/" + system.ID
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"@odata.id":    systemPath,
+			"Id":           system.ID,
+			"UUID":         system.UUID,
+			"Manufacturer": system.Manufacturer,
+			"Model":        system.Model,
+			"SerialNumber": system.SerialNumber,
+			"PowerState":   system.PowerState,
+			"Status": map[string]any{
+				"State":  common.EnabledState,
+				"Health": system.Health,
+			},
+			"Boot":               system.Boot,
+			"EthernetInterfaces": link(systemPath + "/EthernetInterfaces"),
+			"Processors":         link(systemPath + "/Processors"),
+			"Actions": map[string]any{
+				"#ComputerSystem.Reset": map[string]any{
+					"target": systemPath + "/Actions/ComputerSystem.Reset",
+					"ResetType@Redfish.AllowableValues": []redfish.ResetType{
+						redfish.OnResetType,
+						redfish.ForceOffResetType,
+						redfish.GracefulShutdownResetType,
+						redfish.GracefulRestartResetType,
+						redfish.ForceRestartResetType,
+						redfish.PowerCycleResetType,
+					},
+				},
+			},
+		})
+	case len(segments) == 0 && r.Method == http.MethodPatch:
+		s.patchSystem(w, r, system)
+	case len(segments) == 2 && segments[0] == "Actions" && segments[1] == "ComputerSystem.Reset" && r.Method == http.MethodPost:
+		s.resetSystem(w, r, system)
+	case len(segments) == 1 && segments[0] == "EthernetInterfaces":
+		ids := make([]string, 0, len(system.NetworkInterfaces))
+		for _, nic := range system.NetworkInterfaces {
+			ids = append(ids, nic.ID)
+		}
+		writeCollection(w, systemPath+"/EthernetInterfaces", ids)
+	case len(segments) == 2 && segments[0] == "EthernetInterfaces":
+		for _, nic := range system.NetworkInterfaces {
+			if nic.ID == segments[1] {
+				writeJSON(w, http.StatusOK, map[string]any{
+					"@odata.id":           systemPath + "/EthernetInterfaces/" + nic.ID,
+					"Id":                  nic.ID,
+					"MACAddress":          nic.MACAddress,
+					"PermanentMACAddress": nic.PermanentMACAddress,
+				})
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, fmt.Sprintf("ethernet interface %s not found", segments[1]))
+	case len(segments) == 1 && segments[0] == "Processors":
+		ids := make([]string, 0, len(system.Processors))
+		for _, processor := range system.Processors {
+			ids = append(ids, processor.ID)
+		}
+		writeCollection(w, systemPath+"/Processors", ids)
+	case len(segments) == 2 && segments[0] == "Processors":
+		for _, processor := range system.Processors {
+			if processor.ID == segments[1] {
+				writeJSON(w, http.StatusOK, map[string]any{
+					"@odata.id":             systemPath + "/Processors/" + processor.ID,
+					"Id":                    processor.ID,
+					"ProcessorType":         processor.ProcessorType,
+					"ProcessorArchitecture": processor.ProcessorArchitecture,
+					"InstructionSet":        processor.InstructionSet,
+					"Manufacturer":          processor.Manufacturer,
+					"Model":                 processor.Model,
+					"MaxSpeedMHz":           processor.MaxSpeedMHz,
+					"TotalCores":            processor.TotalCores,
+					"TotalThreads":          processor.TotalThreads,
+				})
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, fmt.Sprintf("processor %s not found", segments[1]))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("resource %s not found", r.URL.Path))
+	}
+}
+
+func (s *Service) patchSystem(w http.ResponseWriter, r *http.Request, system *System) {
+	var payload struct {
+		PowerState redfish.PowerState
+		Boot       *redfish.Boot
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if payload.PowerState != "" && payload.PowerState != system.PowerState {
+		s.transition(system, payload.PowerState)
+	}
+	if payload.Boot != nil && !reflect.DeepEqual(*payload.Boot, system.Boot) {
+		system.Boot = *payload.Boot
+		s.bootOverrides[system.ID] = append(s.bootOverrides[system.ID], *payload.Boot)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) resetSystem(w http.ResponseWriter, r *http.Request, system *System) {
+	var payload struct {
+		ResetType redfish.ResetType
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	switch payload.ResetType {
+	case redfish.OnResetType:
+		s.transition(system, redfish.OnPowerState)
+	case redfish.ForceOffResetType, redfish.GracefulShutdownResetType:
+		s.transition(system, redfish.OffPowerState)
+	case redfish.ForceRestartResetType, redfish.GracefulRestartResetType, redfish.PowerCycleResetType:
+		system.PowerState = redfish.OffPowerState
+		s.transition(system, redfish.OnPowerState)
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unsupported reset type %s", payload.ResetType))
+		return
+	}
+	if system.Boot.BootSourceOverrideEnabled == redfish.OnceBootSourceOverrideEnabled && payload.ResetType != redfish.ForceOffResetType && payload.ResetType != redfish.GracefulShutdownResetType {
+		// a one time boot override is consumed by the next boot
+		system.Boot.BootSourceOverrideEnabled = redfish.DisabledBootSourceOverrideEnabled
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// transition moves the system to the target power state after the configured delay.
+func (s *Service) transition(system *System, target redfish.PowerState) {
+	if s.transitionPeriod <= 0 {
+		system.PowerState = target
+		return
+	}
+	if target == redfish.OnPowerState {
+		system.PowerState = redfish.PoweringOnPowerState
+	} else {
+		system.PowerState = redfish.PoweringOffPowerState
+	}
+	s.transitions[system.ID] = &powerTransition{target: target, deadline: time.Now().Add(s.transitionPeriod)}
+}
+
+func link(path string) map[string]string {
+	return map[string]string{"@odata.id": path}
+}
+
+func writeCollection(w http.ResponseWriter, path string, ids []string) {
+	members := make([]map[string]string, 0, len(ids))
+	for _, id := range ids {
+		members = append(members, link(path+"/"+id))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"@odata.id":           path,
+		"Members":             members,
+		"Members@odata.count": len(members),
+	})
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]any{
+		"error": map[string]any{
+			"code":    "Base.1.0.GeneralError",
+			"message": message,
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}